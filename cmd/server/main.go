@@ -2,48 +2,370 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+
+	"url-shortener/internal/clientip"
 	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/middleware"
 	"url-shortener/internal/repository"
 	"url-shortener/internal/server"
 	"url-shortener/internal/service"
 	"url-shortener/internal/shortcode"
+	"url-shortener/internal/webhook"
 )
 
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
 func main() {
 	port := getEnvInt("PORT", 8080)
 	shutdownTimeout := getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second)
 	baseURL := getEnvString("BASE_URL", fmt.Sprintf("http://localhost:%d", port))
+	appEnv := getEnvString("APP_ENV", "production")
 
+	var err error
 	cfg := server.Config{
-		Port:            port,
-		ShutdownTimeout: shutdownTimeout,
-		BaseURL:         baseURL,
+		Port:                  port,
+		Version:               version,
+		ShutdownTimeout:       shutdownTimeout,
+		DrainDelay:            getEnvDuration("SHUTDOWN_DRAIN_DELAY", 0),
+		RequestTimeout:        getEnvDuration("REQUEST_TIMEOUT", 5*time.Second),
+		ReadTimeout:           getEnvDuration("READ_TIMEOUT", 0),
+		ReadHeaderTimeout:     getEnvDuration("READ_HEADER_TIMEOUT", 0),
+		WriteTimeout:          getEnvDuration("WRITE_TIMEOUT", 0),
+		IdleTimeout:           getEnvDuration("IDLE_TIMEOUT", 0),
+		MaxInFlight:           getEnvInt("MAX_INFLIGHT", 0),
+		BaseURL:               baseURL,
+		TLSCertFile:           getEnvString("TLS_CERT", ""),
+		TLSKeyFile:            getEnvString("TLS_KEY", ""),
+		APIKeys:               getEnvList("API_KEYS"),
+		AdminAPIKey:           getEnvString("ADMIN_API_KEY", ""),
+		ReloadFile:            getEnvString("CONFIG_RELOAD_FILE", ""),
+		BlockPrivateURLs:      getEnvBool("BLOCK_PRIVATE_URLS", false),
+		VerifyDestinations:    getEnvBool("VERIFY_DESTINATIONS", false),
+		ValidateRequestSchema: getEnvBool("VALIDATE_REQUEST_SCHEMA", false),
+		GoneForExpired:        getEnvBool("GONE_FOR_EXPIRED", false),
+		TrimCodeSuffixes:      getEnvBool("TRIM_CODE_SUFFIXES", false),
+		RootRedirectURL:       getEnvString("ROOT_REDIRECT_URL", ""),
+		ForwardedBaseURL:      getEnvBool("FORWARDED_BASE_URL", false),
+		SortQueryParams:       getEnvBool("SORT_QUERY_PARAMS", false),
+		MinURLLength:          getEnvInt("MIN_URL_LENGTH", 0),
+		ShortenRateLimit: middleware.RateLimitConfig{
+			Rate:  getEnvFloat("RATE_LIMIT_RPS", 0),
+			Burst: getEnvInt("RATE_LIMIT_BURST", 10),
+		},
+		LogSampling: middleware.LogSampling{
+			Every: getEnvInt("LOG_SAMPLE_EVERY", 0),
+			Rate:  getEnvFloat("LOG_SAMPLE_RATE", 0),
+		},
+		CORS: middleware.CORSConfig{
+			AllowedOrigins: getEnvList("CORS_ALLOWED_ORIGINS"),
+			MaxAge:         getEnvDuration("CORS_MAX_AGE", 10*time.Minute),
+		},
+	}
+
+	if rate := cfg.LogSampling.Rate; rate < 0 || rate > 1 {
+		slog.Error("LOG_SAMPLE_RATE must be between 0 and 1", "log_sample_rate", rate)
+		os.Exit(1)
+	}
+	if cfg.LogSampling.Every > 1 && cfg.LogSampling.Rate > 0 {
+		slog.Error("LOG_SAMPLE_EVERY and LOG_SAMPLE_RATE cannot both be set")
+		os.Exit(1)
+	}
+
+	cfg.APIKeyCodePrefixes, err = middleware.ParseAPIKeyCodePrefixes(getEnvList("API_KEY_CODE_PREFIXES"))
+	if err != nil {
+		slog.Error("invalid API_KEY_CODE_PREFIXES", "error", err)
+		os.Exit(1)
+	}
+	for key := range cfg.APIKeyCodePrefixes {
+		if !slices.Contains(cfg.APIKeys, key) {
+			slog.Error("API_KEY_CODE_PREFIXES names a key missing from API_KEYS")
+			os.Exit(1)
+		}
+	}
+
+	cfg.ClientIP, err = newClientIPResolver()
+	if err != nil {
+		slog.Error("invalid TRUSTED_PROXIES", "error", err)
+		os.Exit(1)
+	}
+
+	if domains := getEnvList("SHORT_DOMAINS"); len(domains) > 0 {
+		cfg.BaseURLFunc, err = handler.BaseURLForHost(domains)
+		if err != nil {
+			slog.Error("invalid SHORT_DOMAINS", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	cfg.JSONCase, err = handler.ParseJSONCase(getEnvString("JSON_CASE", ""))
+	if err != nil {
+		slog.Error("invalid JSON_CASE", "error", err)
+		os.Exit(1)
+	}
+
+	if path := getEnvString("REDIRECT_ERROR_TEMPLATE", ""); path != "" {
+		cfg.RedirectErrorTemplate, err = handler.ParseRedirectErrorTemplate(path)
+		if err != nil {
+			slog.Error("invalid REDIRECT_ERROR_TEMPLATE", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	cfg.CodeAlphabet = getEnvString("CODE_ALPHABET", shortcode.Alphabet)
+
+	cfg.URLSchemes, err = handler.ParseURLSchemes(getEnvList("ALLOWED_URL_SCHEMES"))
+	if err != nil {
+		slog.Error("invalid ALLOWED_URL_SCHEMES", "error", err)
+		os.Exit(1)
+	}
+
+	cfg.TTL, err = loadTTLPolicy()
+	if err != nil {
+		slog.Error("invalid TTL configuration", "error", err)
+		os.Exit(1)
+	}
+
+	cfg.AnonymousMaxTTL = getEnvDuration("ANONYMOUS_MAX_TTL", 0)
+	if cfg.AnonymousMaxTTL > 0 && cfg.AnonymousMaxTTL < cfg.TTL.Min {
+		slog.Error("ANONYMOUS_MAX_TTL is below MIN_TTL", "anonymous_max_ttl", cfg.AnonymousMaxTTL, "min_ttl", cfg.TTL.Min)
+		os.Exit(1)
+	}
+
+	cfg.BlockedHosts, err = loadBlockedHosts()
+	if err != nil {
+		slog.Error("failed to load blocked hosts", "error", err)
+		os.Exit(1)
 	}
 
 	// Initialize dependencies
-	repo := repository.NewMemoryRepository()
-	generator := shortcode.NewGenerator()
-	clock := domain.RealClock{}
-	urlService := service.NewURLService(repo, generator, clock)
+	repo, closeRepo, err := repository.NewFromConfig(repository.Config{
+		Storage:           getEnvString("STORAGE", repository.StorageMemory),
+		MemoryMaxRecords:  getEnvInt("MEMORY_MAX_RECORDS", 0),
+		MemoryShards:      getEnvInt("MEMORY_SHARDS", 0),
+		MemoryEviction:    getEnvString("MEMORY_EVICTION", repository.EvictionReject),
+		DataFile:          getEnvString("DATA_FILE", ""),
+		DataFlushInterval: getEnvDuration("DATA_FLUSH_INTERVAL", 0),
+		DBPath:            getEnvString("DB_PATH", ""),
+
+		ClickCoalesceInterval: getEnvDuration("CLICK_COALESCE_INTERVAL", 0),
+	})
+	if err != nil {
+		slog.Error("failed to initialize storage", "error", err)
+		os.Exit(1)
+	}
+	cfg.Readiness = repo
+
+	generator, err := newGenerator()
+	if err != nil {
+		slog.Error("invalid short code configuration", "error", err)
+		os.Exit(1)
+	}
+	var clock domain.Clock = domain.RealClock{}
+
+	// The dev clock lets tests fast-forward time over HTTP. It is refused
+	// outright unless APP_ENV explicitly names a non-production environment.
+	if getEnvBool("DEV_CLOCK", false) {
+		if appEnv != "development" && appEnv != "test" {
+			slog.Error("DEV_CLOCK requires APP_ENV=development or APP_ENV=test", "app_env", appEnv)
+			os.Exit(1)
+		}
+		devClock := domain.NewAdjustableClock(domain.RealClock{})
+		cfg.DevClock = devClock
+		clock = devClock
+		slog.Warn("dev clock enabled: /admin/clock endpoints can change service time")
+	}
+
+	cfg.Clock = clock
+
+	serviceOpts := []service.Option{
+		service.WithClickDedupWindow(getEnvDuration("CLICK_DEDUP_WINDOW", 0)),
+		service.WithTTLPolicy(cfg.TTL),
+		service.WithIdempotencyWindow(getEnvDuration("IDEMPOTENCY_WINDOW", 24*time.Hour)),
+		service.WithDeleteRetention(getEnvDuration("DELETE_RETENTION", service.DefaultDeleteRetention)),
+		service.WithMaxRetries(getEnvInt("MAX_CODE_RETRIES", service.DefaultMaxRetries)),
+		service.WithDestinationCheck(handler.DestinationAllowed(cfg.URLSchemes)),
+		service.WithSaveRetries(service.SaveRetryPolicy{
+			Retries:        getEnvInt("SAVE_RETRIES", 2),
+			InitialBackoff: getEnvDuration("SAVE_RETRY_BACKOFF", service.DefaultSaveRetryBackoff),
+		}),
+	}
+	if getEnvBool("METRICS_ENABLED", true) {
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+		cfg.Metrics = middleware.NewMetrics(reg)
+		serviceOpts = append(serviceOpts, service.WithMetrics(cfg.Metrics))
+	}
+	if getEnvBool("LATENCY_ENABLED", false) {
+		cfg.Latency = middleware.NewLatencyRecorder(middleware.LatencyConfig{
+			Window: getEnvDuration("LATENCY_WINDOW", 5*time.Minute),
+			Size:   getEnvInt("LATENCY_SAMPLES", 10000),
+		})
+	}
+	if getEnvBool("BOT_FILTER", false) {
+		serviceOpts = append(serviceOpts, service.WithBotFilter(getEnvList("BOT_USER_AGENTS")))
+	}
+	if prefix := getEnvString("CODE_PREFIX", ""); prefix != "" {
+		if err := shortcode.ValidatePrefix(prefix); err != nil {
+			slog.Error("invalid CODE_PREFIX", "error", err)
+			os.Exit(1)
+		}
+		serviceOpts = append(serviceOpts, service.WithCodePrefix(prefix))
+	}
+	if len(cfg.APIKeyCodePrefixes) > 0 {
+		tenants := slices.Collect(maps.Values(cfg.APIKeyCodePrefixes))
+		serviceOpts = append(serviceOpts, service.WithTenantPrefixes(tenants...))
+	}
+	if getEnvBool("REUSE_EXISTING_CODES", false) {
+		serviceOpts = append(serviceOpts, service.WithLongURLReuse())
+	}
+	if url := getEnvString("WEBHOOK_URL", ""); url != "" {
+		serviceOpts = append(serviceOpts, service.WithNotifier(webhook.New(webhook.Config{
+			URL:         url,
+			MaxAttempts: getEnvInt("WEBHOOK_MAX_ATTEMPTS", 0),
+		})))
+	}
+	serviceOpts = append(serviceOpts, service.WithAsyncClicks(
+		getEnvInt("CLICK_BUFFER", 0),
+		getEnvDuration("CLICK_FLUSH_INTERVAL", time.Second),
+	))
+
+	urlService := service.NewURLServiceWithGenerator(repo, generator, clock, serviceOpts...)
+	if cfg.Metrics != nil {
+		cfg.Metrics.TrackCodeSpaceSaturation(urlService)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go urlService.RunReaper(ctx, getEnvDuration("REAP_INTERVAL", time.Hour))
 
 	srv := server.New(cfg, urlService)
+	if cfg.ReloadFile != "" {
+		if err := srv.Reload(); err != nil {
+			slog.Error("invalid CONFIG_RELOAD_FILE", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	slog.Info("starting server", "port", port, "tls", cfg.TLSCertFile != "")
 
-	slog.Info("starting server", "port", port)
+	err = srv.Run(ctx)
+
+	// Write buffered clicks and finish webhook deliveries once no more
+	// requests can arrive
+	closeCtx, cancelClose := context.WithTimeout(context.Background(), shutdownTimeout)
+	if closeErr := urlService.Close(closeCtx); closeErr != nil {
+		slog.Error("closing URL service", "error", closeErr)
+	}
+	cancelClose()
 
-	if err := srv.Run(context.Background()); err != nil {
-		slog.Error("server error", "error", err)
+	// Closed here rather than deferred, as os.Exit below would skip it and
+	// lose the final data file snapshot and coalesced clicks.
+	if closeErr := closeRepo(); closeErr != nil {
+		slog.Error("failed to close storage", "error", closeErr)
+	}
+
+	if err != nil {
+		if errors.Is(err, server.ErrTLSConfig) {
+			slog.Error("check TLS_CERT and TLS_KEY", "error", err)
+		} else {
+			slog.Error("server error", "error", err)
+		}
 		os.Exit(1)
 	}
 
 	slog.Info("server stopped gracefully")
 }
 
+// newClientIPResolver trusts forwarding headers from the proxies listed in
+// TRUSTED_PROXIES. The older TRUST_FORWARDED_FOR=true trusts every peer.
+func newClientIPResolver() (*clientip.Resolver, error) {
+	trusted := getEnvList("TRUSTED_PROXIES")
+	if getEnvBool("TRUST_FORWARDED_FOR", false) {
+		trusted = append(trusted, clientip.TrustAll...)
+	}
+	return clientip.NewResolver(trusted)
+}
+
+// loadTTLPolicy reads DEFAULT_TTL, MIN_TTL, and MAX_TTL, falling back to
+// domain.DefaultTTLPolicy for any that are unset, and the optional
+// MAX_EXPIRY, an RFC 3339 time no link may expire after.
+func loadTTLPolicy() (domain.TTLPolicy, error) {
+	defaults := domain.DefaultTTLPolicy()
+	policy := domain.TTLPolicy{
+		Default: getEnvDuration("DEFAULT_TTL", defaults.Default),
+		Min:     getEnvDuration("MIN_TTL", defaults.Min),
+		Max:     getEnvDuration("MAX_TTL", defaults.Max),
+	}
+	if raw := os.Getenv("MAX_EXPIRY"); raw != "" {
+		maxExpiry, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return domain.TTLPolicy{}, fmt.Errorf("MAX_EXPIRY must be an RFC 3339 time: %w", err)
+		}
+		policy.MaxExpiryAbsolute = maxExpiry
+	}
+	if err := policy.Validate(); err != nil {
+		return domain.TTLPolicy{}, err
+	}
+	return policy, nil
+}
+
+// loadBlockedHosts combines the comma-separated BLOCKED_HOSTS with the
+// BLOCKED_HOSTS_FILE, which lists one host per line and allows # comments.
+func loadBlockedHosts() ([]string, error) {
+	hosts := getEnvList("BLOCKED_HOSTS")
+
+	path := getEnvString("BLOCKED_HOSTS_FILE", "")
+	if path == "" {
+		return hosts, nil
+	}
+
+	raw, err := os.ReadFile(path) //nolint:gosec // path comes from operator config
+	if err != nil {
+		return nil, fmt.Errorf("reading %q: %w", path, err)
+	}
+	for _, line := range strings.Split(string(raw), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		if line = strings.TrimSpace(line); line != "" {
+			hosts = append(hosts, line)
+		}
+	}
+	return hosts, nil
+}
+
+// newGenerator builds the short code generator. Setting CODE_HASH_SALT
+// switches from random codes to codes derived from the long URL.
+func newGenerator() (service.CodeGenerator, error) {
+	length := getEnvInt("CODE_LENGTH", shortcode.DefaultLength)
+	alphabet := getEnvString("CODE_ALPHABET", shortcode.Alphabet)
+
+	if salt := getEnvString("CODE_HASH_SALT", ""); salt != "" {
+		if getEnvBool("CODE_CHECKSUM", false) {
+			return nil, errors.New("CODE_CHECKSUM cannot be combined with CODE_HASH_SALT")
+		}
+		return shortcode.NewHashGenerator(salt, length, alphabet)
+	}
+
+	var opts []shortcode.Option
+	if getEnvBool("CODE_CHECKSUM", false) {
+		opts = append(opts, shortcode.WithChecksum())
+	}
+	return shortcode.NewGeneratorWithConfig(length, alphabet, opts...)
+}
+
 func getEnvInt(key string, defaultVal int) int {
 	if val := os.Getenv(key); val != "" {
 		if i, err := strconv.Atoi(val); err == nil {
@@ -53,6 +375,15 @@ func getEnvInt(key string, defaultVal int) int {
 	return defaultVal
 }
 
+func getEnvFloat(key string, defaultVal float64) float64 {
+	if val := os.Getenv(key); val != "" {
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+	}
+	return defaultVal
+}
+
 func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
 	if val := os.Getenv(key); val != "" {
 		if d, err := time.ParseDuration(val); err == nil {
@@ -62,6 +393,26 @@ func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
 	return defaultVal
 }
 
+func getEnvBool(key string, defaultVal bool) bool {
+	if val := os.Getenv(key); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+	return defaultVal
+}
+
+// getEnvList splits a comma-separated variable, dropping empty entries.
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func getEnvString(key string, defaultVal string) string {
 	if val := os.Getenv(key); val != "" {
 		return val
@@ -0,0 +1,119 @@
+// Package clientip works out which address a request came from when the
+// server may sit behind reverse proxies.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Resolver extracts client IPs, believing forwarding headers only from
+// trusted proxies. A nil *Resolver trusts no one and always returns the
+// direct peer's address.
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// TrustAll lists CIDRs covering every address, for deployments where the
+// server is only reachable through a proxy.
+var TrustAll = []string{"0.0.0.0/0", "::/0"}
+
+// NewResolver returns a Resolver trusting proxies in the given CIDRs, such
+// as "10.0.0.0/8". Bare addresses are accepted as single-host ranges.
+func NewResolver(trustedProxies []string) (*Resolver, error) {
+	res := &Resolver{}
+	for _, raw := range trustedProxies {
+		prefix, err := parsePrefix(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, err
+		}
+		res.trusted = append(res.trusted, prefix)
+	}
+	return res, nil
+}
+
+func parsePrefix(raw string) (netip.Prefix, error) {
+	if strings.Contains(raw, "/") {
+		prefix, err := netip.ParsePrefix(raw)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid trusted proxy %q: %w", raw, err)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(raw)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid trusted proxy %q: %w", raw, err)
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// ClientIP returns the address of the client that sent r. When the direct
+// peer is a trusted proxy, X-Forwarded-For is walked from the right,
+// skipping further trusted proxies, and the first other address is the
+// client; a proxy that sets X-Real-IP instead is also honored. Headers from
+// untrusted peers are ignored, since anyone can send them.
+func (res *Resolver) ClientIP(r *http.Request) string {
+	peer := peerAddr(r)
+	if !res.trusts(peer) {
+		return peer
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		return res.fromForwardedFor(forwarded, peer)
+	}
+	if realIP, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return realIP.Unmap().String()
+	}
+	return peer
+}
+
+// fromForwardedFor picks the client from X-Forwarded-For values. It stops
+// at the first malformed entry, as nothing left of it can be trusted, and
+// returns the last good hop instead.
+func (res *Resolver) fromForwardedFor(values []string, peer string) string {
+	hops := strings.Split(strings.Join(values, ","), ",")
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return client
+		}
+		client = addr.Unmap().String()
+		if !res.trusts(client) {
+			return client
+		}
+	}
+	return client
+}
+
+func (res *Resolver) trusts(ip string) bool {
+	if res == nil {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range res.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerAddr returns the host part of r.RemoteAddr.
+func peerAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.Unmap().String()
+	}
+	return host
+}
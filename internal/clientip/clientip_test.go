@@ -0,0 +1,77 @@
+package clientip_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"url-shortener/internal/clientip"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver_ClientIP(t *testing.T) {
+	res, err := clientip.NewResolver([]string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/32"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{name: "no headers", remoteAddr: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "spoofed forwarded-for from untrusted peer", remoteAddr: "203.0.113.7:5000", forwarded: []string{"198.51.100.1"}, want: "203.0.113.7"},
+		{name: "spoofed real-ip from untrusted peer", remoteAddr: "203.0.113.7:5000", realIP: "198.51.100.1", want: "203.0.113.7"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.1:5000", forwarded: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "trusted single address", remoteAddr: "192.0.2.1:5000", forwarded: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "chain of trusted proxies", remoteAddr: "10.0.0.1:5000", forwarded: []string{"203.0.113.7, 10.0.0.3, 10.0.0.2"}, want: "203.0.113.7"},
+		{
+			name:       "client-supplied entries left of the real client are ignored",
+			remoteAddr: "10.0.0.1:5000",
+			forwarded:  []string{"198.51.100.1, 203.0.113.7"},
+			want:       "203.0.113.7",
+		},
+		{name: "repeated headers", remoteAddr: "10.0.0.1:5000", forwarded: []string{"198.51.100.1", "203.0.113.7, 10.0.0.2"}, want: "203.0.113.7"},
+		{name: "only trusted hops", remoteAddr: "10.0.0.1:5000", forwarded: []string{"10.0.0.3, 10.0.0.2"}, want: "10.0.0.3"},
+		{name: "malformed entry stops the walk", remoteAddr: "10.0.0.1:5000", forwarded: []string{"garbage, 10.0.0.2"}, want: "10.0.0.2"},
+		{name: "real-ip from trusted proxy", remoteAddr: "10.0.0.1:5000", realIP: "203.0.113.7", want: "203.0.113.7"},
+		{name: "malformed real-ip", remoteAddr: "10.0.0.1:5000", realIP: "nope", want: "10.0.0.1"},
+		{name: "ipv6 proxy", remoteAddr: "[2001:db8::1]:5000", forwarded: []string{"2001:db9::7"}, want: "2001:db9::7"},
+		{name: "ipv4-mapped peer", remoteAddr: "[::ffff:10.0.0.1]:5000", forwarded: []string{"203.0.113.7"}, want: "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			assert.Equal(t, tt.want, res.ClientIP(req))
+		})
+	}
+}
+
+func TestResolver_NilTrustsNoOne(t *testing.T) {
+	var res *clientip.Resolver
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+	assert.Equal(t, "10.0.0.1", res.ClientIP(req))
+}
+
+func TestNewResolver_RejectsInvalidCIDRs(t *testing.T) {
+	for _, raw := range []string{"10.0.0.0/33", "not-an-ip", "10.0.0/8"} {
+		_, err := clientip.NewResolver([]string{raw})
+		assert.Error(t, err, raw)
+	}
+}
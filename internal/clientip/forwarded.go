@@ -0,0 +1,127 @@
+package clientip
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// ForwardedOrigin returns the scheme and host the client addressed, as
+// reported by a trusted proxy. The RFC 7239 Forwarded header is preferred;
+// otherwise X-Forwarded-Proto and X-Forwarded-Host are read from the
+// entry appended by the proxy the client connected to; see hopValue.
+// Either result is empty when not reported or malformed, and both are
+// empty for requests from untrusted peers.
+func (res *Resolver) ForwardedOrigin(r *http.Request) (proto, host string) {
+	if !res.trusts(peerAddr(r)) {
+		return "", ""
+	}
+
+	if values := r.Header.Values("Forwarded"); len(values) > 0 {
+		params := res.clientForwardedElement(values)
+		return validProto(params["proto"]), validHost(params["host"])
+	}
+	hops := res.trustedHops(r.Header.Values("X-Forwarded-For"))
+	return validProto(hopValue(r.Header.Values("X-Forwarded-Proto"), hops)),
+		validHost(hopValue(r.Header.Values("X-Forwarded-Host"), hops))
+}
+
+// trustedHops counts the X-Forwarded-For entries, from the right, that
+// name trusted proxies: the proxies between the one the client connected
+// to and the direct peer.
+func (res *Resolver) trustedHops(values []string) int {
+	if len(values) == 0 {
+		return 0
+	}
+	hops := strings.Split(strings.Join(values, ","), ",")
+	n := 0
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil || !res.trusts(addr.Unmap().String()) {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// hopValue picks the entry of an appended X-Forwarded-* header written by
+// the proxy the client connected to: skipping one entry from the right per
+// further trusted proxy, as each appends its own. Entries left of it came
+// from the client and are never used; with fewer entries than hops, the
+// leftmost is taken.
+func hopValue(values []string, hops int) string {
+	if len(values) == 0 {
+		return ""
+	}
+	entries := strings.Split(strings.Join(values, ","), ",")
+	return strings.TrimSpace(entries[max(len(entries)-1-hops, 0)])
+}
+
+// clientForwardedElement picks the Forwarded element written by the proxy
+// the client connected to. As with X-Forwarded-For, elements are walked
+// from the right past those describing connections from other trusted
+// proxies; an element without a usable "for" ends the walk.
+func (res *Resolver) clientForwardedElement(values []string) map[string]string {
+	elements := strings.Split(strings.Join(values, ","), ",")
+	var params map[string]string
+	for i := len(elements) - 1; i >= 0; i-- {
+		params = parseForwardedElement(elements[i])
+		addr, ok := forwardedNode(params["for"])
+		if !ok || !res.trusts(addr) {
+			return params
+		}
+	}
+	return params
+}
+
+// parseForwardedElement splits one element such as
+// `for=192.0.2.60;proto=https;host="go.example.com"` into lowercase keys
+// and unquoted values.
+func parseForwardedElement(element string) map[string]string {
+	params := make(map[string]string)
+	for _, pair := range strings.Split(element, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		params[strings.ToLower(key)] = strings.Trim(value, `"`)
+	}
+	return params
+}
+
+// forwardedNode returns the address in a "for" value, which may carry a
+// port and, for IPv6, brackets. Obfuscated and "unknown" nodes fail.
+func forwardedNode(node string) (string, bool) {
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		node = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(node, "[]"))
+	if err != nil {
+		return "", false
+	}
+	return addr.Unmap().String(), true
+}
+
+func validProto(proto string) string {
+	proto = strings.ToLower(proto)
+	if proto != "http" && proto != "https" {
+		return ""
+	}
+	return proto
+}
+
+// validHost accepts a bare host with an optional port, refusing anything
+// that would change the meaning of a URL built around it.
+func validHost(host string) string {
+	if host == "" {
+		return ""
+	}
+	u, err := url.Parse("http://" + host)
+	if err != nil || u.Host != host || u.User != nil {
+		return ""
+	}
+	return strings.ToLower(host)
+}
@@ -0,0 +1,148 @@
+package clientip_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"url-shortener/internal/clientip"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver_ForwardedOrigin(t *testing.T) {
+	res, err := clientip.NewResolver([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string][]string
+		wantProto  string
+		wantHost   string
+	}{
+		{name: "no headers", remoteAddr: "10.0.0.1:5000"},
+		{
+			name:       "untrusted peer is ignored",
+			remoteAddr: "203.0.113.7:5000",
+			headers:    map[string][]string{"X-Forwarded-Proto": {"https"}, "X-Forwarded-Host": {"evil.example"}},
+		},
+		{
+			name:       "x-forwarded headers",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string][]string{"X-Forwarded-Proto": {"https"}, "X-Forwarded-Host": {"Go.Example.com"}},
+			wantProto:  "https",
+			wantHost:   "go.example.com",
+		},
+		{
+			name:       "client-facing proxy's x-forwarded value wins",
+			remoteAddr: "10.0.0.1:5000",
+			headers: map[string][]string{
+				"X-Forwarded-For":   {"203.0.113.7, 10.0.0.2"},
+				"X-Forwarded-Proto": {"https, http"},
+				"X-Forwarded-Host":  {"go.example.com, internal:8080"},
+			},
+			wantProto: "https",
+			wantHost:  "go.example.com",
+		},
+		{
+			name:       "client-sent x-forwarded values are skipped",
+			remoteAddr: "10.0.0.1:5000",
+			headers: map[string][]string{
+				"X-Forwarded-For":   {"203.0.113.7"},
+				"X-Forwarded-Proto": {"http", "https"},
+				"X-Forwarded-Host":  {"evil.example, go.example.com"},
+			},
+			wantProto: "https",
+			wantHost:  "go.example.com",
+		},
+		{
+			name:       "proto only",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string][]string{"X-Forwarded-Proto": {"HTTPS"}},
+			wantProto:  "https",
+		},
+		{
+			name:       "forwarded header",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string][]string{"Forwarded": {`for=203.0.113.7;proto=https;host="go.example.com:8443"`}},
+			wantProto:  "https",
+			wantHost:   "go.example.com:8443",
+		},
+		{
+			name:       "forwarded header is preferred",
+			remoteAddr: "10.0.0.1:5000",
+			headers: map[string][]string{
+				"Forwarded":         {"for=203.0.113.7;proto=https;host=go.example.com"},
+				"X-Forwarded-Proto": {"http"},
+				"X-Forwarded-Host":  {"other.example"},
+			},
+			wantProto: "https",
+			wantHost:  "go.example.com",
+		},
+		{
+			name:       "forwarded walks past trusted hops",
+			remoteAddr: "10.0.0.1:5000",
+			headers: map[string][]string{"Forwarded": {
+				"for=198.51.100.1;proto=http;host=spoofed.example",
+				`for=203.0.113.7;proto=https;host=go.example.com, for="10.0.0.2:4711";proto=http;host=internal`,
+			}},
+			wantProto: "https",
+			wantHost:  "go.example.com",
+		},
+		{
+			name:       "forwarded with obfuscated node stops the walk",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string][]string{"Forwarded": {"for=_hidden;proto=https;host=go.example.com"}},
+			wantProto:  "https",
+			wantHost:   "go.example.com",
+		},
+		{
+			name:       "forwarded ipv6 node",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string][]string{"Forwarded": {`for="[2001:db8::1]:4711";proto=https;host=go.example.com`}},
+			wantProto:  "https",
+			wantHost:   "go.example.com",
+		},
+		{
+			name:       "malformed values are dropped",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string][]string{"X-Forwarded-Proto": {"javascript"}, "X-Forwarded-Host": {"evil.example/path"}},
+		},
+		{
+			name:       "userinfo in host is dropped",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string][]string{"X-Forwarded-Proto": {"https"}, "X-Forwarded-Host": {"go.example.com@evil.example"}},
+			wantProto:  "https",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for name, values := range tt.headers {
+				for _, v := range values {
+					r.Header.Add(name, v)
+				}
+			}
+
+			proto, host := res.ForwardedOrigin(r)
+
+			assert.Equal(t, tt.wantProto, proto)
+			assert.Equal(t, tt.wantHost, host)
+		})
+	}
+}
+
+func TestResolver_ForwardedOrigin_NilTrustsNoOne(t *testing.T) {
+	var res *clientip.Resolver
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Forwarded-Proto", "https")
+
+	proto, host := res.ForwardedOrigin(r)
+
+	assert.Empty(t, proto)
+	assert.Empty(t, host)
+}
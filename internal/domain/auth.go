@@ -0,0 +1,59 @@
+package domain
+
+import "context"
+
+// AuthTier says how a caller was authenticated, which can decide the
+// limits applied to its requests.
+type AuthTier string
+
+const (
+	// TierAnonymous callers presented no API key.
+	TierAnonymous AuthTier = "anonymous"
+
+	// TierAuthenticated callers presented a valid API key.
+	TierAuthenticated AuthTier = "authenticated"
+)
+
+type authTierKey struct{}
+
+// WithAuthTier returns a context carrying the caller's tier.
+func WithAuthTier(ctx context.Context, tier AuthTier) context.Context {
+	return context.WithValue(ctx, authTierKey{}, tier)
+}
+
+// AuthTierFromContext returns the caller's tier, or TierAnonymous if none.
+func AuthTierFromContext(ctx context.Context) AuthTier {
+	if tier, ok := ctx.Value(authTierKey{}).(AuthTier); ok && tier != "" {
+		return tier
+	}
+	return TierAnonymous
+}
+
+type clientIDKey struct{}
+
+// WithClientID returns a context identifying the calling client, e.g. by a
+// digest of its API key, so state kept per client stays apart.
+func WithClientID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, clientIDKey{}, id)
+}
+
+// ClientIDFromContext returns the ID set by WithClientID, or "" if none.
+func ClientIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(clientIDKey{}).(string)
+	return id
+}
+
+type codePrefixKey struct{}
+
+// WithCodePrefix returns a context whose creates generate codes starting
+// with prefix, e.g. to keep each tenant's links in its own namespace.
+func WithCodePrefix(ctx context.Context, prefix string) context.Context {
+	return context.WithValue(ctx, codePrefixKey{}, prefix)
+}
+
+// CodePrefixFromContext returns the prefix set by WithCodePrefix. ok is
+// false if none was set.
+func CodePrefixFromContext(ctx context.Context) (prefix string, ok bool) {
+	prefix, ok = ctx.Value(codePrefixKey{}).(string)
+	return prefix, ok
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// DateLayout formats the UTC day keys of URLRecord.ClicksByDay.
+const DateLayout = "2006-01-02"
+
+// MaxClickDays bounds the days of click counts kept per record. The oldest
+// days are dropped first.
+const MaxClickDays = 400
+
+// DayClicks is the number of clicks a link got on one UTC day.
+type DayClicks struct {
+	Date   string
+	Clicks int64
+}
+
+// CountDailyClick adds a click at the given time to byDay, returning the
+// updated map.
+func CountDailyClick(byDay map[string]int64, at time.Time) map[string]int64 {
+	return AddDailyClicks(byDay, at.UTC().Format(DateLayout), 1)
+}
+
+// AddDailyClicks is CountDailyClick for n clicks on day, a DateLayout key.
+func AddDailyClicks(byDay map[string]int64, day string, n int64) map[string]int64 {
+	if byDay == nil {
+		byDay = make(map[string]int64)
+	}
+	byDay[day] += n
+
+	if len(byDay) > MaxClickDays {
+		days := make([]string, 0, len(byDay))
+		for day := range byDay {
+			days = append(days, day)
+		}
+		sort.Strings(days)
+		for _, day := range days[:len(days)-MaxClickDays] {
+			delete(byDay, day)
+		}
+	}
+	return byDay
+}
+
+// ClickSeries returns the clicks for every UTC day from from through to,
+// in order, with zero for days without clicks.
+func ClickSeries(byDay map[string]int64, from, to time.Time) []DayClicks {
+	from = truncateDay(from)
+	to = truncateDay(to)
+
+	var series []DayClicks
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(DateLayout)
+		series = append(series, DayClicks{Date: date, Clicks: byDay[date]})
+	}
+	return series
+}
+
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
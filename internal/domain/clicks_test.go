@@ -0,0 +1,48 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountDailyClick_UsesUTCDay(t *testing.T) {
+	est := time.FixedZone("EST", -5*60*60)
+
+	var byDay map[string]int64
+	byDay = domain.CountDailyClick(byDay, time.Date(2024, 1, 15, 23, 30, 0, 0, est)) // 2024-01-16 UTC
+	byDay = domain.CountDailyClick(byDay, time.Date(2024, 1, 16, 1, 0, 0, 0, time.UTC))
+	byDay = domain.CountDailyClick(byDay, time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	assert.Equal(t, map[string]int64{"2024-01-15": 1, "2024-01-16": 2}, byDay)
+}
+
+func TestCountDailyClick_DropsOldestDays(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var byDay map[string]int64
+	for i := 0; i < domain.MaxClickDays+5; i++ {
+		byDay = domain.CountDailyClick(byDay, start.AddDate(0, 0, i))
+	}
+
+	assert.Len(t, byDay, domain.MaxClickDays)
+	assert.NotContains(t, byDay, "2024-01-05")
+	assert.Contains(t, byDay, "2024-01-06")
+}
+
+func TestClickSeries_ZeroFillsInclusiveRange(t *testing.T) {
+	byDay := map[string]int64{"2024-01-14": 9, "2024-01-15": 3, "2024-01-17": 2}
+
+	series := domain.ClickSeries(byDay,
+		time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 17, 18, 0, 0, 0, time.UTC))
+
+	assert.Equal(t, []domain.DayClicks{
+		{Date: "2024-01-15", Clicks: 3},
+		{Date: "2024-01-16", Clicks: 0},
+		{Date: "2024-01-17", Clicks: 2},
+	}, series)
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
 // Clock provides time operations for the application.
 // This abstraction allows deterministic testing without time.Sleep.
@@ -40,3 +43,47 @@ func (c *MockClock) Advance(d time.Duration) {
 func (c *MockClock) Set(t time.Time) {
 	c.current = t
 }
+
+// AdjustableClock implements Clock as a base clock plus a controllable offset.
+// Unlike MockClock it keeps moving with the base clock and is safe for
+// concurrent use, so it can be driven externally while the server runs.
+// It is intended for load and expiry testing only.
+type AdjustableClock struct {
+	mu     sync.RWMutex
+	base   Clock
+	offset time.Duration
+}
+
+// NewAdjustableClock creates an AdjustableClock on top of the given base clock
+// with no initial offset.
+func NewAdjustableClock(base Clock) *AdjustableClock {
+	return &AdjustableClock{base: base}
+}
+
+// Now returns the base clock's time shifted by the current offset.
+func (c *AdjustableClock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.base.Now().Add(c.offset)
+}
+
+// Advance moves the clock forward by the given duration.
+func (c *AdjustableClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset += d
+}
+
+// Set sets the clock to a specific time. The clock keeps ticking from there.
+func (c *AdjustableClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset = t.Sub(c.base.Now())
+}
+
+// Offset returns the current offset from the base clock.
+func (c *AdjustableClock) Offset() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.offset
+}
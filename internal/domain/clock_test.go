@@ -46,3 +46,37 @@ func TestMockClock_Set(t *testing.T) {
 
 	assert.Equal(t, newTime, clock.Now())
 }
+
+func TestAdjustableClock_FollowsBaseClock(t *testing.T) {
+	base := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	clock := domain.NewAdjustableClock(base)
+
+	assert.Equal(t, base.Now(), clock.Now())
+
+	base.Advance(time.Minute)
+	assert.Equal(t, base.Now(), clock.Now())
+}
+
+func TestAdjustableClock_Advance(t *testing.T) {
+	base := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	clock := domain.NewAdjustableClock(base)
+
+	clock.Advance(2 * time.Hour)
+
+	assert.Equal(t, time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC), clock.Now())
+	assert.Equal(t, 2*time.Hour, clock.Offset())
+
+	// Base clock keeps ticking underneath the offset
+	base.Advance(time.Minute)
+	assert.Equal(t, time.Date(2024, 1, 15, 14, 1, 0, 0, time.UTC), clock.Now())
+}
+
+func TestAdjustableClock_Set(t *testing.T) {
+	base := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	clock := domain.NewAdjustableClock(base)
+
+	target := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	clock.Set(target)
+
+	assert.Equal(t, target, clock.Now())
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// CreateParams describes a short link to create.
+type CreateParams struct {
+	LongURL string
+
+	// TTL is the link lifetime. Zero means the service default.
+	TTL time.Duration
+
+	// NoExpiry creates a link that never expires, ignoring TTL.
+	NoExpiry bool
+
+	// CustomAlias, when set, is used as the short code instead of a
+	// generated one.
+	CustomAlias string
+
+	// Permanent requests a 301 redirect instead of the default 302.
+	Permanent bool
+
+	// MaxClicks expires the link after that many redirects. Zero means
+	// unlimited.
+	MaxClicks int64
+
+	// Tags label the link; see URLRecord.Tags.
+	Tags []string
+}
+
+// CreateResult is the outcome of creating one item of a batch: either the
+// created record or the error that prevented it.
+type CreateResult struct {
+	Record *URLRecord
+	Err    error
+}
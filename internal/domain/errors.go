@@ -11,4 +11,52 @@ var (
 
 	// ErrExpired indicates the record has expired.
 	ErrExpired = errors.New("record has expired")
+
+	// ErrAliasTaken indicates the requested custom alias is already in use.
+	ErrAliasTaken = errors.New("custom alias already taken")
+
+	// ErrInvalidAlias indicates the requested custom alias can't be used.
+	ErrInvalidAlias = errors.New("custom alias is not allowed")
+
+	// ErrDisabled indicates the record exists but has been disabled.
+	ErrDisabled = errors.New("short code disabled")
+
+	// ErrClickLimitReached indicates the record has used up its MaxClicks.
+	ErrClickLimitReached = errors.New("click limit reached")
+
+	// ErrCapacityExceeded indicates the store is full and can't take new records.
+	ErrCapacityExceeded = errors.New("storage capacity exceeded")
+
+	// ErrExpiryTooLate indicates a link would outlive TTLPolicy.MaxExpiryAbsolute.
+	ErrExpiryTooLate = errors.New("link would expire after the latest allowed expiry")
+
+	// ErrIdempotencyKeyReused indicates an idempotency key was sent again
+	// with different create parameters.
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused with different parameters")
+
+	// ErrUnsafeDestination indicates a stored long URL fails the destination
+	// check, e.g. a javascript: URL written by an import, so it must not be
+	// redirected to.
+	ErrUnsafeDestination = errors.New("destination not allowed")
+
+	// ErrInvalidChecksum indicates the short code's check character doesn't match.
+	ErrInvalidChecksum = errors.New("short code failed checksum validation")
 )
+
+// ChecksumError reports a short code rejected by its checksum, along with
+// the code the user most likely intended, if one could be found.
+type ChecksumError struct {
+	Suggestion string
+}
+
+func (e *ChecksumError) Error() string {
+	if e.Suggestion == "" {
+		return ErrInvalidChecksum.Error()
+	}
+	return ErrInvalidChecksum.Error() + "; did you mean " + e.Suggestion + "?"
+}
+
+// Unwrap allows errors.Is(err, ErrInvalidChecksum).
+func (e *ChecksumError) Unwrap() error {
+	return ErrInvalidChecksum
+}
@@ -20,3 +20,11 @@ func TestErrors_CanBeWrapped(t *testing.T) {
 	wrapped := fmt.Errorf("operation failed: %w", domain.ErrNotFound)
 	assert.True(t, errors.Is(wrapped, domain.ErrNotFound))
 }
+
+func TestChecksumError_UnwrapsToSentinel(t *testing.T) {
+	var err error = &domain.ChecksumError{Suggestion: "Ab2CdE3F"}
+
+	assert.True(t, errors.Is(err, domain.ErrInvalidChecksum))
+	assert.Contains(t, err.Error(), "did you mean Ab2CdE3F?")
+	assert.Equal(t, domain.ErrInvalidChecksum.Error(), (&domain.ChecksumError{}).Error())
+}
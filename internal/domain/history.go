@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+// MaxHistoryEvents bounds the lifecycle history kept per record.
+// Older events are dropped first.
+const MaxHistoryEvents = 20
+
+// EventType identifies a lifecycle mutation of a record.
+type EventType string
+
+// Lifecycle event types.
+const (
+	EventCreated     EventType = "created"
+	EventTTLExtended EventType = "ttl_extended"
+	EventURLUpdated  EventType = "url_updated"
+	EventRotated     EventType = "rotated"
+	EventDisabled    EventType = "disabled"
+	EventEnabled     EventType = "enabled"
+	EventDeleted     EventType = "deleted"
+	EventRestored    EventType = "restored"
+)
+
+// HistoryEvent records a single mutation of a record for auditing.
+// Details must never hold sensitive values; see RedactURL.
+type HistoryEvent struct {
+	Type    EventType
+	At      time.Time
+	Actor   string
+	Details map[string]string
+}
+
+// Clone creates a deep copy of the event.
+func (e HistoryEvent) Clone() HistoryEvent {
+	clone := e
+	if e.Details != nil {
+		clone.Details = make(map[string]string, len(e.Details))
+		for k, v := range e.Details {
+			clone.Details[k] = v
+		}
+	}
+	return clone
+}
+
+// AppendHistory appends event to history, dropping the oldest events so
+// that at most MaxHistoryEvents remain.
+func AppendHistory(history []HistoryEvent, event HistoryEvent) []HistoryEvent {
+	history = append(history, event)
+	if len(history) > MaxHistoryEvents {
+		history = append([]HistoryEvent(nil), history[len(history)-MaxHistoryEvents:]...)
+	}
+	return history
+}
+
+// RedactURL strips credentials, query string, and fragment from a URL so it
+// can be kept in history without leaking tokens.
+func RedactURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "[unparseable]"
+	}
+	parsed.User = nil
+	parsed.RawQuery = ""
+	parsed.Fragment = ""
+	return parsed.String()
+}
+
+// AnonymousActor is the actor recorded when no authenticated caller is known.
+const AnonymousActor = "anonymous"
+
+type actorKey struct{}
+
+// WithActor returns a context carrying the identity of the caller.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the caller identity, or AnonymousActor if none.
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return AnonymousActor
+}
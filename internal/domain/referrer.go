@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// MaxReferrers bounds the distinct referrers counted per record. Clicks from
+// further referrers are counted under ReferrerOther.
+const MaxReferrers = 100
+
+// Referrer keys that don't name a site.
+const (
+	// ReferrerDirect counts clicks that sent no Referer header.
+	ReferrerDirect = "(direct)"
+
+	// ReferrerOther counts clicks whose Referer couldn't be parsed or that
+	// arrived after MaxReferrers distinct referrers were already counted.
+	ReferrerOther = "(other)"
+)
+
+// ReferrerCount is the number of clicks a referring site sent to a link.
+type ReferrerCount struct {
+	Referrer string
+	Clicks   int64
+}
+
+// ReferrerKey reduces a Referer header to the lowercased host of the
+// referring site, so counts aggregate per site rather than per page and no
+// paths or query strings are kept.
+func ReferrerKey(referer string) string {
+	if referer == "" {
+		return ReferrerDirect
+	}
+	parsed, err := url.Parse(referer)
+	if err != nil || parsed.Hostname() == "" {
+		return ReferrerOther
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// CountReferrer adds a click from referrer to counts, returning the updated
+// map. Once counts holds MaxReferrers distinct referrers, clicks from new
+// ones go to ReferrerOther, so the map stays bounded.
+func CountReferrer(counts map[string]int64, referrer string) map[string]int64 {
+	return AddReferrerClicks(counts, referrer, 1)
+}
+
+// AddReferrerClicks is CountReferrer for n clicks from referrer.
+func AddReferrerClicks(counts map[string]int64, referrer string, n int64) map[string]int64 {
+	if counts == nil {
+		counts = make(map[string]int64)
+	}
+	if _, known := counts[referrer]; !known && len(counts) >= MaxReferrers {
+		referrer = ReferrerOther
+	}
+	counts[referrer] += n
+	return counts
+}
+
+// TopReferrers returns up to limit referrers with the most clicks, most
+// first, breaking ties by name so the order is stable.
+func TopReferrers(counts map[string]int64, limit int) []ReferrerCount {
+	top := make([]ReferrerCount, 0, len(counts))
+	for referrer, clicks := range counts {
+		top = append(top, ReferrerCount{Referrer: referrer, Clicks: clicks})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Clicks != top[j].Clicks {
+			return top[i].Clicks > top[j].Clicks
+		}
+		return top[i].Referrer < top[j].Referrer
+	})
+	if limit >= 0 && len(top) > limit {
+		top = top[:limit]
+	}
+	return top
+}
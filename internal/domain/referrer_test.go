@@ -0,0 +1,63 @@
+package domain_test
+
+import (
+	"fmt"
+	"testing"
+
+	"url-shortener/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferrerKey(t *testing.T) {
+	tests := []struct {
+		referer  string
+		expected string
+	}{
+		{"", domain.ReferrerDirect},
+		{"https://News.Example.com/story/42?utm=x", "news.example.com"},
+		{"http://t.co/abc", "t.co"},
+		{"https://example.com:8443/", "example.com"},
+		{"not a url", domain.ReferrerOther},
+		{"://broken", domain.ReferrerOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.referer, func(t *testing.T) {
+			assert.Equal(t, tt.expected, domain.ReferrerKey(tt.referer))
+		})
+	}
+}
+
+func TestCountReferrer_BoundsDistinctReferrers(t *testing.T) {
+	var counts map[string]int64
+	for i := 0; i < domain.MaxReferrers+10; i++ {
+		counts = domain.CountReferrer(counts, fmt.Sprintf("site%d.example", i))
+	}
+
+	// Referrers past the limit share the other bucket, its only extra key
+	assert.Len(t, counts, domain.MaxReferrers+1)
+	assert.Equal(t, int64(10), counts[domain.ReferrerOther])
+
+	// Known referrers keep counting
+	counts = domain.CountReferrer(counts, "site0.example")
+	assert.Equal(t, int64(2), counts["site0.example"])
+}
+
+func TestTopReferrers(t *testing.T) {
+	counts := map[string]int64{
+		"a.example":           3,
+		"b.example":           7,
+		"c.example":           3,
+		domain.ReferrerDirect: 5,
+	}
+
+	assert.Equal(t, []domain.ReferrerCount{
+		{Referrer: "b.example", Clicks: 7},
+		{Referrer: domain.ReferrerDirect, Clicks: 5},
+		{Referrer: "a.example", Clicks: 3},
+	}, domain.TopReferrers(counts, 3))
+
+	assert.Len(t, domain.TopReferrers(counts, 10), 4)
+	assert.Empty(t, domain.TopReferrers(nil, 10))
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"sort"
+	"time"
+)
+
+// StoreStats summarizes the records in a store.
+type StoreStats struct {
+	TotalLinks   int64
+	ActiveLinks  int64
+	ExpiredLinks int64
+	TotalClicks  int64
+
+	// TopLinks are the most-clicked links, most clicks first, with ties
+	// broken by short code.
+	TopLinks []LinkClicks
+}
+
+// LinkClicks is the click count of one link.
+type LinkClicks struct {
+	ShortCode string
+	LongURL   string
+	Clicks    int64
+}
+
+// Add counts record as of now, keeping at most top links in TopLinks.
+// Expired records include those whose click limit is used up.
+func (s *StoreStats) Add(record *URLRecord, now time.Time, top int) {
+	s.TotalLinks++
+	if record.IsExpired(now) || record.ClickLimitReached() {
+		s.ExpiredLinks++
+	} else {
+		s.ActiveLinks++
+	}
+	s.TotalClicks += record.ClickCount
+	s.TopLinks = AddTopLink(s.TopLinks, LinkClicks{
+		ShortCode: record.ShortCode,
+		LongURL:   record.LongURL,
+		Clicks:    record.ClickCount,
+	}, top)
+}
+
+// AddTopLink inserts link into top, which is ordered as StoreStats.TopLinks,
+// keeping at most n links.
+func AddTopLink(top []LinkClicks, link LinkClicks, n int) []LinkClicks {
+	i := sort.Search(len(top), func(i int) bool {
+		return link.Clicks > top[i].Clicks ||
+			(link.Clicks == top[i].Clicks && link.ShortCode < top[i].ShortCode)
+	})
+	if i >= n {
+		return top
+	}
+	if len(top) < n {
+		top = append(top, LinkClicks{})
+	}
+	copy(top[i+1:], top[i:])
+	top[i] = link
+	return top
+}
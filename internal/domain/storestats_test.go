@@ -0,0 +1,50 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddTopLink_KeepsMostClickedInOrder(t *testing.T) {
+	var top []domain.LinkClicks
+	for _, link := range []domain.LinkClicks{
+		{ShortCode: "c", Clicks: 5},
+		{ShortCode: "a", Clicks: 1},
+		{ShortCode: "d", Clicks: 9},
+		{ShortCode: "b", Clicks: 5},
+		{ShortCode: "e", Clicks: 0},
+	} {
+		top = domain.AddTopLink(top, link, 3)
+	}
+
+	assert.Equal(t, []domain.LinkClicks{
+		{ShortCode: "d", Clicks: 9},
+		{ShortCode: "b", Clicks: 5},
+		{ShortCode: "c", Clicks: 5},
+	}, top)
+}
+
+func TestAddTopLink_ZeroKeepsNothing(t *testing.T) {
+	assert.Empty(t, domain.AddTopLink(nil, domain.LinkClicks{ShortCode: "a", Clicks: 1}, 0))
+}
+
+func TestStoreStats_Add(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	var stats domain.StoreStats
+	stats.Add(&domain.URLRecord{ShortCode: "live", ExpiresAt: now.Add(time.Hour), ClickCount: 3}, now, 10)
+	stats.Add(&domain.URLRecord{ShortCode: "forever", ClickCount: 1}, now, 10)
+	stats.Add(&domain.URLRecord{ShortCode: "expired", ExpiresAt: now.Add(-time.Hour), ClickCount: 7}, now, 10)
+	stats.Add(&domain.URLRecord{ShortCode: "used-up", ExpiresAt: now.Add(time.Hour), ClickCount: 2, MaxClicks: 2}, now, 10)
+
+	assert.Equal(t, int64(4), stats.TotalLinks)
+	assert.Equal(t, int64(2), stats.ActiveLinks)
+	assert.Equal(t, int64(2), stats.ExpiredLinks)
+	assert.Equal(t, int64(13), stats.TotalClicks)
+	assert.Len(t, stats.TopLinks, 4)
+	assert.Equal(t, "expired", stats.TopLinks[0].ShortCode)
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// TTLPolicy sets the lifetime applied to links created without a TTL and
+// the range of TTLs callers may request. The service and the HTTP layer
+// share one policy so they can't disagree.
+type TTLPolicy struct {
+	Default time.Duration
+	Min     time.Duration
+	Max     time.Duration
+
+	// MaxExpiryAbsolute, if set, is the latest time any link may expire,
+	// e.g. the end of a trial deployment. It applies on top of Max, so a
+	// TTL within Max is still refused if it would run past this.
+	MaxExpiryAbsolute time.Time
+}
+
+// DefaultTTLPolicy returns the built-in policy: links live 24 hours unless
+// asked otherwise, for between a minute and a year.
+func DefaultTTLPolicy() TTLPolicy {
+	return TTLPolicy{
+		Default: 24 * time.Hour,
+		Min:     time.Minute,
+		Max:     365 * 24 * time.Hour,
+	}
+}
+
+// Validate reports whether the policy is usable: positive bounds in order,
+// with the default inside them.
+func (p TTLPolicy) Validate() error {
+	if p.Min <= 0 {
+		return fmt.Errorf("minimum TTL must be positive, got %v", p.Min)
+	}
+	if p.Max < p.Min {
+		return fmt.Errorf("maximum TTL %v is below minimum TTL %v", p.Max, p.Min)
+	}
+	if p.Default < p.Min || p.Default > p.Max {
+		return fmt.Errorf("default TTL %v is outside [%v, %v]", p.Default, p.Min, p.Max)
+	}
+	return nil
+}
+
+// CheckExpiry returns an error wrapping ErrExpiryTooLate if expiresAt is
+// after MaxExpiryAbsolute. A zero expiresAt, meaning never, is always too
+// late when MaxExpiryAbsolute is set.
+func (p TTLPolicy) CheckExpiry(expiresAt time.Time) error {
+	if p.MaxExpiryAbsolute.IsZero() {
+		return nil
+	}
+	if expiresAt.IsZero() || expiresAt.After(p.MaxExpiryAbsolute) {
+		return fmt.Errorf("%w, %s", ErrExpiryTooLate, p.MaxExpiryAbsolute.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
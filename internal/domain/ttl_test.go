@@ -0,0 +1,49 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTLPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  domain.TTLPolicy
+		wantErr bool
+	}{
+		{name: "built-in defaults", policy: domain.DefaultTTLPolicy()},
+		{name: "all equal", policy: domain.TTLPolicy{Default: time.Hour, Min: time.Hour, Max: time.Hour}},
+		{name: "zero minimum", policy: domain.TTLPolicy{Default: time.Hour, Min: 0, Max: 2 * time.Hour}, wantErr: true},
+		{name: "max below min", policy: domain.TTLPolicy{Default: time.Hour, Min: time.Hour, Max: time.Minute}, wantErr: true},
+		{name: "default below min", policy: domain.TTLPolicy{Default: time.Minute, Min: time.Hour, Max: 2 * time.Hour}, wantErr: true},
+		{name: "default above max", policy: domain.TTLPolicy{Default: 3 * time.Hour, Min: time.Hour, Max: 2 * time.Hour}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTTLPolicy_CheckExpiry(t *testing.T) {
+	limit := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	capped := domain.TTLPolicy{MaxExpiryAbsolute: limit}
+
+	assert.NoError(t, capped.CheckExpiry(limit))
+	assert.ErrorIs(t, capped.CheckExpiry(limit.Add(time.Second)), domain.ErrExpiryTooLate)
+	assert.ErrorIs(t, capped.CheckExpiry(time.Time{}), domain.ErrExpiryTooLate, "never expiring exceeds any cap")
+
+	uncapped := domain.TTLPolicy{}
+	assert.NoError(t, uncapped.CheckExpiry(time.Time{}))
+	assert.NoError(t, uncapped.CheckExpiry(limit.AddDate(100, 0, 0)))
+}
@@ -1,30 +1,137 @@
 package domain
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 // URLRecord represents a shortened URL entry.
 type URLRecord struct {
-	ShortCode      string
-	LongURL        string
-	CreatedAt      time.Time
-	ExpiresAt      time.Time
+	ShortCode string
+	LongURL   string
+	CreatedAt time.Time
+
+	// ExpiresAt is when the link stops resolving. The zero value means it
+	// never expires.
+	ExpiresAt time.Time
+
+	// TTL is the lifetime the link was created with, whether requested or
+	// the default. It is zero for links that never expire and is not
+	// changed when ExpiresAt is updated later.
+	TTL time.Duration
+
 	ClickCount     int64
 	LastAccessedAt time.Time
+
+	// MaxClicks limits how many times the link may be followed; once
+	// reached, Resolve treats the link as expired. Zero means unlimited.
+	MaxClicks int64
+
+	// RedirectPermanent makes the link redirect with 301 instead of 302.
+	RedirectPermanent bool
+
+	// Enabled is true for new records. A disabled record stops redirecting
+	// but is kept, along with its stats, until re-enabled.
+	Enabled bool
+
+	// DeletedAt is when the record was soft-deleted, or zero if it wasn't.
+	// Repositories treat a deleted record as missing, except that its code
+	// stays taken, until it is restored or purged.
+	DeletedAt time.Time
+
+	// History is a bounded log of lifecycle events, oldest first.
+	History []HistoryEvent
+
+	// Referrers counts clicks per referring site; see CountReferrer.
+	Referrers map[string]int64
+
+	// ClicksByDay counts clicks per UTC day, keyed by DateLayout; see
+	// CountDailyClick.
+	ClicksByDay map[string]int64
+
+	// Tags label the link for grouping, such as by campaign. They are
+	// lowercase and unique, in the order given at creation.
+	Tags []string
 }
 
 // IsExpired returns true if the record has expired at the given time.
+// Records that never expire are never expired.
 func (r *URLRecord) IsExpired(now time.Time) bool {
-	return now.After(r.ExpiresAt)
+	return !r.NeverExpires() && now.After(r.ExpiresAt)
+}
+
+// HasTag reports whether the record is labeled with tag.
+func (r *URLRecord) HasTag(tag string) bool {
+	return slices.Contains(r.Tags, tag)
+}
+
+// IsDeleted reports whether the record has been soft-deleted.
+func (r *URLRecord) IsDeleted() bool {
+	return !r.DeletedAt.IsZero()
+}
+
+// NeverExpires reports whether the record was created without an expiry.
+func (r *URLRecord) NeverExpires() bool {
+	return r.ExpiresAt.IsZero()
+}
+
+// ClickLimitReached reports whether a click-limited record has no clicks
+// left.
+func (r *URLRecord) ClickLimitReached() bool {
+	return r.MaxClicks > 0 && r.ClickCount >= r.MaxClicks
+}
+
+// RemainingClicks returns how many more clicks the record allows, clamped
+// at zero. The second return value is false for unlimited records.
+func (r *URLRecord) RemainingClicks() (int64, bool) {
+	if r.MaxClicks <= 0 {
+		return 0, false
+	}
+	if r.ClickCount >= r.MaxClicks {
+		return 0, true
+	}
+	return r.MaxClicks - r.ClickCount, true
 }
 
 // Clone creates a deep copy of the record.
 func (r *URLRecord) Clone() *URLRecord {
 	return &URLRecord{
-		ShortCode:      r.ShortCode,
-		LongURL:        r.LongURL,
-		CreatedAt:      r.CreatedAt,
-		ExpiresAt:      r.ExpiresAt,
-		ClickCount:     r.ClickCount,
-		LastAccessedAt: r.LastAccessedAt,
+		ShortCode:         r.ShortCode,
+		LongURL:           r.LongURL,
+		CreatedAt:         r.CreatedAt,
+		ExpiresAt:         r.ExpiresAt,
+		TTL:               r.TTL,
+		ClickCount:        r.ClickCount,
+		LastAccessedAt:    r.LastAccessedAt,
+		MaxClicks:         r.MaxClicks,
+		RedirectPermanent: r.RedirectPermanent,
+		Enabled:           r.Enabled,
+		DeletedAt:         r.DeletedAt,
+		History:           cloneHistory(r.History),
+		Referrers:         cloneCounts(r.Referrers),
+		ClicksByDay:       cloneCounts(r.ClicksByDay),
+		Tags:              slices.Clone(r.Tags),
+	}
+}
+
+func cloneHistory(history []HistoryEvent) []HistoryEvent {
+	if history == nil {
+		return nil
+	}
+	clone := make([]HistoryEvent, len(history))
+	for i, event := range history {
+		clone[i] = event.Clone()
+	}
+	return clone
+}
+
+func cloneCounts(counts map[string]int64) map[string]int64 {
+	if counts == nil {
+		return nil
+	}
+	clone := make(map[string]int64, len(counts))
+	for k, v := range counts {
+		clone[k] = v
 	}
+	return clone
 }
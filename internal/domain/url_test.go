@@ -36,6 +36,12 @@ func TestURLRecord_IsExpired(t *testing.T) {
 			checkTime: now,
 			want:      false,
 		},
+		{
+			name:      "never expires - zero expiry",
+			expiresAt: time.Time{},
+			checkTime: now.AddDate(100, 0, 0),
+			want:      false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -48,14 +54,21 @@ func TestURLRecord_IsExpired(t *testing.T) {
 	}
 }
 
+func TestURLRecord_NeverExpires(t *testing.T) {
+	assert.True(t, (&domain.URLRecord{}).NeverExpires())
+	assert.False(t, (&domain.URLRecord{ExpiresAt: time.Now()}).NeverExpires())
+}
+
 func TestURLRecord_Clone(t *testing.T) {
 	original := &domain.URLRecord{
 		ShortCode:      "abc12345",
 		LongURL:        "https://example.com",
 		CreatedAt:      time.Now(),
 		ExpiresAt:      time.Now().Add(time.Hour),
+		TTL:            time.Hour,
 		ClickCount:     42,
 		LastAccessedAt: time.Now(),
+		Tags:           []string{"spring-sale"},
 	}
 
 	clone := original.Clone()
@@ -64,8 +77,37 @@ func TestURLRecord_Clone(t *testing.T) {
 	assert.Equal(t, original.ShortCode, clone.ShortCode)
 	assert.Equal(t, original.LongURL, clone.LongURL)
 	assert.Equal(t, original.ClickCount, clone.ClickCount)
+	assert.Equal(t, original.TTL, clone.TTL)
 
 	// Should be independent (modifying clone doesn't affect original)
 	clone.ClickCount = 100
 	assert.Equal(t, int64(42), original.ClickCount)
+	clone.Tags[0] = "changed"
+	assert.Equal(t, []string{"spring-sale"}, original.Tags)
+}
+
+func TestURLRecord_RemainingClicks(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxClicks     int64
+		clickCount    int64
+		wantRemaining int64
+		wantLimited   bool
+	}{
+		{name: "unlimited", maxClicks: 0, clickCount: 10, wantRemaining: 0, wantLimited: false},
+		{name: "unused single-use", maxClicks: 1, clickCount: 0, wantRemaining: 1, wantLimited: true},
+		{name: "partially used", maxClicks: 5, clickCount: 2, wantRemaining: 3, wantLimited: true},
+		{name: "exhausted", maxClicks: 3, clickCount: 3, wantRemaining: 0, wantLimited: true},
+		{name: "over limit clamps at zero", maxClicks: 3, clickCount: 4, wantRemaining: 0, wantLimited: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &domain.URLRecord{MaxClicks: tt.maxClicks, ClickCount: tt.clickCount}
+
+			remaining, limited := record.RemainingClicks()
+			assert.Equal(t, tt.wantRemaining, remaining)
+			assert.Equal(t, tt.wantLimited, limited)
+		})
+	}
 }
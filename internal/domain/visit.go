@@ -0,0 +1,13 @@
+package domain
+
+// Visit describes the client following a short link.
+// Fields are empty when unknown.
+type Visit struct {
+	ClientIP string
+
+	// Referer is the raw Referer header of the request.
+	Referer string
+
+	// UserAgent is the raw User-Agent header of the request.
+	UserAgent string
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// prefersJSON reports whether an Accept header ranks application/json above
+// text/html, the stand-in for a browser following a redirect. Ties, such as
+// a bare "*/*" or a missing header, go to the redirect.
+func prefersJSON(accept string) bool {
+	if accept == "" {
+		return false
+	}
+	jsonQ := acceptQuality(accept, "application/json")
+	return jsonQ > 0 && jsonQ > acceptQuality(accept, "text/html")
+}
+
+// prefersHTML reports whether an Accept header ranks text/html above
+// application/json, as browsers do. Ties, such as a bare "*/*" or a missing
+// header, go to JSON.
+func prefersHTML(accept string) bool {
+	htmlQ := acceptQuality(accept, "text/html")
+	return htmlQ > 0 && htmlQ > acceptQuality(accept, "application/json")
+}
+
+// acceptQuality returns the q-value accept assigns to mediaType, taken from
+// the most specific matching range as RFC 9110 requires. Malformed ranges
+// are skipped; a type no range matches gets 0.
+func acceptQuality(accept, mediaType string) float64 {
+	quality, best := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		rangeType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		specificity := matchSpecificity(rangeType, mediaType)
+		if specificity <= best {
+			continue
+		}
+		q, ok := parseQuality(params["q"])
+		if !ok {
+			continue
+		}
+		quality, best = q, specificity
+	}
+	return quality
+}
+
+// matchSpecificity ranks how closely rangeType matches mediaType: 2 for an
+// exact match, 1 for "type/*", 0 for "*/*", and -1 for no match.
+func matchSpecificity(rangeType, mediaType string) int {
+	switch {
+	case rangeType == mediaType:
+		return 2
+	case rangeType == "*/*":
+		return 0
+	case strings.HasSuffix(rangeType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(rangeType, "*")):
+		return 1
+	default:
+		return -1
+	}
+}
+
+// parseQuality parses a q parameter, defaulting to 1 when it is absent.
+func parseQuality(raw string) (float64, bool) {
+	if raw == "" {
+		return 1, true
+	}
+	q, err := strconv.ParseFloat(raw, 64)
+	if err != nil || q < 0 || q > 1 {
+		return 0, false
+	}
+	return q, true
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// BaseURLForHost returns a WithBaseURLFunc function that picks the short URL
+// base whose host matches the request's Host header, so links created on
+// one of several short domains point back at it. bases are absolute URLs
+// such as "https://go.acme.com"; only their hosts are accepted, so an
+// arbitrary Host header can't choose the domain. Unrecognized hosts yield
+// "", falling back to the static base URL.
+func BaseURLForHost(bases []string) (func(*http.Request) string, error) {
+	byHost := make(map[string]string, len(bases))
+	for _, base := range bases {
+		u, err := url.Parse(base)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("base URL %q must be an absolute http or https URL", base)
+		}
+		byHost[strings.ToLower(u.Host)] = strings.TrimSuffix(base, "/")
+	}
+
+	return func(r *http.Request) string {
+		host := strings.ToLower(r.Host)
+		if base, ok := byHost[host]; ok {
+			return base
+		}
+		// Clients may send the default port explicitly.
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			return byHost[hostname]
+		}
+		return ""
+	}, nil
+}
+
+// OriginResolver reports the scheme and host a client addressed, as told by
+// trusted proxies. *clientip.Resolver satisfies it.
+type OriginResolver interface {
+	ForwardedOrigin(r *http.Request) (proto, host string)
+}
+
+// WithForwardedOrigin builds short URLs from the scheme and host that
+// resolver reports, so links created through a TLS-terminating proxy get
+// its public https address. Whatever the proxy doesn't report, and the
+// path, come from the base URL. Combined with WithBaseURLFunc, only the
+// hosts it accepts are used.
+func WithForwardedOrigin(resolver OriginResolver) Option {
+	return func(h *Handler) {
+		h.origins = resolver
+	}
+}
+
+// requestBaseURL returns the short URL base for r. A forwarded host stands
+// in for r.Host when baseURLFunc picks among short domains, and if it picks
+// none the static base URL is used, so a forwarded host outside the list
+// never reaches a short URL. Without baseURLFunc, the forwarded scheme and
+// host replace those of the base URL.
+func (h *Handler) requestBaseURL(r *http.Request) string {
+	var proto, host string
+	if h.origins != nil {
+		proto, host = h.origins.ForwardedOrigin(r)
+	}
+
+	if h.baseURLFunc != nil {
+		view := r
+		if host != "" {
+			// A shallow copy will do, as baseURLFunc only reads it.
+			forwarded := *r
+			forwarded.Host = host
+			view = &forwarded
+		}
+		if derived := h.baseURLFunc(view); derived != "" {
+			return derived
+		}
+		return h.baseURL
+	}
+
+	if proto == "" && host == "" {
+		return h.baseURL
+	}
+	u, err := url.Parse(h.baseURL)
+	if err != nil {
+		return h.baseURL
+	}
+	if proto != "" {
+		u.Scheme = proto
+	}
+	if host != "" {
+		u.Host = host
+	}
+	return u.String()
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"url-shortener/internal/domain"
+)
+
+// CreateBatch handles POST /shorten/batch requests. Each item is validated
+// and created independently; one bad item does not fail the others. The
+// response is 200 when every item was created and 207 Multi-Status
+// otherwise, with per-item results in request order. Bodies over
+// maxBatchCreateBodyBytes get 413.
+func (h *Handler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	var req BatchCreateRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchCreateBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeBodyError(w, err)
+		return
+	}
+
+	if len(req.URLs) == 0 {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "urls must not be empty")
+		return
+	}
+	if len(req.URLs) > maxBatchCreate {
+		h.writeError(w, http.StatusBadRequest, "validation_error",
+			fmt.Sprintf("urls must not contain more than %d items", maxBatchCreate))
+		return
+	}
+	// Each item costs as much of the rate limit as a single create; the
+	// middleware has taken the first token already.
+	if ok, retryAfter := chargeRate(r.Context(), len(req.URLs)-1); !ok {
+		WriteRateLimited(w, retryAfter)
+		return
+	}
+
+	resp := BatchCreateResponse{Results: make([]BatchCreateResult, len(req.URLs))}
+
+	// Only valid items reach the service; indexes maps them back.
+	var (
+		params  []domain.CreateParams
+		indexes []int
+	)
+	limits := h.limits.Load()
+	for i, item := range req.URLs {
+		p, err := h.createParams(r.Context(), limits, item)
+		if err != nil {
+			errResp := validationResponse(err)
+			resp.Results[i] = BatchCreateResult{Index: i, Status: http.StatusBadRequest, Error: &errResp}
+			continue
+		}
+		params = append(params, p)
+		indexes = append(indexes, i)
+	}
+
+	for j, result := range h.service.CreateBatch(r.Context(), params) {
+		i := indexes[j]
+		if result.Err != nil {
+			status, errResp := createError(result.Err)
+			resp.Results[i] = BatchCreateResult{Index: i, Status: status, Error: &errResp}
+			continue
+		}
+		created := h.toCreateResponse(r, result.Record)
+		resp.Results[i] = BatchCreateResult{Index: i, Status: http.StatusCreated, Result: &created}
+	}
+
+	for _, result := range resp.Results {
+		if result.Error == nil {
+			resp.Created++
+		} else {
+			resp.Failed++
+		}
+	}
+
+	status := http.StatusOK
+	if resp.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	h.writeJSON(w, status, resp)
+}
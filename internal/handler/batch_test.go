@@ -0,0 +1,135 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateBatchHandler_MixedItems_Returns207WithPerItemResults(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	expiresAt := time.Date(2024, 1, 16, 12, 0, 0, 0, time.UTC)
+	mockService.On("CreateBatch", mock.Anything, []domain.CreateParams{
+		{LongURL: "https://example.com/a", TTL: 24 * time.Hour},
+		{LongURL: "https://example.com/c", TTL: time.Hour, CustomAlias: "taken"},
+	}).Return([]domain.CreateResult{
+		{Record: &domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com/a", ExpiresAt: expiresAt}},
+		{Err: domain.ErrAliasTaken},
+	})
+
+	body := `{"urls": [
+		{"long_url": "https://example.com/a"},
+		{"long_url": "not-a-url"},
+		{"long_url": "https://example.com/c", "ttl_seconds": 3600, "custom_alias": "taken"}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/shorten/batch", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	h.CreateBatch(rec, req)
+
+	assert.Equal(t, http.StatusMultiStatus, rec.Code)
+
+	var resp handler.BatchCreateResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 3)
+	assert.Equal(t, 1, resp.Created)
+	assert.Equal(t, 2, resp.Failed)
+
+	assert.Equal(t, 0, resp.Results[0].Index)
+	assert.Equal(t, http.StatusCreated, resp.Results[0].Status)
+	require.NotNil(t, resp.Results[0].Result)
+	assert.Equal(t, "http://localhost:8080/s/Ab2CdE3F", resp.Results[0].Result.ShortURL)
+	assert.Nil(t, resp.Results[0].Error)
+
+	assert.Equal(t, 1, resp.Results[1].Index)
+	assert.Equal(t, http.StatusBadRequest, resp.Results[1].Status)
+	require.NotNil(t, resp.Results[1].Error)
+	assert.Equal(t, "validation_error", resp.Results[1].Error.Error)
+
+	assert.Equal(t, 2, resp.Results[2].Index)
+	assert.Equal(t, http.StatusConflict, resp.Results[2].Status)
+	require.NotNil(t, resp.Results[2].Error)
+	assert.Equal(t, "alias_taken", resp.Results[2].Error.Error)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateBatchHandler_AllCreated_Returns200(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("CreateBatch", mock.Anything, mock.Anything).Return([]domain.CreateResult{
+		{Record: &domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com/a"}},
+	})
+
+	body := `{"urls": [{"long_url": "https://example.com/a"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/shorten/batch", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	h.CreateBatch(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestCreateBatchHandler_InvalidBatch_Returns400(t *testing.T) {
+	items := make([]string, 101)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"long_url": "https://example.com/%d"}`, i)
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid json", `{"urls": [`},
+		{"empty", `{"urls": []}`},
+		{"missing urls", `{}`},
+		{"too many", `{"urls": [` + strings.Join(items, ",") + `]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+
+			req := httptest.NewRequest(http.MethodPost, "/shorten/batch", bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+
+			h.CreateBatch(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			mockService.AssertNotCalled(t, "CreateBatch", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCreateBatchHandler_BodyTooLarge_Returns413(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	body := `{"urls": [{"long_url": "https://example.com", "notes": "` + strings.Repeat("x", 7<<20) + `"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/shorten/batch", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	h.CreateBatch(rec, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	var resp handler.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "body_too_large", resp.Error)
+	mockService.AssertNotCalled(t, "CreateBatch", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// BulkDelete handles POST /admin/bulk-delete requests, soft-deleting every
+// link whose long URL starts with a prefix, or permanently removing every
+// link that expires before a timestamp.
+func (h *Handler) BulkDelete(w http.ResponseWriter, r *http.Request) {
+	var req BulkDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_json", "invalid JSON body")
+		return
+	}
+
+	hasPrefix, hasBefore := strings.TrimSpace(req.Prefix) != "", req.Before != ""
+	if hasPrefix == hasBefore {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "exactly one of prefix or before is required")
+		return
+	}
+
+	var (
+		codes []string
+		err   error
+	)
+	if hasPrefix {
+		if !validBulkDeletePrefix(req.Prefix) {
+			h.writeError(w, http.StatusBadRequest, "validation_error", "prefix must include a scheme and host, such as https://example.com/campaign")
+			return
+		}
+		codes, err = h.service.DeleteByPrefix(r.Context(), req.Prefix)
+	} else {
+		before, parseErr := time.Parse(time.RFC3339, req.Before)
+		if parseErr != nil {
+			h.writeError(w, http.StatusBadRequest, "validation_error", "before must be an RFC 3339 timestamp")
+			return
+		}
+		codes, err = h.service.DeleteExpiringBefore(r.Context(), before)
+	}
+	if err != nil {
+		h.writeInternalError(w, err, "failed to delete links")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, BulkDeleteResponse{Deleted: len(codes)})
+}
+
+// validBulkDeletePrefix reports whether prefix names at least a scheme and
+// host, so that a prefix such as "https://" can't match every link.
+func validBulkDeletePrefix(prefix string) bool {
+	u, err := url.Parse(prefix)
+	if err != nil || u.Scheme == "" {
+		return false
+	}
+	return u.Host != "" || u.Opaque != ""
+}
@@ -0,0 +1,103 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkDeleteHandler_ByPrefix(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	mockService.On("DeleteByPrefix", mock.Anything, "https://example.com/spring/").
+		Return([]string{"spring01", "spring02"}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/bulk-delete",
+		bytes.NewBufferString(`{"prefix": "https://example.com/spring/"}`))
+	rec := httptest.NewRecorder()
+
+	h.BulkDelete(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp handler.BulkDeleteResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Deleted)
+	mockService.AssertExpectations(t)
+}
+
+func TestBulkDeleteHandler_ByExpiry(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	before := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	mockService.On("DeleteExpiringBefore", mock.Anything, mock.MatchedBy(before.Equal)).
+		Return([]string{"expired1"}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/bulk-delete",
+		bytes.NewBufferString(`{"before": "2024-02-01T00:00:00Z"}`))
+	rec := httptest.NewRecorder()
+
+	h.BulkDelete(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp handler.BulkDeleteResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Deleted)
+	mockService.AssertExpectations(t)
+}
+
+func TestBulkDeleteHandler_InvalidRequests(t *testing.T) {
+	testCases := []struct {
+		name    string
+		body    string
+		wantMsg string
+	}{
+		{name: "neither filter", body: `{}`, wantMsg: "exactly one of prefix or before is required"},
+		{name: "blank prefix", body: `{"prefix": "   "}`, wantMsg: "exactly one of prefix or before is required"},
+		{name: "both filters", body: `{"prefix": "https://example.com/", "before": "2024-02-01T00:00:00Z"}`, wantMsg: "exactly one of prefix or before is required"},
+		{name: "scheme-only prefix", body: `{"prefix": "https://"}`, wantMsg: "prefix must include a scheme and host"},
+		{name: "path-only prefix", body: `{"prefix": "/spring"}`, wantMsg: "prefix must include a scheme and host"},
+		{name: "malformed before", body: `{"before": "last tuesday"}`, wantMsg: "before must be an RFC 3339 timestamp"},
+		{name: "invalid json", body: `{`, wantMsg: "invalid JSON body"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/bulk-delete", bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			h.BulkDelete(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.wantMsg)
+			mockService.AssertNotCalled(t, "DeleteByPrefix", mock.Anything, mock.Anything)
+			mockService.AssertNotCalled(t, "DeleteExpiringBefore", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestBulkDeleteHandler_ServiceError_Returns500(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	mockService.On("DeleteByPrefix", mock.Anything, mock.Anything).Return(nil, errors.New("disk on fire"))
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/bulk-delete",
+		bytes.NewBufferString(`{"prefix": "https://example.com/spring/"}`))
+	rec := httptest.NewRecorder()
+
+	h.BulkDelete(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// JSONCase selects how the keys of JSON responses are spelled.
+type JSONCase string
+
+const (
+	// SnakeCase keeps the keys as the DTOs declare them, e.g. "short_url".
+	SnakeCase JSONCase = "snake"
+
+	// CamelCase rewrites them to camelCase, e.g. "shortUrl".
+	CamelCase JSONCase = "camel"
+)
+
+// ParseJSONCase parses "snake" or "camel". An empty string is SnakeCase.
+func ParseJSONCase(raw string) (JSONCase, error) {
+	switch c := JSONCase(strings.ToLower(strings.TrimSpace(raw))); c {
+	case "", SnakeCase:
+		return SnakeCase, nil
+	case CamelCase:
+		return c, nil
+	default:
+		return "", fmt.Errorf("JSON case must be %q or %q, got %q", SnakeCase, CamelCase, raw)
+	}
+}
+
+// WithJSONCase sets the casing of response keys. Request bodies are always
+// read as snake_case.
+func WithJSONCase(c JSONCase) Option {
+	return func(h *Handler) {
+		h.camelCase = c == CamelCase
+	}
+}
+
+// camelizeKeys rewrites every object key in the JSON document data from
+// snake_case to camelCase, keeping key order and values unchanged.
+func camelizeKeys(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	// Each open container tracks whether it is an object and how many
+	// tokens it has held, which tells keys from values and where commas go.
+	type container struct {
+		object bool
+		tokens int
+	}
+	var stack []container
+
+	var out bytes.Buffer
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if delim, ok := tok.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			out.WriteByte(byte(delim))
+			continue
+		}
+
+		isKey := false
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			switch {
+			case top.object && top.tokens%2 == 1:
+				out.WriteByte(':')
+			case top.tokens > 0:
+				out.WriteByte(',')
+			}
+			isKey = top.object && top.tokens%2 == 0
+			top.tokens++
+		}
+
+		switch v := tok.(type) {
+		case json.Delim:
+			out.WriteByte(byte(v))
+			stack = append(stack, container{object: v == '{'})
+		case string:
+			if isKey {
+				v = snakeToCamel(v)
+			}
+			encoded, _ := json.Marshal(v)
+			out.Write(encoded)
+		default:
+			encoded, _ := json.Marshal(v)
+			out.Write(encoded)
+		}
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// snakeToCamel converts "short_url" to "shortUrl".
+func snakeToCamel(key string) string {
+	if !strings.Contains(key, "_") {
+		return key
+	}
+	var b strings.Builder
+	b.Grow(len(key))
+	upper := false
+	for _, r := range key {
+		if r == '_' {
+			upper = b.Len() > 0
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
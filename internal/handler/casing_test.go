@@ -0,0 +1,162 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSONCase(t *testing.T) {
+	for raw, want := range map[string]handler.JSONCase{
+		"":       handler.SnakeCase,
+		"snake":  handler.SnakeCase,
+		"camel":  handler.CamelCase,
+		" Camel": handler.CamelCase,
+	} {
+		got, err := handler.ParseJSONCase(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, want, got, raw)
+	}
+
+	_, err := handler.ParseJSONCase("kebab")
+	assert.Error(t, err)
+}
+
+// responseKeys decodes body as a JSON object and returns its keys.
+func responseKeys(t *testing.T, body []byte) map[string]json.RawMessage {
+	t.Helper()
+	var obj map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &obj))
+	return obj
+}
+
+func TestJSONCase_CreateResponse(t *testing.T) {
+	testCases := []struct {
+		jsonCase handler.JSONCase
+		want     []string
+		notWant  []string
+	}{
+		{jsonCase: handler.SnakeCase, want: []string{"short_code", "short_url", "long_url", "expires_at"}, notWant: []string{"shortCode"}},
+		{jsonCase: handler.CamelCase, want: []string{"shortCode", "shortUrl", "longUrl", "expiresAt"}, notWant: []string{"short_code"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.jsonCase), func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080", handler.WithJSONCase(tc.jsonCase))
+			mockService.On("Create", mock.Anything, mock.Anything).Return(&domain.URLRecord{
+				ShortCode: "abc12345",
+				LongURL:   "https://example.com/a_b?x_y=1",
+				ExpiresAt: time.Now().Add(time.Hour),
+			}, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"long_url": "https://example.com/a_b?x_y=1"}`))
+			rec := httptest.NewRecorder()
+
+			h.Create(rec, req)
+
+			require.Equal(t, http.StatusCreated, rec.Code)
+			keys := responseKeys(t, rec.Body.Bytes())
+			for _, key := range tc.want {
+				assert.Contains(t, keys, key)
+			}
+			for _, key := range tc.notWant {
+				assert.NotContains(t, keys, key)
+			}
+			// Values are never rewritten.
+			assert.JSONEq(t, `"https://example.com/a_b?x_y=1"`, string(keys[tc.want[2]]))
+		})
+	}
+}
+
+func TestJSONCase_StatsResponse(t *testing.T) {
+	testCases := []struct {
+		jsonCase handler.JSONCase
+		want     []string
+	}{
+		{jsonCase: handler.SnakeCase, want: []string{"short_code", "click_count", "last_accessed_at", "expires_in_seconds", "remaining_clicks", "clicks_per_day", "enabled"}},
+		{jsonCase: handler.CamelCase, want: []string{"shortCode", "clickCount", "lastAccessedAt", "expiresInSeconds", "remainingClicks", "clicksPerDay", "enabled"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.jsonCase), func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080", handler.WithJSONCase(tc.jsonCase))
+			mockService.On("GetStats", mock.Anything, "Ab2CdE3F").Return(&domain.URLRecord{
+				ShortCode:  "Ab2CdE3F",
+				LongURL:    "https://example.com",
+				CreatedAt:  time.Now(),
+				ExpiresAt:  time.Now().Add(time.Hour),
+				ClickCount: 7,
+				Enabled:    true,
+			}, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F", nil)
+			req.SetPathValue("code", "Ab2CdE3F")
+			rec := httptest.NewRecorder()
+
+			h.Stats(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			keys := responseKeys(t, rec.Body.Bytes())
+			allFields, err := json.Marshal(handler.StatsResponse{})
+			require.NoError(t, err)
+			assert.Len(t, keys, len(responseKeys(t, allFields)))
+			for _, key := range tc.want {
+				assert.Contains(t, keys, key)
+			}
+			assert.JSONEq(t, "7", string(keys[tc.want[1]]))
+			assert.JSONEq(t, "null", string(keys[tc.want[2]]))
+		})
+	}
+}
+
+func TestJSONCase_ErrorResponse(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithJSONCase(handler.CamelCase))
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"long_url": "https://example.com", "custom_alias": "a"}`))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	var resp map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "validation_error", resp["error"])
+	assert.Equal(t, "custom_alias", resp["field"], "field names the request field as sent")
+	assert.NotEmpty(t, resp["message"])
+}
+
+func TestJSONCase_NestedObjects(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithJSONCase(handler.CamelCase))
+	mockService.On("CreateBatch", mock.Anything, mock.Anything).Return([]domain.CreateResult{
+		{Record: &domain.URLRecord{
+			ShortCode: "abc12345",
+			LongURL:   "https://example.com",
+			ExpiresAt: time.Now().Add(time.Hour),
+		}},
+	})
+
+	body := `{"urls": [{"long_url": "https://example.com"}, {"long_url": "not a url"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/shorten/batch", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	h.CreateBatch(rec, req)
+
+	require.Equal(t, http.StatusMultiStatus, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"shortUrl":`)
+	assert.Contains(t, rec.Body.String(), `"error":{"error":"validation_error"`)
+	assert.NotContains(t, rec.Body.String(), `"short_url"`)
+}
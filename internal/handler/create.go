@@ -1,51 +1,205 @@
 package handler
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
+
+	"url-shortener/internal/domain"
 )
 
-const defaultTTL = 24 * time.Hour
+// IdempotencyKeyHeader lets clients retry POST /shorten without creating
+// duplicate links: repeats of a key get the original link back.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds the keys the service has to remember.
+const maxIdempotencyKeyLength = 255
 
-// Create handles POST /shorten requests.
+// Create handles POST /shorten requests. The 201 response carries the new
+// short URL in its Location header as well as its body. A request repeating
+// an earlier Idempotency-Key gets the link created for it, marked with an
+// Idempotent-Replayed header, or 422 if its body differs.
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		h.writeError(w, http.StatusBadRequest, "validation_error",
+			fmt.Sprintf("%s must not exceed %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+		return
+	}
+
 	var req CreateRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_json", "invalid JSON body")
+	if !h.decodeCreateRequest(w, r, &req) {
 		return
 	}
 
-	// Validate URL
-	if err := validateURL(req.LongURL); err != nil {
-		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+	params, err := h.createParams(r.Context(), h.limits.Load(), req)
+	if err != nil {
+		h.writeValidationError(w, r, err)
+		return
+	}
+
+	// Call service
+	var (
+		record   *domain.URLRecord
+		attempts int
+		replayed bool
+	)
+	if key != "" {
+		ctx := r.Context()
+		if domain.ClientIDFromContext(ctx) == "" {
+			// Anonymous callers are told apart by address instead.
+			ctx = domain.WithClientID(ctx, "ip:"+h.clientIP(r))
+		}
+		record, attempts, replayed, err = h.service.CreateIdempotent(ctx, key, params)
+	} else {
+		record, attempts, err = h.service.Create(r.Context(), params)
+	}
+	if err != nil {
+		status, errResp := createError(err)
+		h.writeError(w, status, errResp.Error, errResp.Message)
 		return
 	}
 
+	if replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+	if attempts > 0 {
+		w.Header().Set("X-Code-Generation-Attempts", strconv.Itoa(attempts))
+	}
+	resp := h.toCreateResponse(r, record)
+	w.Header().Set("Location", resp.ShortURL)
+	h.writeJSON(w, http.StatusCreated, resp)
+}
+
+// createParams validates req against limits and converts it to service
+// parameters. The returned error message is safe to show to the client.
+func (h *Handler) createParams(ctx context.Context, limits *activeLimits, req CreateRequest) (domain.CreateParams, error) {
+	// Validate URL
+	if err := h.urlRules.validate(req.LongURL); err != nil {
+		return domain.CreateParams{}, err
+	}
+	longURL := normalizeURL(req.LongURL, h.sortQuery)
+	if len(limits.blockedHosts) > 0 {
+		if err := validateHostNotBlocked(longURL, limits.blockedHosts); err != nil {
+			return domain.CreateParams{}, err
+		}
+	}
+	if h.hostResolver != nil {
+		if err := validatePublicHost(ctx, longURL, h.hostResolver); err != nil {
+			return domain.CreateParams{}, err
+		}
+	}
+
 	// Determine TTL
-	ttl := defaultTTL
-	if req.TTLSeconds != nil {
+	maxTTL := h.maxTTL(ctx, limits)
+	ttl := min(limits.ttl.Default, maxTTL)
+	if req.NoExpiry {
+		if req.TTLSeconds != nil {
+			return domain.CreateParams{}, invalidField("no_expiry", "no_expiry cannot be combined with ttl_seconds")
+		}
+		if h.anonymousCapped(ctx) {
+			return domain.CreateParams{}, invalidField("no_expiry", "no_expiry requires an API key")
+		}
+		ttl = 0
+	} else if req.TTLSeconds != nil {
 		ttl = time.Duration(*req.TTLSeconds) * time.Second
-		if err := validateTTL(ttl); err != nil {
-			h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
-			return
+		if err := limits.validateTTL(ttl, maxTTL); err != nil {
+			return domain.CreateParams{}, err
 		}
 	}
 
-	// Call service
-	record, err := h.service.Create(r.Context(), req.LongURL, ttl)
+	var maxClicks int64
+	if req.MaxClicks != nil {
+		if *req.MaxClicks < 1 {
+			return domain.CreateParams{}, invalidField("max_clicks", "max_clicks must be at least 1")
+		}
+		maxClicks = *req.MaxClicks
+	}
+
+	// Validate custom alias
+	if req.CustomAlias != "" {
+		if err := validateAlias(req.CustomAlias, h.alphabet); err != nil {
+			return domain.CreateParams{}, err
+		}
+	}
+
+	tags, err := normalizeTags(req.Tags)
 	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, "internal_error", "failed to create short URL")
-		return
+		return domain.CreateParams{}, err
+	}
+
+	// Last, since it is the only check that goes over the network
+	if req.VerifyDestination {
+		if h.destinationClient == nil {
+			return domain.CreateParams{}, invalidField("verify_destination", "verify_destination is not enabled on this server")
+		}
+		if err := verifyDestination(ctx, longURL, h.destinationClient, h.hostResolver, limits.blockedHosts); err != nil {
+			return domain.CreateParams{}, err
+		}
 	}
 
-	// Build response
-	resp := CreateResponse{
+	return domain.CreateParams{
+		LongURL:     longURL,
+		TTL:         ttl,
+		NoExpiry:    req.NoExpiry,
+		CustomAlias: req.CustomAlias,
+		Permanent:   req.Permanent,
+		MaxClicks:   maxClicks,
+		Tags:        tags,
+	}, nil
+}
+
+// writeValidationError answers a request that failed validation with 400
+// validation_error, naming the offending field when err is a
+// ValidationError, and logs the failure to help debug client integrations.
+func (h *Handler) writeValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	resp := validationResponse(err)
+	slog.InfoContext(r.Context(), "request failed validation",
+		"method", r.Method, "path", r.URL.Path, "field", resp.Field, "reason", resp.Message)
+	h.writeJSON(w, http.StatusBadRequest, resp)
+}
+
+// validationResponse converts a validation error to a validation_error
+// response.
+func validationResponse(err error) ErrorResponse {
+	resp := ErrorResponse{Error: "validation_error", Message: err.Error()}
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		resp.Field = invalid.Field
+	}
+	return resp
+}
+
+// createError maps a URLService.Create error to a response.
+func createError(err error) (int, ErrorResponse) {
+	switch {
+	case errors.Is(err, domain.ErrAliasTaken):
+		return http.StatusConflict, ErrorResponse{Error: "alias_taken", Message: "custom_alias is already taken"}
+	case errors.Is(err, domain.ErrInvalidAlias):
+		return http.StatusBadRequest, ErrorResponse{Error: "validation_error", Message: "custom_alias is not allowed"}
+	case errors.Is(err, domain.ErrIdempotencyKeyReused):
+		return http.StatusUnprocessableEntity, ErrorResponse{Error: "idempotency_key_reused", Message: IdempotencyKeyHeader + " was already used for a different request"}
+	case errors.Is(err, domain.ErrExpiryTooLate):
+		return http.StatusBadRequest, ErrorResponse{Error: "validation_error", Message: err.Error(), Field: "ttl_seconds"}
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable, ErrorResponse{Error: "timeout", Message: "request timed out, try again later"}
+	case errors.Is(err, domain.ErrCapacityExceeded):
+		return http.StatusServiceUnavailable, ErrorResponse{Error: "capacity_exceeded", Message: "storage is full, try again later"}
+	default:
+		return http.StatusInternalServerError, ErrorResponse{Error: "internal_error", Message: "failed to create short URL"}
+	}
+}
+
+func (h *Handler) toCreateResponse(r *http.Request, record *domain.URLRecord) CreateResponse {
+	return CreateResponse{
 		ShortCode: record.ShortCode,
-		ShortURL:  h.baseURL + "/s/" + record.ShortCode,
+		ShortURL:  h.shortURL(r, record.ShortCode),
 		LongURL:   record.LongURL,
-		ExpiresAt: record.ExpiresAt.Format(time.RFC3339),
+		ExpiresAt: formatExpiry(record),
+		Tags:      record.Tags,
 	}
-
-	h.writeJSON(w, http.StatusCreated, resp)
 }
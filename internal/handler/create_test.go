@@ -4,11 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
+	"url-shortener/internal/clientip"
 	"url-shortener/internal/domain"
 	"url-shortener/internal/handler"
 
@@ -22,17 +28,48 @@ type MockURLService struct {
 	mock.Mock
 }
 
-func (m *MockURLService) Create(ctx context.Context, longURL string, ttl time.Duration) (*domain.URLRecord, error) {
-	args := m.Called(ctx, longURL, ttl)
+// Create returns the record and error given to Return. Tests that care
+// about attempts pass Return(record, attempts, err); otherwise one attempt
+// is reported.
+func (m *MockURLService) Create(ctx context.Context, params domain.CreateParams) (*domain.URLRecord, int, error) {
+	args := m.Called(ctx, params)
+	attempts, errIndex := 1, 1
+	if len(args) == 3 {
+		attempts, errIndex = args.Int(1), 2
+	}
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(errIndex)
+	}
+	return args.Get(0).(*domain.URLRecord), attempts, args.Error(errIndex)
+}
+
+func (m *MockURLService) CreateIdempotent(ctx context.Context, key string, params domain.CreateParams) (*domain.URLRecord, int, bool, error) {
+	args := m.Called(ctx, key, params)
+	if args.Get(0) == nil {
+		return nil, 0, false, args.Error(3)
+	}
+	return args.Get(0).(*domain.URLRecord), args.Int(1), args.Bool(2), args.Error(3)
+}
+
+func (m *MockURLService) CreateBatch(ctx context.Context, items []domain.CreateParams) []domain.CreateResult {
+	args := m.Called(ctx, items)
+	return args.Get(0).([]domain.CreateResult)
+}
+
+func (m *MockURLService) Resolve(ctx context.Context, shortCode string, visit domain.Visit) (*domain.URLRecord, error) {
+	args := m.Called(ctx, shortCode, visit)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.URLRecord), args.Error(1)
 }
 
-func (m *MockURLService) Resolve(ctx context.Context, shortCode string) (string, error) {
+func (m *MockURLService) Lookup(ctx context.Context, shortCode string) (*domain.URLRecord, error) {
 	args := m.Called(ctx, shortCode)
-	return args.String(0), args.Error(1)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URLRecord), args.Error(1)
 }
 
 func (m *MockURLService) GetStats(ctx context.Context, shortCode string) (*domain.URLRecord, error) {
@@ -43,6 +80,120 @@ func (m *MockURLService) GetStats(ctx context.Context, shortCode string) (*domai
 	return args.Get(0).(*domain.URLRecord), args.Error(1)
 }
 
+func (m *MockURLService) GetStatsIncludingExpired(ctx context.Context, shortCode string) (*domain.URLRecord, error) {
+	args := m.Called(ctx, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URLRecord), args.Error(1)
+}
+
+func (m *MockURLService) GetClickSeries(ctx context.Context, shortCode string, from, to time.Time) ([]domain.DayClicks, error) {
+	args := m.Called(ctx, shortCode, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.DayClicks), args.Error(1)
+}
+
+func (m *MockURLService) GetReferrers(ctx context.Context, shortCode string, limit int) ([]domain.ReferrerCount, error) {
+	args := m.Called(ctx, shortCode, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.ReferrerCount), args.Error(1)
+}
+
+func (m *MockURLService) GetStatsBatch(ctx context.Context, shortCodes []string, consistent bool) ([]*domain.URLRecord, error) {
+	args := m.Called(ctx, shortCodes, consistent)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.URLRecord), args.Error(1)
+}
+
+func (m *MockURLService) GetHistory(ctx context.Context, shortCode string) ([]domain.HistoryEvent, error) {
+	args := m.Called(ctx, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.HistoryEvent), args.Error(1)
+}
+
+func (m *MockURLService) UpdateTTL(ctx context.Context, shortCode string, ttl time.Duration) (*domain.URLRecord, error) {
+	args := m.Called(ctx, shortCode, ttl)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URLRecord), args.Error(1)
+}
+
+func (m *MockURLService) SetEnabled(ctx context.Context, shortCode string, enabled bool) (*domain.URLRecord, error) {
+	args := m.Called(ctx, shortCode, enabled)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URLRecord), args.Error(1)
+}
+
+func (m *MockURLService) Delete(ctx context.Context, shortCode string) error {
+	args := m.Called(ctx, shortCode)
+	return args.Error(0)
+}
+
+func (m *MockURLService) Restore(ctx context.Context, shortCode string) (*domain.URLRecord, error) {
+	args := m.Called(ctx, shortCode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URLRecord), args.Error(1)
+}
+
+func (m *MockURLService) GetStoreStats(ctx context.Context, top int) (domain.StoreStats, error) {
+	args := m.Called(ctx, top)
+	return args.Get(0).(domain.StoreStats), args.Error(1)
+}
+
+func (m *MockURLService) PurgeExpired(ctx context.Context) ([]string, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockURLService) DeleteByPrefix(ctx context.Context, prefix string) ([]string, error) {
+	args := m.Called(ctx, prefix)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockURLService) DeleteExpiringBefore(ctx context.Context, before time.Time) ([]string, error) {
+	args := m.Called(ctx, before)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockURLService) List(ctx context.Context, offset, limit int) ([]*domain.URLRecord, int, error) {
+	args := m.Called(ctx, offset, limit)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]*domain.URLRecord), args.Int(1), args.Error(2)
+}
+
+func (m *MockURLService) ListByTag(ctx context.Context, tag string, offset, limit int) ([]*domain.URLRecord, int, error) {
+	args := m.Called(ctx, tag, offset, limit)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]*domain.URLRecord), args.Int(1), args.Error(2)
+}
+
 func TestCreateHandler_ValidRequest_Returns201(t *testing.T) {
 	// Arrange
 	mockService := new(MockURLService)
@@ -55,7 +206,7 @@ func TestCreateHandler_ValidRequest_Returns201(t *testing.T) {
 		ExpiresAt: time.Date(2024, 1, 16, 12, 0, 0, 0, time.UTC),
 	}
 
-	mockService.On("Create", mock.Anything, "https://example.com/path", 24*time.Hour).
+	mockService.On("Create", mock.Anything, domain.CreateParams{LongURL: "https://example.com/path", TTL: 24 * time.Hour}).
 		Return(expectedRecord, nil)
 
 	body := `{"long_url": "https://example.com/path"}`
@@ -77,11 +228,65 @@ func TestCreateHandler_ValidRequest_Returns201(t *testing.T) {
 	assert.Equal(t, "Ab2CdE3F", resp.ShortCode)
 	assert.Equal(t, "http://localhost:8080/s/Ab2CdE3F", resp.ShortURL)
 	assert.Equal(t, "https://example.com/path", resp.LongURL)
-	assert.Equal(t, "2024-01-16T12:00:00Z", resp.ExpiresAt)
+	require.NotNil(t, resp.ExpiresAt)
+	assert.Equal(t, "2024-01-16T12:00:00Z", *resp.ExpiresAt)
 
 	mockService.AssertExpectations(t)
 }
 
+func TestCreateHandler_LocationHeaderMatchesShortURL(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Create", mock.Anything, mock.Anything).Return(&domain.URLRecord{
+		ShortCode: "Ab2CdE3F",
+		LongURL:   "https://example.com/path",
+		CreatedAt: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+		ExpiresAt: time.Date(2024, 1, 16, 12, 0, 0, 0, time.UTC),
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"long_url": "https://example.com/path"}`))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var resp handler.CreateResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "http://localhost:8080/s/Ab2CdE3F", rec.Header().Get("Location"))
+	assert.Equal(t, resp.ShortURL, rec.Header().Get("Location"))
+}
+
+func TestCreateHandler_CodeGenerationAttemptsHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		attempts   int
+		wantHeader string
+	}{
+		{name: "no collisions", attempts: 1, wantHeader: "1"},
+		{name: "after collisions", attempts: 3, wantHeader: "3"},
+		{name: "no code generated", attempts: 0, wantHeader: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+			mockService.On("Create", mock.Anything, mock.Anything).
+				Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com"}, tt.attempts, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"long_url": "https://example.com"}`))
+			rec := httptest.NewRecorder()
+
+			h.Create(rec, req)
+
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			assert.Equal(t, tt.wantHeader, rec.Header().Get("X-Code-Generation-Attempts"))
+			assert.NotContains(t, rec.Body.String(), "attempts")
+		})
+	}
+}
+
 func TestCreateHandler_WithCustomTTL_UsesTTL(t *testing.T) {
 	mockService := new(MockURLService)
 	h := handler.New(mockService, "http://localhost:8080")
@@ -93,7 +298,7 @@ func TestCreateHandler_WithCustomTTL_UsesTTL(t *testing.T) {
 	}
 
 	// Expect TTL of 3600 seconds = 1 hour
-	mockService.On("Create", mock.Anything, "https://example.com", time.Hour).
+	mockService.On("Create", mock.Anything, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour}).
 		Return(expectedRecord, nil)
 
 	body := `{"long_url": "https://example.com", "ttl_seconds": 3600}`
@@ -108,6 +313,149 @@ func TestCreateHandler_WithCustomTTL_UsesTTL(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestCreateHandler_TTLPolicy(t *testing.T) {
+	policy := domain.TTLPolicy{Default: 2 * time.Hour, Min: 10 * time.Minute, Max: 7 * 24 * time.Hour}
+
+	tests := []struct {
+		name        string
+		body        string
+		wantTTL     time.Duration
+		wantMessage string
+	}{
+		{name: "omitted uses default", body: `{"long_url": "https://example.com"}`, wantTTL: 2 * time.Hour},
+		{name: "at minimum", body: `{"long_url": "https://example.com", "ttl_seconds": 600}`, wantTTL: 10 * time.Minute},
+		{name: "at maximum", body: `{"long_url": "https://example.com", "ttl_seconds": 604800}`, wantTTL: 7 * 24 * time.Hour},
+		{name: "below minimum", body: `{"long_url": "https://example.com", "ttl_seconds": 599}`, wantMessage: "ttl_seconds must be at least 600"},
+		{name: "above maximum", body: `{"long_url": "https://example.com", "ttl_seconds": 604801}`, wantMessage: "ttl_seconds must not exceed 604800"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080", handler.WithTTLPolicy(policy))
+
+			if tt.wantMessage == "" {
+				mockService.On("Create", mock.Anything, domain.CreateParams{LongURL: "https://example.com", TTL: tt.wantTTL}).
+					Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com"}, nil)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+
+			h.Create(rec, req)
+
+			if tt.wantMessage == "" {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				mockService.AssertExpectations(t)
+				return
+			}
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var resp handler.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tt.wantMessage, resp.Message)
+			mockService.AssertNotCalled(t, "Create")
+		})
+	}
+}
+
+func TestCreateHandler_IdempotencyKey(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	params := domain.CreateParams{LongURL: "https://example.com", TTL: 24 * time.Hour}
+	record := &domain.URLRecord{
+		ShortCode: "Ab2CdE3F",
+		LongURL:   "https://example.com",
+		ExpiresAt: time.Date(2024, 1, 16, 12, 0, 0, 0, time.UTC),
+	}
+	mockService.On("CreateIdempotent", mock.Anything, "retry-1", params).Return(record, 1, false, nil).Once()
+	mockService.On("CreateIdempotent", mock.Anything, "retry-1", params).Return(record, 0, true, nil).Once()
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"long_url": "https://example.com"}`))
+		req.Header.Set(handler.IdempotencyKeyHeader, "retry-1")
+		rec := httptest.NewRecorder()
+		h.Create(rec, req)
+		return rec
+	}
+
+	first := send()
+	replay := send()
+
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(t, first.Body.String(), replay.Body.String())
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestCreateHandler_IdempotencyKeyReused_Returns422(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	anonymous := mock.MatchedBy(func(ctx context.Context) bool {
+		return domain.ClientIDFromContext(ctx) == "ip:203.0.113.9"
+	})
+	mockService.On("CreateIdempotent", anonymous, "retry-1", mock.Anything).
+		Return(nil, 0, false, domain.ErrIdempotencyKeyReused)
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"long_url": "https://example.com/other"}`))
+	req.RemoteAddr = "203.0.113.9:4000"
+	req.Header.Set(handler.IdempotencyKeyHeader, "retry-1")
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"error":"idempotency_key_reused"`)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateHandler_IdempotencyKey_ScopesAnonymousClientsBehindProxy(t *testing.T) {
+	proxies, err := clientip.NewResolver([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithClientIPResolver(proxies))
+
+	record := &domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com"}
+	for _, ip := range []string{"203.0.113.1", "203.0.113.2"} {
+		client := mock.MatchedBy(func(ctx context.Context) bool {
+			return domain.ClientIDFromContext(ctx) == "ip:"+ip
+		})
+		mockService.On("CreateIdempotent", client, "retry-1", mock.Anything).Return(record, 1, false, nil).Once()
+	}
+
+	for _, ip := range []string{"203.0.113.1", "203.0.113.2"} {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"long_url": "https://example.com"}`))
+		req.RemoteAddr = "10.0.0.1:5000"
+		req.Header.Set("X-Forwarded-For", ip)
+		req.Header.Set(handler.IdempotencyKeyHeader, "retry-1")
+		rec := httptest.NewRecorder()
+
+		h.Create(rec, req)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateHandler_IdempotencyKeyTooLong_Returns400(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"long_url": "https://example.com"}`))
+	req.Header.Set(handler.IdempotencyKeyHeader, strings.Repeat("k", 256))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Idempotency-Key must not exceed 255 characters")
+	mockService.AssertNotCalled(t, "CreateIdempotent", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestCreateHandler_InvalidURL_Returns400(t *testing.T) {
 	mockService := new(MockURLService)
 	h := handler.New(mockService, "http://localhost:8080")
@@ -204,3 +552,854 @@ func TestCreateHandler_URLTooLong_Returns400(t *testing.T) {
 	json.Unmarshal(rec.Body.Bytes(), &resp)
 	assert.Contains(t, resp.Message, "exceeds maximum length")
 }
+
+func TestCreateHandler_NoExpiry(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Create", mock.Anything, domain.CreateParams{LongURL: "https://example.com", NoExpiry: true}).
+		Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com"}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten",
+		bytes.NewBufferString(`{"long_url": "https://example.com", "no_expiry": true}`))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"expires_at":null`)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateHandler_ValidationErrorNamesField(t *testing.T) {
+	testCases := []struct {
+		name      string
+		body      string
+		wantField string
+	}{
+		{name: "missing long_url", body: `{}`, wantField: "long_url"},
+		{name: "bad scheme", body: `{"long_url": "ftp://example.com"}`, wantField: "long_url"},
+		{name: "ttl too short", body: `{"long_url": "https://example.com", "ttl_seconds": 1}`, wantField: "ttl_seconds"},
+		{name: "ttl too long", body: `{"long_url": "https://example.com", "ttl_seconds": 999999999}`, wantField: "ttl_seconds"},
+		{name: "bad alias", body: `{"long_url": "https://example.com", "custom_alias": "a!"}`, wantField: "custom_alias"},
+		{name: "bad max_clicks", body: `{"long_url": "https://example.com", "max_clicks": 0}`, wantField: "max_clicks"},
+		{name: "no_expiry with ttl", body: `{"long_url": "https://example.com", "no_expiry": true, "ttl_seconds": 3600}`, wantField: "no_expiry"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := handler.New(new(MockURLService), "http://localhost:8080")
+
+			req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			h.Create(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var resp handler.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "validation_error", resp.Error)
+			assert.Equal(t, tc.wantField, resp.Field)
+			assert.NotEmpty(t, resp.Message)
+		})
+	}
+}
+
+func TestCreateHandler_NonValidationErrorsOmitField(t *testing.T) {
+	h := handler.New(new(MockURLService), "http://localhost:8080")
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{not json`))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.NotContains(t, rec.Body.String(), `"field"`)
+}
+
+func TestCreateHandler_BaseURLForHost(t *testing.T) {
+	mockService := new(MockURLService)
+	byHost, err := handler.BaseURLForHost([]string{"https://go.acme.com", "https://l.acme.io/"})
+	require.NoError(t, err)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithBaseURLFunc(byHost))
+
+	mockService.On("Create", mock.Anything, domain.CreateParams{LongURL: "https://example.com", TTL: 24 * time.Hour}).
+		Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com"}, nil)
+
+	testCases := []struct {
+		host    string
+		wantURL string
+	}{
+		{host: "go.acme.com", wantURL: "https://go.acme.com/s/Ab2CdE3F"},
+		{host: "L.ACME.IO", wantURL: "https://l.acme.io/s/Ab2CdE3F"},
+		{host: "go.acme.com:443", wantURL: "https://go.acme.com/s/Ab2CdE3F"},
+		{host: "evil.example", wantURL: "http://localhost:8080/s/Ab2CdE3F"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.host, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/shorten",
+				bytes.NewBufferString(`{"long_url": "https://example.com"}`))
+			req.Host = tc.host
+
+			rec := httptest.NewRecorder()
+			h.Create(rec, req)
+
+			require.Equal(t, http.StatusCreated, rec.Code)
+
+			var resp handler.CreateResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tc.wantURL, resp.ShortURL)
+		})
+	}
+}
+
+func TestCreateHandler_ForwardedOrigin(t *testing.T) {
+	proxies, err := clientip.NewResolver([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	byHost, err := handler.BaseURLForHost([]string{"https://go.acme.com"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		opts       []handler.Option
+		remoteAddr string
+		headers    map[string]string
+		wantURL    string
+	}{
+		{
+			name:       "proxy-reported scheme and host",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "short.example.com"},
+			wantURL:    "https://short.example.com/s/Ab2CdE3F",
+		},
+		{
+			name:       "forwarded header",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{"Forwarded": "for=203.0.113.7;proto=https;host=short.example.com"},
+			wantURL:    "https://short.example.com/s/Ab2CdE3F",
+		},
+		{
+			name:       "scheme only keeps the base host",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{"X-Forwarded-Proto": "https"},
+			wantURL:    "https://localhost:8080/s/Ab2CdE3F",
+		},
+		{
+			name:       "untrusted peer falls back to the base URL",
+			remoteAddr: "203.0.113.7:5000",
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.example"},
+			wantURL:    "http://localhost:8080/s/Ab2CdE3F",
+		},
+		{
+			name:       "forwarded host picks a short domain",
+			opts:       []handler.Option{handler.WithBaseURLFunc(byHost)},
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{"X-Forwarded-Proto": "http", "X-Forwarded-Host": "go.acme.com"},
+			wantURL:    "https://go.acme.com/s/Ab2CdE3F",
+		},
+		{
+			name:       "forwarded host outside the short domains falls back to the base URL",
+			opts:       []handler.Option{handler.WithBaseURLFunc(byHost)},
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.example"},
+			wantURL:    "http://localhost:8080/s/Ab2CdE3F",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			opts := append([]handler.Option{handler.WithForwardedOrigin(proxies)}, tc.opts...)
+			h := handler.New(mockService, "http://localhost:8080", opts...)
+			mockService.On("Create", mock.Anything, mock.Anything).
+				Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com"}, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/shorten",
+				bytes.NewBufferString(`{"long_url": "https://example.com"}`))
+			req.RemoteAddr = tc.remoteAddr
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
+
+			rec := httptest.NewRecorder()
+			h.Create(rec, req)
+
+			require.Equal(t, http.StatusCreated, rec.Code)
+			var resp handler.CreateResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tc.wantURL, resp.ShortURL)
+			assert.Equal(t, tc.wantURL, rec.Header().Get("Location"))
+		})
+	}
+}
+
+func TestBaseURLForHost_RejectsInvalidBase(t *testing.T) {
+	for _, base := range []string{"go.acme.com", "ftp://go.acme.com", "https://"} {
+		_, err := handler.BaseURLForHost([]string{base})
+		assert.Error(t, err, base)
+	}
+}
+
+func TestCreateHandler_BaseURLFunc_UsesRequestDerivedBase(t *testing.T) {
+	mockService := new(MockURLService)
+	regional := func(r *http.Request) string {
+		switch r.Header.Get("X-Region") {
+		case "eu":
+			return "https://eu.sho.rt"
+		case "us":
+			return "https://us.sho.rt"
+		}
+		return ""
+	}
+	h := handler.New(mockService, "http://localhost:8080", handler.WithBaseURLFunc(regional))
+
+	mockService.On("Create", mock.Anything, domain.CreateParams{LongURL: "https://example.com", TTL: 24 * time.Hour}).
+		Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com"}, nil)
+
+	testCases := []struct {
+		region  string
+		wantURL string
+	}{
+		{region: "eu", wantURL: "https://eu.sho.rt/s/Ab2CdE3F"},
+		{region: "us", wantURL: "https://us.sho.rt/s/Ab2CdE3F"},
+		{region: "", wantURL: "http://localhost:8080/s/Ab2CdE3F"},
+	}
+
+	for _, tc := range testCases {
+		t.Run("region "+tc.region, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/shorten",
+				bytes.NewBufferString(`{"long_url": "https://example.com"}`))
+			req.Header.Set("X-Region", tc.region)
+
+			rec := httptest.NewRecorder()
+			h.Create(rec, req)
+
+			require.Equal(t, http.StatusCreated, rec.Code)
+
+			var resp handler.CreateResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tc.wantURL, resp.ShortURL)
+		})
+	}
+}
+
+func TestCreateHandler_CustomAlias_Returns201(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Create", mock.Anything, domain.CreateParams{
+		LongURL:     "https://example.com",
+		TTL:         24 * time.Hour,
+		CustomAlias: "spring-offer",
+	}).Return(&domain.URLRecord{ShortCode: "spring-offer", LongURL: "https://example.com"}, nil)
+
+	body := `{"long_url": "https://example.com", "custom_alias": "spring-offer"}`
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var resp handler.CreateResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "http://localhost:8080/s/spring-offer", resp.ShortURL)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateHandler_CustomAliasTaken_Returns409(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Create", mock.Anything, mock.Anything).Return(nil, domain.ErrAliasTaken)
+
+	body := `{"long_url": "https://example.com", "custom_alias": "spring-offer"}`
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	var resp handler.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "alias_taken", resp.Error)
+}
+
+func TestCreateHandler_CapacityExceeded_Returns503(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Create", mock.Anything, mock.Anything).
+		Return(nil, fmt.Errorf("saving record: %w", domain.ErrCapacityExceeded))
+
+	body := `{"long_url": "https://example.com"}`
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var resp handler.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "capacity_exceeded", resp.Error)
+}
+
+func TestCreateHandler_ExpiryTooLate_Returns400(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Create", mock.Anything, mock.Anything).
+		Return(nil, fmt.Errorf("%w, 2024-02-01T00:00:00Z", domain.ErrExpiryTooLate))
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"long_url": "https://example.com"}`))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var resp handler.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "validation_error", resp.Error)
+	assert.Equal(t, "link would expire after the latest allowed expiry, 2024-02-01T00:00:00Z", resp.Message)
+}
+
+func TestCreateHandler_Timeout_Returns503(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Create", mock.Anything, mock.Anything).
+		Return(nil, fmt.Errorf("saving record: %w", context.DeadlineExceeded))
+
+	body := `{"long_url": "https://example.com"}`
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var resp handler.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "timeout", resp.Error)
+}
+
+func TestCreateHandler_InvalidCustomAlias_Returns400(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	testCases := []struct {
+		name  string
+		alias string
+	}{
+		{name: "too short", alias: "ab"},
+		{name: "too long", alias: "this-alias-is-way-too-long-to-be-accepted"},
+		{name: "ambiguous character", alias: "offer-0"},
+		{name: "slash", alias: "spring/offer"},
+		{name: "underscore", alias: "spring_offer"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body, _ := json.Marshal(handler.CreateRequest{LongURL: "https://example.com", CustomAlias: tc.alias})
+			req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBuffer(body))
+			rec := httptest.NewRecorder()
+
+			h.Create(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+			var resp handler.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "validation_error", resp.Error)
+			assert.Contains(t, resp.Message, "custom_alias")
+		})
+	}
+
+	mockService.AssertNotCalled(t, "Create")
+}
+
+func TestCreateHandler_CustomAlias_UsesConfiguredAlphabet(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithCodeAlphabet("0123456789abcdef"))
+
+	mockService.On("Create", mock.Anything, mock.MatchedBy(func(p domain.CreateParams) bool {
+		return p.CustomAlias == "cafe-00"
+	})).Return(&domain.URLRecord{ShortCode: "cafe-00", LongURL: "https://example.com"}, nil)
+
+	for alias, want := range map[string]int{
+		"cafe-00": http.StatusCreated,    // '0' is outside the default alphabet
+		"offer-x": http.StatusBadRequest, // 'o', 'r' and 'x' are outside this one
+	} {
+		body, _ := json.Marshal(handler.CreateRequest{LongURL: "https://example.com", CustomAlias: alias})
+		rec := httptest.NewRecorder()
+		h.Create(rec, httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBuffer(body)))
+		assert.Equal(t, want, rec.Code, alias)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateHandler_Permanent_PassedToService(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Create", mock.Anything, domain.CreateParams{
+		LongURL:   "https://example.com",
+		TTL:       24 * time.Hour,
+		Permanent: true,
+	}).Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com"}, nil)
+
+	body := `{"long_url": "https://example.com", "permanent": true}`
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateHandler_MaxClicks_PassedToService(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Create", mock.Anything, domain.CreateParams{
+		LongURL:   "https://example.com",
+		TTL:       24 * time.Hour,
+		MaxClicks: 1,
+	}).Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com"}, nil)
+
+	body := `{"long_url": "https://example.com", "max_clicks": 1}`
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateHandler_InvalidMaxClicks_Returns400(t *testing.T) {
+	for _, maxClicks := range []string{"0", "-3"} {
+		t.Run(maxClicks, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+
+			body := `{"long_url": "https://example.com", "max_clicks": ` + maxClicks + `}`
+			req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(body))
+			rec := httptest.NewRecorder()
+
+			h.Create(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), "max_clicks")
+			mockService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCreateHandler_HostBlocklist(t *testing.T) {
+	tests := []struct {
+		name    string
+		longURL string
+		blocked bool
+	}{
+		{"exact host", "https://evil.com/page", true},
+		{"subdomain", "https://a.evil.com/page", true},
+		{"nested subdomain", "https://x.y.evil.com", true},
+		{"case and trailing dot", "https://A.EVIL.com./page", true},
+		{"with port", "https://evil.com:8443/page", true},
+		{"other host in list", "https://competitor.example/x", true},
+		{"suffix without dot", "https://notevil.com", false},
+		{"blocked name in path", "https://example.com/evil.com", false},
+		{"parent of blocked host", "https://example", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080",
+				handler.WithHostBlocklist([]string{"evil.com", " competitor.example ", ""}))
+
+			if !tt.blocked {
+				mockService.On("Create", mock.Anything, mock.Anything).
+					Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: tt.longURL}, nil)
+			}
+
+			body := `{"long_url": "` + tt.longURL + `"}`
+			req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(body))
+			rec := httptest.NewRecorder()
+
+			h.Create(rec, req)
+
+			if !tt.blocked {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				return
+			}
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var resp handler.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "validation_error", resp.Error)
+			assert.Equal(t, "destination host not allowed", resp.Message)
+			mockService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCreateHandler_SetLimitsAppliesToLaterRequests(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithHostBlocklist([]string{"evil.com"}))
+	mockService.On("Create", mock.Anything, mock.Anything).
+		Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com"}, nil)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(body))
+		rec := httptest.NewRecorder()
+		h.Create(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusBadRequest, create(`{"long_url": "https://evil.com/"}`).Code)
+	require.Equal(t, http.StatusCreated, create(`{"long_url": "https://competitor.example/", "ttl_seconds": 60}`).Code)
+
+	policy := domain.DefaultTTLPolicy()
+	policy.Min = 2 * time.Minute
+	h.SetLimits(handler.Limits{BlockedHosts: []string{"competitor.example"}, TTL: policy})
+
+	assert.Equal(t, http.StatusCreated, create(`{"long_url": "https://evil.com/"}`).Code, "replaced blocklist no longer applies")
+	rec := create(`{"long_url": "https://example.com/", "ttl_seconds": 60}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "new TTL minimum applies")
+	assert.Contains(t, rec.Body.String(), "ttl_seconds must be at least 120")
+	assert.Equal(t, http.StatusBadRequest, create(`{"long_url": "https://competitor.example/"}`).Code)
+}
+
+func TestCreateHandler_NoBlocklistByDefault(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Create", mock.Anything, mock.Anything).
+		Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://evil.com"}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"long_url": "https://evil.com"}`))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
+
+// stubResolver resolves hosts from a fixed table.
+type stubResolver map[string][]string
+
+func (s stubResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := s[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+	return addrs, nil
+}
+
+func TestCreateHandler_PrivateURLBlocking(t *testing.T) {
+	resolver := stubResolver{
+		"example.com":       {"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"},
+		"internal.corp":     {"10.1.2.3"},
+		"mixed.example":     {"93.184.216.34", "192.168.1.10"},
+		"metadata.internal": {"169.254.169.254"},
+		"v6-local.example":  {"fe80::1"},
+	}
+
+	testCases := []struct {
+		name    string
+		longURL string
+		blocked bool
+	}{
+		{name: "public hostname", longURL: "https://example.com/page", blocked: false},
+		{name: "public IPv4 literal", longURL: "http://93.184.216.34/", blocked: false},
+		{name: "public IPv6 literal", longURL: "http://[2606:2800:220:1:248:1893:25c8:1946]/", blocked: false},
+		{name: "IPv4 loopback", longURL: "http://127.0.0.1:8080/admin", blocked: true},
+		{name: "IPv4 cloud metadata", longURL: "http://169.254.169.254/latest/meta-data/", blocked: true},
+		{name: "IPv4 RFC1918 10/8", longURL: "http://10.0.0.5/", blocked: true},
+		{name: "IPv4 RFC1918 172.16/12", longURL: "http://172.20.1.1/", blocked: true},
+		{name: "IPv4 RFC1918 192.168/16", longURL: "http://192.168.0.1/", blocked: true},
+		{name: "IPv4 unspecified", longURL: "http://0.0.0.0/", blocked: true},
+		{name: "IPv6 loopback", longURL: "http://[::1]/", blocked: true},
+		{name: "IPv6 unspecified", longURL: "http://[::]/", blocked: true},
+		{name: "IPv6 link-local", longURL: "http://[fe80::1]/", blocked: true},
+		{name: "IPv6 unique local", longURL: "http://[fd00::1]/", blocked: true},
+		{name: "IPv4-mapped IPv6 loopback", longURL: "http://[::ffff:127.0.0.1]/", blocked: true},
+		{name: "localhost", longURL: "http://localhost:3000/", blocked: true},
+		{name: "localhost uppercase with trailing dot", longURL: "http://LOCALHOST./", blocked: true},
+		{name: "localhost subdomain", longURL: "http://app.localhost/", blocked: true},
+		{name: "hostname resolving to private IP", longURL: "https://internal.corp/", blocked: true},
+		{name: "hostname resolving to link-local", longURL: "http://metadata.internal/", blocked: true},
+		{name: "hostname with any private address", longURL: "https://mixed.example/", blocked: true},
+		{name: "hostname resolving to IPv6 link-local", longURL: "https://v6-local.example/", blocked: true},
+		{name: "unresolvable hostname", longURL: "https://nope.invalid/", blocked: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080", handler.WithPrivateURLBlocking(resolver))
+
+			if !tc.blocked {
+				mockService.On("Create", mock.Anything, mock.Anything).Return(&domain.URLRecord{
+					ShortCode: "abc12345",
+					LongURL:   tc.longURL,
+					ExpiresAt: time.Now().Add(time.Hour),
+				}, nil)
+			}
+
+			body, _ := json.Marshal(handler.CreateRequest{LongURL: tc.longURL})
+			req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			h.Create(rec, req)
+
+			if !tc.blocked {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				return
+			}
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+			var resp handler.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "validation_error", resp.Error)
+			assert.Contains(t, resp.Message, "long_url")
+			mockService.AssertNotCalled(t, "Create")
+		})
+	}
+}
+
+func TestCreateHandler_PrivateURLsAllowedByDefault(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Create", mock.Anything, mock.Anything).Return(&domain.URLRecord{
+		ShortCode: "abc12345",
+		LongURL:   "http://localhost:3000/",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"long_url": "http://localhost:3000/"}`))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
+
+// stubTransport answers HEAD requests from a fixed table of URL to status and
+// Location, failing for unknown URLs.
+type stubTransport map[string]stubDestination
+
+type stubDestination struct {
+	status   int
+	location string
+}
+
+func (s stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	dest, ok := s[req.URL.String()]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	header := http.Header{}
+	if dest.location != "" {
+		header.Set("Location", dest.location)
+	}
+	return &http.Response{
+		StatusCode: dest.status,
+		Header:     header,
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
+
+func TestCreateHandler_VerifyDestination(t *testing.T) {
+	transport := stubTransport{
+		"https://ok.example/page":           {status: http.StatusOK},
+		"https://head-refused.example/page": {status: http.StatusMethodNotAllowed},
+		"https://down.example/page":         {status: http.StatusServiceUnavailable},
+		"https://moved.example/page":        {status: http.StatusMovedPermanently, location: "/new"},
+		"https://moved.example/new":         {status: http.StatusOK},
+		"https://loop.example/a":            {status: http.StatusFound, location: "https://loop.example/b"},
+		"https://loop.example/b":            {status: http.StatusFound, location: "/a"},
+		"https://to-ftp.example/page":       {status: http.StatusFound, location: "ftp://files.example/"},
+	}
+	for i := range 6 {
+		transport[fmt.Sprintf("https://chain.example/%d", i)] = stubDestination{
+			status:   http.StatusFound,
+			location: fmt.Sprintf("/%d", i+1),
+		}
+	}
+	transport["https://chain.example/6"] = stubDestination{status: http.StatusOK}
+	transport["https://short-chain.example/0"] = stubDestination{status: http.StatusFound, location: "https://chain.example/2"}
+	transport["https://to-internal.example/page"] = stubDestination{status: http.StatusFound, location: "http://internal.example/admin"}
+	transport["https://to-loopback.example/page"] = stubDestination{status: http.StatusFound, location: "http://127.0.0.1:8080/"}
+	transport["https://to-blocked.example/page"] = stubDestination{status: http.StatusFound, location: "https://evil.example/"}
+	transport["http://internal.example/admin"] = stubDestination{status: http.StatusOK}
+	transport["http://127.0.0.1:8080/"] = stubDestination{status: http.StatusOK}
+	transport["https://evil.example/"] = stubDestination{status: http.StatusOK}
+
+	resolver := stubResolver{"internal.example": {"10.0.0.5"}}
+	for rawURL := range transport {
+		u, _ := url.Parse(rawURL)
+		if _, ok := resolver[u.Hostname()]; !ok && net.ParseIP(u.Hostname()) == nil {
+			resolver[u.Hostname()] = []string{"203.0.113.10"}
+		}
+	}
+	resolver["gone.example"] = []string{"203.0.113.11"}
+
+	testCases := []struct {
+		name    string
+		longURL string
+		fails   bool
+	}{
+		{name: "reachable", longURL: "https://ok.example/page"},
+		{name: "client error is accepted", longURL: "https://head-refused.example/page"},
+		{name: "redirect to reachable page", longURL: "https://moved.example/page"},
+		{name: "redirects within the limit", longURL: "https://short-chain.example/0"},
+		{name: "server error", longURL: "https://down.example/page", fails: true},
+		{name: "unreachable", longURL: "https://gone.example/page", fails: true},
+		{name: "redirect loop", longURL: "https://loop.example/a", fails: true},
+		{name: "too many redirects", longURL: "https://chain.example/0", fails: true},
+		{name: "redirect to another scheme", longURL: "https://to-ftp.example/page", fails: true},
+		{name: "redirect to a private host", longURL: "https://to-internal.example/page", fails: true},
+		{name: "redirect to loopback", longURL: "https://to-loopback.example/page", fails: true},
+		{name: "redirect to a blocked host", longURL: "https://to-blocked.example/page", fails: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080",
+				handler.WithDestinationVerification(transport),
+				handler.WithPrivateURLBlocking(resolver),
+				handler.WithHostBlocklist([]string{"evil.example"}))
+
+			if !tc.fails {
+				mockService.On("Create", mock.Anything, mock.Anything).Return(&domain.URLRecord{
+					ShortCode: "abc12345",
+					LongURL:   tc.longURL,
+					ExpiresAt: time.Now().Add(time.Hour),
+				}, nil)
+			}
+
+			body, _ := json.Marshal(handler.CreateRequest{LongURL: tc.longURL, VerifyDestination: true})
+			req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			h.Create(rec, req)
+
+			if !tc.fails {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				return
+			}
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+			// Every failure reads the same, so verification can't be used
+			// to probe hosts.
+			var resp handler.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "validation_error", resp.Error)
+			assert.Equal(t, "long_url", resp.Field)
+			assert.Equal(t, "long_url could not be verified", resp.Message)
+			mockService.AssertNotCalled(t, "Create")
+		})
+	}
+}
+
+func TestCreateHandler_VerifyDestinationSkippedUnlessRequested(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080",
+		handler.WithDestinationVerification(stubTransport{}),
+		handler.WithPrivateURLBlocking(stubResolver{"gone.example": {"203.0.113.11"}}))
+
+	mockService.On("Create", mock.Anything, mock.Anything).Return(&domain.URLRecord{
+		ShortCode: "abc12345",
+		LongURL:   "https://gone.example/page",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"long_url": "https://gone.example/page"}`))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func TestCreateHandler_VerifyDestinationDisabled(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"long_url": "https://ok.example/page", "verify_destination": true}`))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var resp handler.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "verify_destination", resp.Field)
+	mockService.AssertNotCalled(t, "Create")
+}
+
+func TestCreateHandler_NormalizesLongURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		longURL   string
+		sortQuery bool
+		expected  string
+	}{
+		{"lowercases host", "https://Example.COM/Path", false, "https://example.com/Path"},
+		{"keeps path case", "https://example.com/CaseSensitive/ID", false, "https://example.com/CaseSensitive/ID"},
+		{"strips default https port", "https://example.com:443/a", false, "https://example.com/a"},
+		{"strips default http port", "http://example.com:80/a", false, "http://example.com/a"},
+		{"keeps non-default port", "https://example.com:8443/a", false, "https://example.com:8443/a"},
+		{"drops bare root slash", "https://Example.com/", false, "https://example.com"},
+		{"keeps trailing slash on path", "https://example.com/docs/", false, "https://example.com/docs/"},
+		{"collapses duplicate slashes", "https://example.com//a///b", false, "https://example.com/a/b"},
+		{"keeps escaped slash", "https://example.com/a%2Fb", false, "https://example.com/a%2Fb"},
+		{"keeps fragment", "https://Example.com/page#Section-2", false, "https://example.com/page#Section-2"},
+		{"keeps query order by default", "https://example.com/?b=2&a=1", false, "https://example.com?b=2&a=1"},
+		{"sorts query when enabled", "https://example.com/p?b=2&a=1#top", true, "https://example.com/p?a=1&b=2#top"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			var opts []handler.Option
+			if tt.sortQuery {
+				opts = append(opts, handler.WithSortedQueryParams())
+			}
+			h := handler.New(mockService, "http://localhost:8080", opts...)
+
+			mockService.On("Create", mock.Anything, domain.CreateParams{LongURL: tt.expected, TTL: 24 * time.Hour}).
+				Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: tt.expected}, nil)
+
+			body, err := json.Marshal(handler.CreateRequest{LongURL: tt.longURL})
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			h.Create(rec, req)
+
+			require.Equal(t, http.StatusCreated, rec.Code)
+			var resp handler.CreateResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tt.expected, resp.LongURL)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
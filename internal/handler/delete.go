@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"url-shortener/internal/domain"
+)
+
+// Delete handles DELETE /s/{code} requests, soft-deleting the link so it
+// stops resolving while it can still be restored.
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if code == "" {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "short code is required")
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), code); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			h.writeError(w, http.StatusNotFound, "not_found", "short code not found")
+			return
+		}
+		h.writeInternalError(w, err, "failed to delete link")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Restore handles POST /s/{code}/restore requests, undoing a delete made
+// within the retention period.
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if code == "" {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "short code is required")
+		return
+	}
+
+	record, err := h.service.Restore(r.Context(), code)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			h.writeError(w, http.StatusNotFound, "not_found", "short code not deleted or past retention")
+			return
+		}
+		h.writeInternalError(w, err, "failed to restore link")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.toStatsResponse(r, record, h.clock.Now()))
+}
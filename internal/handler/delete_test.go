@@ -0,0 +1,90 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteHandler(t *testing.T) {
+	testCases := []struct {
+		name       string
+		serviceErr error
+		wantStatus int
+	}{
+		{name: "deleted", wantStatus: http.StatusNoContent},
+		{name: "not found", serviceErr: domain.ErrNotFound, wantStatus: http.StatusNotFound},
+		{name: "storage failure", serviceErr: errors.New("disk full"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+			mockService.On("Delete", mock.Anything, "Ab2CdE3F").Return(tc.serviceErr)
+
+			req := httptest.NewRequest(http.MethodDelete, "/s/Ab2CdE3F", nil)
+			req.SetPathValue("code", "Ab2CdE3F")
+			rec := httptest.NewRecorder()
+
+			h.Delete(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			if tc.wantStatus == http.StatusNoContent {
+				assert.Empty(t, rec.Body.String())
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRestoreHandler(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	mockService.On("Restore", mock.Anything, "Ab2CdE3F").Return(&domain.URLRecord{
+		ShortCode: "Ab2CdE3F",
+		LongURL:   "https://example.com",
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+		Enabled:   true,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/s/Ab2CdE3F/restore", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+
+	h.Restore(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp handler.StatsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "Ab2CdE3F", resp.ShortCode)
+	assert.Equal(t, "https://example.com", resp.LongURL)
+}
+
+func TestRestoreHandler_NotRestorable(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	mockService.On("Restore", mock.Anything, "Ab2CdE3F").Return(nil, domain.ErrNotFound)
+
+	req := httptest.NewRequest(http.MethodPost, "/s/Ab2CdE3F/restore", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+
+	h.Restore(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	var resp handler.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "not_found", resp.Error)
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+const (
+	// destinationTimeout bounds the whole verification, redirects included.
+	destinationTimeout = 3 * time.Second
+
+	// maxDestinationRedirects is how many redirects a verified destination
+	// may go through before it is rejected.
+	maxDestinationRedirects = 5
+)
+
+// errUnverifiedDestination is the only answer verification gives, whatever
+// went wrong, so clients can't tell a closed port from a refused or private
+// host and use verification to map networks the server can see.
+var errUnverifiedDestination = invalidField("long_url", "long_url could not be verified")
+
+// pinnedAddrsKey carries the addresses vetted for a verification request
+// to dialPinned.
+type pinnedAddrsKey struct{}
+
+// newDestinationClient returns a client for verifyDestination that leaves
+// redirects to the caller, which follows them itself to vet every hop. A
+// nil transport uses newDestinationTransport.
+func newDestinationClient(transport http.RoundTripper) *http.Client {
+	if transport == nil {
+		transport = newDestinationTransport()
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   destinationTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// newDestinationTransport connects only through dialPinned, never through a
+// proxy, and opens a new connection per request so none is reused for a
+// host it wasn't vetted for.
+func newDestinationTransport() *http.Transport {
+	return &http.Transport{
+		DialContext:         dialPinned,
+		TLSHandshakeTimeout: destinationTimeout,
+		DisableKeepAlives:   true,
+	}
+}
+
+// dialPinned connects to one of the addresses verifyDestination vetted for
+// the request instead of resolving the host again, so a DNS answer that
+// changes after the check (DNS rebinding) can't steer the connection to an
+// internal address.
+func dialPinned(ctx context.Context, network, addr string) (net.Conn, error) {
+	ips, _ := ctx.Value(pinnedAddrsKey{}).([]net.IP)
+	if len(ips) == 0 {
+		return nil, errors.New("no vetted address to dial")
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	for _, ip := range ips {
+		var conn net.Conn
+		conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// verifyDestination sends HEAD to rawURL, following redirects, and rejects
+// destinations that can't be reached, answer 5xx, redirect in a loop, or
+// redirect more than maxDestinationRedirects times. Other 4xx answers are
+// accepted, since many servers refuse HEAD. Every hop must pass the host
+// blocklist and resolve only to public addresses (see publicAddrs), and is
+// dialed at exactly those addresses. Every failure is reported as
+// errUnverifiedDestination; the reason is only logged.
+func verifyDestination(ctx context.Context, rawURL string, client *http.Client, resolver HostResolver, blocked hostSet) error {
+	ctx, cancel := context.WithTimeout(ctx, destinationTimeout)
+	defer cancel()
+
+	unverified := func(hop, reason string) error {
+		slog.DebugContext(ctx, "destination verification failed", "url", domain.RedactURL(hop), "reason", reason)
+		return errUnverifiedDestination
+	}
+
+	visited := make(map[string]bool)
+	current := rawURL
+	for redirects := 0; ; redirects++ {
+		if visited[current] {
+			return unverified(current, "redirect loop")
+		}
+		visited[current] = true
+
+		parsed, err := url.Parse(current)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+			return unverified(current, "invalid URL")
+		}
+		if blocked.matches(parsed.Hostname()) {
+			return unverified(current, "blocked host")
+		}
+		ips, err := publicAddrs(ctx, current, resolver)
+		if err != nil {
+			return unverified(current, "host is not public")
+		}
+
+		req, err := http.NewRequestWithContext(context.WithValue(ctx, pinnedAddrsKey{}, ips), http.MethodHead, current, nil)
+		if err != nil {
+			return unverified(current, "invalid URL")
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return unverified(current, "unreachable")
+		}
+		resp.Body.Close()
+
+		location := resp.Header.Get("Location")
+		switch {
+		case resp.StatusCode >= 500:
+			return unverified(current, "server error")
+		case resp.StatusCode >= 300 && resp.StatusCode < 400 && location != "":
+			if redirects == maxDestinationRedirects {
+				return unverified(current, "too many redirects")
+			}
+			next, err := req.URL.Parse(location)
+			if err != nil {
+				return unverified(current, "invalid redirect")
+			}
+			current = next.String()
+		default:
+			return nil
+		}
+	}
+}
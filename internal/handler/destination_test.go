@@ -0,0 +1,40 @@
+package handler_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialPinned_DialsOnlyVettedAddresses(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+
+	// The host name is never resolved: the connection goes to the vetted
+	// address whatever DNS would say now.
+	ctx := handler.WithPinnedAddrs(context.Background(), net.ParseIP("127.0.0.1"))
+	conn, err := handler.DialPinned(ctx, "tcp", net.JoinHostPort("rebind.invalid", port))
+	require.NoError(t, err)
+	assert.Equal(t, ln.Addr().String(), conn.RemoteAddr().String())
+	conn.Close()
+
+	_, err = handler.DialPinned(context.Background(), "tcp", net.JoinHostPort("rebind.invalid", port))
+	assert.Error(t, err, "requests without vetted addresses must not be dialed")
+}
@@ -3,34 +3,208 @@ package handler
 // === Requests ===
 
 type CreateRequest struct {
-	LongURL    string `json:"long_url"`
-	TTLSeconds *int64 `json:"ttl_seconds,omitempty"`
+	LongURL     string `json:"long_url"`
+	TTLSeconds  *int64 `json:"ttl_seconds,omitempty"`
+	CustomAlias string `json:"custom_alias,omitempty"`
+	Permanent   bool   `json:"permanent,omitempty"`
+	MaxClicks   *int64 `json:"max_clicks,omitempty"`
+	NoExpiry    bool   `json:"no_expiry,omitempty"`
+
+	// Tags label the link for GET /urls?tag= filtering.
+	Tags []string `json:"tags,omitempty"`
+
+	// VerifyDestination asks for the long URL to be checked for dead
+	// servers and redirect loops before the link is created.
+	VerifyDestination bool `json:"verify_destination,omitempty"`
+}
+
+type BatchCreateRequest struct {
+	URLs []CreateRequest `json:"urls"`
+}
+
+type UpdateTTLRequest struct {
+	TTLSeconds *int64 `json:"ttl_seconds"`
+}
+
+type UpdateStatusRequest struct {
+	Enabled *bool `json:"enabled"`
 }
 
 // === Responses ===
 
 type CreateResponse struct {
-	ShortCode string `json:"short_code"`
-	ShortURL  string `json:"short_url"`
-	LongURL   string `json:"long_url"`
-	ExpiresAt string `json:"expires_at"`
+	ShortCode string   `json:"short_code"`
+	ShortURL  string   `json:"short_url"`
+	LongURL   string   `json:"long_url"`
+	ExpiresAt *string  `json:"expires_at"`
+	Tags      []string `json:"tags,omitempty"`
+}
+
+// BatchCreateResult is the outcome of one item of a batch create. Status is
+// the code a single POST /shorten would have returned for the item.
+type BatchCreateResult struct {
+	Index  int             `json:"index"`
+	Status int             `json:"status"`
+	Result *CreateResponse `json:"result,omitempty"`
+	Error  *ErrorResponse  `json:"error,omitempty"`
+}
+
+type BatchCreateResponse struct {
+	Results []BatchCreateResult `json:"results"`
+	Created int                 `json:"created"`
+	Failed  int                 `json:"failed"`
 }
 
 type StatsResponse struct {
 	ShortCode      string  `json:"short_code"`
+	ShortURL       string  `json:"short_url"`
 	LongURL        string  `json:"long_url"`
 	CreatedAt      string  `json:"created_at"`
-	ExpiresAt      string  `json:"expires_at"`
+	ExpiresAt      *string `json:"expires_at"`
 	ClickCount     int64   `json:"click_count"`
 	LastAccessedAt *string `json:"last_accessed_at"`
+	Enabled        bool    `json:"enabled"`
+	Expired        bool    `json:"expired"`
+
+	// ExpiresInSeconds is the time left before ExpiresAt, 0 once expired.
+	// Both are null for links that never expire.
+	ExpiresInSeconds *int64 `json:"expires_in_seconds"`
+
+	// TTLSeconds is the TTL the link was created with, requested or
+	// defaulted. It is null for links that never expire and for links
+	// stored before it was recorded.
+	TTLSeconds *int64 `json:"ttl_seconds"`
+
+	// RemainingClicks is null for links without a click limit.
+	RemainingClicks *int64 `json:"remaining_clicks"`
+
+	// ClicksPerDay is ClickCount averaged over the days since creation,
+	// counting links younger than a day as one day old.
+	ClicksPerDay float64 `json:"clicks_per_day"`
+
+	Tags []string `json:"tags,omitempty"`
+}
+
+type InfoResponse struct {
+	ShortCode  string  `json:"short_code"`
+	LongURL    string  `json:"long_url"`
+	ExpiresAt  *string `json:"expires_at"`
+	ClickCount int64   `json:"click_count"`
+}
+
+type ResolveResponse struct {
+	LongURL   string `json:"long_url"`
+	ShortCode string `json:"short_code"`
+}
+
+type ReferrersResponse struct {
+	ShortCode string             `json:"short_code"`
+	Referrers []ReferrerResponse `json:"referrers"`
+}
+
+type ReferrerResponse struct {
+	Referrer string `json:"referrer"`
+	Clicks   int64  `json:"clicks"`
+}
+
+type TimeseriesResponse struct {
+	ShortCode string              `json:"short_code"`
+	From      string              `json:"from"`
+	To        string              `json:"to"`
+	Days      []DayClicksResponse `json:"days"`
+}
+
+type DayClicksResponse struct {
+	Date   string `json:"date"`
+	Clicks int64  `json:"clicks"`
+}
+
+type BatchStatsResponse struct {
+	Stats    []StatsResponse `json:"stats"`
+	NotFound []string        `json:"not_found"`
+}
+
+type ListResponse struct {
+	URLs   []StatsResponse `json:"urls"`
+	Total  int             `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
+
+type HistoryResponse struct {
+	ShortCode string                 `json:"short_code"`
+	Events    []HistoryEventResponse `json:"events"`
+}
+
+type HistoryEventResponse struct {
+	Type    string            `json:"type"`
+	At      string            `json:"at"`
+	Actor   string            `json:"actor"`
+	Details map[string]string `json:"details,omitempty"`
 }
 
 type HealthResponse struct {
-	Status    string `json:"status"`
-	Timestamp string `json:"timestamp"`
+	Status        string `json:"status"`
+	Timestamp     string `json:"timestamp"`
+	Version       string `json:"version"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+}
+
+// ServiceInfoResponse describes the service at GET /.
+type ServiceInfoResponse struct {
+	Name      string   `json:"name"`
+	Version   string   `json:"version"`
+	Endpoints []string `json:"endpoints"`
 }
 
 type ErrorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message"`
+
+	// Field names the request field that failed validation, if any.
+	Field string `json:"field,omitempty"`
+
+	// Errors lists every failing field when the body failed schema
+	// validation.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is one failing field of a validation_error.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// StoreStatsResponse summarizes every stored link for GET /admin/stats.
+type StoreStatsResponse struct {
+	TotalLinks   int64             `json:"total_links"`
+	ActiveLinks  int64             `json:"active_links"`
+	ExpiredLinks int64             `json:"expired_links"`
+	TotalClicks  int64             `json:"total_clicks"`
+	TopLinks     []TopLinkResponse `json:"top_links"`
+}
+
+type TopLinkResponse struct {
+	ShortCode  string `json:"short_code"`
+	LongURL    string `json:"long_url"`
+	ClickCount int64  `json:"click_count"`
+}
+
+// ReapResponse reports how many expired links POST /admin/reap deleted.
+type ReapResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+// BulkDeleteRequest selects the links POST /admin/bulk-delete removes:
+// those whose long URL starts with Prefix, or those expiring before
+// Before, an RFC 3339 timestamp. Exactly one must be set.
+type BulkDeleteRequest struct {
+	Prefix string `json:"prefix"`
+	Before string `json:"before"`
+}
+
+// BulkDeleteResponse reports how many links POST /admin/bulk-delete
+// removed.
+type BulkDeleteResponse struct {
+	Deleted int `json:"deleted"`
 }
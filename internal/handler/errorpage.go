@@ -0,0 +1,86 @@
+package handler
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// defaultRedirectErrorHTML is the page browsers get when a redirect fails.
+//
+//go:embed templates/redirect_error.html
+var defaultRedirectErrorHTML string
+
+var defaultRedirectErrorTemplate = template.Must(template.New("redirect_error").Parse(defaultRedirectErrorHTML))
+
+// RedirectErrorPage is the data a redirect error template is executed with.
+type RedirectErrorPage struct {
+	// Status and StatusText are the response status, e.g. 404 and
+	// "Not Found".
+	Status     int
+	StatusText string
+
+	// Error and Message are the error and message of the JSON
+	// ErrorResponse API clients get, e.g. "not_found".
+	Error   string
+	Message string
+
+	// ShortCode is the code that was requested.
+	ShortCode string
+}
+
+// WithRedirectErrorTemplate replaces the embedded HTML page that browsers
+// get when a redirect fails with tmpl, executed with a RedirectErrorPage.
+func WithRedirectErrorTemplate(tmpl *template.Template) Option {
+	return func(h *Handler) {
+		h.redirectErrorTemplate = tmpl
+	}
+}
+
+// ParseRedirectErrorTemplate reads an html/template file for
+// WithRedirectErrorTemplate. It is executed once against sample data, so
+// references to fields RedirectErrorPage lacks fail here instead of on a
+// visitor's request.
+func ParseRedirectErrorTemplate(path string) (*template.Template, error) {
+	tmpl, err := template.ParseFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	sample := RedirectErrorPage{
+		Status:     http.StatusNotFound,
+		StatusText: http.StatusText(http.StatusNotFound),
+		Error:      "not_found",
+		Message:    "short code not found or expired",
+		ShortCode:  "Ab2CdE3F",
+	}
+	if err := tmpl.Execute(io.Discard, sample); err != nil {
+		return nil, fmt.Errorf("executing %s: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+// writeRedirectError answers a failed redirect with the HTML error page if
+// the client prefers HTML to JSON, as browsers do, and with an
+// ErrorResponse otherwise.
+func (h *Handler) writeRedirectError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	if !prefersHTML(r.Header.Get("Accept")) {
+		h.writeError(w, status, code, message)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	err := h.redirectErrorTemplate.Execute(w, RedirectErrorPage{
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Error:      code,
+		Message:    message,
+		ShortCode:  r.PathValue("code"),
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "rendering redirect error page failed", "error", err)
+	}
+}
@@ -0,0 +1,162 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const browserAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+func TestRedirectHandler_ErrorNegotiation(t *testing.T) {
+	tests := []struct {
+		name     string
+		accept   string
+		wantHTML bool
+	}{
+		{name: "no header"},
+		{name: "anything", accept: "*/*"},
+		{name: "json", accept: "application/json"},
+		{name: "html", accept: "text/html", wantHTML: true},
+		{name: "browser", accept: browserAccept, wantHTML: true},
+		{name: "json ranked above html", accept: "text/html;q=0.4, application/json"},
+		{name: "html ranked above json", accept: "application/json;q=0.5, text/html", wantHTML: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+
+			mockService.On("Resolve", mock.Anything, "Ab2CdE3F", mock.Anything).
+				Return(nil, domain.ErrNotFound)
+
+			req := httptest.NewRequest(http.MethodGet, "/s/Ab2CdE3F", nil)
+			req.SetPathValue("code", "Ab2CdE3F")
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+
+			h.Redirect(rec, req)
+
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+			assert.Contains(t, rec.Header().Values("Vary"), "Accept")
+
+			if tt.wantHTML {
+				assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+				assert.Contains(t, rec.Body.String(), "<title>404 Not Found</title>")
+				assert.Contains(t, rec.Body.String(), "This link has expired or does not exist")
+				assert.Contains(t, rec.Body.String(), "<code>Ab2CdE3F</code>")
+				return
+			}
+
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			var resp handler.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "not_found", resp.Error)
+		})
+	}
+}
+
+func TestRedirectHandler_ErrorPageStatuses(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantHeading string
+	}{
+		{"disabled", domain.ErrDisabled, http.StatusGone, "This link has been disabled"},
+		{"expired", domain.ErrExpired, http.StatusNotFound, "This link has expired or does not exist"},
+		{"invalid checksum", domain.ErrInvalidChecksum, http.StatusNotFound, "This link looks mistyped"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+
+			mockService.On("Resolve", mock.Anything, "Ab2CdE3F", mock.Anything).Return(nil, tt.err)
+
+			req := httptest.NewRequest(http.MethodGet, "/s/Ab2CdE3F", nil)
+			req.SetPathValue("code", "Ab2CdE3F")
+			req.Header.Set("Accept", browserAccept)
+			rec := httptest.NewRecorder()
+
+			h.Redirect(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+			assert.Contains(t, rec.Body.String(), tt.wantHeading)
+		})
+	}
+}
+
+func TestRedirectHandler_ServiceErrorStaysJSONForBrowsers(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Resolve", mock.Anything, "Ab2CdE3F", mock.Anything).
+		Return(nil, errors.New("database error"))
+
+	req := httptest.NewRequest(http.MethodGet, "/s/Ab2CdE3F", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	req.Header.Set("Accept", browserAccept)
+	rec := httptest.NewRecorder()
+
+	h.Redirect(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+}
+
+func TestRedirectHandler_CustomErrorTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "error.html")
+	require.NoError(t, os.WriteFile(path, []byte(`<p>{{.Status}} {{.Error}}: {{.ShortCode}}</p>`), 0o600))
+
+	tmpl, err := handler.ParseRedirectErrorTemplate(path)
+	require.NoError(t, err)
+
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithRedirectErrorTemplate(tmpl))
+
+	mockService.On("Resolve", mock.Anything, "<b>", mock.Anything).Return(nil, domain.ErrDisabled)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/x", nil)
+	req.SetPathValue("code", "<b>")
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+
+	h.Redirect(rec, req)
+
+	assert.Equal(t, http.StatusGone, rec.Code)
+	assert.Equal(t, "<p>410 disabled: &lt;b&gt;</p>", rec.Body.String(), "values should be HTML-escaped")
+}
+
+func TestParseRedirectErrorTemplate_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := handler.ParseRedirectErrorTemplate(filepath.Join(dir, "missing.html"))
+	assert.Error(t, err)
+
+	syntax := filepath.Join(dir, "syntax.html")
+	require.NoError(t, os.WriteFile(syntax, []byte(`{{.Status`), 0o600))
+	_, err = handler.ParseRedirectErrorTemplate(syntax)
+	assert.Error(t, err)
+
+	unknownField := filepath.Join(dir, "field.html")
+	require.NoError(t, os.WriteFile(unknownField, []byte(`{{.LongURL}}`), 0o600))
+	_, err = handler.ParseRedirectErrorTemplate(unknownField)
+	assert.Error(t, err, "fields RedirectErrorPage lacks should be caught at parse time")
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+)
+
+// statsETag returns a weak ETag for a stats response. It covers the fields
+// that change after creation, so any click, TTL or status change, or the
+// link expiring yields a new tag. ExpiresInSeconds and ClicksPerDay are left
+// out: they follow from the other fields and the time, and would otherwise
+// change the tag as the clock moves.
+func statsETag(resp StatsResponse) string {
+	lastAccessed := ""
+	if resp.LastAccessedAt != nil {
+		lastAccessed = *resp.LastAccessedAt
+	}
+	expiresAt := ""
+	if resp.ExpiresAt != nil {
+		expiresAt = *resp.ExpiresAt
+	}
+	remaining := int64(-1)
+	if resp.RemainingClicks != nil {
+		remaining = *resp.RemainingClicks
+	}
+
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d|%s|%s|%t|%t|%d", resp.ClickCount, lastAccessed, expiresAt, resp.Enabled, resp.Expired, remaining)
+	return fmt.Sprintf(`W/"%x"`, h.Sum64())
+}
+
+// notModified reports whether r's If-None-Match lists etag or "*". Weak and
+// strong forms of a tag compare equal, as RFC 9110 requires for
+// If-None-Match.
+func notModified(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"context"
+	"net"
+)
+
+// DialPinned exposes dialPinned to tests.
+var DialPinned = dialPinned
+
+// WithPinnedAddrs returns ctx carrying the vetted addresses dialPinned uses.
+func WithPinnedAddrs(ctx context.Context, ips ...net.IP) context.Context {
+	return context.WithValue(ctx, pinnedAddrsKey{}, ips)
+}
@@ -1,13 +1,20 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"html/template"
+	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"url-shortener/internal/domain"
+	"url-shortener/internal/jsonschema"
+	"url-shortener/internal/qrcode"
+	"url-shortener/internal/shortcode"
 )
 
 // Sentinel errors for handler layer
@@ -19,29 +26,347 @@ var (
 // URLService defines the service interface.
 // This allows testing handlers without real service implementation.
 type URLService interface {
-	Create(ctx context.Context, longURL string, ttl time.Duration) (*domain.URLRecord, error)
-	Resolve(ctx context.Context, shortCode string) (string, error)
+	Create(ctx context.Context, params domain.CreateParams) (*domain.URLRecord, int, error)
+	CreateIdempotent(ctx context.Context, key string, params domain.CreateParams) (*domain.URLRecord, int, bool, error)
+	CreateBatch(ctx context.Context, items []domain.CreateParams) []domain.CreateResult
+	Resolve(ctx context.Context, shortCode string, visit domain.Visit) (*domain.URLRecord, error)
+	Lookup(ctx context.Context, shortCode string) (*domain.URLRecord, error)
 	GetStats(ctx context.Context, shortCode string) (*domain.URLRecord, error)
+	GetStatsIncludingExpired(ctx context.Context, shortCode string) (*domain.URLRecord, error)
+	GetClickSeries(ctx context.Context, shortCode string, from, to time.Time) ([]domain.DayClicks, error)
+	GetReferrers(ctx context.Context, shortCode string, limit int) ([]domain.ReferrerCount, error)
+	GetStatsBatch(ctx context.Context, shortCodes []string, consistent bool) ([]*domain.URLRecord, error)
+	GetHistory(ctx context.Context, shortCode string) ([]domain.HistoryEvent, error)
+	List(ctx context.Context, offset, limit int) ([]*domain.URLRecord, int, error)
+	ListByTag(ctx context.Context, tag string, offset, limit int) ([]*domain.URLRecord, int, error)
+	UpdateTTL(ctx context.Context, shortCode string, ttl time.Duration) (*domain.URLRecord, error)
+	SetEnabled(ctx context.Context, shortCode string, enabled bool) (*domain.URLRecord, error)
+	Delete(ctx context.Context, shortCode string) error
+	Restore(ctx context.Context, shortCode string) (*domain.URLRecord, error)
+	PurgeExpired(ctx context.Context) ([]string, error)
+	DeleteByPrefix(ctx context.Context, prefix string) ([]string, error)
+	DeleteExpiringBefore(ctx context.Context, before time.Time) ([]string, error)
+	GetStoreStats(ctx context.Context, top int) (domain.StoreStats, error)
 }
 
 // Handler holds dependencies for HTTP handlers.
 type Handler struct {
-	service URLService
-	baseURL string
+	service     URLService
+	baseURL     string
+	baseURLFunc func(*http.Request) string
+
+	// origins is set when short URLs follow the scheme and host reported
+	// by trusted proxies.
+	origins OriginResolver
+
+	// clientIPs, if set, identifies visitors behind trusted proxies.
+	clientIPs ClientIPResolver
+
+	// hostResolver is set when long URLs on private networks are blocked.
+	hostResolver HostResolver
+
+	// limits holds the settings SetLimits can swap while serving. Each
+	// request loads it once, so it sees either the old or new settings.
+	limits atomic.Pointer[activeLimits]
+
+	qrEncoder QREncoder
+
+	// sortQuery sorts query parameters when normalizing long URLs.
+	sortQuery bool
+
+	// alphabet is the characters generated codes use; custom aliases may
+	// use them and '-'.
+	alphabet string
+
+	// urlRules are the accepted schemes and minimum length of long URLs.
+	urlRules urlRules
+
+	// clock supplies "today" for date ranges that omit their end.
+	clock domain.Clock
+
+	// goneForExpired reports expired links as 410 instead of 404.
+	goneForExpired bool
+
+	// trimCodeSuffixes drops a known extension from redirect codes.
+	trimCodeSuffixes bool
+
+	// destinationClient is set when creates may ask for verify_destination.
+	destinationClient *http.Client
+
+	// camelCase spells response keys in camelCase instead of snake_case.
+	camelCase bool
+
+	// redirectErrorTemplate renders failed redirects for browsers.
+	redirectErrorTemplate *template.Template
+
+	// createSchema is set when POST /shorten bodies are checked against
+	// a JSON Schema before decoding.
+	createSchema *jsonschema.Schema
+
+	// anonymousMaxTTL, when positive, caps the TTL of links created by
+	// domain.TierAnonymous callers below the policy's maximum.
+	anonymousMaxTTL time.Duration
+}
+
+// Limits are the validation settings that can be changed while serving,
+// e.g. on a config reload.
+type Limits struct {
+	// BlockedHosts lists domains long URLs may not point to, including
+	// their subdomains.
+	BlockedHosts []string
+
+	// TTL supplies the default TTL and the accepted ttl_seconds range.
+	TTL domain.TTLPolicy
+}
+
+// activeLimits is Limits prepared for lookups.
+type activeLimits struct {
+	blockedHosts hostSet
+	ttl          domain.TTLPolicy
+}
+
+// QREncoder renders content as a square PNG QR code of the given pixel size.
+type QREncoder interface {
+	Encode(content string, size int) ([]byte, error)
+}
+
+// Option configures optional Handler behavior.
+type Option func(*Handler)
+
+// WithBaseURLFunc derives the short URL base from each request, e.g. to emit
+// a regional domain when one deployment serves several. If fn returns an
+// empty string the static baseURL is used.
+func WithBaseURLFunc(fn func(*http.Request) string) Option {
+	return func(h *Handler) {
+		h.baseURLFunc = fn
+	}
+}
+
+// WithPrivateURLBlocking rejects long URLs whose host is localhost or
+// resolves to a loopback, link-local, private, or unspecified address, so
+// the service can't be used to reach internal hosts. A nil resolver uses
+// net.DefaultResolver.
+func WithPrivateURLBlocking(resolver HostResolver) Option {
+	return func(h *Handler) {
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		h.hostResolver = resolver
+	}
+}
+
+// WithHostBlocklist rejects long URLs whose host is one of hosts or a
+// subdomain of one, with the message "destination host not allowed".
+func WithHostBlocklist(hosts []string) Option {
+	return func(h *Handler) {
+		limits := *h.limits.Load()
+		limits.blockedHosts = newHostSet(hosts)
+		h.limits.Store(&limits)
+	}
+}
+
+// WithQREncoder renders the QR code endpoint's images with enc instead of
+// qrcode.PNGEncoder.
+func WithQREncoder(enc QREncoder) Option {
+	return func(h *Handler) {
+		h.qrEncoder = enc
+	}
+}
+
+// WithSortedQueryParams sorts the query parameters of long URLs by key
+// before they are stored. Some servers care about parameter order, so this
+// is opt-in.
+func WithSortedQueryParams() Option {
+	return func(h *Handler) {
+		h.sortQuery = true
+	}
+}
+
+// WithClock sets the clock used for defaults relative to the current date.
+// It defaults to domain.RealClock.
+func WithClock(clock domain.Clock) Option {
+	return func(h *Handler) {
+		h.clock = clock
+	}
+}
+
+// WithTTLPolicy sets the TTL applied when ttl_seconds is omitted and the
+// range ttl_seconds must fall in. It defaults to domain.DefaultTTLPolicy.
+func WithTTLPolicy(policy domain.TTLPolicy) Option {
+	return func(h *Handler) {
+		limits := *h.limits.Load()
+		limits.ttl = policy
+		h.limits.Store(&limits)
+	}
+}
+
+// WithGoneForExpired makes Redirect answer expired links with 410 Gone and
+// error "expired" rather than the 404 used for unknown codes.
+func WithGoneForExpired() Option {
+	return func(h *Handler) {
+		h.goneForExpired = true
+	}
+}
+
+// WithDestinationVerification lets create requests set verify_destination
+// to have the long URL checked with a HEAD request before it is accepted;
+// see verifyDestination. Without this option such requests fail
+// validation. It implies WithPrivateURLBlocking with net.DefaultResolver
+// unless a resolver is given, so the server never fetches internal hosts.
+// A nil transport dials only the addresses each hop was vetted at; a
+// non-nil one replaces it, which is meant for tests.
+func WithDestinationVerification(transport http.RoundTripper) Option {
+	return func(h *Handler) {
+		h.destinationClient = newDestinationClient(transport)
+	}
+}
+
+// WithAnonymousMaxTTL caps the TTL anonymous callers (see
+// domain.AuthTierFromContext) may request, and their default TTL, at max.
+// Anonymous callers also can't create links that never expire.
+// Authenticated callers keep the TTL policy's maximum.
+func WithAnonymousMaxTTL(max time.Duration) Option {
+	return func(h *Handler) {
+		h.anonymousMaxTTL = max
+	}
+}
+
+// WithCodeSuffixTrimming makes Redirect and RedirectHead ignore an
+// extension such as ".html" that editors append to pasted links. Routes
+// must be registered separately to also accept a trailing slash.
+func WithCodeSuffixTrimming() Option {
+	return func(h *Handler) {
+		h.trimCodeSuffixes = true
+	}
+}
+
+// WithCodeAlphabet checks custom aliases against alphabet, the characters
+// the service's generator uses, instead of shortcode.Alphabet.
+func WithCodeAlphabet(alphabet string) Option {
+	return func(h *Handler) {
+		h.alphabet = alphabet
+	}
 }
 
 // New creates a new Handler with the given dependencies.
-func New(service URLService, baseURL string) *Handler {
-	return &Handler{
-		service: service,
-		baseURL: baseURL,
+func New(service URLService, baseURL string, opts ...Option) *Handler {
+	h := &Handler{
+		service:   service,
+		baseURL:   baseURL,
+		clock:     domain.RealClock{},
+		urlRules:  defaultURLRules(),
+		alphabet:  shortcode.Alphabet,
+		qrEncoder: qrcode.PNGEncoder{},
+
+		redirectErrorTemplate: defaultRedirectErrorTemplate,
+	}
+	h.limits.Store(&activeLimits{ttl: domain.DefaultTTLPolicy()})
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.destinationClient != nil && h.hostResolver == nil {
+		h.hostResolver = net.DefaultResolver
+	}
+	return h
+}
+
+// SetLimits replaces the host blocklist and TTL policy in one step.
+// Requests already being served finish with the settings they started with.
+func (h *Handler) SetLimits(limits Limits) {
+	h.limits.Store(&activeLimits{
+		blockedHosts: newHostSet(limits.BlockedHosts),
+		ttl:          limits.TTL,
+	})
+}
+
+// maxTTL returns the largest TTL the caller of ctx may give a new link.
+func (h *Handler) maxTTL(ctx context.Context, limits *activeLimits) time.Duration {
+	if h.anonymousCapped(ctx) {
+		return min(h.anonymousMaxTTL, limits.ttl.Max)
+	}
+	return limits.ttl.Max
+}
+
+// anonymousCapped reports whether the caller of ctx is held to
+// anonymousMaxTTL.
+func (h *Handler) anonymousCapped(ctx context.Context) bool {
+	return h.anonymousMaxTTL > 0 && domain.AuthTierFromContext(ctx) == domain.TierAnonymous
+}
+
+// shortURL builds the full short URL for code as seen by the given request.
+func (h *Handler) shortURL(r *http.Request, code string) string {
+	return h.requestBaseURL(r) + "/s/" + code
+}
+
+// formatExpiry formats the record's expiry for responses, or returns nil
+// for records that never expire.
+func formatExpiry(record *domain.URLRecord) *string {
+	if record.NeverExpires() {
+		return nil
 	}
+	formatted := record.ExpiresAt.Format(time.RFC3339)
+	return &formatted
+}
+
+// ClientIPResolver reports the address of the client that sent a request,
+// as told by trusted proxies. *clientip.Resolver satisfies it.
+type ClientIPResolver interface {
+	ClientIP(r *http.Request) string
+}
+
+// WithClientIPResolver identifies visitors with resolver instead of the
+// direct peer's address, so clients behind a reverse proxy aren't all
+// taken for the proxy, e.g. by click deduplication.
+func WithClientIPResolver(resolver ClientIPResolver) Option {
+	return func(h *Handler) {
+		h.clientIPs = resolver
+	}
+}
+
+// clientIP returns the address of the client that sent r, which is the
+// direct peer's unless WithClientIPResolver says otherwise.
+func (h *Handler) clientIP(r *http.Request) string {
+	if h.clientIPs != nil {
+		return h.clientIPs.ClientIP(r)
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// visit describes the client making the request.
+func (h *Handler) visit(r *http.Request) domain.Visit {
+	return domain.Visit{ClientIP: h.clientIP(r), Referer: r.Referer(), UserAgent: r.UserAgent()}
 }
 
 func (h *Handler) writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
+	if !h.camelCase {
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(data)
+		return
+	}
+
+	var buf bytes.Buffer
+	_ = json.NewEncoder(&buf).Encode(data)
+	body, err := camelizeKeys(buf.Bytes())
+	if err != nil {
+		body = buf.Bytes()
+	}
 	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(data)
+	_, _ = w.Write(body)
+}
+
+// writeInternalError reports a failed service call. A call cut short by the
+// request deadline gets 503 "timeout" so clients know to retry; anything
+// else is a 500 with message.
+func (h *Handler) writeInternalError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		h.writeError(w, http.StatusServiceUnavailable, "timeout", "request timed out, try again later")
+		return
+	}
+	h.writeError(w, http.StatusInternalServerError, "internal_error", message)
 }
 
 func (h *Handler) writeError(w http.ResponseWriter, status int, code, message string) {
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// History handles GET /s/{code}/history requests.
+func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if code == "" {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "short code is required")
+		return
+	}
+
+	events, err := h.service.GetHistory(r.Context(), code)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			h.writeError(w, http.StatusNotFound, "not_found", "short code not found")
+			return
+		}
+		h.writeInternalError(w, err, "failed to get history")
+		return
+	}
+
+	resp := HistoryResponse{
+		ShortCode: code,
+		Events:    make([]HistoryEventResponse, 0, len(events)),
+	}
+	for _, event := range events {
+		resp.Events = append(resp.Events, HistoryEventResponse{
+			Type:    string(event.Type),
+			At:      event.At.Format(time.RFC3339),
+			Actor:   event.Actor,
+			Details: event.Details,
+		})
+	}
+
+	h.writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,62 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryHandler_ReturnsEvents(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("GetHistory", mock.Anything, "Ab2CdE3F").
+		Return([]domain.HistoryEvent{{
+			Type:    domain.EventCreated,
+			At:      time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+			Actor:   "anonymous",
+			Details: map[string]string{"ttl_seconds": "86400"},
+		}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/Ab2CdE3F/history", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+
+	h.History(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp handler.HistoryResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "Ab2CdE3F", resp.ShortCode)
+	require.Len(t, resp.Events, 1)
+	assert.Equal(t, "created", resp.Events[0].Type)
+	assert.Equal(t, "2024-01-15T12:00:00Z", resp.Events[0].At)
+	assert.Equal(t, "anonymous", resp.Events[0].Actor)
+	assert.Equal(t, "86400", resp.Events[0].Details["ttl_seconds"])
+}
+
+func TestHistoryHandler_NotFound_Returns404(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("GetHistory", mock.Anything, "notfound").
+		Return(nil, domain.ErrNotFound)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/notfound/history", nil)
+	req.SetPathValue("code", "notfound")
+	rec := httptest.NewRecorder()
+
+	h.History(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"url-shortener/internal/domain"
+)
+
+// Info handles GET /s/{code}/info requests, previewing where a short link
+// goes. Unlike Redirect it neither redirects nor counts a click, so it is
+// safe to call when rendering a link preview.
+func (h *Handler) Info(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if code == "" {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "short code is required")
+		return
+	}
+
+	record, err := h.service.GetStats(r.Context(), code)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrExpired) {
+			h.writeError(w, http.StatusNotFound, "not_found", "short code not found or expired")
+			return
+		}
+		h.writeInternalError(w, err, "failed to get short code")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, InfoResponse{
+		ShortCode:  record.ShortCode,
+		LongURL:    record.LongURL,
+		ExpiresAt:  formatExpiry(record),
+		ClickCount: record.ClickCount,
+	})
+}
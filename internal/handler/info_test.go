@@ -0,0 +1,88 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfoHandler_ValidCode_ReturnsDestination(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("GetStats", mock.Anything, "Ab2CdE3F").Return(&domain.URLRecord{
+		ShortCode:  "Ab2CdE3F",
+		LongURL:    "https://example.com/page",
+		CreatedAt:  time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+		ExpiresAt:  time.Date(2024, 1, 16, 12, 0, 0, 0, time.UTC),
+		ClickCount: 7,
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/Ab2CdE3F/info", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+
+	h.Info(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Location"))
+
+	var resp handler.InfoResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "Ab2CdE3F", resp.ShortCode)
+	assert.Equal(t, "https://example.com/page", resp.LongURL)
+	require.NotNil(t, resp.ExpiresAt)
+	assert.Equal(t, "2024-01-16T12:00:00Z", *resp.ExpiresAt)
+	assert.Equal(t, int64(7), resp.ClickCount)
+
+	// Previewing must not go through Resolve, which counts a click
+	mockService.AssertNotCalled(t, "Resolve", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestInfoHandler_NotFoundOrExpired_Returns404(t *testing.T) {
+	for _, svcErr := range []error{domain.ErrNotFound, domain.ErrExpired} {
+		t.Run(svcErr.Error(), func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+
+			mockService.On("GetStats", mock.Anything, "gone1234").Return(nil, svcErr)
+
+			req := httptest.NewRequest(http.MethodGet, "/s/gone1234/info", nil)
+			req.SetPathValue("code", "gone1234")
+			rec := httptest.NewRecorder()
+
+			h.Info(rec, req)
+
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+
+			var resp handler.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "not_found", resp.Error)
+		})
+	}
+}
+
+func TestInfoHandler_ServiceError_Returns500(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("GetStats", mock.Anything, "Ab2CdE3F").Return(nil, errors.New("db down"))
+
+	req := httptest.NewRequest(http.MethodGet, "/s/Ab2CdE3F/info", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+
+	h.Info(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http"
+
+	"url-shortener/internal/domain"
+)
+
+// List handles GET /urls?limit=&offset=&tag= requests. With tag, only
+// links labeled with it are listed and counted.
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit, offset, err := parsePagination(query.Get("limit"), query.Get("offset"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	var (
+		records []*domain.URLRecord
+		total   int
+	)
+	if query.Has("tag") {
+		tag, tagErr := normalizeTag("tag", query.Get("tag"))
+		if tagErr != nil {
+			h.writeError(w, http.StatusBadRequest, "validation_error", tagErr.Error())
+			return
+		}
+		records, total, err = h.service.ListByTag(r.Context(), tag, offset, limit)
+	} else {
+		records, total, err = h.service.List(r.Context(), offset, limit)
+	}
+	if err != nil {
+		h.writeInternalError(w, err, "failed to list URLs")
+		return
+	}
+
+	resp := ListResponse{
+		URLs:   make([]StatsResponse, 0, len(records)),
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}
+	for _, record := range records {
+		resp.URLs = append(resp.URLs, h.toStatsResponse(r, record, h.clock.Now()))
+	}
+
+	h.writeJSON(w, http.StatusOK, resp)
+}
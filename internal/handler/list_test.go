@@ -0,0 +1,99 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListHandler_DefaultPagination(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	mockService.On("List", mock.Anything, 0, 20).Return([]*domain.URLRecord{
+		{ShortCode: "code0001", LongURL: "https://example.com/1", CreatedAt: now, ExpiresAt: now.Add(time.Hour), ClickCount: 3},
+		{ShortCode: "code0002", LongURL: "https://example.com/2", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+	}, 42, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/urls", nil)
+	rec := httptest.NewRecorder()
+
+	h.List(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp handler.ListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 42, resp.Total)
+	assert.Equal(t, 20, resp.Limit)
+	assert.Equal(t, 0, resp.Offset)
+	require.Len(t, resp.URLs, 2)
+	assert.Equal(t, "code0001", resp.URLs[0].ShortCode)
+	assert.Equal(t, int64(3), resp.URLs[0].ClickCount)
+}
+
+func TestListHandler_EmptyStore(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("List", mock.Anything, 0, 20).Return([]*domain.URLRecord{}, 0, nil)
+
+	rec := httptest.NewRecorder()
+	h.List(rec, httptest.NewRequest(http.MethodGet, "/urls", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"urls":[],"total":0,"limit":20,"offset":0}`, rec.Body.String())
+}
+
+func TestListHandler_PassesLimitAndOffset(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("List", mock.Anything, 100, 100).Return([]*domain.URLRecord{}, 100, nil)
+
+	rec := httptest.NewRecorder()
+	h.List(rec, httptest.NewRequest(http.MethodGet, "/urls?limit=100&offset=100", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestListHandler_InvalidParams_Returns400(t *testing.T) {
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{name: "limit zero", query: "limit=0"},
+		{name: "limit above max", query: "limit=101"},
+		{name: "limit not a number", query: "limit=ten"},
+		{name: "negative offset", query: "offset=-1"},
+		{name: "offset not a number", query: "offset=1.5"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+
+			rec := httptest.NewRecorder()
+			h.List(rec, httptest.NewRequest(http.MethodGet, "/urls?"+tc.query, nil))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+			var resp handler.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "validation_error", resp.Error)
+			mockService.AssertNotCalled(t, "List")
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package handler
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec is the OpenAPI 3.0 description of the public API. It is
+// maintained by hand, so update it along with the routes and DTOs.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPI handles GET /openapi.json requests, serving the API spec.
+func (h *Handler) OpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(openAPISpec)
+}
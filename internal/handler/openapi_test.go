@@ -0,0 +1,44 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIHandler_ServesSpec(t *testing.T) {
+	h := handler.New(new(MockURLService), "http://localhost:8080")
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	rec := httptest.NewRecorder()
+
+	h.OpenAPI(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var spec struct {
+		OpenAPI    string                    `json:"openapi"`
+		Paths      map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &spec))
+
+	assert.Regexp(t, `^3\.0\.`, spec.OpenAPI)
+	assert.Contains(t, spec.Paths["/shorten"], "post")
+	assert.Contains(t, spec.Paths["/s/{code}"], "get")
+	assert.Contains(t, spec.Paths["/s/{code}"], "head")
+	assert.Contains(t, spec.Paths["/stats/{code}"], "get")
+	assert.Contains(t, spec.Paths["/health"], "get")
+	for _, schema := range []string{"CreateRequest", "CreateResponse", "ErrorResponse"} {
+		assert.Contains(t, spec.Components.Schemas, schema)
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"url-shortener/internal/domain"
+)
+
+// QR handles GET /s/{code}/qr requests, returning a PNG QR code of the
+// short URL. Looking the code up does not count as a click.
+func (h *Handler) QR(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if code == "" {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "short code is required")
+		return
+	}
+
+	size, err := parseQRSize(r.URL.Query().Get("size"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	record, err := h.service.GetStats(r.Context(), code)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrExpired) {
+			h.writeError(w, http.StatusNotFound, "not_found", "short code not found or expired")
+			return
+		}
+		h.writeInternalError(w, err, "failed to get short code")
+		return
+	}
+
+	png, err := h.qrEncoder.Encode(h.shortURL(r, record.ShortCode), size)
+	if err != nil {
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "failed to render QR code")
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(png)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(png)
+}
@@ -0,0 +1,142 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// stubQREncoder records what it was asked to encode.
+type stubQREncoder struct {
+	content string
+	size    int
+	err     error
+}
+
+func (s *stubQREncoder) Encode(content string, size int) ([]byte, error) {
+	s.content = content
+	s.size = size
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []byte("\x89PNG-stub"), nil
+}
+
+func newQRRequest(code, query string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/s/"+code+"/qr"+query, nil)
+	req.SetPathValue("code", code)
+	return req
+}
+
+func TestQRHandler_ReturnsPNGOfShortURL(t *testing.T) {
+	mockService := new(MockURLService)
+	encoder := &stubQREncoder{}
+	h := handler.New(mockService, "http://localhost:8080", handler.WithQREncoder(encoder))
+
+	mockService.On("GetStats", mock.Anything, "abc12345").Return(&domain.URLRecord{
+		ShortCode: "abc12345",
+		LongURL:   "https://example.com",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	h.QR(rec, newQRRequest("abc12345", ""))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "\x89PNG-stub", rec.Body.String())
+	assert.Equal(t, "http://localhost:8080/s/abc12345", encoder.content)
+	assert.Equal(t, 256, encoder.size, "default size")
+
+	// Rendering a QR code must not count as a click
+	mockService.AssertNotCalled(t, "Resolve")
+}
+
+func TestQRHandler_DefaultEncoder(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("GetStats", mock.Anything, "abc12345").Return(&domain.URLRecord{
+		ShortCode: "abc12345",
+		LongURL:   "https://example.com",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	h.QR(rec, newQRRequest("abc12345", ""))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "\x89PNG"), "should render a real PNG without WithQREncoder")
+}
+
+func TestQRHandler_CustomSize(t *testing.T) {
+	mockService := new(MockURLService)
+	encoder := &stubQREncoder{}
+	h := handler.New(mockService, "http://localhost:8080", handler.WithQREncoder(encoder))
+
+	mockService.On("GetStats", mock.Anything, "abc12345").Return(&domain.URLRecord{ShortCode: "abc12345"}, nil)
+
+	rec := httptest.NewRecorder()
+	h.QR(rec, newQRRequest("abc12345", "?size=512"))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 512, encoder.size)
+}
+
+func TestQRHandler_InvalidSize_Returns400(t *testing.T) {
+	for _, size := range []string{"63", "1025", "big", "-1"} {
+		t.Run(size, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080", handler.WithQREncoder(&stubQREncoder{}))
+
+			rec := httptest.NewRecorder()
+			h.QR(rec, newQRRequest("abc12345", "?size="+size))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+			var resp handler.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "validation_error", resp.Error)
+		})
+	}
+}
+
+func TestQRHandler_NotFoundOrExpired_Returns404(t *testing.T) {
+	for _, serviceErr := range []error{domain.ErrNotFound, domain.ErrExpired} {
+		t.Run(serviceErr.Error(), func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080", handler.WithQREncoder(&stubQREncoder{}))
+
+			mockService.On("GetStats", mock.Anything, "abc12345").Return(nil, serviceErr)
+
+			rec := httptest.NewRecorder()
+			h.QR(rec, newQRRequest("abc12345", ""))
+
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		})
+	}
+}
+
+func TestQRHandler_EncoderFailure_Returns500(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithQREncoder(&stubQREncoder{err: errors.New("boom")}))
+
+	mockService.On("GetStats", mock.Anything, "abc12345").Return(&domain.URLRecord{ShortCode: "abc12345"}, nil)
+
+	rec := httptest.NewRecorder()
+	h.QR(rec, newQRRequest("abc12345", ""))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateCharge takes n more tokens from the caller's rate limit. When fewer
+// than n are left it takes none and returns false and how long until they
+// would be available.
+type RateCharge func(n int) (ok bool, retryAfter time.Duration)
+
+type rateChargeKey struct{}
+
+// WithRateCharge returns a copy of ctx carrying charge, which handlers call
+// for requests costing more than the one token a rate limit middleware
+// takes, such as a batch create.
+func WithRateCharge(ctx context.Context, charge RateCharge) context.Context {
+	return context.WithValue(ctx, rateChargeKey{}, charge)
+}
+
+// chargeRate takes n more tokens from the rate limit in ctx, if any.
+func chargeRate(ctx context.Context, n int) (bool, time.Duration) {
+	charge, ok := ctx.Value(rateChargeKey{}).(RateCharge)
+	if !ok || n <= 0 {
+		return true, 0
+	}
+	return charge(n)
+}
+
+// WriteRateLimited answers 429 with a Retry-After header of retryAfter
+// rounded up to whole seconds, at least one.
+func WriteRateLimited(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Error:   "rate_limited",
+		Message: "Too many requests, retry later",
+	})
+}
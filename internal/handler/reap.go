@@ -0,0 +1,15 @@
+package handler
+
+import "net/http"
+
+// Reap handles POST /admin/reap requests, deleting expired links now
+// rather than at the reaper's next run.
+func (h *Handler) Reap(w http.ResponseWriter, r *http.Request) {
+	codes, err := h.service.PurgeExpired(r.Context())
+	if err != nil {
+		h.writeInternalError(w, err, "failed to delete expired links")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, ReapResponse{Deleted: len(codes)})
+}
@@ -0,0 +1,45 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReapHandler_ReportsDeletedCount(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	mockService.On("PurgeExpired", mock.Anything).Return([]string{"expired1", "expired2"}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/reap", nil)
+	rec := httptest.NewRecorder()
+
+	h.Reap(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp handler.ReapResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Deleted)
+}
+
+func TestReapHandler_ServiceError_Returns500(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	mockService.On("PurgeExpired", mock.Anything).Return(nil, errors.New("disk on fire"))
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/reap", nil)
+	rec := httptest.NewRecorder()
+
+	h.Reap(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "internal_error")
+}
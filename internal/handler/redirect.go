@@ -3,27 +3,153 @@ package handler
 import (
 	"errors"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"url-shortener/internal/domain"
 )
 
-// Redirect handles GET /s/{code} requests.
+// Redirect handles GET /s/{code} requests. Query parameters on the short link
+// are carried over to the destination; see mergeQuery. Clients whose Accept
+// header prefers JSON get the destination in a 200 body instead of a
+// redirect; the click is counted either way. Browsers get an HTML page
+// instead of a JSON error when the link can't be followed.
 func (h *Handler) Redirect(w http.ResponseWriter, r *http.Request) {
-	code := r.PathValue("code")
+	code := h.redirectCode(r)
+	if code == "" {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "short code is required")
+		return
+	}
+
+	// The response depends on Accept, so caches must key on it.
+	w.Header().Add("Vary", "Accept")
+
+	record, err := h.service.Resolve(r.Context(), code, h.visit(r))
+	if err != nil {
+		h.writeResolveError(w, r, err)
+		return
+	}
+
+	if prefersJSON(r.Header.Get("Accept")) {
+		h.writeJSON(w, http.StatusOK, ResolveResponse{
+			LongURL:   mergeQuery(record.LongURL, r.URL.Query()),
+			ShortCode: record.ShortCode,
+		})
+		return
+	}
+
+	h.redirectTo(w, r, record)
+}
+
+// RedirectHead handles HEAD /s/{code} requests. It answers with the status
+// and Location a GET would get, but without a body and without counting a
+// click, since HEAD comes from link checkers and unfurlers, not visitors.
+func (h *Handler) RedirectHead(w http.ResponseWriter, r *http.Request) {
+	code := h.redirectCode(r)
 	if code == "" {
 		h.writeError(w, http.StatusBadRequest, "validation_error", "short code is required")
 		return
 	}
 
-	longURL, err := h.service.Resolve(r.Context(), code)
+	// Errors depend on Accept, so caches must key on it.
+	w.Header().Add("Vary", "Accept")
+
+	record, err := h.service.Lookup(r.Context(), code)
 	if err != nil {
-		if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrExpired) {
-			h.writeError(w, http.StatusNotFound, "not_found", "short code not found or expired")
-			return
+		h.writeResolveError(w, r, err)
+		return
+	}
+
+	h.redirectTo(w, r, record)
+}
+
+// codeSuffixes are the extensions WithCodeSuffixTrimming drops.
+var codeSuffixes = []string{".html", ".htm"}
+
+// redirectCode returns the code a redirect request is for, without one
+// known extension when suffix trimming is on; see validateAlias for why
+// trimming can't turn one stored code into another.
+func (h *Handler) redirectCode(r *http.Request) string {
+	code := r.PathValue("code")
+	if h.trimCodeSuffixes {
+		for _, suffix := range codeSuffixes {
+			if trimmed, ok := strings.CutSuffix(code, suffix); ok {
+				return trimmed
+			}
 		}
+	}
+	return code
+}
+
+// writeResolveError maps a Resolve or Lookup error to its response, an
+// HTML page for browsers; see writeRedirectError.
+func (h *Handler) writeResolveError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, domain.ErrInvalidChecksum) {
+		h.writeRedirectError(w, r, http.StatusNotFound, "invalid_code", err.Error())
+		return
+	}
+	if errors.Is(err, domain.ErrExpired) && h.goneForExpired {
+		h.writeRedirectError(w, r, http.StatusGone, "expired", "short code has expired")
+		return
+	}
+	if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrExpired) {
+		h.writeRedirectError(w, r, http.StatusNotFound, "not_found", "short code not found or expired")
+		return
+	}
+	if errors.Is(err, domain.ErrDisabled) {
+		h.writeRedirectError(w, r, http.StatusGone, "disabled", "short code has been disabled")
+		return
+	}
+	if errors.Is(err, domain.ErrUnsafeDestination) {
+		// The service has logged the code and redacted destination.
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "failed to resolve URL")
 		return
 	}
+	h.writeInternalError(w, err, "failed to resolve URL")
+}
+
+// redirectTo redirects to record's destination. HEAD responses carry only
+// the status and headers.
+func (h *Handler) redirectTo(w http.ResponseWriter, r *http.Request, record *domain.URLRecord) {
+	if remaining, limited := record.RemainingClicks(); limited {
+		w.Header().Set("X-Remaining-Clicks", strconv.FormatInt(remaining, 10))
+	}
+
+	status := http.StatusFound
+	if record.RedirectPermanent {
+		status = http.StatusMovedPermanently
+	}
+
+	target := mergeQuery(record.LongURL, r.URL.Query())
+	if r.Method == http.MethodHead {
+		w.Header().Set("Location", target)
+		w.WriteHeader(status)
+		return
+	}
+
+	http.Redirect(w, r, target, status)
+}
+
+// mergeQuery adds params to the query string of longURL, keeping its path
+// and fragment. A param already present in longURL is replaced by the
+// incoming values, so campaign tags such as utm_source can be overridden per
+// share. longURL is returned untouched when there is nothing to merge.
+func mergeQuery(longURL string, params url.Values) string {
+	if len(params) == 0 {
+		return longURL
+	}
+
+	u, err := url.Parse(longURL)
+	if err != nil {
+		return longURL
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
 
-	http.Redirect(w, r, longURL, http.StatusFound)
+	return u.String()
 }
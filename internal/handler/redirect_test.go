@@ -1,24 +1,32 @@
 package handler_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"url-shortener/internal/clientip"
 	"url-shortener/internal/domain"
 	"url-shortener/internal/handler"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/service"
+	"url-shortener/internal/shortcode"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRedirectHandler_ValidCode_Returns302(t *testing.T) {
 	mockService := new(MockURLService)
 	h := handler.New(mockService, "http://localhost:8080")
 
-	mockService.On("Resolve", mock.Anything, "Ab2CdE3F").
-		Return("https://example.com/destination", nil)
+	mockService.On("Resolve", mock.Anything, "Ab2CdE3F", mock.Anything).
+		Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com/destination"}, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/s/Ab2CdE3F", nil)
 	req.SetPathValue("code", "Ab2CdE3F")
@@ -37,8 +45,8 @@ func TestRedirectHandler_NotFound_Returns404(t *testing.T) {
 	mockService := new(MockURLService)
 	h := handler.New(mockService, "http://localhost:8080")
 
-	mockService.On("Resolve", mock.Anything, "notfound").
-		Return("", domain.ErrNotFound)
+	mockService.On("Resolve", mock.Anything, "notfound", mock.Anything).
+		Return(nil, domain.ErrNotFound)
 
 	req := httptest.NewRequest(http.MethodGet, "/s/notfound", nil)
 	req.SetPathValue("code", "notfound")
@@ -54,8 +62,8 @@ func TestRedirectHandler_Expired_Returns404(t *testing.T) {
 	mockService := new(MockURLService)
 	h := handler.New(mockService, "http://localhost:8080")
 
-	mockService.On("Resolve", mock.Anything, "expired1").
-		Return("", domain.ErrExpired)
+	mockService.On("Resolve", mock.Anything, "expired1", mock.Anything).
+		Return(nil, domain.ErrExpired)
 
 	req := httptest.NewRequest(http.MethodGet, "/s/expired1", nil)
 	req.SetPathValue("code", "expired1")
@@ -70,12 +78,61 @@ func TestRedirectHandler_Expired_Returns404(t *testing.T) {
 	assert.Contains(t, rec.Body.String(), "not found or expired")
 }
 
+func TestRedirectHandler_Disabled_Returns410(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Resolve", mock.Anything, "disabld1", mock.Anything).
+		Return(nil, domain.ErrDisabled)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/disabld1", nil)
+	req.SetPathValue("code", "disabld1")
+
+	rec := httptest.NewRecorder()
+
+	h.Redirect(rec, req)
+
+	assert.Equal(t, http.StatusGone, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"disabled"`)
+	assert.Empty(t, rec.Header().Get("Location"))
+}
+
+func TestRedirectHandler_GoneForExpired(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithGoneForExpired())
+
+	mockService.On("Resolve", mock.Anything, "expired1", mock.Anything).
+		Return(nil, domain.ErrExpired)
+	mockService.On("Resolve", mock.Anything, "notfound", mock.Anything).
+		Return(nil, domain.ErrNotFound)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/expired1", nil)
+	req.SetPathValue("code", "expired1")
+	rec := httptest.NewRecorder()
+
+	h.Redirect(rec, req)
+
+	assert.Equal(t, http.StatusGone, rec.Code)
+	var resp handler.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "expired", resp.Error)
+
+	// Unknown codes are still 404
+	req = httptest.NewRequest(http.MethodGet, "/s/notfound", nil)
+	req.SetPathValue("code", "notfound")
+	rec = httptest.NewRecorder()
+
+	h.Redirect(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
 func TestRedirectHandler_ServiceError_Returns500(t *testing.T) {
 	mockService := new(MockURLService)
 	h := handler.New(mockService, "http://localhost:8080")
 
-	mockService.On("Resolve", mock.Anything, "error123").
-		Return("", errors.New("database connection failed"))
+	mockService.On("Resolve", mock.Anything, "error123", mock.Anything).
+		Return(nil, errors.New("database connection failed"))
 
 	req := httptest.NewRequest(http.MethodGet, "/s/error123", nil)
 	req.SetPathValue("code", "error123")
@@ -86,3 +143,442 @@ func TestRedirectHandler_ServiceError_Returns500(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
 }
+
+func TestRedirectHandler_InvalidChecksum_Returns404WithSuggestion(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Resolve", mock.Anything, "bA2CdE3F", mock.Anything).
+		Return(nil, &domain.ChecksumError{Suggestion: "Ab2CdE3F"})
+
+	req := httptest.NewRequest(http.MethodGet, "/s/bA2CdE3F", nil)
+	req.SetPathValue("code", "bA2CdE3F")
+
+	rec := httptest.NewRecorder()
+
+	h.Redirect(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), "invalid_code")
+	assert.Contains(t, rec.Body.String(), "did you mean Ab2CdE3F?")
+}
+
+func TestRedirectHandler_ClickLimited_SetsRemainingClicksHeader(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Resolve", mock.Anything, "Ab2CdE3F", mock.Anything).
+		Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com", ClickCount: 1, MaxClicks: 3}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/Ab2CdE3F", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+
+	h.Redirect(rec, req)
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("X-Remaining-Clicks"))
+}
+
+func TestRedirectHandler_Unlimited_OmitsRemainingClicksHeader(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Resolve", mock.Anything, "Ab2CdE3F", mock.Anything).
+		Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com", ClickCount: 1}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/Ab2CdE3F", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+
+	h.Redirect(rec, req)
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Empty(t, rec.Header().Values("X-Remaining-Clicks"))
+}
+
+func TestRedirectHandler_PassesClientIPToService(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Resolve", mock.Anything, "Ab2CdE3F", domain.Visit{ClientIP: "203.0.113.7"}).
+		Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/Ab2CdE3F", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	req.RemoteAddr = "203.0.113.7:54321"
+	rec := httptest.NewRecorder()
+
+	h.Redirect(rec, req)
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestRedirectHandler_ResolvesClientIPBehindProxy(t *testing.T) {
+	proxies, err := clientip.NewResolver([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithClientIPResolver(proxies))
+
+	mockService.On("Resolve", mock.Anything, "Ab2CdE3F", domain.Visit{ClientIP: "203.0.113.7"}).
+		Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/Ab2CdE3F", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	rec := httptest.NewRecorder()
+
+	h.Redirect(rec, req)
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestRedirectHandler_PassesRefererToService(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Resolve", mock.Anything, "Ab2CdE3F", domain.Visit{ClientIP: "203.0.113.7", Referer: "https://news.example.com/story"}).
+		Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/Ab2CdE3F", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	req.RemoteAddr = "203.0.113.7:54321"
+	req.Header.Set("Referer", "https://news.example.com/story")
+	rec := httptest.NewRecorder()
+
+	h.Redirect(rec, req)
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestRedirectHandler_PassesUserAgentToService(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Resolve", mock.Anything, "Ab2CdE3F", domain.Visit{ClientIP: "203.0.113.7", UserAgent: "Googlebot/2.1"}).
+		Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/Ab2CdE3F", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	req.RemoteAddr = "203.0.113.7:54321"
+	req.Header.Set("User-Agent", "Googlebot/2.1")
+	rec := httptest.NewRecorder()
+
+	h.Redirect(rec, req)
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestRedirectHandler_BotFilter(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), domain.RealClock{},
+		service.WithBotFilter(nil))
+	h := handler.New(svc, "http://localhost:8080")
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com/page", TTL: time.Hour})
+	require.NoError(t, err)
+
+	for _, ua := range []string{
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+		"",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/s/"+record.ShortCode, nil)
+		req.SetPathValue("code", record.ShortCode)
+		req.Header.Set("User-Agent", ua)
+		rec := httptest.NewRecorder()
+
+		h.Redirect(rec, req)
+
+		assert.Equal(t, http.StatusFound, rec.Code, "bots are still redirected: %q", ua)
+		assert.Equal(t, "https://example.com/page", rec.Header().Get("Location"))
+	}
+
+	stats, err := svc.GetStats(ctx, record.ShortCode)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.ClickCount, "only the browser click counts")
+}
+
+func TestRedirectHandler_StatusDependsOnRecord(t *testing.T) {
+	testCases := []struct {
+		name       string
+		permanent  bool
+		wantStatus int
+	}{
+		{name: "temporary by default", permanent: false, wantStatus: http.StatusFound},
+		{name: "permanent when requested", permanent: true, wantStatus: http.StatusMovedPermanently},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+
+			mockService.On("Resolve", mock.Anything, "Ab2CdE3F", mock.Anything).
+				Return(&domain.URLRecord{
+					ShortCode:         "Ab2CdE3F",
+					LongURL:           "https://example.com/campaign",
+					RedirectPermanent: tc.permanent,
+				}, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/s/Ab2CdE3F", nil)
+			req.SetPathValue("code", "Ab2CdE3F")
+			rec := httptest.NewRecorder()
+
+			h.Redirect(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			assert.Equal(t, "https://example.com/campaign", rec.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRedirectHandler_MergesQueryParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		longURL  string
+		query    string
+		expected string
+	}{
+		{
+			name:     "no params keeps long URL as stored",
+			longURL:  "https://example.com/page?b=2&a=1#top",
+			query:    "",
+			expected: "https://example.com/page?b=2&a=1#top",
+		},
+		{
+			name:     "params added to long URL without query",
+			longURL:  "https://example.com/page",
+			query:    "utm_source=x&utm_medium=social",
+			expected: "https://example.com/page?utm_medium=social&utm_source=x",
+		},
+		{
+			name:     "params appended to existing query before fragment",
+			longURL:  "https://example.com/page?ref=home#section",
+			query:    "utm_source=x",
+			expected: "https://example.com/page?ref=home&utm_source=x#section",
+		},
+		{
+			name:     "conflicting key takes the incoming values",
+			longURL:  "https://example.com/page?utm_source=newsletter&id=7",
+			query:    "utm_source=x&utm_source=y",
+			expected: "https://example.com/page?id=7&utm_source=x&utm_source=y",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+
+			mockService.On("Resolve", mock.Anything, "Ab2CdE3F", mock.Anything).
+				Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: tt.longURL}, nil)
+
+			target := "/s/Ab2CdE3F"
+			if tt.query != "" {
+				target += "?" + tt.query
+			}
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			req.SetPathValue("code", "Ab2CdE3F")
+			rec := httptest.NewRecorder()
+
+			h.Redirect(rec, req)
+
+			assert.Equal(t, http.StatusFound, rec.Code)
+			assert.Equal(t, tt.expected, rec.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRedirectHeadHandler_MatchesGetWithoutBodyOrClick(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("Lookup", mock.Anything, "Ab2CdE3F").
+		Return(&domain.URLRecord{
+			ShortCode:         "Ab2CdE3F",
+			LongURL:           "https://example.com/destination",
+			RedirectPermanent: true,
+		}, nil)
+
+	req := httptest.NewRequest(http.MethodHead, "/s/Ab2CdE3F", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+
+	h.RedirectHead(rec, req)
+
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "https://example.com/destination", rec.Header().Get("Location"))
+	assert.Empty(t, rec.Body.String())
+
+	// Resolve counts clicks, so HEAD must not call it
+	mockService.AssertNotCalled(t, "Resolve", mock.Anything, mock.Anything, mock.Anything)
+	mockService.AssertExpectations(t)
+}
+
+func TestRedirectHeadHandler_ErrorsMatchGet(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"not found", domain.ErrNotFound, http.StatusNotFound},
+		{"expired", domain.ErrExpired, http.StatusNotFound},
+		{"disabled", domain.ErrDisabled, http.StatusGone},
+		{"service error", errors.New("database error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+
+			mockService.On("Lookup", mock.Anything, "Ab2CdE3F").Return(nil, tt.err)
+
+			req := httptest.NewRequest(http.MethodHead, "/s/Ab2CdE3F", nil)
+			req.SetPathValue("code", "Ab2CdE3F")
+			rec := httptest.NewRecorder()
+
+			h.RedirectHead(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Empty(t, rec.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRedirectHandler_AcceptNegotiation(t *testing.T) {
+	tests := []struct {
+		name     string
+		accept   string
+		wantJSON bool
+	}{
+		{name: "no header", accept: ""},
+		{name: "json", accept: "application/json", wantJSON: true},
+		{name: "html", accept: "text/html"},
+		{name: "anything", accept: "*/*"},
+		{name: "browser", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+		{name: "json with fallback", accept: "application/json, */*;q=0.1", wantJSON: true},
+		{name: "json ranked below html", accept: "application/json;q=0.5, text/html"},
+		{name: "json ranked above html", accept: "text/html;q=0.4, application/json;q=0.9", wantJSON: true},
+		{name: "json refused", accept: "application/json;q=0, */*"},
+		{name: "application wildcard", accept: "application/*", wantJSON: true},
+		{name: "malformed ranges skipped", accept: "application/json;q=abc, text/html"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+
+			mockService.On("Resolve", mock.Anything, "Ab2CdE3F", mock.Anything).
+				Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com/destination"}, nil).Once()
+
+			req := httptest.NewRequest(http.MethodGet, "/s/Ab2CdE3F?ref=api", nil)
+			req.SetPathValue("code", "Ab2CdE3F")
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+
+			h.Redirect(rec, req)
+
+			// The click is counted whichever representation is served.
+			mockService.AssertExpectations(t)
+			assert.Contains(t, rec.Header().Values("Vary"), "Accept")
+
+			if !tt.wantJSON {
+				assert.Equal(t, http.StatusFound, rec.Code)
+				assert.Equal(t, "https://example.com/destination?ref=api", rec.Header().Get("Location"))
+				return
+			}
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Empty(t, rec.Header().Get("Location"))
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			var resp handler.ResolveResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, handler.ResolveResponse{
+				LongURL:   "https://example.com/destination?ref=api",
+				ShortCode: "Ab2CdE3F",
+			}, resp)
+		})
+	}
+}
+
+func TestRedirectHandler_CodeSuffixTrimming(t *testing.T) {
+	tests := []struct {
+		name     string
+		pathCode string
+		trim     bool
+		wantCode string
+	}{
+		{name: "html extension", pathCode: "Ab2CdE3F.html", trim: true, wantCode: "Ab2CdE3F"},
+		{name: "htm extension", pathCode: "Ab2CdE3F.htm", trim: true, wantCode: "Ab2CdE3F"},
+		{name: "only one extension is dropped", pathCode: "Ab2CdE3F.html.html", trim: true, wantCode: "Ab2CdE3F.html"},
+		{name: "unknown extension is kept", pathCode: "Ab2CdE3F.pdf", trim: true, wantCode: "Ab2CdE3F.pdf"},
+		{name: "off by default", pathCode: "Ab2CdE3F.html", wantCode: "Ab2CdE3F.html"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			var opts []handler.Option
+			if tt.trim {
+				opts = append(opts, handler.WithCodeSuffixTrimming())
+			}
+			h := handler.New(mockService, "http://localhost:8080", opts...)
+
+			mockService.On("Resolve", mock.Anything, "Ab2CdE3F", mock.Anything).
+				Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com/destination"}, nil).Maybe()
+			mockService.On("Resolve", mock.Anything, mock.Anything, mock.Anything).
+				Return(nil, domain.ErrNotFound).Maybe()
+
+			req := httptest.NewRequest(http.MethodGet, "/s/"+tt.pathCode, nil)
+			req.SetPathValue("code", tt.pathCode)
+			rec := httptest.NewRecorder()
+
+			h.Redirect(rec, req)
+
+			mockService.AssertCalled(t, "Resolve", mock.Anything, tt.wantCode, mock.Anything)
+			if tt.wantCode == "Ab2CdE3F" {
+				assert.Equal(t, http.StatusFound, rec.Code)
+			} else {
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			}
+		})
+	}
+}
+
+func TestRedirectHandler_UnsafeDestination_Returns500(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	mockService.On("Resolve", mock.Anything, "Ab2CdE3F", mock.Anything).Return(nil, domain.ErrUnsafeDestination)
+	mockService.On("Lookup", mock.Anything, "Ab2CdE3F").Return(nil, domain.ErrUnsafeDestination)
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		req := httptest.NewRequest(method, "/s/Ab2CdE3F", nil)
+		req.SetPathValue("code", "Ab2CdE3F")
+		rec := httptest.NewRecorder()
+
+		if method == http.MethodHead {
+			h.RedirectHead(rec, req)
+		} else {
+			h.Redirect(rec, req)
+		}
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code, method)
+		assert.Empty(t, rec.Header().Get("Location"), method)
+		if method == http.MethodGet {
+			var resp handler.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "internal_error", resp.Error)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"url-shortener/internal/domain"
+)
+
+// Referrers handles GET /stats/{code}/referrers?limit= requests, listing the
+// sites that sent the most clicks to a link.
+func (h *Handler) Referrers(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if code == "" {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "short code is required")
+		return
+	}
+
+	limit, err := parseReferrerLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	referrers, err := h.service.GetReferrers(r.Context(), code, limit)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrExpired) {
+			h.writeError(w, http.StatusNotFound, "not_found", "short code not found or expired")
+			return
+		}
+		h.writeInternalError(w, err, "failed to get referrers")
+		return
+	}
+
+	resp := ReferrersResponse{
+		ShortCode: code,
+		Referrers: make([]ReferrerResponse, 0, len(referrers)),
+	}
+	for _, referrer := range referrers {
+		resp.Referrers = append(resp.Referrers, ReferrerResponse{
+			Referrer: referrer.Referrer,
+			Clicks:   referrer.Clicks,
+		})
+	}
+
+	h.writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,102 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferrersHandler_ReturnsTopReferrers(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("GetReferrers", mock.Anything, "Ab2CdE3F", 2).Return([]domain.ReferrerCount{
+		{Referrer: "news.example.com", Clicks: 12},
+		{Referrer: domain.ReferrerDirect, Clicks: 4},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F/referrers?limit=2", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+
+	h.Referrers(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp handler.ReferrersResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "Ab2CdE3F", resp.ShortCode)
+	assert.Equal(t, []handler.ReferrerResponse{
+		{Referrer: "news.example.com", Clicks: 12},
+		{Referrer: "(direct)", Clicks: 4},
+	}, resp.Referrers)
+	mockService.AssertExpectations(t)
+}
+
+func TestReferrersHandler_DefaultLimitAndEmptyList(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("GetReferrers", mock.Anything, "Ab2CdE3F", 10).Return([]domain.ReferrerCount{}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F/referrers", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+
+	h.Referrers(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"short_code":"Ab2CdE3F","referrers":[]}`, rec.Body.String())
+}
+
+func TestReferrersHandler_InvalidLimit_Returns400(t *testing.T) {
+	h := handler.New(new(MockURLService), "http://localhost:8080")
+
+	for _, limit := range []string{"0", "101", "abc"} {
+		req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F/referrers?limit="+limit, nil)
+		req.SetPathValue("code", "Ab2CdE3F")
+		rec := httptest.NewRecorder()
+
+		h.Referrers(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "limit=%s", limit)
+	}
+}
+
+func TestReferrersHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"not found", domain.ErrNotFound, http.StatusNotFound},
+		{"expired", domain.ErrExpired, http.StatusNotFound},
+		{"service error", errors.New("database error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+
+			mockService.On("GetReferrers", mock.Anything, "Ab2CdE3F", 10).Return(nil, tt.err)
+
+			req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F/referrers", nil)
+			req.SetPathValue("code", "Ab2CdE3F")
+			rec := httptest.NewRecorder()
+
+			h.Referrers(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"url-shortener/internal/jsonschema"
+)
+
+// schemaFS holds the JSON Schemas of request bodies. Like openapi.json they
+// are maintained by hand, so update them along with the DTOs.
+//
+//go:embed schemas/*.json
+var schemaFS embed.FS
+
+// createRequestSchema describes the POST /shorten body.
+var createRequestSchema = mustLoadSchema("schemas/create_request.json")
+
+// mustLoadSchema compiles an embedded schema. The schemas ship with the
+// binary, so a broken one is a programming error.
+func mustLoadSchema(name string) *jsonschema.Schema {
+	data, err := schemaFS.ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+	s, err := jsonschema.Compile(data)
+	if err != nil {
+		panic(name + ": " + err.Error())
+	}
+	return s
+}
+
+// WithSchemaValidation checks POST /shorten bodies against the embedded
+// JSON Schema before decoding them, so a body with wrong types or unknown
+// fields is rejected with every failing field listed at once.
+func WithSchemaValidation() Option {
+	return func(h *Handler) {
+		h.createSchema = createRequestSchema
+	}
+}
+
+// decodeCreateRequest decodes the POST /shorten body into req, checking it
+// against the request schema first when schema validation is on. It writes
+// the error response and returns false if the body is rejected, with 413
+// if it is longer than maxCreateBodyBytes.
+func (h *Handler) decodeCreateRequest(w http.ResponseWriter, r *http.Request, req *CreateRequest) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxCreateBodyBytes)
+	if h.createSchema == nil {
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			h.writeBodyError(w, err)
+			return false
+		}
+		return true
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.writeBodyError(w, err)
+		return false
+	}
+	violations, err := h.createSchema.Validate(body)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_json", "invalid JSON body")
+		return false
+	}
+	if len(violations) > 0 {
+		h.writeSchemaError(w, r, violations)
+		return false
+	}
+	if err := json.Unmarshal(body, req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_json", "invalid JSON body")
+		return false
+	}
+	return true
+}
+
+// writeBodyError answers a create body that couldn't be read or decoded.
+func (h *Handler) writeBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		h.writeError(w, http.StatusRequestEntityTooLarge, "body_too_large",
+			fmt.Sprintf("request body must not exceed %d bytes", tooLarge.Limit))
+		return
+	}
+	h.writeError(w, http.StatusBadRequest, "invalid_json", "invalid JSON body")
+}
+
+// writeSchemaError answers a body that failed its schema with 400
+// validation_error, listing each violation in Errors.
+func (h *Handler) writeSchemaError(w http.ResponseWriter, r *http.Request, violations []jsonschema.Violation) {
+	resp := ErrorResponse{Error: "validation_error"}
+	messages := make([]string, len(violations))
+	for i, v := range violations {
+		messages[i] = v.String()
+		resp.Errors = append(resp.Errors, FieldError{Field: v.Path, Message: messages[i]})
+	}
+	resp.Message = "request body does not match the schema: " + strings.Join(messages, "; ")
+	if len(violations) == 1 {
+		resp.Field = violations[0].Path
+	}
+
+	slog.InfoContext(r.Context(), "request failed schema validation",
+		"method", r.Method, "path", r.URL.Path, "violations", len(violations), "reason", resp.Message)
+	h.writeJSON(w, http.StatusBadRequest, resp)
+}
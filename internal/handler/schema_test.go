@@ -0,0 +1,124 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func postShorten(h *handler.Handler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.Create(rec, req)
+	return rec
+}
+
+func TestCreateHandler_SchemaValidation_WrongType(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithSchemaValidation())
+
+	rec := postShorten(h, `{"long_url": "https://example.com", "ttl_seconds": "3600"}`)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var resp handler.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "validation_error", resp.Error)
+	assert.Equal(t, "ttl_seconds", resp.Field)
+	assert.Equal(t, []handler.FieldError{
+		{Field: "ttl_seconds", Message: "ttl_seconds must be of type integer or null, got string"},
+	}, resp.Errors)
+	assert.Contains(t, resp.Message, "ttl_seconds must be of type integer or null, got string")
+	mockService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestCreateHandler_SchemaValidation_ListsEveryField(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithSchemaValidation())
+
+	rec := postShorten(h, `{"ttl_seconds": 1.5, "permanent": "yes", "tags": ["ok", 7], "notes": "x"}`)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var resp handler.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "validation_error", resp.Error)
+	assert.Empty(t, resp.Field)
+
+	fields := make([]string, len(resp.Errors))
+	for i, e := range resp.Errors {
+		fields[i] = e.Field
+	}
+	assert.Equal(t, []string{"long_url", "notes", "permanent", "tags[1]", "ttl_seconds"}, fields)
+	mockService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestCreateHandler_SchemaValidation_ValidBody(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithSchemaValidation())
+
+	mockService.On("Create", mock.Anything, domain.CreateParams{
+		LongURL: "https://example.com",
+		TTL:     time.Hour,
+		Tags:    []string{"docs"},
+	}).Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com"}, nil)
+
+	rec := postShorten(h, `{"long_url": "https://example.com", "ttl_seconds": 3600, "tags": ["docs"]}`)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateHandler_SchemaValidation_InvalidJSON(t *testing.T) {
+	h := handler.New(new(MockURLService), "http://localhost:8080", handler.WithSchemaValidation())
+
+	rec := postShorten(h, `{"long_url": `)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var resp handler.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "invalid_json", resp.Error)
+}
+
+func TestCreateHandler_BodyTooLarge_Returns413(t *testing.T) {
+	body := `{"long_url": "https://example.com", "notes": "` + strings.Repeat("x", 1<<20) + `"}`
+
+	for name, opts := range map[string][]handler.Option{
+		"schema validation": {handler.WithSchemaValidation()},
+		"plain decoding":    nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080", opts...)
+
+			rec := postShorten(h, body)
+
+			assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+			var resp handler.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "body_too_large", resp.Error)
+			mockService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCreateHandler_WithoutSchemaValidation_IgnoresUnknownFields(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	mockService.On("Create", mock.Anything, mock.Anything).
+		Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com"}, nil)
+
+	rec := postShorten(h, `{"long_url": "https://example.com", "notes": "x"}`)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
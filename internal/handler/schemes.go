@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultURLSchemes are the long URL schemes accepted without
+// WithURLSchemes.
+var DefaultURLSchemes = []string{"http", "https"}
+
+// unsafeURLSchemes run content in the browser instead of navigating, so
+// they are refused even when configured.
+var unsafeURLSchemes = map[string]bool{
+	"javascript": true,
+	"data":       true,
+	"vbscript":   true,
+}
+
+// ParseURLSchemes lowercases schemes and checks that each is a valid URL
+// scheme that may be shortened. An empty list means DefaultURLSchemes.
+func ParseURLSchemes(schemes []string) ([]string, error) {
+	if len(schemes) == 0 {
+		return DefaultURLSchemes, nil
+	}
+
+	parsed := make([]string, 0, len(schemes))
+	for _, scheme := range schemes {
+		scheme = strings.ToLower(strings.TrimSpace(scheme))
+		if !validScheme(scheme) {
+			return nil, fmt.Errorf("invalid URL scheme %q", scheme)
+		}
+		if unsafeURLSchemes[scheme] {
+			return nil, fmt.Errorf("URL scheme %q is not allowed", scheme)
+		}
+		parsed = append(parsed, scheme)
+	}
+	return parsed, nil
+}
+
+// validScheme reports whether s is a scheme as defined by RFC 3986: a
+// letter followed by letters, digits, "+", "-", or ".".
+func validScheme(s string) bool {
+	if s == "" || s[0] < 'a' || s[0] > 'z' {
+		return false
+	}
+	for _, c := range s[1:] {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// WithURLSchemes replaces DefaultURLSchemes as the schemes long URLs may
+// use, e.g. to also accept ftp or mailto. schemes should come from
+// ParseURLSchemes; unsafe schemes such as javascript are dropped anyway.
+func WithURLSchemes(schemes []string) Option {
+	return func(h *Handler) {
+		h.urlRules.schemes = safeSchemes(schemes)
+	}
+}
+
+// DestinationAllowed returns the check for service.WithDestinationCheck
+// matching a handler built with WithURLSchemes(schemes): http and https
+// are always allowed, as are the safe ones among schemes, or
+// DefaultURLSchemes if empty, so narrowing the schemes doesn't break links
+// created before.
+func DestinationAllowed(schemes []string) func(longURL string) bool {
+	rules := defaultURLRules()
+	if len(schemes) > 0 {
+		rules.schemes = safeSchemes(schemes)
+	}
+	return rules.allowsDestination
+}
+
+// safeSchemes lowercases schemes and drops the unsafe ones.
+func safeSchemes(schemes []string) []string {
+	var safe []string
+	for _, scheme := range schemes {
+		if scheme = strings.ToLower(scheme); !unsafeURLSchemes[scheme] {
+			safe = append(safe, scheme)
+		}
+	}
+	return safe
+}
+
+// WithMinURLLength rejects long URLs shorter than n characters.
+func WithMinURLLength(n int) Option {
+	return func(h *Handler) {
+		h.urlRules.minLength = n
+	}
+}
+
+// urlRules are the shape checks every long URL must pass.
+type urlRules struct {
+	schemes   []string
+	minLength int
+}
+
+func defaultURLRules() urlRules {
+	return urlRules{schemes: DefaultURLSchemes}
+}
+
+// validate checks rawURL against the rules before it is normalized.
+func (u urlRules) validate(rawURL string) error {
+	if rawURL == "" {
+		return invalidField("long_url", "long_url is required")
+	}
+
+	if len(rawURL) > maxURLLength {
+		return invalidField("long_url", "long_url exceeds maximum length of %d characters", maxURLLength)
+	}
+	if len(rawURL) < u.minLength {
+		return invalidField("long_url", "long_url must be at least %d characters", u.minLength)
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return invalidField("long_url", "invalid URL format")
+	}
+
+	if !u.allowsScheme(parsed.Scheme) {
+		return invalidField("long_url", "URL scheme must be %s", u.describeSchemes())
+	}
+
+	if needsHost(parsed) && parsed.Host == "" {
+		return invalidField("long_url", "URL must have a host")
+	}
+
+	return nil
+}
+
+// allowsDestination reports whether a stored long URL may be sent to a
+// client; see DestinationAllowed. It repeats the scheme and host checks of validate, except that
+// http and https are always allowed, so narrowing the schemes doesn't break
+// links created before.
+func (u urlRules) allowsDestination(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	web := parsed.Scheme == "http" || parsed.Scheme == "https"
+	return (web || u.allowsScheme(parsed.Scheme)) && !(needsHost(parsed) && parsed.Host == "")
+}
+
+func (u urlRules) allowsScheme(scheme string) bool {
+	if unsafeURLSchemes[scheme] {
+		return false
+	}
+	for _, allowed := range u.schemes {
+		if scheme == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// describeSchemes lists the schemes for error messages, e.g. "http or
+// https" or "one of ftp, http, https".
+func (u urlRules) describeSchemes() string {
+	switch len(u.schemes) {
+	case 1:
+		return u.schemes[0]
+	case 2:
+		return u.schemes[0] + " or " + u.schemes[1]
+	default:
+		return "one of " + strings.Join(u.schemes, ", ")
+	}
+}
+
+// needsHost reports whether parsed must name a host. Web URLs always do;
+// opaque URLs of other schemes, such as mailto:user@example.com, have none.
+func needsHost(parsed *url.URL) bool {
+	return parsed.Opaque == "" || parsed.Scheme == "http" || parsed.Scheme == "https"
+}
@@ -0,0 +1,130 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateHandler_URLSchemes(t *testing.T) {
+	testCases := []struct {
+		name        string
+		schemes     []string
+		longURL     string
+		wantMessage string
+	}{
+		{name: "default allows https", longURL: "https://example.com/page"},
+		{name: "default rejects ftp", longURL: "ftp://files.example.com/a.txt", wantMessage: "URL scheme must be http or https"},
+		{name: "ftp allowed", schemes: []string{"http", "https", "ftp"}, longURL: "ftp://files.example.com/a.txt"},
+		{name: "mailto allowed without host", schemes: []string{"https", "mailto"}, longURL: "mailto:support@example.com"},
+		{name: "http dropped from list", schemes: []string{"https"}, longURL: "http://example.com/page", wantMessage: "URL scheme must be https"},
+		{name: "disallowed scheme lists allowed ones", schemes: []string{"http", "https", "ftp"}, longURL: "gopher://example.com", wantMessage: "URL scheme must be one of http, https, ftp"},
+		{name: "javascript never allowed", schemes: []string{"https", "javascript"}, longURL: "javascript:alert(1)", wantMessage: "URL scheme must be https"},
+		{name: "file URL still needs a host", schemes: []string{"https", "file"}, longURL: "file:///etc/passwd", wantMessage: "URL must have a host"},
+		{name: "opaque http still needs a host", schemes: []string{"http", "mailto"}, longURL: "http:example.com", wantMessage: "URL must have a host"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			var opts []handler.Option
+			if tc.schemes != nil {
+				opts = append(opts, handler.WithURLSchemes(tc.schemes))
+			}
+			h := handler.New(mockService, "http://localhost:8080", opts...)
+			mockService.On("Create", mock.Anything, mock.Anything).Return(&domain.URLRecord{
+				ShortCode: "Ab2CdE3F",
+				LongURL:   tc.longURL,
+				ExpiresAt: time.Now().Add(time.Hour),
+			}, nil)
+
+			body, _ := json.Marshal(handler.CreateRequest{LongURL: tc.longURL})
+			req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			h.Create(rec, req)
+
+			if tc.wantMessage == "" {
+				assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+				return
+			}
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			var resp handler.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "validation_error", resp.Error)
+			assert.Equal(t, tc.wantMessage, resp.Message)
+			mockService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCreateHandler_MinURLLength(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithMinURLLength(20))
+	mockService.On("Create", mock.Anything, mock.Anything).Return(&domain.URLRecord{
+		ShortCode: "Ab2CdE3F",
+		LongURL:   "https://example.com/long-enough",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+
+	for longURL, want := range map[string]int{
+		"https://a.co":                    http.StatusBadRequest,
+		"https://example.com/long-enough": http.StatusCreated,
+	} {
+		body, _ := json.Marshal(handler.CreateRequest{LongURL: longURL})
+		req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		h.Create(rec, req)
+
+		assert.Equal(t, want, rec.Code, longURL)
+		if want == http.StatusBadRequest {
+			assert.Contains(t, rec.Body.String(), "long_url must be at least 20 characters")
+		}
+	}
+}
+
+func TestParseURLSchemes(t *testing.T) {
+	schemes, err := handler.ParseURLSchemes(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http", "https"}, schemes)
+
+	schemes, err = handler.ParseURLSchemes([]string{"HTTPS", " ftp ", "svn+ssh"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https", "ftp", "svn+ssh"}, schemes)
+
+	for _, bad := range []string{"javascript", "Data", "vbscript", "1http", "ht tp", ""} {
+		_, err := handler.ParseURLSchemes([]string{"https", bad})
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestDestinationAllowed(t *testing.T) {
+	allowed := handler.DestinationAllowed(nil)
+	for _, longURL := range []string{
+		"javascript:alert(document.cookie)",
+		"JavaScript:alert(1)",
+		"data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==",
+		"//evil.example.com",
+		"ftp://files.example.com/a.txt",
+	} {
+		assert.False(t, allowed(longURL), longURL)
+	}
+	assert.True(t, allowed("https://example.com/page"))
+
+	allowed = handler.DestinationAllowed([]string{"https", "ftp", "javascript"})
+	assert.True(t, allowed("ftp://files.example.com/a.txt"))
+	assert.True(t, allowed("http://example.com/page"), "http links created before the list was narrowed keep working")
+	assert.False(t, allowed("gopher://example.com/"))
+	assert.False(t, allowed("javascript:alert(1)"))
+}
@@ -3,35 +3,136 @@ package handler
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"url-shortener/internal/domain"
 )
 
-// Stats handles GET /stats/{code} requests.
+// Stats handles GET /stats/{code} requests. Expired links are reported as
+// not found unless include_expired=true is passed. Responses carry a weak
+// ETag; pollers sending it back in If-None-Match get 304 until it changes.
+// Appending .csv to the code returns the same stats as a CSV download.
 func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
-	code := r.PathValue("code")
+	code, asCSV := strings.CutSuffix(r.PathValue("code"), csvSuffix)
 	if code == "" {
 		h.writeError(w, http.StatusBadRequest, "validation_error", "short code is required")
 		return
 	}
 
-	record, err := h.service.GetStats(r.Context(), code)
+	includeExpired := false
+	if raw := r.URL.Query().Get("include_expired"); raw != "" {
+		var err error
+		includeExpired, err = strconv.ParseBool(raw)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "validation_error", "include_expired must be true or false")
+			return
+		}
+	}
+
+	getStats := h.service.GetStats
+	if includeExpired {
+		getStats = h.service.GetStatsIncludingExpired
+	}
+
+	record, err := getStats(r.Context(), code)
 	if err != nil {
 		if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrExpired) {
 			h.writeError(w, http.StatusNotFound, "not_found", "short code not found or expired")
 			return
 		}
-		h.writeError(w, http.StatusInternalServerError, "internal_error", "failed to get stats")
+		h.writeInternalError(w, err, "failed to get stats")
+		return
+	}
+
+	resp := h.toStatsResponse(r, record, h.clock.Now())
+	etag := statsETag(resp)
+	if asCSV {
+		etag = csvETag(etag)
+	}
+	w.Header().Set("ETag", etag)
+	if notModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if asCSV {
+		writeStatsCSV(w, resp)
 		return
 	}
+	h.writeJSON(w, http.StatusOK, resp)
+}
 
+// StatsBatch handles GET /stats?codes=a,b,c requests.
+// Passing consistent=true reads all codes as a single point-in-time snapshot.
+func (h *Handler) StatsBatch(w http.ResponseWriter, r *http.Request) {
+	codes, err := parseCodes(r.URL.Query().Get("codes"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	consistent := false
+	if raw := r.URL.Query().Get("consistent"); raw != "" {
+		consistent, err = strconv.ParseBool(raw)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "validation_error", "consistent must be true or false")
+			return
+		}
+	}
+
+	records, err := h.service.GetStatsBatch(r.Context(), codes, consistent)
+	if err != nil {
+		h.writeInternalError(w, err, "failed to get stats")
+		return
+	}
+
+	resp := BatchStatsResponse{
+		Stats:    make([]StatsResponse, 0, len(records)),
+		NotFound: []string{},
+	}
+
+	now := h.clock.Now()
+	found := make(map[string]bool, len(records))
+	for _, record := range records {
+		resp.Stats = append(resp.Stats, h.toStatsResponse(r, record, now))
+		found[record.ShortCode] = true
+	}
+	for _, code := range codes {
+		if !found[code] {
+			resp.NotFound = append(resp.NotFound, code)
+		}
+	}
+
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// toStatsResponse converts record, reporting it as expired if it had expired
+// by now. Its short URL uses the base URL r was addressed to.
+func (h *Handler) toStatsResponse(r *http.Request, record *domain.URLRecord, now time.Time) StatsResponse {
 	resp := StatsResponse{
 		ShortCode:  record.ShortCode,
+		ShortURL:   h.shortURL(r, record.ShortCode),
 		LongURL:    record.LongURL,
 		CreatedAt:  record.CreatedAt.Format(time.RFC3339),
-		ExpiresAt:  record.ExpiresAt.Format(time.RFC3339),
+		ExpiresAt:  formatExpiry(record),
 		ClickCount: record.ClickCount,
+		Enabled:    record.Enabled,
+		Expired:    record.IsExpired(now),
+		Tags:       record.Tags,
+	}
+
+	days := max(now.Sub(record.CreatedAt).Hours()/24, 1)
+	resp.ClicksPerDay = float64(record.ClickCount) / days
+
+	if !record.NeverExpires() {
+		seconds := max(int64(record.ExpiresAt.Sub(now)/time.Second), 0)
+		resp.ExpiresInSeconds = &seconds
+	}
+	if record.TTL > 0 {
+		ttl := int64(record.TTL / time.Second)
+		resp.TTLSeconds = &ttl
 	}
 
 	// Only set LastAccessedAt if it's not zero
@@ -40,5 +141,9 @@ func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
 		resp.LastAccessedAt = &formatted
 	}
 
-	h.writeJSON(w, http.StatusOK, resp)
+	if remaining, limited := record.RemainingClicks(); limited {
+		resp.RemainingClicks = &remaining
+	}
+
+	return resp
 }
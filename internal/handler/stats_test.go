@@ -1,14 +1,21 @@
 package handler_test
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"url-shortener/internal/domain"
 	"url-shortener/internal/handler"
+	"url-shortener/internal/middleware"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/service"
+	"url-shortener/internal/shortcode"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -48,7 +55,8 @@ func TestStatsHandler_ValidCode_Returns200(t *testing.T) {
 	assert.Equal(t, "Ab2CdE3F", resp.ShortCode)
 	assert.Equal(t, "https://example.com", resp.LongURL)
 	assert.Equal(t, "2024-01-15T12:00:00Z", resp.CreatedAt)
-	assert.Equal(t, "2024-01-16T12:00:00Z", resp.ExpiresAt)
+	require.NotNil(t, resp.ExpiresAt)
+	assert.Equal(t, "2024-01-16T12:00:00Z", *resp.ExpiresAt)
 	assert.Equal(t, int64(42), resp.ClickCount)
 	assert.NotNil(t, resp.LastAccessedAt)
 	assert.Equal(t, "2024-01-15T15:30:00Z", *resp.LastAccessedAt)
@@ -102,3 +110,442 @@ func TestStatsHandler_NotFound_Returns404(t *testing.T) {
 
 	assert.Equal(t, http.StatusNotFound, rec.Code)
 }
+
+func TestStatsHandler_IncludeExpired(t *testing.T) {
+	now := time.Date(2024, 1, 20, 12, 0, 0, 0, time.UTC)
+	expired := &domain.URLRecord{
+		ShortCode:  "Ab2CdE3F",
+		LongURL:    "https://example.com",
+		CreatedAt:  time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+		ExpiresAt:  time.Date(2024, 1, 16, 12, 0, 0, 0, time.UTC),
+		ClickCount: 7,
+	}
+
+	t.Run("expired links are not found by default", func(t *testing.T) {
+		mockService := new(MockURLService)
+		h := handler.New(mockService, "http://localhost:8080", handler.WithClock(domain.NewMockClock(now)))
+		mockService.On("GetStats", mock.Anything, "Ab2CdE3F").Return(nil, domain.ErrExpired)
+
+		req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F", nil)
+		req.SetPathValue("code", "Ab2CdE3F")
+		rec := httptest.NewRecorder()
+
+		h.Stats(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		mockService.AssertNotCalled(t, "GetStatsIncludingExpired", mock.Anything, mock.Anything)
+	})
+
+	t.Run("include_expired returns the record flagged as expired", func(t *testing.T) {
+		mockService := new(MockURLService)
+		h := handler.New(mockService, "http://localhost:8080", handler.WithClock(domain.NewMockClock(now)))
+		mockService.On("GetStatsIncludingExpired", mock.Anything, "Ab2CdE3F").Return(expired, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F?include_expired=true", nil)
+		req.SetPathValue("code", "Ab2CdE3F")
+		rec := httptest.NewRecorder()
+
+		h.Stats(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp handler.StatsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.True(t, resp.Expired)
+		assert.Equal(t, int64(7), resp.ClickCount)
+		mockService.AssertNotCalled(t, "GetStats", mock.Anything, mock.Anything)
+	})
+
+	t.Run("live links are not flagged", func(t *testing.T) {
+		mockService := new(MockURLService)
+		h := handler.New(mockService, "http://localhost:8080", handler.WithClock(domain.NewMockClock(now)))
+		live := *expired
+		live.ExpiresAt = now.Add(time.Hour)
+		mockService.On("GetStatsIncludingExpired", mock.Anything, "Ab2CdE3F").Return(&live, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F?include_expired=true", nil)
+		req.SetPathValue("code", "Ab2CdE3F")
+		rec := httptest.NewRecorder()
+
+		h.Stats(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"expired":false`)
+	})
+
+	t.Run("invalid flag", func(t *testing.T) {
+		mockService := new(MockURLService)
+		h := handler.New(mockService, "http://localhost:8080")
+
+		req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F?include_expired=maybe", nil)
+		req.SetPathValue("code", "Ab2CdE3F")
+		rec := httptest.NewRecorder()
+
+		h.Stats(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "include_expired must be true or false")
+	})
+}
+
+func TestStatsHandler_ETag(t *testing.T) {
+	now := time.Date(2024, 1, 15, 16, 0, 0, 0, time.UTC)
+	before := &domain.URLRecord{
+		ShortCode:      "Ab2CdE3F",
+		LongURL:        "https://example.com",
+		CreatedAt:      time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+		ExpiresAt:      time.Date(2024, 1, 16, 12, 0, 0, 0, time.UTC),
+		ClickCount:     42,
+		LastAccessedAt: time.Date(2024, 1, 15, 15, 30, 0, 0, time.UTC),
+		Enabled:        true,
+	}
+	after := *before
+	after.ClickCount++
+	after.LastAccessedAt = now
+
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithClock(domain.NewMockClock(now)))
+	mockService.On("GetStats", mock.Anything, "Ab2CdE3F").Return(before, nil).Twice()
+	mockService.On("GetStats", mock.Anything, "Ab2CdE3F").Return(&after, nil).Once()
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F", nil)
+		req.SetPathValue("code", "Ab2CdE3F")
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.Stats(rec, req)
+		return rec
+	}
+
+	first := get("")
+	assert.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.True(t, strings.HasPrefix(etag, `W/"`), "ETag should be weak: %s", etag)
+
+	unchanged := get(etag)
+	assert.Equal(t, http.StatusNotModified, unchanged.Code)
+	assert.Empty(t, unchanged.Body.String())
+	assert.Equal(t, etag, unchanged.Header().Get("ETag"))
+
+	// A click in between changes the tag, so the full body is sent again.
+	clicked := get(etag)
+	assert.Equal(t, http.StatusOK, clicked.Code)
+	assert.NotEqual(t, etag, clicked.Header().Get("ETag"))
+	assert.Contains(t, clicked.Body.String(), `"click_count":43`)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestStatsHandler_IfNoneMatchForms(t *testing.T) {
+	record := &domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com", Enabled: true}
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	mockService.On("GetStats", mock.Anything, "Ab2CdE3F").Return(record, nil)
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F", nil)
+		req.SetPathValue("code", "Ab2CdE3F")
+		req.Header.Set("If-None-Match", ifNoneMatch)
+		rec := httptest.NewRecorder()
+		h.Stats(rec, req)
+		return rec
+	}
+
+	etag := get(`"unrelated"`).Header().Get("ETag")
+	strong := strings.TrimPrefix(etag, "W/")
+
+	assert.Equal(t, http.StatusOK, get(`"unrelated"`).Code)
+	assert.Equal(t, http.StatusNotModified, get(`"other", `+etag).Code)
+	assert.Equal(t, http.StatusNotModified, get(strong).Code, "weak comparison ignores W/")
+	assert.Equal(t, http.StatusNotModified, get("*").Code)
+}
+
+func TestStatsHandler_ExpiresInSeconds(t *testing.T) {
+	expiresAt := time.Date(2024, 1, 16, 12, 0, 0, 0, time.UTC)
+	record := &domain.URLRecord{
+		ShortCode: "Ab2CdE3F",
+		LongURL:   "https://example.com",
+		CreatedAt: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+		ExpiresAt: expiresAt,
+	}
+
+	testCases := []struct {
+		name string
+		now  time.Time
+		want int64
+	}{
+		{name: "before expiry", now: expiresAt.Add(-90*time.Minute - 500*time.Millisecond), want: 5400},
+		{name: "at expiry", now: expiresAt, want: 0},
+		{name: "after expiry is clamped", now: expiresAt.Add(time.Hour), want: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080", handler.WithClock(domain.NewMockClock(tc.now)))
+			mockService.On("GetStatsIncludingExpired", mock.Anything, "Ab2CdE3F").Return(record, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F?include_expired=true", nil)
+			req.SetPathValue("code", "Ab2CdE3F")
+			rec := httptest.NewRecorder()
+
+			h.Stats(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			var resp handler.StatsResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.NotNil(t, resp.ExpiresInSeconds)
+			assert.Equal(t, tc.want, *resp.ExpiresInSeconds)
+		})
+	}
+}
+
+func TestStatsHandler_NeverExpiringLink(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	mockService.On("GetStats", mock.Anything, "Ab2CdE3F").Return(&domain.URLRecord{
+		ShortCode: "Ab2CdE3F",
+		LongURL:   "https://example.com",
+		CreatedAt: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+
+	h.Stats(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"expires_at":null`)
+	assert.Contains(t, rec.Body.String(), `"expires_in_seconds":null`)
+	assert.Contains(t, rec.Body.String(), `"ttl_seconds":null`)
+	assert.Contains(t, rec.Body.String(), `"expired":false`)
+}
+
+func TestStatsHandler_TTLSecondsRoundTrip(t *testing.T) {
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), domain.RealClock{})
+	h := handler.New(svc, "http://localhost:8080",
+		handler.WithTTLPolicy(domain.TTLPolicy{Default: 2 * time.Hour, Min: time.Minute, Max: 48 * time.Hour}))
+
+	statsFor := func(body string) handler.StatsResponse {
+		rec := httptest.NewRecorder()
+		h.Create(rec, httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(body)))
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var created handler.CreateResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+
+		req := httptest.NewRequest(http.MethodGet, "/stats/"+created.ShortCode, nil)
+		req.SetPathValue("code", created.ShortCode)
+		rec = httptest.NewRecorder()
+		h.Stats(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var stats handler.StatsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+		return stats
+	}
+
+	requested := statsFor(`{"long_url": "https://example.com/a", "ttl_seconds": 5400}`)
+	require.NotNil(t, requested.TTLSeconds)
+	assert.Equal(t, int64(5400), *requested.TTLSeconds)
+
+	defaulted := statsFor(`{"long_url": "https://example.com/b"}`)
+	require.NotNil(t, defaulted.TTLSeconds)
+	assert.Equal(t, int64(7200), *defaulted.TTLSeconds)
+
+	assert.Nil(t, statsFor(`{"long_url": "https://example.com/c", "no_expiry": true}`).TTLSeconds)
+}
+
+func TestStatsBatchHandler_ReturnsFoundAndNotFound(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("GetStatsBatch", mock.Anything, []string{"code0001", "missing1"}, true).
+		Return([]*domain.URLRecord{{
+			ShortCode:  "code0001",
+			LongURL:    "https://example.com",
+			CreatedAt:  time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+			ExpiresAt:  time.Date(2024, 1, 16, 12, 0, 0, 0, time.UTC),
+			ClickCount: 7,
+		}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats?codes=code0001,missing1,code0001&consistent=true", nil)
+	rec := httptest.NewRecorder()
+
+	h.StatsBatch(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp handler.BatchStatsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Stats, 1)
+	assert.Equal(t, "code0001", resp.Stats[0].ShortCode)
+	assert.Equal(t, int64(7), resp.Stats[0].ClickCount)
+	assert.Equal(t, []string{"missing1"}, resp.NotFound)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestStatsBatchHandler_InvalidParams_Returns400(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	for _, query := range []string{"", "?codes=", "?codes=a&consistent=maybe"} {
+		req := httptest.NewRequest(http.MethodGet, "/stats"+query, nil)
+		rec := httptest.NewRecorder()
+
+		h.StatsBatch(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "query %q", query)
+	}
+
+	mockService.AssertNotCalled(t, "GetStatsBatch")
+}
+
+func TestStatsHandler_RemainingClicks(t *testing.T) {
+	testCases := []struct {
+		name      string
+		maxClicks int64
+		want      string
+	}{
+		{name: "unlimited is null", maxClicks: 0, want: `"remaining_clicks":null`},
+		{name: "limited reports remaining", maxClicks: 10, want: `"remaining_clicks":6`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+
+			mockService.On("GetStats", mock.Anything, "Ab2CdE3F").
+				Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", ClickCount: 4, MaxClicks: tc.maxClicks}, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F", nil)
+			req.SetPathValue("code", "Ab2CdE3F")
+			rec := httptest.NewRecorder()
+
+			h.Stats(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.want)
+		})
+	}
+}
+
+// slowStatsService answers GetStats after delay unless ctx is done first,
+// like a stuck storage call that honors cancellation.
+type slowStatsService struct {
+	*MockURLService
+	delay time.Duration
+}
+
+func (s slowStatsService) GetStats(ctx context.Context, shortCode string) (*domain.URLRecord, error) {
+	select {
+	case <-time.After(s.delay):
+		return &domain.URLRecord{ShortCode: shortCode}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestStatsHandler_SlowServiceTimesOut_Returns503(t *testing.T) {
+	h := handler.New(slowStatsService{MockURLService: new(MockURLService), delay: 5 * time.Second}, "http://localhost:8080")
+	wrapped := middleware.Timeout(20 * time.Millisecond)(http.HandlerFunc(h.Stats))
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+
+	start := time.Now()
+	wrapped.ServeHTTP(rec, req)
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var resp handler.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "timeout", resp.Error)
+}
+
+func TestStatsHandler_FastServiceWithinTimeout_Returns200(t *testing.T) {
+	h := handler.New(slowStatsService{MockURLService: new(MockURLService), delay: time.Millisecond}, "http://localhost:8080")
+	wrapped := middleware.Timeout(time.Second)(http.HandlerFunc(h.Stats))
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+
+	wrapped.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestStatsHandler_ClicksPerDay(t *testing.T) {
+	createdAt := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name    string
+		elapsed time.Duration
+		clicks  int64
+		want    float64
+	}{
+		{name: "freshly created counts as one day", elapsed: 0, clicks: 0, want: 0},
+		{name: "under a day counts as one day", elapsed: time.Hour, clicks: 30, want: 30},
+		{name: "several days", elapsed: 4 * 24 * time.Hour, clicks: 30, want: 7.5},
+		{name: "partial days", elapsed: 60 * time.Hour, clicks: 10, want: 4},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := domain.NewMockClock(createdAt)
+			clock.Advance(tc.elapsed)
+			record := &domain.URLRecord{
+				ShortCode:  "Ab2CdE3F",
+				LongURL:    "https://example.com",
+				CreatedAt:  createdAt,
+				ExpiresAt:  createdAt.AddDate(0, 1, 0),
+				ClickCount: tc.clicks,
+			}
+
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080", handler.WithClock(clock))
+			mockService.On("GetStats", mock.Anything, "Ab2CdE3F").Return(record, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F", nil)
+			req.SetPathValue("code", "Ab2CdE3F")
+			rec := httptest.NewRecorder()
+
+			h.Stats(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			var resp handler.StatsResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.InDelta(t, tc.want, resp.ClicksPerDay, 1e-9)
+		})
+	}
+}
+
+func TestStatsHandler_ShortURL(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "https://sho.rt")
+
+	mockService.On("GetStats", mock.Anything, "Ab2CdE3F").Return(&domain.URLRecord{
+		ShortCode: "Ab2CdE3F",
+		LongURL:   "https://example.com",
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+
+	h.Stats(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp handler.StatsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "Ab2CdE3F", resp.ShortCode)
+	assert.Equal(t, "https://sho.rt/s/Ab2CdE3F", resp.ShortURL)
+}
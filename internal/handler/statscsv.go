@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// csvSuffix selects the CSV form of GET /stats/{code}; see validateAlias
+// for why it can't be part of a code.
+const csvSuffix = ".csv"
+
+// statsCSVHeader names the columns of a stats CSV export, matching the JSON
+// field names of StatsResponse. New columns go at the end, so exports stay
+// readable by position.
+var statsCSVHeader = []string{
+	"short_code", "short_url", "long_url", "created_at", "expires_at",
+	"click_count", "last_accessed_at", "enabled", "expired",
+	"expires_in_seconds", "remaining_clicks", "clicks_per_day", "tags",
+	"ttl_seconds",
+}
+
+// writeStatsCSV writes resp as a header row and one data row, offered as a
+// download named after the code. Null JSON fields are left empty, and tags
+// are joined with spaces.
+func writeStatsCSV(w http.ResponseWriter, resp StatsResponse) {
+	row := []string{
+		resp.ShortCode,
+		resp.ShortURL,
+		resp.LongURL,
+		resp.CreatedAt,
+		optionalString(resp.ExpiresAt),
+		strconv.FormatInt(resp.ClickCount, 10),
+		optionalString(resp.LastAccessedAt),
+		strconv.FormatBool(resp.Enabled),
+		strconv.FormatBool(resp.Expired),
+		optionalInt(resp.ExpiresInSeconds),
+		optionalInt(resp.RemainingClicks),
+		strconv.FormatFloat(resp.ClicksPerDay, 'f', -1, 64),
+		strings.Join(resp.Tags, " "),
+		optionalInt(resp.TTLSeconds),
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-stats.csv"`, resp.ShortCode))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	_ = cw.WriteAll([][]string{statsCSVHeader, row})
+}
+
+// csvETag derives the CSV representation's tag from the JSON one, so a tag
+// cached for one format never validates the other.
+func csvETag(etag string) string {
+	return strings.TrimSuffix(etag, `"`) + `-csv"`
+}
+
+func optionalString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func optionalInt(n *int64) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatInt(*n, 10)
+}
@@ -0,0 +1,107 @@
+package handler_test
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsHandler_CSVSuffix_ReturnsCSV(t *testing.T) {
+	now := time.Date(2024, 1, 16, 3, 30, 0, 0, time.UTC)
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithClock(domain.NewMockClock(now)))
+
+	mockService.On("GetStats", mock.Anything, "Ab2CdE3F").Return(&domain.URLRecord{
+		ShortCode:      "Ab2CdE3F",
+		LongURL:        "https://example.com/path?a=1,b=2",
+		CreatedAt:      time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+		ExpiresAt:      time.Date(2024, 1, 16, 12, 0, 0, 0, time.UTC),
+		TTL:            24 * time.Hour,
+		ClickCount:     42,
+		LastAccessedAt: time.Date(2024, 1, 15, 15, 30, 0, 0, time.UTC),
+		Enabled:        true,
+		Tags:           []string{"spring", "email"},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F.csv", nil)
+	req.SetPathValue("code", "Ab2CdE3F.csv")
+	rec := httptest.NewRecorder()
+
+	h.Stats(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="Ab2CdE3F-stats.csv"`, rec.Header().Get("Content-Disposition"))
+	assert.NotEmpty(t, rec.Header().Get("ETag"))
+
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, []string{
+		"short_code", "short_url", "long_url", "created_at", "expires_at",
+		"click_count", "last_accessed_at", "enabled", "expired",
+		"expires_in_seconds", "remaining_clicks", "clicks_per_day", "tags",
+		"ttl_seconds",
+	}, rows[0])
+	assert.Equal(t, []string{
+		"Ab2CdE3F", "http://localhost:8080/s/Ab2CdE3F", "https://example.com/path?a=1,b=2",
+		"2024-01-15T12:00:00Z", "2024-01-16T12:00:00Z",
+		"42", "2024-01-15T15:30:00Z", "true", "false",
+		"30600", "", "42", "spring email",
+		"86400",
+	}, rows[1])
+	mockService.AssertExpectations(t)
+}
+
+func TestStatsHandler_CSVETagDiffersFromJSON(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	mockService.On("GetStats", mock.Anything, "Ab2CdE3F").Return(&domain.URLRecord{
+		ShortCode: "Ab2CdE3F",
+		LongURL:   "https://example.com",
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+
+	get := func(code, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/stats/"+code, nil)
+		req.SetPathValue("code", code)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.Stats(rec, req)
+		return rec
+	}
+
+	jsonTag := get("Ab2CdE3F", "").Header().Get("ETag")
+	csvTag := get("Ab2CdE3F.csv", "").Header().Get("ETag")
+	assert.NotEqual(t, jsonTag, csvTag)
+
+	assert.Equal(t, http.StatusOK, get("Ab2CdE3F.csv", jsonTag).Code)
+	assert.Equal(t, http.StatusNotModified, get("Ab2CdE3F.csv", csvTag).Code)
+}
+
+func TestStatsHandler_CSVUnknownCode_Returns404(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	mockService.On("GetStats", mock.Anything, "Zz9YyX8W").Return(nil, domain.ErrNotFound)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/Zz9YyX8W.csv", nil)
+	req.SetPathValue("code", "Zz9YyX8W.csv")
+	rec := httptest.NewRecorder()
+
+	h.Stats(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+}
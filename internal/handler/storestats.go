@@ -0,0 +1,36 @@
+package handler
+
+import "net/http"
+
+// StoreStats handles GET /admin/stats?top=N requests, summarizing every
+// stored link and listing the N most clicked (10 by default).
+func (h *Handler) StoreStats(w http.ResponseWriter, r *http.Request) {
+	top, err := parseTopLinks(r.URL.Query().Get("top"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	stats, err := h.service.GetStoreStats(r.Context(), top)
+	if err != nil {
+		h.writeInternalError(w, err, "failed to get store stats")
+		return
+	}
+
+	resp := StoreStatsResponse{
+		TotalLinks:   stats.TotalLinks,
+		ActiveLinks:  stats.ActiveLinks,
+		ExpiredLinks: stats.ExpiredLinks,
+		TotalClicks:  stats.TotalClicks,
+		TopLinks:     make([]TopLinkResponse, 0, len(stats.TopLinks)),
+	}
+	for _, link := range stats.TopLinks {
+		resp.TopLinks = append(resp.TopLinks, TopLinkResponse{
+			ShortCode:  link.ShortCode,
+			LongURL:    link.LongURL,
+			ClickCount: link.Clicks,
+		})
+	}
+
+	h.writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,78 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreStatsHandler(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	mockService.On("GetStoreStats", mock.Anything, 2).Return(domain.StoreStats{
+		TotalLinks:   5,
+		ActiveLinks:  3,
+		ExpiredLinks: 2,
+		TotalClicks:  20,
+		TopLinks: []domain.LinkClicks{
+			{ShortCode: "Ab2CdE3F", LongURL: "https://example.com/a", Clicks: 9},
+			{ShortCode: "Gh4JkL5M", LongURL: "https://example.com/b", Clicks: 4},
+		},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/stats?top=2", nil)
+	rec := httptest.NewRecorder()
+
+	h.StoreStats(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp handler.StoreStatsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, handler.StoreStatsResponse{
+		TotalLinks:   5,
+		ActiveLinks:  3,
+		ExpiredLinks: 2,
+		TotalClicks:  20,
+		TopLinks: []handler.TopLinkResponse{
+			{ShortCode: "Ab2CdE3F", LongURL: "https://example.com/a", ClickCount: 9},
+			{ShortCode: "Gh4JkL5M", LongURL: "https://example.com/b", ClickCount: 4},
+		},
+	}, resp)
+}
+
+func TestStoreStatsHandler_DefaultsAndEmpty(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	mockService.On("GetStoreStats", mock.Anything, 10).Return(domain.StoreStats{}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/stats", nil)
+	rec := httptest.NewRecorder()
+
+	h.StoreStats(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"total_links":0,"active_links":0,"expired_links":0,"total_clicks":0,"top_links":[]}`, rec.Body.String())
+}
+
+func TestStoreStatsHandler_InvalidTop(t *testing.T) {
+	for _, top := range []string{"-1", "101", "many"} {
+		mockService := new(MockURLService)
+		h := handler.New(mockService, "http://localhost:8080")
+
+		req := httptest.NewRequest(http.MethodGet, "/admin/stats?top="+top, nil)
+		rec := httptest.NewRecorder()
+
+		h.StoreStats(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, top)
+		mockService.AssertNotCalled(t, "GetStoreStats", mock.Anything, mock.Anything)
+	}
+}
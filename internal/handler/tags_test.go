@@ -0,0 +1,146 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/service"
+	"url-shortener/internal/shortcode"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateHandler_NormalizesTags(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	mockService.On("Create", mock.Anything, mock.MatchedBy(func(p domain.CreateParams) bool {
+		return assert.ObjectsAreEqual([]string{"spring-sale", "email"}, p.Tags)
+	})).Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com", Tags: []string{"spring-sale", "email"}}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten",
+		bytes.NewBufferString(`{"long_url": "https://example.com", "tags": [" Spring-Sale", "email", "SPRING-SALE"]}`))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var resp handler.CreateResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"spring-sale", "email"}, resp.Tags)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateHandler_InvalidTags_Returns400(t *testing.T) {
+	tooMany := `"a","b","c","d","e","f","g","h","i","j","k"`
+	testCases := []struct {
+		name    string
+		tags    string
+		wantMsg string
+	}{
+		{name: "too many", tags: tooMany, wantMsg: "tags must list at most 10 tags"},
+		{name: "empty", tags: `""`, wantMsg: "tags must be between 1 and 32 characters"},
+		{name: "too long", tags: `"` + strings.Repeat("a", 33) + `"`, wantMsg: "tags must be between 1 and 32 characters"},
+		{name: "invalid character", tags: `"spring sale"`, wantMsg: `contains invalid character ' '`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+
+			req := httptest.NewRequest(http.MethodPost, "/shorten",
+				bytes.NewBufferString(`{"long_url": "https://example.com", "tags": [`+tc.tags+`]}`))
+			rec := httptest.NewRecorder()
+
+			h.Create(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var resp handler.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "tags", resp.Field)
+			assert.Contains(t, resp.Message, tc.wantMsg)
+			mockService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestListHandler_TagFilter(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	mockService.On("ListByTag", mock.Anything, "spring", 0, 20).Return([]*domain.URLRecord{
+		{ShortCode: "spring01", LongURL: "https://example.com/1", Tags: []string{"spring"}},
+	}, 1, nil)
+
+	rec := httptest.NewRecorder()
+	h.List(rec, httptest.NewRequest(http.MethodGet, "/urls?tag=Spring", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp handler.ListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Total)
+	require.Len(t, resp.URLs, 1)
+	assert.Equal(t, []string{"spring"}, resp.URLs[0].Tags)
+	mockService.AssertNotCalled(t, "List", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestListHandler_InvalidTag_Returns400(t *testing.T) {
+	for _, query := range []string{"tag=", "tag=spring%20sale"} {
+		mockService := new(MockURLService)
+		h := handler.New(mockService, "http://localhost:8080")
+
+		rec := httptest.NewRecorder()
+		h.List(rec, httptest.NewRequest(http.MethodGet, "/urls?"+query, nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestTags_RoundTripAndFilter(t *testing.T) {
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), domain.RealClock{})
+	h := handler.New(svc, "http://localhost:8080")
+
+	create := func(longURL, tags string) string {
+		req := httptest.NewRequest(http.MethodPost, "/shorten",
+			bytes.NewBufferString(`{"long_url": "`+longURL+`", "tags": [`+tags+`]}`))
+		rec := httptest.NewRecorder()
+		h.Create(rec, req)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var resp handler.CreateResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp.ShortCode
+	}
+	springEmail := create("https://example.com/a", `"spring", "email"`)
+	create("https://example.com/b", `"summer"`)
+	springSocial := create("https://example.com/c", `"Spring", "social"`)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/"+springEmail, nil)
+	req.SetPathValue("code", springEmail)
+	rec := httptest.NewRecorder()
+	h.Stats(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stats handler.StatsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.Equal(t, []string{"spring", "email"}, stats.Tags)
+
+	rec = httptest.NewRecorder()
+	h.List(rec, httptest.NewRequest(http.MethodGet, "/urls?tag=spring", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list handler.ListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	assert.Equal(t, 2, list.Total)
+	codes := make([]string, 0, len(list.URLs))
+	for _, u := range list.URLs {
+		codes = append(codes, u.ShortCode)
+		assert.Contains(t, u.Tags, "spring")
+	}
+	assert.ElementsMatch(t, []string{springEmail, springSocial}, codes)
+}
@@ -0,0 +1,101 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateHandler_MaxTTLDependsOnAuthTier(t *testing.T) {
+	const week = 7 * 24 * time.Hour
+	ninetyDays := int64((90 * 24 * time.Hour).Seconds())
+	weekSeconds := int64(week.Seconds())
+	twoYears := int64((2 * 365 * 24 * time.Hour).Seconds())
+
+	testCases := []struct {
+		name       string
+		tier       domain.AuthTier
+		body       handler.CreateRequest
+		wantStatus int
+		wantTTL    time.Duration
+		wantMsg    string
+	}{
+		{name: "anonymous within its cap", tier: domain.TierAnonymous, body: handler.CreateRequest{TTLSeconds: &weekSeconds}, wantStatus: http.StatusCreated, wantTTL: week},
+		{name: "anonymous over its cap", tier: domain.TierAnonymous, body: handler.CreateRequest{TTLSeconds: &ninetyDays}, wantStatus: http.StatusBadRequest, wantMsg: "ttl_seconds must not exceed 604800"},
+		{name: "untagged request is anonymous", body: handler.CreateRequest{TTLSeconds: &ninetyDays}, wantStatus: http.StatusBadRequest, wantMsg: "ttl_seconds must not exceed 604800"},
+		{name: "anonymous cannot skip expiry", tier: domain.TierAnonymous, body: handler.CreateRequest{NoExpiry: true}, wantStatus: http.StatusBadRequest, wantMsg: "no_expiry requires an API key"},
+		{name: "authenticated over anonymous cap", tier: domain.TierAuthenticated, body: handler.CreateRequest{TTLSeconds: &ninetyDays}, wantStatus: http.StatusCreated, wantTTL: 90 * 24 * time.Hour},
+		{name: "authenticated still bound by policy max", tier: domain.TierAuthenticated, body: handler.CreateRequest{TTLSeconds: &twoYears}, wantStatus: http.StatusBadRequest, wantMsg: "ttl_seconds must not exceed 31536000"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080", handler.WithAnonymousMaxTTL(week))
+			if tc.wantStatus == http.StatusCreated {
+				mockService.On("Create", mock.Anything, mock.MatchedBy(func(p domain.CreateParams) bool {
+					return p.TTL == tc.wantTTL
+				})).Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com/page", ExpiresAt: time.Now().Add(tc.wantTTL)}, nil)
+			}
+
+			tc.body.LongURL = "https://example.com/page"
+			body, _ := json.Marshal(tc.body)
+			ctx := context.Background()
+			if tc.tier != "" {
+				ctx = domain.WithAuthTier(ctx, tc.tier)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewReader(body)).WithContext(ctx)
+			rec := httptest.NewRecorder()
+
+			h.Create(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantMsg != "" {
+				assert.Contains(t, rec.Body.String(), tc.wantMsg)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateHandler_AnonymousDefaultTTLIsCapped(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithAnonymousMaxTTL(time.Hour))
+	mockService.On("Create", mock.Anything, domain.CreateParams{LongURL: "https://example.com/page", TTL: time.Hour}).
+		Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com/page", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(`{"long_url": "https://example.com/page"}`))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateHandler_NoAnonymousCapByDefault(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+	ninetyDays := int64((90 * 24 * time.Hour).Seconds())
+	mockService.On("Create", mock.Anything, mock.Anything).
+		Return(&domain.URLRecord{ShortCode: "Ab2CdE3F", LongURL: "https://example.com/page", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+	body, _ := json.Marshal(handler.CreateRequest{LongURL: "https://example.com/page", TTLSeconds: &ninetyDays})
+	req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
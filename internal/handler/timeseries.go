@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"url-shortener/internal/domain"
+)
+
+// Timeseries handles GET /stats/{code}/timeseries?from=&to= requests,
+// returning clicks per UTC day for charting.
+func (h *Handler) Timeseries(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if code == "" {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "short code is required")
+		return
+	}
+
+	query := r.URL.Query()
+	from, to, err := parseDateRange(query.Get("from"), query.Get("to"), h.clock.Now())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	days, err := h.service.GetClickSeries(r.Context(), code, from, to)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrExpired) {
+			h.writeError(w, http.StatusNotFound, "not_found", "short code not found or expired")
+			return
+		}
+		h.writeInternalError(w, err, "failed to get click timeseries")
+		return
+	}
+
+	resp := TimeseriesResponse{
+		ShortCode: code,
+		From:      from.Format(domain.DateLayout),
+		To:        to.Format(domain.DateLayout),
+		Days:      make([]DayClicksResponse, 0, len(days)),
+	}
+	for _, day := range days {
+		resp.Days = append(resp.Days, DayClicksResponse{Date: day.Date, Clicks: day.Clicks})
+	}
+
+	h.writeJSON(w, http.StatusOK, resp)
+}
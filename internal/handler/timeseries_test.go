@@ -0,0 +1,128 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeseriesHandler_ExplicitRange(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	from := time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)
+	mockService.On("GetClickSeries", mock.Anything, "Ab2CdE3F", from, to).Return([]domain.DayClicks{
+		{Date: "2024-01-14", Clicks: 0},
+		{Date: "2024-01-15", Clicks: 5},
+		{Date: "2024-01-16", Clicks: 2},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F/timeseries?from=2024-01-14&to=2024-01-16", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+
+	h.Timeseries(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp handler.TimeseriesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "2024-01-14", resp.From)
+	assert.Equal(t, "2024-01-16", resp.To)
+	assert.Equal(t, []handler.DayClicksResponse{
+		{Date: "2024-01-14", Clicks: 0},
+		{Date: "2024-01-15", Clicks: 5},
+		{Date: "2024-01-16", Clicks: 2},
+	}, resp.Days)
+	mockService.AssertExpectations(t)
+}
+
+func TestTimeseriesHandler_DefaultsToLast30DaysByClock(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 3, 1, 23, 59, 0, 0, time.UTC))
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080", handler.WithClock(clock))
+
+	today := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	mockService.On("GetClickSeries", mock.Anything, "Ab2CdE3F", today.AddDate(0, 0, -29), today).
+		Return([]domain.DayClicks{}, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F/timeseries", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+	h.Timeseries(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Crossing midnight moves the default window forward a day
+	clock.Advance(2 * time.Minute)
+	tomorrow := today.AddDate(0, 0, 1)
+	mockService.On("GetClickSeries", mock.Anything, "Ab2CdE3F", tomorrow.AddDate(0, 0, -29), tomorrow).
+		Return([]domain.DayClicks{}, nil).Once()
+
+	rec = httptest.NewRecorder()
+	h.Timeseries(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestTimeseriesHandler_InvalidRange_Returns400(t *testing.T) {
+	h := handler.New(new(MockURLService), "http://localhost:8080")
+
+	for _, query := range []string{
+		"from=2024-13-01&to=2024-01-16",
+		"from=2024-01-14&to=yesterday",
+		"from=2024-01-16&to=2024-01-14",
+		"from=2023-01-01&to=2024-01-02",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F/timeseries?"+query, nil)
+		req.SetPathValue("code", "Ab2CdE3F")
+		rec := httptest.NewRecorder()
+
+		h.Timeseries(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestTimeseriesHandler_MaxSpanAccepted(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("GetClickSeries", mock.Anything, "Ab2CdE3F", mock.Anything, mock.Anything).
+		Return([]domain.DayClicks{}, nil)
+
+	// 2024 is a leap year: 366 days inclusive
+	req := httptest.NewRequest(http.MethodGet, "/stats/Ab2CdE3F/timeseries?from=2024-01-01&to=2024-12-31", nil)
+	req.SetPathValue("code", "Ab2CdE3F")
+	rec := httptest.NewRecorder()
+
+	h.Timeseries(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestTimeseriesHandler_NotFound_Returns404(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("GetClickSeries", mock.Anything, "notfound", mock.Anything, mock.Anything).
+		Return(nil, domain.ErrNotFound)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/notfound/timeseries", nil)
+	req.SetPathValue("code", "notfound")
+	rec := httptest.NewRecorder()
+
+	h.Timeseries(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// UpdateTTL handles PATCH /s/{code} requests. The new expiry is now plus
+// ttl_seconds, not the creation time plus ttl_seconds.
+func (h *Handler) UpdateTTL(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if code == "" {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "short code is required")
+		return
+	}
+
+	var req UpdateTTLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_json", "invalid JSON body")
+		return
+	}
+
+	if req.TTLSeconds == nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "ttl_seconds is required")
+		return
+	}
+	ttl := time.Duration(*req.TTLSeconds) * time.Second
+	limits := h.limits.Load()
+	if err := limits.validateTTL(ttl, limits.ttl.Max); err != nil {
+		h.writeValidationError(w, r, err)
+		return
+	}
+
+	record, err := h.service.UpdateTTL(r.Context(), code, ttl)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrExpired) {
+			h.writeError(w, http.StatusNotFound, "not_found", "short code not found or expired")
+			return
+		}
+		if errors.Is(err, domain.ErrExpiryTooLate) {
+			h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+			return
+		}
+		h.writeInternalError(w, err, "failed to update TTL")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.toStatsResponse(r, record, h.clock.Now()))
+}
+
+// UpdateStatus handles PATCH /s/{code}/status requests, enabling or
+// disabling a link without deleting it.
+func (h *Handler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if code == "" {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "short code is required")
+		return
+	}
+
+	var req UpdateStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_json", "invalid JSON body")
+		return
+	}
+
+	if req.Enabled == nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", "enabled is required")
+		return
+	}
+
+	record, err := h.service.SetEnabled(r.Context(), code, *req.Enabled)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrExpired) {
+			h.writeError(w, http.StatusNotFound, "not_found", "short code not found or expired")
+			return
+		}
+		h.writeInternalError(w, err, "failed to update status")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.toStatsResponse(r, record, h.clock.Now()))
+}
@@ -0,0 +1,141 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newPatchRequest(code, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPatch, "/s/"+code, bytes.NewBufferString(body))
+	req.SetPathValue("code", code)
+	return req
+}
+
+func TestUpdateTTLHandler_Success_ReturnsStats(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	mockService.On("UpdateTTL", mock.Anything, "abc12345", 48*time.Hour).Return(&domain.URLRecord{
+		ShortCode: "abc12345",
+		LongURL:   "https://example.com",
+		CreatedAt: now,
+		ExpiresAt: now.Add(48 * time.Hour),
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	h.UpdateTTL(rec, newPatchRequest("abc12345", `{"ttl_seconds": 172800}`))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp handler.StatsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "abc12345", resp.ShortCode)
+	require.NotNil(t, resp.ExpiresAt)
+	assert.Equal(t, "2024-01-17T12:00:00Z", *resp.ExpiresAt)
+}
+
+func TestUpdateTTLHandler_NotFoundOrExpired_Returns404(t *testing.T) {
+	for _, serviceErr := range []error{domain.ErrNotFound, domain.ErrExpired} {
+		t.Run(serviceErr.Error(), func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+
+			mockService.On("UpdateTTL", mock.Anything, "abc12345", time.Hour).Return(nil, serviceErr)
+
+			rec := httptest.NewRecorder()
+			h.UpdateTTL(rec, newPatchRequest("abc12345", `{"ttl_seconds": 3600}`))
+
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		})
+	}
+}
+
+func TestUpdateTTLHandler_InvalidTTL_Returns400(t *testing.T) {
+	testCases := []struct {
+		name      string
+		body      string
+		wantError string
+	}{
+		{name: "missing ttl", body: `{}`, wantError: "validation_error"},
+		{name: "below minimum", body: `{"ttl_seconds": 30}`, wantError: "validation_error"},
+		{name: "above maximum", body: `{"ttl_seconds": 31536001}`, wantError: "validation_error"},
+		{name: "invalid json", body: `{`, wantError: "invalid_json"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockURLService)
+			h := handler.New(mockService, "http://localhost:8080")
+
+			rec := httptest.NewRecorder()
+			h.UpdateTTL(rec, newPatchRequest("abc12345", tc.body))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+			var resp handler.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tc.wantError, resp.Error)
+			mockService.AssertNotCalled(t, "UpdateTTL")
+		})
+	}
+}
+
+func TestUpdateStatusHandler_Disable_ReturnsStats(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	mockService.On("SetEnabled", mock.Anything, "abc12345", false).Return(&domain.URLRecord{
+		ShortCode: "abc12345",
+		LongURL:   "https://example.com",
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+		Enabled:   false,
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	h.UpdateStatus(rec, newPatchRequest("abc12345", `{"enabled": false}`))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp handler.StatsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "abc12345", resp.ShortCode)
+	assert.False(t, resp.Enabled)
+	mockService.AssertExpectations(t)
+}
+
+func TestUpdateStatusHandler_MissingEnabled_Returns400(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	rec := httptest.NewRecorder()
+	h.UpdateStatus(rec, newPatchRequest("abc12345", `{}`))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockService.AssertNotCalled(t, "SetEnabled", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateStatusHandler_NotFound_Returns404(t *testing.T) {
+	mockService := new(MockURLService)
+	h := handler.New(mockService, "http://localhost:8080")
+
+	mockService.On("SetEnabled", mock.Anything, "abc12345", true).Return(nil, domain.ErrNotFound)
+
+	rec := httptest.NewRecorder()
+	h.UpdateStatus(rec, newPatchRequest("abc12345", `{"enabled": true}`))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -1,48 +1,402 @@
 package handler
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net"
 	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+
+	"url-shortener/internal/domain"
 )
 
 const (
 	maxURLLength = 2048
-	minTTL       = 60 * time.Second     // 1 minute
-	maxTTL       = 365 * 24 * time.Hour // 1 year
+
+	// maxCreateBodyBytes bounds POST /shorten bodies, leaving ample room
+	// for the longest valid request.
+	maxCreateBodyBytes = 64 << 10
+
+	minAliasLength = 3
+	maxAliasLength = 32
+
+	maxTags      = 10
+	maxTagLength = 32
+
+	maxBatchCodes  = 100
+	maxBatchCreate = 100
+
+	// maxBatchCreateBodyBytes bounds POST /shorten/batch bodies, so the
+	// item limit can't be dodged by sending more before it is checked.
+	maxBatchCreateBodyBytes = maxBatchCreate * maxCreateBodyBytes
+
+	defaultReferrerLimit = 10
+	maxReferrerLimit     = 100
+
+	defaultTimeseriesDays = 30
+	maxTimeseriesDays     = 366
+
+	defaultListLimit = 20
+	maxListLimit     = 100
+
+	defaultTopLinks = 10
+	maxTopLinks     = 100
+
+	defaultQRSize = 256
+	minQRSize     = 64
+	maxQRSize     = 1024
 )
 
-func validateURL(rawURL string) error {
-	if rawURL == "" {
-		return errors.New("long_url is required")
+// ValidationError reports a request field that failed validation. Reason
+// is the message shown to clients.
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Reason
+}
+
+// invalidField returns a ValidationError for field with a formatted reason.
+func invalidField(field, format string, args ...any) error {
+	return &ValidationError{Field: field, Reason: fmt.Sprintf(format, args...)}
+}
+
+// normalizeURL rewrites rawURL, which must have passed urlRules.validate, so that
+// equivalent URLs are stored alike: the host is lowercased, default ports
+// and a bare "/" path are dropped, and repeated slashes in the path are
+// collapsed. Path case, the fragment, and the query are otherwise kept as
+// given since servers may treat them as significant; sortQuery additionally
+// sorts query parameters by key.
+func normalizeURL(rawURL string, sortQuery bool) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	u.Host = strings.TrimSuffix(u.Host, ":")
+
+	path := u.EscapedPath()
+	for strings.Contains(path, "//") {
+		path = strings.ReplaceAll(path, "//", "/")
+	}
+	if path == "/" {
+		path = ""
+	}
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		u.Path, u.RawPath = unescaped, path
 	}
 
-	if len(rawURL) > maxURLLength {
-		return errors.New("long_url exceeds maximum length of 2048 characters")
+	if sortQuery && u.RawQuery != "" {
+		if query, err := url.ParseQuery(u.RawQuery); err == nil {
+			u.RawQuery = query.Encode()
+		}
 	}
 
+	return u.String()
+}
+
+// errBlockedHost is returned for destinations on the host blocklist.
+var errBlockedHost = invalidField("long_url", "destination host not allowed")
+
+// hostSet matches hosts against a list of domains, including their
+// subdomains: "evil.com" matches "evil.com" and "a.evil.com" but not
+// "notevil.com".
+type hostSet map[string]struct{}
+
+func newHostSet(hosts []string) hostSet {
+	set := make(hostSet, len(hosts))
+	for _, host := range hosts {
+		if host = normalizeHost(host); host != "" {
+			set[host] = struct{}{}
+		}
+	}
+	return set
+}
+
+// matches reports whether host or any of its parent domains is in the set.
+func (s hostSet) matches(host string) bool {
+	host = normalizeHost(host)
+	for host != "" {
+		if _, ok := s[host]; ok {
+			return true
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			return false
+		}
+		host = parent
+	}
+	return false
+}
+
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+}
+
+// validateHostNotBlocked rejects URLs whose host is on the blocklist.
+func validateHostNotBlocked(rawURL string, blocked hostSet) error {
 	parsed, err := url.Parse(rawURL)
 	if err != nil {
-		return errors.New("invalid URL format")
+		return invalidField("long_url", "invalid URL format")
+	}
+	if blocked.matches(parsed.Hostname()) {
+		return errBlockedHost
 	}
+	return nil
+}
+
+// HostResolver looks up the IP addresses of a host. *net.Resolver
+// satisfies it.
+type HostResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// errPrivateHost is returned for destinations on non-public networks.
+var errPrivateHost = invalidField("long_url", "long_url must not point to a private, loopback, or link-local address")
 
-	if parsed.Scheme != "http" && parsed.Scheme != "https" {
-		return errors.New("URL scheme must be http or https")
+// validatePublicHost rejects URLs whose host is localhost or is, or resolves
+// to, a loopback, link-local, private, or unspecified address. Unresolvable
+// hosts are rejected too, so the check fails closed. It does not protect
+// against DNS records that change after validation; verifyDestination dials
+// the addresses publicAddrs returns for that.
+func validatePublicHost(ctx context.Context, rawURL string, resolver HostResolver) error {
+	_, err := publicAddrs(ctx, rawURL, resolver)
+	return err
+}
+
+// publicAddrs is validatePublicHost that also returns the addresses the host
+// was checked at: the host itself for IP literals, none for opaque URLs.
+func publicAddrs(ctx context.Context, rawURL string, resolver HostResolver) ([]net.IP, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, invalidField("long_url", "invalid URL format")
 	}
 
-	if parsed.Host == "" {
-		return errors.New("URL must have a host")
+	// Opaque URLs such as mailto: name no host to reach
+	if !needsHost(parsed) {
+		return nil, nil
 	}
 
-	return nil
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return nil, errPrivateHost
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if isNonPublicIP(ip) {
+			return nil, errPrivateHost
+		}
+		return []net.IP{ip}, nil
+	}
+
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return nil, invalidField("long_url", "long_url host %q could not be resolved", host)
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		if isNonPublicIP(addr.IP) {
+			return nil, errPrivateHost
+		}
+		ips[i] = addr.IP
+	}
+
+	return ips, nil
 }
 
-func validateTTL(ttl time.Duration) error {
-	if ttl < minTTL {
-		return errors.New("ttl_seconds must be at least 60")
+func isNonPublicIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified()
+}
+
+// validateTTL checks ttl against the minimum TTL and maxTTL, the bound that
+// applies to the caller; see Handler.maxTTL.
+func (l *activeLimits) validateTTL(ttl, maxTTL time.Duration) error {
+	if ttl < l.ttl.Min {
+		return invalidField("ttl_seconds", "ttl_seconds must be at least %d", int64(l.ttl.Min.Seconds()))
 	}
 	if ttl > maxTTL {
-		return errors.New("ttl_seconds must not exceed 31536000 (1 year)")
+		return invalidField("ttl_seconds", "ttl_seconds must not exceed %d", int64(maxTTL.Seconds()))
+	}
+	return nil
+}
+
+// validateAlias checks that a custom alias uses only characters from
+// alphabet plus hyphen. Code alphabets hold only ASCII letters and digits,
+// and code prefixes add only '-' and '_', so no stored code contains '.':
+// extensions such as csvSuffix and codeSuffixes can be cut from a path
+// without turning one code into another.
+func validateAlias(alias, alphabet string) error {
+	if len(alias) < minAliasLength || len(alias) > maxAliasLength {
+		return invalidField("custom_alias", "custom_alias must be between %d and %d characters", minAliasLength, maxAliasLength)
+	}
+
+	for _, c := range alias {
+		if c != '-' && !strings.ContainsRune(alphabet, c) {
+			return invalidField("custom_alias", "custom_alias contains invalid character %q", c)
+		}
 	}
+
 	return nil
 }
+
+// normalizeTags lowercases tags and drops repeats, keeping their order. Each
+// must pass normalizeTag, and at most maxTags may be given.
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > maxTags {
+		return nil, invalidField("tags", "tags must list at most %d tags", maxTags)
+	}
+
+	var normalized []string
+	for _, tag := range tags {
+		tag, err := normalizeTag("tags", tag)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+// normalizeTag lowercases tag and checks that it is 1 to maxTagLength
+// letters, digits, hyphens, or underscores. Errors name field.
+func normalizeTag(field, tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || len(tag) > maxTagLength {
+		return "", invalidField(field, "tags must be between 1 and %d characters", maxTagLength)
+	}
+	for _, c := range tag {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return "", invalidField(field, "tag %q contains invalid character %q", tag, c)
+		}
+	}
+	return tag, nil
+}
+
+// parseCodes splits a comma-separated list of short codes, dropping blanks
+// and duplicates while preserving order.
+func parseCodes(raw string) ([]string, error) {
+	seen := make(map[string]bool)
+	var codes []string
+	for _, code := range strings.Split(raw, ",") {
+		code = strings.TrimSpace(code)
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+
+	if len(codes) == 0 {
+		return nil, errors.New("codes is required")
+	}
+	if len(codes) > maxBatchCodes {
+		return nil, fmt.Errorf("codes must not contain more than %d entries", maxBatchCodes)
+	}
+
+	return codes, nil
+}
+
+// parsePagination reads limit and offset query values, applying the
+// default limit when it is absent.
+func parsePagination(rawLimit, rawOffset string) (limit, offset int, err error) {
+	limit = defaultListLimit
+	if rawLimit != "" {
+		limit, err = strconv.Atoi(rawLimit)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			return 0, 0, fmt.Errorf("limit must be an integer between 1 and %d", maxListLimit)
+		}
+	}
+
+	if rawOffset != "" {
+		offset, err = strconv.Atoi(rawOffset)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+	}
+
+	return limit, offset, nil
+}
+
+// parseReferrerLimit reads how many top referrers to return.
+func parseReferrerLimit(raw string) (int, error) {
+	if raw == "" {
+		return defaultReferrerLimit, nil
+	}
+
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > maxReferrerLimit {
+		return 0, fmt.Errorf("limit must be an integer between 1 and %d", maxReferrerLimit)
+	}
+	return limit, nil
+}
+
+// parseTopLinks reads how many top links GET /admin/stats should list,
+// defaulting to defaultTopLinks.
+func parseTopLinks(raw string) (int, error) {
+	if raw == "" {
+		return defaultTopLinks, nil
+	}
+
+	top, err := strconv.Atoi(raw)
+	if err != nil || top < 0 || top > maxTopLinks {
+		return 0, fmt.Errorf("top must be an integer between 0 and %d", maxTopLinks)
+	}
+	return top, nil
+}
+
+// parseDateRange reads an inclusive from/to range of YYYY-MM-DD UTC dates.
+// to defaults to today and from to the 30 days ending at to. The range may
+// span at most maxTimeseriesDays days.
+func parseDateRange(rawFrom, rawTo string, today time.Time) (from, to time.Time, err error) {
+	to = today.UTC().Truncate(24 * time.Hour)
+	if rawTo != "" {
+		if to, err = time.Parse(domain.DateLayout, rawTo); err != nil {
+			return time.Time{}, time.Time{}, errors.New("to must be a date in YYYY-MM-DD format")
+		}
+	}
+
+	from = to.AddDate(0, 0, -(defaultTimeseriesDays - 1))
+	if rawFrom != "" {
+		if from, err = time.Parse(domain.DateLayout, rawFrom); err != nil {
+			return time.Time{}, time.Time{}, errors.New("from must be a date in YYYY-MM-DD format")
+		}
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("from must not be after to")
+	}
+	if to.Sub(from) >= maxTimeseriesDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("date range must not span more than %d days", maxTimeseriesDays)
+	}
+
+	return from, to, nil
+}
+
+// parseQRSize reads the optional QR image size in pixels.
+func parseQRSize(raw string) (int, error) {
+	if raw == "" {
+		return defaultQRSize, nil
+	}
+
+	size, err := strconv.Atoi(raw)
+	if err != nil || size < minQRSize || size > maxQRSize {
+		return 0, fmt.Errorf("size must be an integer between %d and %d", minQRSize, maxQRSize)
+	}
+	return size, nil
+}
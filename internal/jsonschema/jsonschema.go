@@ -0,0 +1,258 @@
+// Package jsonschema checks JSON documents against the subset of JSON Schema
+// the API's request schemas use: type, properties, required,
+// additionalProperties, items, minItems, maxItems, minLength, maxLength,
+// pattern, minimum, and maximum. Other keywords, such as $schema and
+// description, are ignored.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a compiled schema. It is safe for concurrent use.
+type Schema struct {
+	Type                 typeList           `json:"type"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+
+	pattern *regexp.Regexp
+}
+
+// Violation is one way a document fails its schema.
+type Violation struct {
+	// Path locates the failing value, e.g. "ttl_seconds" or "tags[2]". It
+	// is empty for the document itself.
+	Path string
+
+	// Message says what is wrong, e.g. "must be of type integer, got
+	// string".
+	Message string
+}
+
+// String prefixes the message with the path, or with "body" for the
+// document itself.
+func (v Violation) String() string {
+	if v.Path == "" {
+		return "body " + v.Message
+	}
+	return v.Path + " " + v.Message
+}
+
+// typeNames are the values accepted by the type keyword.
+var typeNames = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// typeList is the type keyword, which may be a single name or a list.
+type typeList []string
+
+func (t *typeList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = typeList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("type must be a string or an array of strings")
+	}
+	*t = many
+	return nil
+}
+
+// Compile parses a schema document.
+func Compile(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	if err := s.compile(""); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// compile checks the keywords of s and its subschemas and compiles their
+// patterns.
+func (s *Schema) compile(path string) error {
+	for _, name := range s.Type {
+		if !slices.Contains(typeNames, name) {
+			return fmt.Errorf("schema %s: unknown type %q", schemaPath(path), name)
+		}
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("schema %s: pattern: %w", schemaPath(path), err)
+		}
+		s.pattern = re
+	}
+	for name, prop := range s.Properties {
+		if prop == nil {
+			return fmt.Errorf("schema %s: property %q has no schema", schemaPath(path), name)
+		}
+		if err := prop.compile(joinPath(path, name)); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile(path + "[]")
+	}
+	return nil
+}
+
+// Validate parses data and checks it against s, returning every violation
+// found. The error is non-nil only when data is not valid JSON.
+func (s *Schema) Validate(data []byte) ([]Violation, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("invalid character after top-level value")
+	}
+
+	var violations []Violation
+	s.validate(doc, "", &violations)
+	return violations, nil
+}
+
+// validate appends the ways v, found at path, fails s to violations.
+func (s *Schema) validate(v any, path string, violations *[]Violation) {
+	fail := func(path, format string, args ...any) {
+		*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(name string) bool { return hasType(v, name) }) {
+		fail(path, "must be of type %s, got %s", strings.Join(s.Type, " or "), typeOf(v))
+		return
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				fail(joinPath(path, name), "is required")
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			prop, ok := s.Properties[name]
+			switch {
+			case ok:
+				prop.validate(v[name], joinPath(path, name), violations)
+			case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				fail(joinPath(path, name), "is not allowed")
+			}
+		}
+
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail(path, "must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail(path, "must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail(path, "must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail(path, "must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail(path, "must match %s", s.Pattern)
+		}
+
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			fail(path, "is out of range")
+			return
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			fail(path, "must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail(path, "must be at most %v", *s.Maximum)
+		}
+	}
+}
+
+// hasType reports whether v, as decoded with UseNumber, is of the named
+// JSON type. Integers are numbers with no fractional part, so 1.0 is one.
+func hasType(v any, name string) bool {
+	if name == "integer" {
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	}
+	return typeOf(v) == name
+}
+
+// typeOf names the JSON type of v, reporting all numbers as "number".
+func typeOf(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+// joinPath appends a property name to a path.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// schemaPath names the schema at path in compile errors.
+func schemaPath(path string) string {
+	if path == "" {
+		return "root"
+	}
+	return path
+}
@@ -0,0 +1,120 @@
+package jsonschema_test
+
+import (
+	"testing"
+
+	"url-shortener/internal/jsonschema"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["name"],
+	"additionalProperties": false,
+	"properties": {
+		"name": {"type": "string", "minLength": 2, "maxLength": 5, "pattern": "^[a-z]+$"},
+		"count": {"type": "integer", "minimum": 1, "maximum": 10},
+		"ratio": {"type": ["number", "null"]},
+		"enabled": {"type": "boolean"},
+		"labels": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+		"nested": {"type": "object", "properties": {"id": {"type": "integer"}}}
+	}
+}`
+
+func compileTestSchema(t *testing.T) *jsonschema.Schema {
+	t.Helper()
+	s, err := jsonschema.Compile([]byte(testSchema))
+	require.NoError(t, err)
+	return s
+}
+
+func TestValidate_Valid(t *testing.T) {
+	s := compileTestSchema(t)
+
+	for _, body := range []string{
+		`{"name": "ab"}`,
+		`{"name": "abc", "count": 10, "ratio": 0.5, "enabled": true, "labels": ["x", "y"], "nested": {"id": 3}}`,
+		`{"name": "abc", "count": 2.0, "ratio": null}`,
+	} {
+		violations, err := s.Validate([]byte(body))
+		require.NoError(t, err, body)
+		assert.Empty(t, violations, body)
+	}
+}
+
+func TestValidate_ReportsEveryViolation(t *testing.T) {
+	s := compileTestSchema(t)
+
+	violations, err := s.Validate([]byte(`{
+		"count": "3",
+		"ratio": "high",
+		"enabled": 1,
+		"labels": ["x", 2, "z"],
+		"nested": {"id": 1.5},
+		"extra": true
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []jsonschema.Violation{
+		{Path: "name", Message: "is required"},
+		{Path: "count", Message: "must be of type integer, got string"},
+		{Path: "enabled", Message: "must be of type boolean, got number"},
+		{Path: "extra", Message: "is not allowed"},
+		{Path: "labels", Message: "must have at most 2 items"},
+		{Path: "labels[1]", Message: "must be of type string, got number"},
+		{Path: "nested.id", Message: "must be of type integer, got number"},
+		{Path: "ratio", Message: "must be of type number or null, got string"},
+	}, violations)
+}
+
+func TestValidate_Bounds(t *testing.T) {
+	s := compileTestSchema(t)
+
+	tests := []struct {
+		body string
+		want jsonschema.Violation
+	}{
+		{`{"name": "a"}`, jsonschema.Violation{Path: "name", Message: "must be at least 2 characters"}},
+		{`{"name": "abcdef"}`, jsonschema.Violation{Path: "name", Message: "must be at most 5 characters"}},
+		{`{"name": "AB"}`, jsonschema.Violation{Path: "name", Message: "must match ^[a-z]+$"}},
+		{`{"name": "ab", "count": 0}`, jsonschema.Violation{Path: "count", Message: "must be at least 1"}},
+		{`{"name": "ab", "count": 11}`, jsonschema.Violation{Path: "count", Message: "must be at most 10"}},
+	}
+	for _, tt := range tests {
+		violations, err := s.Validate([]byte(tt.body))
+		require.NoError(t, err, tt.body)
+		assert.Equal(t, []jsonschema.Violation{tt.want}, violations, tt.body)
+	}
+}
+
+func TestValidate_WrongRootType(t *testing.T) {
+	s := compileTestSchema(t)
+
+	violations, err := s.Validate([]byte(`["name"]`))
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, "body must be of type object, got array", violations[0].String())
+}
+
+func TestValidate_InvalidJSON(t *testing.T) {
+	s := compileTestSchema(t)
+
+	for _, body := range []string{`{"name": `, `{"name": "ab"} {}`, ``} {
+		_, err := s.Validate([]byte(body))
+		assert.Error(t, err, body)
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for _, schema := range []string{
+		`{"type": "text"}`,
+		`{"type": 3}`,
+		`{"properties": {"name": {"pattern": "["}}}`,
+		`not json`,
+	} {
+		_, err := jsonschema.Compile([]byte(schema))
+		assert.Error(t, err, schema)
+	}
+}
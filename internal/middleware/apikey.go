@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/shortcode"
+)
+
+// APIKeyAuth returns a middleware that only lets through requests presenting
+// one of keys via "Authorization: Bearer <key>" or "X-API-Key", marking them
+// domain.TierAuthenticated and identifying them by a digest of the key with
+// domain.WithClientID. Others get 401 unauthorized. With no keys it
+// lets every request through.
+func APIKeyAuth(keys []string) func(http.Handler) http.Handler {
+	return apiKeyAuth(keys, false)
+}
+
+// OptionalAPIKeyAuth is APIKeyAuth that also lets through requests
+// presenting no key at all, as domain.TierAnonymous, so they can be given
+// tighter limits instead of being refused. A wrong key is still refused.
+func OptionalAPIKeyAuth(keys []string) func(http.Handler) http.Handler {
+	return apiKeyAuth(keys, true)
+}
+
+func apiKeyAuth(keys []string, allowAnonymous bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(keys) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := presentedAPIKey(r)
+			if key == "" && allowAnonymous {
+				next.ServeHTTP(w, r.WithContext(domain.WithAuthTier(r.Context(), domain.TierAnonymous)))
+				return
+			}
+			if !validAPIKey(key, keys) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				_ = json.NewEncoder(w).Encode(handler.ErrorResponse{
+					Error:   "unauthorized",
+					Message: "valid API key required",
+				})
+				return
+			}
+
+			ctx := domain.WithAuthTier(r.Context(), domain.TierAuthenticated)
+			next.ServeHTTP(w, r.WithContext(domain.WithClientID(ctx, apiKeyID(key))))
+		})
+	}
+}
+
+// presentedAPIKey returns the key from the Authorization bearer token,
+// falling back to X-API-Key.
+func presentedAPIKey(r *http.Request) string {
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return bearer
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// apiKeyID identifies the client presenting key without keeping the key
+// itself around.
+func apiKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "key:" + hex.EncodeToString(sum[:8])
+}
+
+// validAPIKey compares key against every configured key in constant time,
+// so response timing doesn't reveal how close a guess was or which key
+// matched.
+func validAPIKey(key string, keys []string) bool {
+	valid := 0
+	for _, candidate := range keys {
+		valid |= subtle.ConstantTimeCompare([]byte(key), []byte(candidate))
+	}
+	return key != "" && valid == 1
+}
+
+// APIKeyCodePrefixes returns a middleware that namespaces the codes created
+// by requests presenting one of the keys in prefixes with that key's prefix,
+// via domain.WithCodePrefix. Other requests pass through unchanged. It
+// doesn't check keys itself, so it belongs inside APIKeyAuth.
+func APIKeyCodePrefixes(prefixes map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(prefixes) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := presentedAPIKey(r)
+			for candidate, prefix := range prefixes {
+				if key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(candidate)) == 1 {
+					r = r.WithContext(domain.WithCodePrefix(r.Context(), prefix))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ParseAPIKeyCodePrefixes parses "key=prefix" entries for
+// APIKeyCodePrefixes. Each prefix must pass shortcode.ValidatePrefix, and
+// no prefix may start another, so one tenant's codes can't look like
+// another's.
+func ParseAPIKeyCodePrefixes(entries []string) (map[string]string, error) {
+	prefixes := make(map[string]string, len(entries))
+	for i, entry := range entries {
+		// Errors leave the key out, as they end up in logs.
+		key, prefix, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("API key code prefix %d must be key=prefix", i+1)
+		}
+		if err := shortcode.ValidatePrefix(prefix); err != nil {
+			return nil, err
+		}
+		if _, dup := prefixes[key]; dup {
+			return nil, fmt.Errorf("API key code prefix %d repeats an earlier key", i+1)
+		}
+		for _, other := range prefixes {
+			if strings.HasPrefix(prefix, other) || strings.HasPrefix(other, prefix) {
+				return nil, fmt.Errorf("code prefixes %q and %q overlap", other, prefix)
+			}
+		}
+		prefixes[key] = prefix
+	}
+	return prefixes, nil
+}
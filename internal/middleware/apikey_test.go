@@ -0,0 +1,155 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	wrapped := middleware.APIKeyAuth([]string{"key-one", "key-two"})(okHandler())
+
+	testCases := []struct {
+		name       string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{name: "missing key", wantStatus: http.StatusUnauthorized},
+		{name: "wrong key", header: "X-API-Key", value: "nope", wantStatus: http.StatusUnauthorized},
+		{name: "wrong bearer", header: "Authorization", value: "Bearer nope", wantStatus: http.StatusUnauthorized},
+		{name: "empty bearer", header: "Authorization", value: "Bearer ", wantStatus: http.StatusUnauthorized},
+		{name: "bearer key", header: "Authorization", value: "Bearer key-one", wantStatus: http.StatusOK},
+		{name: "api key header", header: "X-API-Key", value: "key-two", wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			rec := httptest.NewRecorder()
+
+			wrapped.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			if tc.wantStatus == http.StatusUnauthorized {
+				assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+				assert.Contains(t, rec.Body.String(), `"error":"unauthorized"`)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuth_NoKeysAllowsAll(t *testing.T) {
+	wrapped := middleware.APIKeyAuth(nil)(okHandler())
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+	rec := httptest.NewRecorder()
+
+	wrapped.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestOptionalAPIKeyAuth_TagsTier(t *testing.T) {
+	var gotTier domain.AuthTier
+	wrapped := middleware.OptionalAPIKeyAuth([]string{"key-one"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTier = domain.AuthTierFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	testCases := []struct {
+		name       string
+		header     string
+		value      string
+		wantStatus int
+		wantTier   domain.AuthTier
+	}{
+		{name: "no key is anonymous", wantStatus: http.StatusOK, wantTier: domain.TierAnonymous},
+		{name: "valid key is authenticated", header: "X-API-Key", value: "key-one", wantStatus: http.StatusOK, wantTier: domain.TierAuthenticated},
+		{name: "wrong key is refused", header: "Authorization", value: "Bearer nope", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotTier = ""
+			req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			rec := httptest.NewRecorder()
+
+			wrapped.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			assert.Equal(t, tc.wantTier, gotTier)
+		})
+	}
+}
+
+func TestAPIKeyAuth_TagsAuthenticated(t *testing.T) {
+	var gotTier domain.AuthTier
+	var gotClients []string
+	wrapped := middleware.APIKeyAuth([]string{"key-one", "key-two"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTier = domain.AuthTierFromContext(r.Context())
+		gotClients = append(gotClients, domain.ClientIDFromContext(r.Context()))
+	}))
+
+	for _, key := range []string{"key-one", "key-two"} {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+		req.Header.Set("X-API-Key", key)
+		wrapped.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, domain.TierAuthenticated, gotTier)
+	require.Len(t, gotClients, 2)
+	assert.NotEmpty(t, gotClients[0])
+	assert.NotEqual(t, gotClients[0], gotClients[1], "each key should identify its own client")
+	assert.NotContains(t, gotClients[0], "key-one", "the key itself must not be kept")
+}
+
+func TestAPIKeyCodePrefixes(t *testing.T) {
+	var gotPrefix string
+	var gotOK bool
+	wrapped := middleware.APIKeyCodePrefixes(map[string]string{"key-one": "t1-"})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotPrefix, gotOK = domain.CodePrefixFromContext(r.Context())
+		}))
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+	req.Header.Set("Authorization", "Bearer key-one")
+	wrapped.ServeHTTP(httptest.NewRecorder(), req)
+	assert.True(t, gotOK)
+	assert.Equal(t, "t1-", gotPrefix)
+
+	req = httptest.NewRequest(http.MethodPost, "/shorten", nil)
+	req.Header.Set("X-API-Key", "key-two")
+	wrapped.ServeHTTP(httptest.NewRecorder(), req)
+	assert.False(t, gotOK, "keys without a prefix keep the default")
+}
+
+func TestParseAPIKeyCodePrefixes(t *testing.T) {
+	prefixes, err := middleware.ParseAPIKeyCodePrefixes([]string{"key-one=t1-", "key-two=t2-"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"key-one": "t1-", "key-two": "t2-"}, prefixes)
+
+	for _, entries := range [][]string{
+		{"key-one"},
+		{"=t1-"},
+		{"key-one="},
+		{"key-one=t/1"},
+		{"key-one=t1-", "key-one=t2-"},
+		{"key-one=t1-", "key-two=t1-x-"},
+	} {
+		_, err := middleware.ParseAPIKeyCodePrefixes(entries)
+		assert.Error(t, err, entries)
+	}
+}
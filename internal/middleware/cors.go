@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures CORS.
+type CORSConfig struct {
+	// AllowedOrigins lists origins allowed to make cross-origin requests,
+	// such as "https://app.example.com". "*" allows any origin.
+	AllowedOrigins []string
+
+	// AllowedMethods and AllowedHeaders are advertised in preflight
+	// responses. They default to DefaultCORSMethods and DefaultCORSHeaders.
+	AllowedMethods []string
+	AllowedHeaders []string
+
+	// MaxAge lets browsers cache preflight results. Zero omits the header.
+	MaxAge time.Duration
+}
+
+// DefaultCORSMethods covers every method the API serves.
+var DefaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete}
+
+// DefaultCORSHeaders covers the request headers the API reads.
+var DefaultCORSHeaders = []string{"Content-Type", "Authorization", "X-API-Key", "Idempotency-Key", "If-None-Match", RequestIDHeader}
+
+// CORS returns a middleware that answers preflight requests from allowed
+// origins with 204 and adds Access-Control-Allow-Origin to their other
+// responses. Requests from other origins pass through without CORS headers,
+// so the browser blocks them. It must wrap the mux, which would otherwise
+// reject OPTIONS with 405.
+func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
+	if len(cfg.AllowedMethods) == 0 {
+		cfg.AllowedMethods = DefaultCORSMethods
+	}
+	if len(cfg.AllowedHeaders) == 0 {
+		cfg.AllowedHeaders = DefaultCORSHeaders
+	}
+
+	anyOrigin := slices.Contains(cfg.AllowedOrigins, "*")
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// The allowed origin depends on the request when it is
+			// echoed, so caches must key on it.
+			if !anyOrigin {
+				w.Header().Add("Vary", "Origin")
+			}
+			if !anyOrigin && !slices.Contains(cfg.AllowedOrigins, origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if anyOrigin {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if !preflight {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			w.Header().Set("Access-Control-Allow-Headers", headers)
+			if cfg.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+
+	"url-shortener/internal/handler"
+)
+
+// Drain turns new requests away once the server starts shutting down, so
+// the requests it already has can finish while clients go elsewhere. The
+// zero value is ready to use and lets every request through.
+type Drain struct {
+	draining atomic.Bool
+}
+
+// Start puts the server into draining. It cannot be undone.
+func (d *Drain) Start() {
+	d.draining.Store(true)
+}
+
+// Draining reports whether Start has been called.
+func (d *Drain) Draining() bool {
+	return d.draining.Load()
+}
+
+// Middleware answers requests that arrive while draining with 503
+// shutting_down and Connection: close, so keep-alive clients reconnect
+// instead of reusing a connection that is about to go away. Requests that
+// reached next before draining started are unaffected.
+func (d *Drain) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !d.Draining() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Connection", "close")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(handler.ErrorResponse{
+			Error:   "shutting_down",
+			Message: "server is shutting down, retry later",
+		})
+	})
+}
@@ -0,0 +1,70 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"url-shortener/internal/handler"
+	"url-shortener/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrain_PassesRequestsUntilStarted(t *testing.T) {
+	var drain middleware.Drain
+	wrapped := drain.Middleware(okHandler())
+
+	rec := httptest.NewRecorder()
+	wrapped.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/s/abc12345", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, drain.Draining())
+}
+
+func TestDrain_RejectsNewRequestsWhileDraining(t *testing.T) {
+	var drain middleware.Drain
+	called := false
+	wrapped := drain.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	drain.Start()
+	rec := httptest.NewRecorder()
+	wrapped.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/s/abc12345", nil))
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "close", rec.Header().Get("Connection"))
+
+	var resp handler.ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "shutting_down", resp.Error)
+}
+
+func TestDrain_InFlightRequestsFinish(t *testing.T) {
+	var drain middleware.Drain
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	wrapped := drain.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		wrapped.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		close(done)
+	}()
+
+	<-entered
+	drain.Start()
+	close(release)
+	<-done
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// gzipMinSize is the smallest response body worth compressing. Smaller
+// bodies are sent as-is since gzip framing would outweigh the savings.
+const gzipMinSize = 1024
+
+// Gzip is a middleware that compresses response bodies for clients that
+// send "Accept-Encoding: gzip". Responses smaller than gzipMinSize, bodiless
+// responses, and responses that already set Content-Encoding are passed
+// through unchanged.
+func Gzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		wrapped := &gzipResponseWriter{ResponseWriter: w}
+		defer wrapped.Close()
+
+		next.ServeHTTP(wrapped, r)
+	})
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.TrimSpace(coding)
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+
+		q := strings.ReplaceAll(params, " ", "")
+		if q == "q=0" || q == "q=0.0" || q == "q=0.00" || q == "q=0.000" {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of the body until it knows whether
+// the response is large enough to compress, then either streams through a
+// gzip.Writer or writes the buffered bytes directly.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if w.status != 0 || w.decided {
+		return
+	}
+	w.status = code
+
+	// Bodiless responses are forwarded immediately so callers relying on
+	// the header being sent (redirects, 204s) see no delay.
+	if !bodyAllowed(code) {
+		w.decide(false)
+	}
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) >= gzipMinSize {
+			if err := w.decide(true); err != nil {
+				return 0, err
+			}
+		}
+		return len(b), nil
+	}
+
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// decide sends the header, choosing gzip if the body is large enough and
+// not already encoded, and flushes any buffered bytes.
+func (w *gzipResponseWriter) decide(large bool) error {
+	w.decided = true
+
+	h := w.Header()
+	if large && h.Get("Content-Encoding") == "" {
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", http.DetectContentType(w.buf))
+		}
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if w.gz != nil {
+		_, err := w.gz.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// Close flushes a small buffered body and finishes the gzip stream.
+func (w *gzipResponseWriter) Close() {
+	if !w.decided {
+		if w.status == 0 {
+			// Handler wrote nothing; let net/http send its implicit 200.
+			return
+		}
+		_ = w.decide(false)
+	}
+
+	if w.gz != nil {
+		_ = w.gz.Close()
+	}
+}
+
+func bodyAllowed(code int) bool {
+	switch {
+	case code >= 100 && code <= 199:
+		return false
+	case code == http.StatusNoContent, code == http.StatusNotModified:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package middleware_test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"url-shortener/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func largeJSONHandler(body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(body))
+	})
+}
+
+func TestGzip_CompressesLargeResponses(t *testing.T) {
+	body := `{"items":"` + strings.Repeat("a", 4096) + `"}`
+	wrapped := middleware.Gzip(largeJSONHandler(body))
+
+	req := httptest.NewRequest(http.MethodGet, "/urls", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	rec := httptest.NewRecorder()
+
+	wrapped.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Values("Vary"), "Accept-Encoding")
+	assert.Less(t, rec.Body.Len(), len(body))
+
+	gr, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	decompressed, err := io.ReadAll(gr)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(decompressed))
+}
+
+func TestGzip_PlainOutputForNonGzipClients(t *testing.T) {
+	body := strings.Repeat("b", 4096)
+	wrapped := middleware.Gzip(largeJSONHandler(body))
+
+	req := httptest.NewRequest(http.MethodGet, "/urls", nil)
+	rec := httptest.NewRecorder()
+
+	wrapped.ServeHTTP(rec, req)
+
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, body, rec.Body.String())
+}
+
+func TestGzip_RespectsQZero(t *testing.T) {
+	body := strings.Repeat("b", 4096)
+	wrapped := middleware.Gzip(largeJSONHandler(body))
+
+	req := httptest.NewRequest(http.MethodGet, "/urls", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0, identity")
+	rec := httptest.NewRecorder()
+
+	wrapped.ServeHTTP(rec, req)
+
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, body, rec.Body.String())
+}
+
+func TestGzip_SkipsSmallResponses(t *testing.T) {
+	body := `{"status":"ok"}`
+	wrapped := middleware.Gzip(largeJSONHandler(body))
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	wrapped.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, body, rec.Body.String())
+}
+
+func TestGzip_SkipsAlreadyEncodedResponses(t *testing.T) {
+	body := strings.Repeat("c", 4096)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		w.Write([]byte(body))
+	})
+	wrapped := middleware.Gzip(handler)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip, br")
+	rec := httptest.NewRecorder()
+
+	wrapped.ServeHTTP(rec, req)
+
+	assert.Equal(t, "br", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, body, rec.Body.String())
+}
+
+func TestGzip_PassesThroughRedirects(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://example.com", http.StatusFound)
+	})
+	wrapped := middleware.Gzip(handler)
+
+	req := httptest.NewRequest(http.MethodGet, "/s/abc12345", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	wrapped.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "https://example.com", rec.Header().Get("Location"))
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+}
+
+func TestGzip_CooperatesWithTiming(t *testing.T) {
+	body := strings.Repeat("d", 4096)
+	wrapped := middleware.Timing(middleware.Gzip(largeJSONHandler(body)))
+
+	req := httptest.NewRequest(http.MethodGet, "/urls", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	wrapped.ServeHTTP(rec, req)
+
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.NotEmpty(t, rec.Header().Get("X-Processing-Time-Micros"))
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// InFlight counts requests that are currently being served, e.g. to report
+// how many are still draining during shutdown. The zero value is ready to
+// use.
+type InFlight struct {
+	count atomic.Int64
+}
+
+// Middleware counts each request from the moment it enters next until next
+// returns.
+func (f *InFlight) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.count.Add(1)
+		defer f.count.Add(-1)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Count returns the number of requests currently in flight.
+func (f *InFlight) Count() int64 {
+	return f.count.Load()
+}
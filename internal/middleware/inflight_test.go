@@ -0,0 +1,38 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"url-shortener/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInFlight_CountsRequestsWhileServing(t *testing.T) {
+	var inFlight middleware.InFlight
+
+	var during int64
+	handler := inFlight.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		during = inFlight.Count()
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, int64(1), during)
+	assert.Equal(t, int64(0), inFlight.Count())
+}
+
+func TestInFlight_DecrementsWhenHandlerPanics(t *testing.T) {
+	var inFlight middleware.InFlight
+
+	handler := inFlight.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.Panics(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+	assert.Equal(t, int64(0), inFlight.Count())
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+const (
+	defaultLatencyWindow = 5 * time.Minute
+	defaultLatencySize   = 10000
+)
+
+// LatencyConfig configures a LatencyRecorder.
+type LatencyConfig struct {
+	// Window is how far back percentiles look. Defaults to 5 minutes.
+	Window time.Duration
+
+	// Size caps the samples kept, bounding memory under heavy traffic; the
+	// oldest are overwritten first. Defaults to 10000.
+	Size int
+
+	// Clock defaults to domain.RealClock.
+	Clock domain.Clock
+}
+
+// LatencyRecorder keeps recent request durations in a fixed-size ring
+// buffer and reports percentiles over them, for when Prometheus isn't
+// available. It is safe for concurrent use.
+type LatencyRecorder struct {
+	window time.Duration
+	clock  domain.Clock
+
+	mu      sync.Mutex
+	samples []latencySample
+	next    int
+}
+
+type latencySample struct {
+	at       time.Time
+	duration time.Duration
+}
+
+// LatencySummary reports request latency percentiles in milliseconds over
+// the samples recorded within the window.
+type LatencySummary struct {
+	WindowSeconds float64 `json:"window_seconds"`
+	Count         int     `json:"count"`
+	P50Ms         float64 `json:"p50_ms"`
+	P95Ms         float64 `json:"p95_ms"`
+	P99Ms         float64 `json:"p99_ms"`
+}
+
+// NewLatencyRecorder creates a recorder, applying defaults for zero fields.
+func NewLatencyRecorder(cfg LatencyConfig) *LatencyRecorder {
+	if cfg.Window <= 0 {
+		cfg.Window = defaultLatencyWindow
+	}
+	if cfg.Size <= 0 {
+		cfg.Size = defaultLatencySize
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = domain.RealClock{}
+	}
+
+	return &LatencyRecorder{
+		window:  cfg.Window,
+		clock:   cfg.Clock,
+		samples: make([]latencySample, 0, cfg.Size),
+	}
+}
+
+// Record adds a request duration.
+func (l *LatencyRecorder) Record(d time.Duration) {
+	sample := latencySample{at: l.clock.Now(), duration: d}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.samples) < cap(l.samples) {
+		l.samples = append(l.samples, sample)
+		return
+	}
+	l.samples[l.next] = sample
+	l.next = (l.next + 1) % len(l.samples)
+}
+
+// Summary computes percentiles over the samples recorded within the window.
+func (l *LatencyRecorder) Summary() LatencySummary {
+	cutoff := l.clock.Now().Add(-l.window)
+
+	l.mu.Lock()
+	durations := make([]time.Duration, 0, len(l.samples))
+	for _, s := range l.samples {
+		if !s.at.Before(cutoff) {
+			durations = append(durations, s.duration)
+		}
+	}
+	l.mu.Unlock()
+
+	slices.Sort(durations)
+
+	return LatencySummary{
+		WindowSeconds: l.window.Seconds(),
+		Count:         len(durations),
+		P50Ms:         percentileMs(durations, 50),
+		P95Ms:         percentileMs(durations, 95),
+		P99Ms:         percentileMs(durations, 99),
+	}
+}
+
+// percentileMs returns the nearest-rank percentile p of sorted, in
+// milliseconds, or 0 when there are no samples.
+func percentileMs(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	rank = min(max(rank, 1), len(sorted))
+	return float64(sorted[rank-1]) / float64(time.Millisecond)
+}
+
+// Handler serves the current LatencySummary as JSON.
+func (l *LatencyRecorder) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(l.Summary())
+	})
+}
@@ -0,0 +1,127 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyRecorder_Percentiles(t *testing.T) {
+	rec := middleware.NewLatencyRecorder(middleware.LatencyConfig{})
+
+	// 1ms..100ms, recorded out of order
+	for i := 100; i >= 1; i-- {
+		rec.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	summary := rec.Summary()
+	assert.Equal(t, 100, summary.Count)
+	assert.Equal(t, float64(300), summary.WindowSeconds)
+	assert.InDelta(t, 50, summary.P50Ms, 1)
+	assert.InDelta(t, 95, summary.P95Ms, 1)
+	assert.InDelta(t, 99, summary.P99Ms, 1)
+}
+
+func TestLatencyRecorder_Empty(t *testing.T) {
+	rec := middleware.NewLatencyRecorder(middleware.LatencyConfig{})
+
+	summary := rec.Summary()
+	assert.Equal(t, 0, summary.Count)
+	assert.Zero(t, summary.P50Ms)
+	assert.Zero(t, summary.P99Ms)
+}
+
+func TestLatencyRecorder_IgnoresSamplesOutsideWindow(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	rec := middleware.NewLatencyRecorder(middleware.LatencyConfig{Window: time.Minute, Clock: clock})
+
+	for i := 0; i < 10; i++ {
+		rec.Record(time.Second)
+	}
+	clock.Advance(2 * time.Minute)
+	for i := 0; i < 10; i++ {
+		rec.Record(10 * time.Millisecond)
+	}
+
+	summary := rec.Summary()
+	assert.Equal(t, 10, summary.Count)
+	assert.InDelta(t, 10, summary.P99Ms, 0.001)
+}
+
+func TestLatencyRecorder_BoundedSize(t *testing.T) {
+	rec := middleware.NewLatencyRecorder(middleware.LatencyConfig{Size: 10})
+
+	// The ten slow samples are overwritten by the newer fast ones
+	for i := 0; i < 10; i++ {
+		rec.Record(time.Second)
+	}
+	for i := 0; i < 25; i++ {
+		rec.Record(time.Millisecond)
+	}
+
+	summary := rec.Summary()
+	assert.Equal(t, 10, summary.Count)
+	assert.InDelta(t, 1, summary.P99Ms, 0.001)
+}
+
+func TestLatencyRecorder_ConcurrentRecord(t *testing.T) {
+	rec := middleware.NewLatencyRecorder(middleware.LatencyConfig{Size: 500})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				rec.Record(time.Millisecond)
+				_ = rec.Summary()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 500, rec.Summary().Count)
+}
+
+func TestTimingWithRecorder_FeedsRecorder(t *testing.T) {
+	rec := middleware.NewLatencyRecorder(middleware.LatencyConfig{})
+	wrapped := middleware.TimingWithRecorder(rec)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 3; i++ {
+		resp := httptest.NewRecorder()
+		wrapped.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/test", nil))
+		assert.NotEmpty(t, resp.Header().Get("X-Processing-Time-Micros"))
+	}
+
+	summary := rec.Summary()
+	assert.Equal(t, 3, summary.Count)
+	assert.GreaterOrEqual(t, summary.P50Ms, 5.0)
+}
+
+func TestLatencyRecorder_Handler(t *testing.T) {
+	rec := middleware.NewLatencyRecorder(middleware.LatencyConfig{})
+	rec.Record(2 * time.Millisecond)
+
+	resp := httptest.NewRecorder()
+	rec.Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/debug/latency", nil))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+
+	var summary middleware.LatencySummary
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &summary))
+	assert.Equal(t, 1, summary.Count)
+	assert.InDelta(t, 2, summary.P50Ms, 0.001)
+}
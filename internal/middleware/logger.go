@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"url-shortener/internal/clientip"
+)
+
+// Logger returns a middleware that writes one structured log line per
+// request with method, path, status, bytes written, duration, and the
+// client's address. Responses with a 5xx status are logged at warn level,
+// everything else at info. Wrap it in RequestID to include the request ID.
+func Logger(logger *slog.Logger) func(http.Handler) http.Handler {
+	return LoggerWithClientIP(logger, nil)
+}
+
+// LoggerWithClientIP is Logger, logging the client address res resolves
+// rather than the direct peer, which behind a proxy is the proxy itself.
+func LoggerWithClientIP(logger *slog.Logger, res *clientip.Resolver) func(http.Handler) http.Handler {
+	return SampledLogger(logger, res, LogSampling{})
+}
+
+// LogSampling thins out request logs at high volume. Responses with a
+// status of 400 or above are always logged. The zero value logs every
+// request.
+type LogSampling struct {
+	// Every logs one in Every other requests, starting with the first.
+	// Values below 2 log them all.
+	Every int
+
+	// Rate, when Every is unset and Rate is below 1, logs each other
+	// request with this probability.
+	Rate float64
+
+	// Rand returns a number in [0, 1) to compare with Rate. It defaults
+	// to math/rand/v2's Float64.
+	Rand func() float64
+}
+
+// SampledLogger is LoggerWithClientIP, logging only the requests sampling
+// picks.
+func SampledLogger(logger *slog.Logger, res *clientip.Resolver, sampling LogSampling) func(http.Handler) http.Handler {
+	sample := sampling.sampler()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			wrapped := newResponseRecorder(w)
+			next.ServeHTTP(wrapped, r)
+
+			if wrapped.status < http.StatusBadRequest && !sample() {
+				return
+			}
+
+			level := slog.LevelInfo
+			if wrapped.status >= http.StatusInternalServerError {
+				level = slog.LevelWarn
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", wrapped.status),
+				slog.Int64("bytes", wrapped.bytes),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("client_ip", res.ClientIP(r)),
+			}
+			if id := RequestIDFromContext(r.Context()); id != "" {
+				attrs = append(attrs, slog.String("request_id", id))
+			}
+
+			logger.LogAttrs(r.Context(), level, "request", attrs...)
+		})
+	}
+}
+
+// sampler returns a func reporting whether to log a request that succeeded.
+func (s LogSampling) sampler() func() bool {
+	switch {
+	case s.Every > 1:
+		var seen atomic.Uint64
+		every := uint64(s.Every)
+		return func() bool {
+			return (seen.Add(1)-1)%every == 0
+		}
+	case s.Rate > 0 && s.Rate < 1:
+		random := s.Rand
+		if random == nil {
+			random = rand.Float64
+		}
+		return func() bool {
+			return random() < s.Rate
+		}
+	default:
+		return func() bool { return true }
+	}
+}
@@ -0,0 +1,220 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"url-shortener/internal/clientip"
+	"url-shortener/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs returns a JSON logger writing to buf and a func decoding its
+// single log line.
+func captureLogs(t *testing.T) (*slog.Logger, func() map[string]any) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	return logger, func() map[string]any {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry), "expected one JSON log line, got %q", buf.String())
+		return entry
+	}
+}
+
+func TestLogger_ImplicitStatusOK(t *testing.T) {
+	logger, entry := captureLogs(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	middleware.Logger(logger)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	log := entry()
+	assert.Equal(t, "INFO", log["level"])
+	assert.Equal(t, "request", log["msg"])
+	assert.Equal(t, "GET", log["method"])
+	assert.Equal(t, "/health", log["path"])
+	assert.Equal(t, float64(http.StatusOK), log["status"])
+	assert.Equal(t, float64(5), log["bytes"])
+	assert.Contains(t, log, "duration")
+}
+
+func TestLogger_ExplicitWriteHeader(t *testing.T) {
+	logger, entry := captureLogs(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"short_code":"abc12345"}`))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+	rec := httptest.NewRecorder()
+	middleware.Logger(logger)(handler).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	log := entry()
+	assert.Equal(t, "INFO", log["level"])
+	assert.Equal(t, float64(http.StatusCreated), log["status"])
+	assert.Equal(t, float64(len(`{"short_code":"abc12345"}`)), log["bytes"])
+}
+
+func TestLogger_ServerErrorsLogAtWarn(t *testing.T) {
+	logger, entry := captureLogs(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	middleware.Logger(logger)(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	log := entry()
+	assert.Equal(t, "WARN", log["level"])
+	assert.Equal(t, float64(http.StatusInternalServerError), log["status"])
+	assert.Equal(t, float64(0), log["bytes"])
+}
+
+func TestLogger_IncludesRequestID(t *testing.T) {
+	logger, entry := captureLogs(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	middleware.RequestID(middleware.Logger(logger)(handler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "req-123", entry()["request_id"])
+}
+
+func TestLogger_ClientIP(t *testing.T) {
+	res, err := clientip.NewResolver([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	t.Run("direct peer by default", func(t *testing.T) {
+		logger, entry := captureLogs(t)
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.RemoteAddr = "10.0.0.1:5000"
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+		middleware.Logger(logger)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, "10.0.0.1", entry()["client_ip"])
+	})
+
+	t.Run("forwarded client behind trusted proxy", func(t *testing.T) {
+		logger, entry := captureLogs(t)
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.RemoteAddr = "10.0.0.1:5000"
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+		middleware.LoggerWithClientIP(logger, res)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, "203.0.113.7", entry()["client_ip"])
+	})
+}
+
+func TestLogger_ComposesWithTiming(t *testing.T) {
+	logger, entry := captureLogs(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	rec := httptest.NewRecorder()
+	middleware.Timing(middleware.Logger(logger)(handler)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.NotEmpty(t, rec.Header().Get("X-Processing-Time-Micros"))
+	assert.Equal(t, float64(http.StatusNotFound), entry()["status"])
+}
+
+// sampledStatuses sends n requests through a sampled logger, where every
+// tenth request fails with 500, and returns the statuses logged.
+func sampledStatuses(t *testing.T, n int, sampling middleware.LogSampling) []int {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	var calls int
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls%10 == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	wrapped := middleware.SampledLogger(logger, nil, sampling)(handler)
+	for range n {
+		wrapped.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/s/abc12345", nil))
+	}
+
+	var statuses []int
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry struct{ Status int }
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		statuses = append(statuses, entry.Status)
+	}
+	return statuses
+}
+
+func countStatus(statuses []int, status int) int {
+	var n int
+	for _, s := range statuses {
+		if s == status {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSampledLogger_Every(t *testing.T) {
+	statuses := sampledStatuses(t, 1000, middleware.LogSampling{Every: 10})
+
+	// 900 requests succeed, one in ten of which is logged, and all 100
+	// failures are.
+	assert.Equal(t, 90, countStatus(statuses, http.StatusOK))
+	assert.Equal(t, 100, countStatus(statuses, http.StatusInternalServerError))
+}
+
+func TestSampledLogger_Rate(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	statuses := sampledStatuses(t, 10000, middleware.LogSampling{Rate: 0.05, Rand: rng.Float64})
+
+	assert.InDelta(t, 450, countStatus(statuses, http.StatusOK), 60, "about 5% of 9000 successes")
+	assert.Equal(t, 1000, countStatus(statuses, http.StatusInternalServerError))
+}
+
+func TestSampledLogger_AlwaysLogsClientErrors(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	never := func() float64 { return 1 }
+	wrapped := middleware.SampledLogger(logger, nil, middleware.LogSampling{Rate: 0.01, Rand: never})(handler)
+	for range 50 {
+		wrapped.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/s/missing", nil))
+	}
+
+	assert.Equal(t, 50, strings.Count(buf.String(), "\n"))
+}
+
+func TestSampledLogger_ZeroValueLogsEverything(t *testing.T) {
+	statuses := sampledStatuses(t, 100, middleware.LogSampling{})
+
+	assert.Len(t, statuses, 100)
+}
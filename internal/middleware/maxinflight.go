@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"url-shortener/internal/handler"
+)
+
+// busyRetryAfter is the Retry-After, in seconds, sent with 503 server_busy.
+// Requests are short, so slots free up quickly.
+const busyRetryAfter = "1"
+
+// MaxInFlight returns a middleware that serves at most n requests at once.
+// Requests beyond that are rejected straight away with 503 server_busy and
+// a Retry-After header instead of queueing, so a load spike can't pile up
+// unbounded work. A zero or negative n disables it.
+func MaxInFlight(n int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if n <= 0 {
+			return next
+		}
+		slots := make(chan struct{}, n)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case slots <- struct{}{}:
+			default:
+				w.Header().Set("Retry-After", busyRetryAfter)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_ = json.NewEncoder(w).Encode(handler.ErrorResponse{
+					Error:   "server_busy",
+					Message: "server is busy, retry later",
+				})
+				return
+			}
+			defer func() { <-slots }()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"url-shortener/internal/handler"
+	"url-shortener/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxInFlight_RejectsRequestsOverLimit(t *testing.T) {
+	const limit = 3
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	wrapped := middleware.MaxInFlight(limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Fill every slot with a slow request.
+	slow := make([]*httptest.ResponseRecorder, limit)
+	var wg sync.WaitGroup
+	for i := range slow {
+		slow[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wrapped.ServeHTTP(slow[i], httptest.NewRequest(http.MethodGet, "/test", nil))
+		}()
+		<-started
+	}
+
+	// Requests beyond the limit are turned away without waiting.
+	for range 5 {
+		rec := httptest.NewRecorder()
+		wrapped.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+		var resp handler.ErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "server_busy", resp.Error)
+	}
+
+	close(release)
+	wg.Wait()
+	for _, rec := range slow {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// Finished requests free their slots.
+	go func() { <-started }()
+	rec := httptest.NewRecorder()
+	wrapped.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestMaxInFlight_ZeroDisables(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	wrapped := middleware.MaxInFlight(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	recs := make([]*httptest.ResponseRecorder, 10)
+	var wg sync.WaitGroup
+	for i := range recs {
+		recs[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wrapped.ServeHTTP(recs[i], httptest.NewRequest(http.MethodGet, "/test", nil))
+		}()
+		<-started
+	}
+	close(release)
+	wg.Wait()
+
+	for _, rec := range recs {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+}
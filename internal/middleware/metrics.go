@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"url-shortener/internal/service"
+)
+
+// unmatchedRoute labels requests that matched no registered pattern, so
+// arbitrary paths can't blow up label cardinality.
+const unmatchedRoute = "unmatched"
+
+// saturationTimeout bounds the record count behind each scrape of the
+// saturation gauge, so a slow store can't stall /metrics.
+const saturationTimeout = 2 * time.Second
+
+// Metrics records HTTP and business metrics into a Prometheus registry.
+// It also implements service.Metrics so the service layer can report
+// created codes, resolves, collisions, and dropped notifications into the
+// same registry.
+type Metrics struct {
+	gatherer   prometheus.Gatherer
+	registerer prometheus.Registerer
+
+	requests   *prometheus.CounterVec
+	durations  *prometheus.HistogramVec
+	created    prometheus.Counter
+	resolves   *prometheus.CounterVec
+	redirects  prometheus.Counter
+	collisions prometheus.Counter
+	dropped    prometheus.Counter
+}
+
+// NewMetrics creates the collectors and registers them with reg. Passing a
+// fresh registry keeps tests independent of the global default registry.
+func NewMetrics(reg *prometheus.Registry) *Metrics {
+	m := &Metrics{
+		gatherer:   reg,
+		registerer: reg,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by route pattern, method, and status code.",
+		}, []string{"route", "method", "status"}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by route pattern and method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		created: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "url_shortener_codes_created_total",
+			Help: "Short codes created.",
+		}),
+		resolves: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "url_shortener_resolves_total",
+			Help: "Short code resolves by outcome.",
+		}, []string{"outcome"}),
+		redirects: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "url_shortener_redirects_total",
+			Help: "Redirects served for successfully resolved short codes.",
+		}),
+		collisions: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "url_shortener_code_collisions_total",
+			Help: "Generated short codes that were already taken.",
+		}),
+		dropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "url_shortener_notifications_dropped_total",
+			Help: "Link events dropped because the notifier queue was full.",
+		}),
+	}
+
+	reg.MustRegister(m.requests, m.durations, m.created, m.resolves, m.redirects, m.collisions, m.dropped)
+	return m
+}
+
+// Middleware records per-route request counts and durations. It must wrap
+// the ServeMux directly so the matched pattern is visible on the request
+// once the mux has routed it.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		wrapped := newResponseRecorder(w)
+		next.ServeHTTP(wrapped, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		m.requests.WithLabelValues(route, r.Method, strconv.Itoa(wrapped.status)).Inc()
+		m.durations.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.gatherer, promhttp.HandlerOpts{})
+}
+
+// CodeCreated implements service.Metrics.
+func (m *Metrics) CodeCreated() {
+	m.created.Inc()
+}
+
+// Resolved implements service.Metrics.
+func (m *Metrics) Resolved(outcome service.ResolveOutcome) {
+	m.resolves.WithLabelValues(string(outcome)).Inc()
+	if outcome == service.ResolveOK {
+		m.redirects.Inc()
+	}
+}
+
+// CodeCollided implements service.Metrics.
+func (m *Metrics) CodeCollided() {
+	m.collisions.Inc()
+}
+
+// NotificationDropped implements service.Metrics.
+func (m *Metrics) NotificationDropped() {
+	m.dropped.Inc()
+}
+
+// CodeSpace reports how full a generator's code space is. It is
+// implemented by service.URLService.
+type CodeSpace interface {
+	CodeSpaceKnown() bool
+	CodeSpaceSaturation(ctx context.Context) (float64, error)
+}
+
+// TrackCodeSpaceSaturation exports space's saturation as a gauge evaluated
+// on each scrape. The gauge reads NaN while CodeSpaceSaturation fails, and
+// is not registered at all unless space.CodeSpaceKnown.
+func (m *Metrics) TrackCodeSpaceSaturation(space CodeSpace) {
+	if !space.CodeSpaceKnown() {
+		return
+	}
+
+	m.registerer.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "url_shortener_code_space_saturation",
+		Help: "Stored records as a fraction of the codes the generator can produce (records / alphabet^length).",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), saturationTimeout)
+		defer cancel()
+
+		v, err := space.CodeSpaceSaturation(ctx)
+		if err != nil {
+			slog.Warn("estimating code space saturation", "error", err)
+			return math.NaN()
+		}
+		return v
+	}))
+}
@@ -0,0 +1,154 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"url-shortener/internal/middleware"
+	"url-shortener/internal/service"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics_RecordsPerRouteRequests(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics := middleware.NewMetrics(reg)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /s/{code}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusFound)
+	})
+	wrapped := metrics.Middleware(mux)
+
+	for _, path := range []string{"/s/abc12345", "/s/xyz98765", "/nope"} {
+		wrapped.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	expected := `
+# HELP http_requests_total HTTP requests by route pattern, method, and status code.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",route="GET /s/{code}",status="302"} 2
+http_requests_total{method="GET",route="unmatched",status="404"} 1
+`
+	err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "http_requests_total")
+	require.NoError(t, err)
+
+	count, err := testutil.GatherAndCount(reg, "http_request_duration_seconds")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "one histogram series per route and method")
+}
+
+func TestMetrics_RecordsBusinessEvents(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics := middleware.NewMetrics(reg)
+
+	var _ service.Metrics = metrics
+
+	metrics.CodeCreated()
+	metrics.CodeCreated()
+	metrics.Resolved(service.ResolveOK)
+	metrics.Resolved(service.ResolveNotFound)
+
+	expected := `
+# HELP url_shortener_codes_created_total Short codes created.
+# TYPE url_shortener_codes_created_total counter
+url_shortener_codes_created_total 2
+# HELP url_shortener_redirects_total Redirects served for successfully resolved short codes.
+# TYPE url_shortener_redirects_total counter
+url_shortener_redirects_total 1
+# HELP url_shortener_resolves_total Short code resolves by outcome.
+# TYPE url_shortener_resolves_total counter
+url_shortener_resolves_total{outcome="not_found"} 1
+url_shortener_resolves_total{outcome="ok"} 1
+`
+	err := testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"url_shortener_codes_created_total", "url_shortener_redirects_total", "url_shortener_resolves_total")
+	require.NoError(t, err)
+}
+
+func TestMetrics_HandlerServesRegistry(t *testing.T) {
+	metrics := middleware.NewMetrics(prometheus.NewRegistry())
+	metrics.CodeCreated()
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "url_shortener_codes_created_total 1")
+}
+
+func TestMetrics_CountsCollisions(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics := middleware.NewMetrics(reg)
+
+	metrics.CodeCollided()
+	metrics.CodeCollided()
+
+	expected := `
+# HELP url_shortener_code_collisions_total Generated short codes that were already taken.
+# TYPE url_shortener_code_collisions_total counter
+url_shortener_code_collisions_total 2
+`
+	err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "url_shortener_code_collisions_total")
+	require.NoError(t, err)
+}
+
+// fakeCodeSpace returns saturation, or err if set.
+type fakeCodeSpace struct {
+	known      bool
+	saturation float64
+	err        error
+}
+
+func (f *fakeCodeSpace) CodeSpaceKnown() bool { return f.known }
+
+func (f *fakeCodeSpace) CodeSpaceSaturation(context.Context) (float64, error) {
+	return f.saturation, f.err
+}
+
+func TestMetrics_TrackCodeSpaceSaturation(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics := middleware.NewMetrics(reg)
+
+	space := &fakeCodeSpace{known: true, saturation: 0.25}
+	metrics.TrackCodeSpaceSaturation(space)
+
+	expected := `
+# HELP url_shortener_code_space_saturation Stored records as a fraction of the codes the generator can produce (records / alphabet^length).
+# TYPE url_shortener_code_space_saturation gauge
+url_shortener_code_space_saturation 0.25
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "url_shortener_code_space_saturation"))
+
+	// The gauge is evaluated on each scrape.
+	space.saturation = 0.5
+	assert.Contains(t, scrape(t, metrics), "url_shortener_code_space_saturation 0.5")
+}
+
+func TestMetrics_TrackCodeSpaceSaturation_Errors(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics := middleware.NewMetrics(reg)
+
+	metrics.TrackCodeSpaceSaturation(&fakeCodeSpace{err: service.ErrUnknownCodeSpace})
+	count, err := testutil.GatherAndCount(reg, "url_shortener_code_space_saturation")
+	require.NoError(t, err)
+	assert.Zero(t, count, "an unknown code space should not be exported")
+
+	metrics.TrackCodeSpaceSaturation(&fakeCodeSpace{known: true, err: errors.New("store unavailable")})
+	assert.Contains(t, scrape(t, metrics), "url_shortener_code_space_saturation NaN")
+}
+
+func scrape(t *testing.T, metrics *middleware.Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
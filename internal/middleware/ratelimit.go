@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"url-shortener/internal/clientip"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+// RateLimitConfig configures RateLimit.
+type RateLimitConfig struct {
+	// Rate is the sustained number of requests per second allowed per
+	// client IP. Burst is how many may be made at once from a full bucket.
+	Rate  float64
+	Burst int
+
+	// ClientIP identifies the client behind trusted proxies. When nil,
+	// clients are keyed by the direct peer's address.
+	ClientIP *clientip.Resolver
+
+	// Clock defaults to domain.RealClock.
+	Clock domain.Clock
+}
+
+// RateLimit returns a middleware enforcing a token bucket per client IP.
+// Requests over the limit get 429 with a Retry-After header. It is meant
+// to wrap individual routes, such as POST /shorten, rather than the mux.
+func RateLimit(cfg RateLimitConfig) func(http.Handler) http.Handler {
+	return NewRateLimiter(cfg).Middleware
+}
+
+// RateLimiter is the limiter behind RateLimit, for callers that need to
+// change the limit while serving.
+type RateLimiter struct {
+	limiter *ipRateLimiter
+}
+
+// NewRateLimiter creates a limiter for cfg.
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	return &RateLimiter{limiter: newIPRateLimiter(cfg)}
+}
+
+// Middleware limits requests to next. Wrapping several routes with the same
+// limiter makes them share each client's budget. Each request takes one
+// token; next can take more from the same bucket through the
+// handler.RateCharge in the request context.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := l.limiter.clientKey(r)
+		ok, retryAfter := l.limiter.allow(key, 1)
+		if !ok {
+			handler.WriteRateLimited(w, retryAfter)
+			return
+		}
+
+		ctx := handler.WithRateCharge(r.Context(), func(n int) (bool, time.Duration) {
+			return l.limiter.allow(key, n)
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// SetRate changes the sustained rate and burst. Clients keep the tokens
+// they have, capped at the new burst. A zero rate lets every request
+// through.
+func (l *RateLimiter) SetRate(rate float64, burst int) {
+	l.limiter.setRate(rate, burst)
+}
+
+// ipRateLimiter holds one token bucket per client. Buckets that have
+// refilled completely are swept, since they behave like a fresh bucket.
+type ipRateLimiter struct {
+	mu        sync.Mutex
+	cfg       RateLimitConfig
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newIPRateLimiter(cfg RateLimitConfig) *ipRateLimiter {
+	if cfg.Clock == nil {
+		cfg.Clock = domain.RealClock{}
+	}
+	cfg.Burst = max(cfg.Burst, 1)
+	return &ipRateLimiter{
+		cfg:     cfg,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes n tokens from key's bucket. When fewer are available it
+// takes none and returns false and how long until there are n. A bucket
+// never holds more than the burst, so larger n are always refused.
+func (l *ipRateLimiter) allow(key string, n int) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cfg.Rate <= 0 {
+		return true, 0
+	}
+
+	now := l.cfg.Clock.Now()
+	if now.Sub(l.lastSweep) >= l.fillTime() {
+		l.sweep(now)
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.cfg.Burst), last: now}
+		l.buckets[key] = bucket
+	}
+
+	l.refill(bucket, now)
+
+	if bucket.tokens >= float64(n) {
+		bucket.tokens -= float64(n)
+		return true, 0
+	}
+
+	missing := float64(n) - bucket.tokens
+	return false, time.Duration(missing / l.cfg.Rate * float64(time.Second))
+}
+
+func (l *ipRateLimiter) setRate(rate float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cfg.Rate = rate
+	l.cfg.Burst = max(burst, 1)
+	for _, bucket := range l.buckets {
+		bucket.tokens = math.Min(bucket.tokens, float64(l.cfg.Burst))
+	}
+}
+
+func (l *ipRateLimiter) refill(bucket *tokenBucket, now time.Time) {
+	elapsed := now.Sub(bucket.last).Seconds()
+	if elapsed > 0 {
+		bucket.tokens = math.Min(float64(l.cfg.Burst), bucket.tokens+elapsed*l.cfg.Rate)
+		bucket.last = now
+	}
+}
+
+// fillTime is how long an empty bucket takes to refill completely.
+func (l *ipRateLimiter) fillTime() time.Duration {
+	return time.Duration(float64(l.cfg.Burst) / l.cfg.Rate * float64(time.Second))
+}
+
+func (l *ipRateLimiter) sweep(now time.Time) {
+	for key, bucket := range l.buckets {
+		l.refill(bucket, now)
+		if bucket.tokens >= float64(l.cfg.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+func (l *ipRateLimiter) clientKey(r *http.Request) string {
+	return l.cfg.ClientIP.ClientIP(r)
+}
@@ -0,0 +1,134 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/clientip"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRateLimited(cfg middleware.RateLimitConfig) http.Handler {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	return middleware.RateLimit(cfg)(ok)
+}
+
+func shortenFrom(h http.Handler, remoteAddr string, forwardedFor string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimit_AllowsBurstThenRejects(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	h := newRateLimited(middleware.RateLimitConfig{Rate: 1, Burst: 3, Clock: clock})
+
+	for i := 0; i < 3; i++ {
+		rec := shortenFrom(h, "203.0.113.7:5000", "")
+		assert.Equal(t, http.StatusCreated, rec.Code, "request %d should be within burst", i+1)
+	}
+
+	rec := shortenFrom(h, "203.0.113.7:5000", "")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var resp handler.ErrorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, "rate_limited", resp.Error)
+}
+
+func TestRateLimit_RefillsOverTime(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	h := newRateLimited(middleware.RateLimitConfig{Rate: 0.5, Burst: 1, Clock: clock})
+
+	assert.Equal(t, http.StatusCreated, shortenFrom(h, "203.0.113.7:5000", "").Code)
+
+	rec := shortenFrom(h, "203.0.113.7:5000", "")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+
+	clock.Advance(time.Second)
+	assert.Equal(t, http.StatusTooManyRequests, shortenFrom(h, "203.0.113.7:5000", "").Code)
+
+	clock.Advance(time.Second)
+	assert.Equal(t, http.StatusCreated, shortenFrom(h, "203.0.113.7:5000", "").Code)
+}
+
+func TestRateLimit_SeparateBucketsPerIP(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	h := newRateLimited(middleware.RateLimitConfig{Rate: 1, Burst: 1, Clock: clock})
+
+	assert.Equal(t, http.StatusCreated, shortenFrom(h, "203.0.113.7:5000", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, shortenFrom(h, "203.0.113.7:5001", "").Code,
+		"a different port on the same IP shares the bucket")
+	assert.Equal(t, http.StatusCreated, shortenFrom(h, "198.51.100.1:5000", "").Code)
+}
+
+func TestRateLimit_ForwardedFor(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	t.Run("ignored by default", func(t *testing.T) {
+		h := newRateLimited(middleware.RateLimitConfig{Rate: 1, Burst: 1, Clock: clock})
+
+		assert.Equal(t, http.StatusCreated, shortenFrom(h, "10.0.0.1:5000", "203.0.113.7").Code)
+		assert.Equal(t, http.StatusTooManyRequests, shortenFrom(h, "10.0.0.1:5000", "198.51.100.1").Code)
+	})
+
+	t.Run("trusted proxy", func(t *testing.T) {
+		res, err := clientip.NewResolver([]string{"10.0.0.0/8"})
+		require.NoError(t, err)
+		h := newRateLimited(middleware.RateLimitConfig{Rate: 1, Burst: 1, Clock: clock, ClientIP: res})
+
+		assert.Equal(t, http.StatusCreated, shortenFrom(h, "10.0.0.1:5000", "203.0.113.7, 10.0.0.2").Code)
+		assert.Equal(t, http.StatusCreated, shortenFrom(h, "10.0.0.1:5000", "198.51.100.1").Code)
+		assert.Equal(t, http.StatusTooManyRequests, shortenFrom(h, "10.0.0.1:5000", "203.0.113.7").Code)
+	})
+
+	t.Run("spoofed by untrusted peer", func(t *testing.T) {
+		res, err := clientip.NewResolver([]string{"10.0.0.0/8"})
+		require.NoError(t, err)
+		h := newRateLimited(middleware.RateLimitConfig{Rate: 1, Burst: 1, Clock: clock, ClientIP: res})
+
+		assert.Equal(t, http.StatusCreated, shortenFrom(h, "203.0.113.7:5000", "198.51.100.1").Code)
+		assert.Equal(t, http.StatusTooManyRequests, shortenFrom(h, "203.0.113.7:5000", "198.51.100.2").Code,
+			"a new X-Forwarded-For must not buy a fresh bucket")
+	})
+}
+
+func TestRateLimiter_SetRate(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	limiter := middleware.NewRateLimiter(middleware.RateLimitConfig{Rate: 1, Burst: 5, Clock: clock})
+	h := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	assert.Equal(t, http.StatusCreated, shortenFrom(h, "203.0.113.7:5000", "").Code)
+
+	// Shrinking the burst caps the tokens clients already hold
+	limiter.SetRate(1, 2)
+	assert.Equal(t, http.StatusCreated, shortenFrom(h, "203.0.113.7:5000", "").Code)
+	assert.Equal(t, http.StatusCreated, shortenFrom(h, "203.0.113.7:5000", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, shortenFrom(h, "203.0.113.7:5000", "").Code)
+
+	// A zero rate turns limiting off
+	limiter.SetRate(0, 0)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusCreated, shortenFrom(h, "203.0.113.7:5000", "").Code)
+	}
+}
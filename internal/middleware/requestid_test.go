@@ -0,0 +1,64 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"url-shortener/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveWithRequestID(incoming string) (*httptest.ResponseRecorder, string) {
+	var seen string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = middleware.RequestIDFromContext(r.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if incoming != "" {
+		req.Header.Set("X-Request-ID", incoming)
+	}
+	rec := httptest.NewRecorder()
+	middleware.RequestID(handler).ServeHTTP(rec, req)
+
+	return rec, seen
+}
+
+func TestRequestID_PassesThroughIncomingID(t *testing.T) {
+	rec, seen := serveWithRequestID("upstream-abc-123")
+
+	assert.Equal(t, "upstream-abc-123", seen)
+	assert.Equal(t, "upstream-abc-123", rec.Header().Get("X-Request-ID"))
+}
+
+func TestRequestID_GeneratesWhenMissing(t *testing.T) {
+	rec, seen := serveWithRequestID("")
+
+	assert.Len(t, seen, 32)
+	assert.Equal(t, seen, rec.Header().Get("X-Request-ID"))
+
+	_, other := serveWithRequestID("")
+	assert.NotEqual(t, seen, other, "generated IDs should be unique")
+}
+
+func TestRequestID_ReplacesMalformedIDs(t *testing.T) {
+	for _, incoming := range []string{
+		"has space",
+		"line\nbreak",
+		strings.Repeat("a", 129),
+	} {
+		rec, seen := serveWithRequestID(incoming)
+
+		assert.NotEqual(t, incoming, seen)
+		assert.Len(t, seen, 32)
+		assert.Equal(t, seen, rec.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestRequestIDFromContext_EmptyWithoutMiddleware(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Empty(t, middleware.RequestIDFromContext(req.Context()))
+}
@@ -0,0 +1,43 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-shortener/internal/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout_SetsContextDeadline(t *testing.T) {
+	var deadline time.Time
+	var hasDeadline bool
+	wrapped := middleware.Timeout(50 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, hasDeadline = r.Context().Deadline()
+		<-r.Context().Done()
+		assert.ErrorIs(t, r.Context().Err(), context.DeadlineExceeded)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	start := time.Now()
+	wrapped.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	require.True(t, hasDeadline)
+	assert.WithinDuration(t, start.Add(50*time.Millisecond), deadline, 20*time.Millisecond)
+	assert.Less(t, time.Since(start), time.Second, "handler should be released by the deadline")
+}
+
+func TestTimeout_ZeroDisables(t *testing.T) {
+	var hasDeadline bool
+	wrapped := middleware.Timeout(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline = r.Context().Deadline()
+	}))
+
+	wrapped.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	assert.False(t, hasDeadline)
+}
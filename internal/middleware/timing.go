@@ -9,6 +9,18 @@ import (
 // Timing is a middleware that adds X-Processing-Time-Micros header to all responses.
 // The header value is the time taken to process the request in microseconds.
 func Timing(next http.Handler) http.Handler {
+	return timing(next, nil)
+}
+
+// TimingWithRecorder is Timing that also records each request's total
+// duration into rec.
+func TimingWithRecorder(rec *LatencyRecorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return timing(next, rec)
+	}
+}
+
+func timing(next http.Handler, rec *LatencyRecorder) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
@@ -18,6 +30,10 @@ func Timing(next http.Handler) http.Handler {
 		}
 
 		next.ServeHTTP(wrapped, r)
+
+		if rec != nil {
+			rec.Record(time.Since(start))
+		}
 	})
 }
 
@@ -0,0 +1,15 @@
+// Package qrcode renders QR codes, isolating the third-party encoder from
+// the handler layer.
+package qrcode
+
+import (
+	qr "github.com/skip2/go-qrcode"
+)
+
+// PNGEncoder renders QR codes as PNG images with medium error correction.
+type PNGEncoder struct{}
+
+// Encode returns a size x size PNG QR code holding content.
+func (PNGEncoder) Encode(content string, size int) ([]byte, error) {
+	return qr.Encode(content, qr.Medium, size)
+}
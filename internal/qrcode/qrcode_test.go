@@ -0,0 +1,22 @@
+package qrcode_test
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"url-shortener/internal/qrcode"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPNGEncoder_EncodesPNGOfRequestedSize(t *testing.T) {
+	data, err := qrcode.PNGEncoder{}.Encode("http://localhost:8080/s/abc12345", 256)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 256, img.Bounds().Dx())
+	assert.Equal(t, 256, img.Bounds().Dy())
+}
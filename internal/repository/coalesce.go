@@ -0,0 +1,415 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// Defaults for the NewCoalescingRepository arguments left zero.
+const (
+	defaultCoalesceInterval = time.Second
+	defaultCoalesceShards   = 32
+)
+
+// ClickDelta is the clicks a code received while buffered by a
+// CoalescingRepository, added to its record in one step.
+type ClickDelta struct {
+	Code string
+
+	// Clicks is the number of clicks. ByDay splits them by UTC day, keyed
+	// by domain.DateLayout, and Referrers by referrer; clicks recorded
+	// without a referrer are counted in Clicks and ByDay only.
+	Clicks    int64
+	ByDay     map[string]int64
+	Referrers map[string]int64
+
+	// LastAccessedAt is the time of the latest click.
+	LastAccessedAt time.Time
+}
+
+// ClickBatcher is a Repository that can add many buffered clicks in one
+// locked pass or transaction.
+type ClickBatcher interface {
+	Repository
+
+	// ApplyClickDeltas adds each delta's clicks to its record as that many
+	// RecordClick calls would, except that MaxClicks isn't checked. Deltas
+	// for codes that don't exist or are soft-deleted are skipped.
+	ApplyClickDeltas(ctx context.Context, deltas []ClickDelta) error
+}
+
+// CoalescingRepository wraps a ClickBatcher so that clicks on links without
+// MaxClicks don't take the store's write lock one at a time: they are summed
+// per code in a sharded buffer and applied together by ApplyClickDeltas,
+// every interval and on Flush and Close. Links with MaxClicks are counted
+// directly, so their limits stay exact.
+//
+// To tell which links have MaxClicks, and to check expiry, the record of
+// each clicked code is read once and cached until the next flush. Writes
+// through the CoalescingRepository drop the cached record; changes made to
+// the store by other means are seen after the next flush.
+//
+// Buffered clicks are invisible to reads until flushed, and are lost if the
+// process dies without Close. Other methods pass straight through.
+type CoalescingRepository struct {
+	ClickBatcher
+
+	shards []*clickBuffer
+
+	// flushMu serializes flushes, so deltas reach the store in order.
+	flushMu sync.Mutex
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// clickBuffer holds the pending deltas of the codes hashed to one shard,
+// and their cached records.
+type clickBuffer struct {
+	mu      sync.Mutex
+	deltas  map[string]*ClickDelta
+	records map[string]*domain.URLRecord
+
+	// gen counts the times records were dropped, so a record read from the
+	// store while one was isn't cached.
+	gen uint64
+}
+
+func newClickBuffer() *clickBuffer {
+	return &clickBuffer{
+		deltas:  make(map[string]*ClickDelta),
+		records: make(map[string]*domain.URLRecord),
+	}
+}
+
+// NewCoalescingRepository wraps repo, applying buffered clicks every
+// interval (one second if zero or less). Close must be called so the last
+// clicks aren't lost.
+func NewCoalescingRepository(repo ClickBatcher, interval time.Duration) *CoalescingRepository {
+	if interval <= 0 {
+		interval = defaultCoalesceInterval
+	}
+
+	r := &CoalescingRepository{
+		ClickBatcher: repo,
+		shards:       make([]*clickBuffer, defaultCoalesceShards),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	for i := range r.shards {
+		r.shards[i] = newClickBuffer()
+	}
+
+	go r.flushEvery(interval)
+	return r
+}
+
+func (r *CoalescingRepository) flushEvery(interval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if err := r.Flush(context.Background()); err != nil {
+				slog.Error("applying buffered clicks", "error", err)
+			}
+		}
+	}
+}
+
+// buffer returns the shard buffering code's clicks.
+func (r *CoalescingRepository) buffer(code string) *clickBuffer {
+	return r.shards[fnv32a(code)%uint32(len(r.shards))]
+}
+
+// add buffers a click and returns how many clicks code now has pending.
+func (r *CoalescingRepository) add(code, referrer string, at time.Time) int64 {
+	b := r.buffer(code)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	d, ok := b.deltas[code]
+	if !ok {
+		d = &ClickDelta{Code: code, ByDay: make(map[string]int64, 1)}
+		b.deltas[code] = d
+	}
+	d.Clicks++
+	d.ByDay[at.UTC().Format(domain.DateLayout)]++
+	if referrer != "" {
+		if d.Referrers == nil {
+			d.Referrers = make(map[string]int64, 1)
+		}
+		d.Referrers[referrer]++
+	}
+	if at.After(d.LastAccessedAt) {
+		d.LastAccessedAt = at
+	}
+	return d.Clicks
+}
+
+// requeue puts back deltas that couldn't be applied, merging them with the
+// clicks buffered since.
+func (r *CoalescingRepository) requeue(deltas []ClickDelta) {
+	for _, failed := range deltas {
+		b := r.buffer(failed.Code)
+		b.mu.Lock()
+		if d, ok := b.deltas[failed.Code]; ok {
+			mergeClickDelta(d, failed)
+		} else {
+			b.deltas[failed.Code] = &failed
+		}
+		b.mu.Unlock()
+	}
+}
+
+// record returns code's record, cached since the last flush or read from
+// the wrapped repository. It is shared and must not be modified.
+func (r *CoalescingRepository) record(ctx context.Context, code string) (*domain.URLRecord, error) {
+	b := r.buffer(code)
+	b.mu.Lock()
+	record, ok := b.records[code]
+	gen := b.gen
+	b.mu.Unlock()
+	if ok {
+		return record, nil
+	}
+
+	record, err := r.ClickBatcher.FindByShortCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	if b.gen == gen {
+		b.records[code] = record
+	}
+	b.mu.Unlock()
+	return record, nil
+}
+
+// forget drops the cached records of codes.
+func (r *CoalescingRepository) forget(codes ...string) {
+	for _, code := range codes {
+		b := r.buffer(code)
+		b.mu.Lock()
+		delete(b.records, code)
+		b.gen++
+		b.mu.Unlock()
+	}
+}
+
+// forgetAll drops every cached record.
+func (r *CoalescingRepository) forgetAll() {
+	for _, b := range r.shards {
+		b.mu.Lock()
+		clear(b.records)
+		b.gen++
+		b.mu.Unlock()
+	}
+}
+
+// SaveIfNotExists saves the record and drops any clicks still buffered for
+// an earlier record under the same code, so they aren't counted for it.
+func (r *CoalescingRepository) SaveIfNotExists(ctx context.Context, record *domain.URLRecord) error {
+	if err := r.ClickBatcher.SaveIfNotExists(ctx, record); err != nil {
+		return err
+	}
+
+	b := r.buffer(record.ShortCode)
+	b.mu.Lock()
+	delete(b.deltas, record.ShortCode)
+	delete(b.records, record.ShortCode)
+	b.gen++
+	b.mu.Unlock()
+	return nil
+}
+
+// UpdateExpiry passes through and drops the cached record.
+func (r *CoalescingRepository) UpdateExpiry(ctx context.Context, code string, expiresAt time.Time) error {
+	defer r.forget(code)
+	return r.ClickBatcher.UpdateExpiry(ctx, code, expiresAt)
+}
+
+// SetEnabled passes through and drops the cached record.
+func (r *CoalescingRepository) SetEnabled(ctx context.Context, code string, enabled bool) error {
+	defer r.forget(code)
+	return r.ClickBatcher.SetEnabled(ctx, code, enabled)
+}
+
+// AppendHistory passes through and drops the cached record.
+func (r *CoalescingRepository) AppendHistory(ctx context.Context, code string, event domain.HistoryEvent) error {
+	defer r.forget(code)
+	return r.ClickBatcher.AppendHistory(ctx, code, event)
+}
+
+// SoftDelete passes through and drops the cached record.
+func (r *CoalescingRepository) SoftDelete(ctx context.Context, code string, deletedAt time.Time) error {
+	defer r.forget(code)
+	return r.ClickBatcher.SoftDelete(ctx, code, deletedAt)
+}
+
+// Restore passes through and drops the cached record.
+func (r *CoalescingRepository) Restore(ctx context.Context, code string, deletedSince time.Time) error {
+	defer r.forget(code)
+	return r.ClickBatcher.Restore(ctx, code, deletedSince)
+}
+
+// SoftDeleteByPrefix passes through and drops the cached records.
+func (r *CoalescingRepository) SoftDeleteByPrefix(ctx context.Context, prefix string, deletedAt time.Time) ([]string, error) {
+	defer r.forgetAll()
+	return r.ClickBatcher.SoftDeleteByPrefix(ctx, prefix, deletedAt)
+}
+
+// PurgeDeleted passes through and drops the cached records.
+func (r *CoalescingRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	defer r.forgetAll()
+	return r.ClickBatcher.PurgeDeleted(ctx, deletedBefore)
+}
+
+// DeleteExpired passes through and drops the cached records.
+func (r *CoalescingRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	defer r.forgetAll()
+	return r.ClickBatcher.DeleteExpired(ctx, before)
+}
+
+// DeleteExpiredCodes passes through and drops the cached records.
+func (r *CoalescingRepository) DeleteExpiredCodes(ctx context.Context, before time.Time) ([]string, error) {
+	defer r.forgetAll()
+	return r.ClickBatcher.DeleteExpiredCodes(ctx, before)
+}
+
+// IncrementClickCount counts a click like RecordClick without a referrer.
+func (r *CoalescingRepository) IncrementClickCount(ctx context.Context, code string, accessTime time.Time) error {
+	return r.RecordClick(ctx, code, "", accessTime)
+}
+
+// RecordClick buffers the click, or records it directly if the record has
+// MaxClicks. Returns domain.ErrNotFound if the code doesn't exist.
+func (r *CoalescingRepository) RecordClick(ctx context.Context, code, referrer string, accessTime time.Time) error {
+	record, err := r.record(ctx, code)
+	if err != nil {
+		return err
+	}
+	if record.MaxClicks > 0 {
+		return r.ClickBatcher.RecordClick(ctx, code, referrer, accessTime)
+	}
+
+	r.add(code, referrer, accessTime)
+	return nil
+}
+
+// ResolveAndIncrement checks the record like the wrapped repository and
+// buffers the click, or passes through if the record has MaxClicks. The
+// returned ClickCount and LastAccessedAt include the clicks buffered so
+// far; ClicksByDay and Referrers catch up on the next flush. Its maps and
+// slices are shared with the cached record and must not be modified.
+func (r *CoalescingRepository) ResolveAndIncrement(ctx context.Context, code, referrer string, now time.Time) (*domain.URLRecord, error) {
+	record, err := r.record(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if record.MaxClicks > 0 {
+		return r.ClickBatcher.ResolveAndIncrement(ctx, code, referrer, now)
+	}
+	if record.IsExpired(now) {
+		return nil, domain.ErrExpired
+	}
+	if !record.Enabled {
+		return nil, domain.ErrDisabled
+	}
+
+	counted := *record
+	counted.ClickCount += r.add(code, referrer, now)
+	counted.LastAccessedAt = now
+	return &counted, nil
+}
+
+// Flush applies the clicks buffered so far in one ApplyClickDeltas call,
+// and drops the cached records. If it fails, the clicks are put back to be
+// applied by the next flush.
+func (r *CoalescingRepository) Flush(ctx context.Context) error {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+
+	var deltas []ClickDelta
+	for _, b := range r.shards {
+		b.mu.Lock()
+		pending := b.deltas
+		if len(pending) > 0 {
+			b.deltas = make(map[string]*ClickDelta, len(pending))
+		}
+		b.mu.Unlock()
+
+		for _, d := range pending {
+			deltas = append(deltas, *d)
+		}
+	}
+	// Records cached before the deltas are applied lack their clicks.
+	defer r.forgetAll()
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	if err := r.ClickBatcher.ApplyClickDeltas(ctx, deltas); err != nil {
+		r.requeue(deltas)
+		return fmt.Errorf("applying %d click deltas: %w", len(deltas), err)
+	}
+	return nil
+}
+
+// Close stops the periodic flush and applies the remaining clicks. Clicks
+// recorded afterwards stay buffered until Flush is called. It does not
+// close the wrapped repository.
+func (r *CoalescingRepository) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		<-r.done
+	})
+	return r.Flush(context.Background())
+}
+
+// mergeClickDelta adds other's clicks to d.
+func mergeClickDelta(d *ClickDelta, other ClickDelta) {
+	d.Clicks += other.Clicks
+	for day, n := range other.ByDay {
+		d.ByDay[day] += n
+	}
+	for referrer, n := range other.Referrers {
+		if d.Referrers == nil {
+			d.Referrers = make(map[string]int64, len(other.Referrers))
+		}
+		d.Referrers[referrer] += n
+	}
+	if other.LastAccessedAt.After(d.LastAccessedAt) {
+		d.LastAccessedAt = other.LastAccessedAt
+	}
+}
+
+// applyClickDelta adds d's clicks to record. Referrers are added in sorted
+// order, so which ones fall into domain.ReferrerOther doesn't depend on map
+// iteration.
+func applyClickDelta(record *domain.URLRecord, d ClickDelta) {
+	record.ClickCount += d.Clicks
+	if d.LastAccessedAt.After(record.LastAccessedAt) {
+		record.LastAccessedAt = d.LastAccessedAt
+	}
+	for day, n := range d.ByDay {
+		record.ClicksByDay = domain.AddDailyClicks(record.ClicksByDay, day, n)
+	}
+	for _, referrer := range slices.Sorted(maps.Keys(d.Referrers)) {
+		record.Referrers = domain.AddReferrerClicks(record.Referrers, referrer, d.Referrers[referrer])
+	}
+}
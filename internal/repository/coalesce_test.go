@@ -0,0 +1,358 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRepository counts the click writes reaching a MemoryRepository,
+// each of which takes its write lock once, and the record reads. While
+// failApply is set, ApplyClickDeltas fails.
+type countingRepository struct {
+	*repository.MemoryRepository
+	writes    atomic.Int64
+	reads     atomic.Int64
+	failApply atomic.Bool
+}
+
+func (r *countingRepository) FindByShortCode(ctx context.Context, code string) (*domain.URLRecord, error) {
+	r.reads.Add(1)
+	return r.MemoryRepository.FindByShortCode(ctx, code)
+}
+
+func (r *countingRepository) RecordClick(ctx context.Context, code, referrer string, accessTime time.Time) error {
+	r.writes.Add(1)
+	return r.MemoryRepository.RecordClick(ctx, code, referrer, accessTime)
+}
+
+func (r *countingRepository) ResolveAndIncrement(ctx context.Context, code, referrer string, now time.Time) (*domain.URLRecord, error) {
+	r.writes.Add(1)
+	return r.MemoryRepository.ResolveAndIncrement(ctx, code, referrer, now)
+}
+
+func (r *countingRepository) ApplyClickDeltas(ctx context.Context, deltas []repository.ClickDelta) error {
+	r.writes.Add(1)
+	if r.failApply.Load() {
+		return errors.New("store unavailable")
+	}
+	return r.MemoryRepository.ApplyClickDeltas(ctx, deltas)
+}
+
+func saveClickTestRecord(t testing.TB, repo repository.Repository, code string, maxClicks int64) {
+	t.Helper()
+	require.NoError(t, repo.SaveIfNotExists(context.Background(), &domain.URLRecord{
+		ShortCode: code,
+		LongURL:   "https://example.com/" + code,
+		MaxClicks: maxClicks,
+		Enabled:   true,
+	}))
+}
+
+func TestCoalescingRepository_CountsAreExactAfterFlush(t *testing.T) {
+	ctx := context.Background()
+	day1 := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+
+	inner := &countingRepository{MemoryRepository: repository.NewMemoryRepository()}
+	codes := []string{"code0001", "code0002", "code0003"}
+	for _, code := range codes {
+		saveClickTestRecord(t, inner, code, 0)
+	}
+
+	// A short interval makes periodic flushes race with the clicks.
+	repo := repository.NewCoalescingRepository(inner, time.Millisecond)
+
+	const goroutines, clicks = 20, 150
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range clicks {
+				code := codes[(g+i)%len(codes)]
+				at := day1
+				if i%2 == 1 {
+					at = day2
+				}
+				if i%3 == 0 {
+					assert.NoError(t, repo.RecordClick(ctx, code, "news.example", at))
+					continue
+				}
+				_, err := repo.ResolveAndIncrement(ctx, code, "", at)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, repo.Close())
+
+	var total, byDay, fromNews int64
+	for _, code := range codes {
+		record, err := inner.FindByShortCode(ctx, code)
+		require.NoError(t, err)
+		total += record.ClickCount
+		for _, n := range record.ClicksByDay {
+			byDay += n
+		}
+		fromNews += record.Referrers["news.example"]
+		assert.Equal(t, day2, record.LastAccessedAt)
+	}
+
+	assert.EqualValues(t, goroutines*clicks, total)
+	assert.Equal(t, total, byDay)
+	assert.EqualValues(t, goroutines*clicks/3, fromNews)
+	assert.Less(t, inner.writes.Load(), int64(goroutines*clicks), "clicks should have been applied in batches")
+}
+
+func TestCoalescingRepository_ReadsSeeClicksOnceFlushed(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	inner := repository.NewMemoryRepository()
+	saveClickTestRecord(t, inner, "abc12345", 0)
+	repo := repository.NewCoalescingRepository(inner, time.Hour)
+	defer repo.Close()
+
+	for i := range 3 {
+		record, err := repo.ResolveAndIncrement(ctx, "abc12345", "", now)
+		require.NoError(t, err)
+		assert.EqualValues(t, i+1, record.ClickCount, "returned record should include buffered clicks")
+	}
+
+	record, err := repo.FindByShortCode(ctx, "abc12345")
+	require.NoError(t, err)
+	assert.Zero(t, record.ClickCount)
+
+	require.NoError(t, repo.Flush(ctx))
+	record, err = repo.FindByShortCode(ctx, "abc12345")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, record.ClickCount)
+	assert.Equal(t, map[string]int64{"2024-01-15": 3}, record.ClicksByDay)
+}
+
+func TestCoalescingRepository_ReadsRecordOncePerFlush(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	inner := &countingRepository{MemoryRepository: repository.NewMemoryRepository()}
+	saveClickTestRecord(t, inner, "abc12345", 0)
+	repo := repository.NewCoalescingRepository(inner, time.Hour)
+	defer repo.Close()
+
+	for range 5 {
+		_, err := repo.ResolveAndIncrement(ctx, "abc12345", "", now)
+		require.NoError(t, err)
+		require.NoError(t, repo.RecordClick(ctx, "abc12345", "", now))
+	}
+	assert.EqualValues(t, 1, inner.reads.Load(), "the record should be read once until the next flush")
+
+	require.NoError(t, repo.SetEnabled(ctx, "abc12345", false))
+	_, err := repo.ResolveAndIncrement(ctx, "abc12345", "", now)
+	assert.ErrorIs(t, err, domain.ErrDisabled, "writes should drop the cached record")
+
+	require.NoError(t, repo.SetEnabled(ctx, "abc12345", true))
+	require.NoError(t, repo.Flush(ctx))
+	record, err := repo.ResolveAndIncrement(ctx, "abc12345", "", now)
+	require.NoError(t, err)
+	assert.EqualValues(t, 11, record.ClickCount, "flushed clicks should show after the cached record is dropped")
+}
+
+func TestCoalescingRepository_FailedFlushKeepsClicks(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	inner := &countingRepository{MemoryRepository: repository.NewMemoryRepository()}
+	saveClickTestRecord(t, inner, "abc12345", 0)
+	repo := repository.NewCoalescingRepository(inner, time.Hour)
+	defer repo.Close()
+
+	require.NoError(t, repo.RecordClick(ctx, "abc12345", "news.example", now))
+	require.NoError(t, repo.RecordClick(ctx, "abc12345", "", now))
+	inner.failApply.Store(true)
+	require.Error(t, repo.Flush(ctx))
+
+	require.NoError(t, repo.RecordClick(ctx, "abc12345", "news.example", now.Add(time.Minute)))
+	inner.failApply.Store(false)
+	require.NoError(t, repo.Flush(ctx))
+
+	record, err := inner.FindByShortCode(ctx, "abc12345")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, record.ClickCount)
+	assert.Equal(t, map[string]int64{"2024-01-15": 3}, record.ClicksByDay)
+	assert.Equal(t, map[string]int64{"news.example": 2}, record.Referrers)
+	assert.True(t, now.Add(time.Minute).Equal(record.LastAccessedAt))
+}
+
+func TestCoalescingRepository_ClickLimitStaysExact(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	inner := repository.NewMemoryRepository()
+	saveClickTestRecord(t, inner, "limited1", 5)
+	repo := repository.NewCoalescingRepository(inner, time.Hour)
+	defer repo.Close()
+
+	var wg sync.WaitGroup
+	var counted atomic.Int64
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.ResolveAndIncrement(ctx, "limited1", "", now); err == nil {
+				counted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 5, counted.Load())
+	record, err := repo.FindByShortCode(ctx, "limited1")
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, record.ClickCount, "limited links should be counted without buffering")
+	assert.ErrorIs(t, repo.IncrementClickCount(ctx, "limited1", now), domain.ErrClickLimitReached)
+}
+
+func TestCoalescingRepository_Errors(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	inner := repository.NewMemoryRepository()
+	require.NoError(t, inner.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "expired1", ExpiresAt: now.Add(-time.Minute), Enabled: true}))
+	require.NoError(t, inner.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "disabled", Enabled: false}))
+	repo := repository.NewCoalescingRepository(inner, time.Hour)
+	defer repo.Close()
+
+	_, err := repo.ResolveAndIncrement(ctx, "missing1", "", now)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = repo.ResolveAndIncrement(ctx, "expired1", "", now)
+	assert.ErrorIs(t, err, domain.ErrExpired)
+	_, err = repo.ResolveAndIncrement(ctx, "disabled", "", now)
+	assert.ErrorIs(t, err, domain.ErrDisabled)
+	assert.ErrorIs(t, repo.RecordClick(ctx, "missing1", "", now), domain.ErrNotFound)
+
+	require.NoError(t, repo.Flush(ctx))
+	for _, code := range []string{"expired1", "disabled"} {
+		record, err := inner.FindByShortCode(ctx, code)
+		require.NoError(t, err)
+		assert.Zero(t, record.ClickCount, code)
+	}
+}
+
+func TestCoalescingRepository_RecreatedCodeStartsAtZero(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	inner := repository.NewMemoryRepository()
+	repo := repository.NewCoalescingRepository(inner, time.Hour)
+	defer repo.Close()
+
+	require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "reused01", ExpiresAt: now.Add(time.Minute), Enabled: true}))
+	_, err := repo.ResolveAndIncrement(ctx, "reused01", "", now)
+	require.NoError(t, err)
+
+	_, err = repo.DeleteExpired(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "reused01", Enabled: true}))
+
+	require.NoError(t, repo.Flush(ctx))
+	record, err := repo.FindByShortCode(ctx, "reused01")
+	require.NoError(t, err)
+	assert.Zero(t, record.ClickCount, "clicks on the deleted record must not carry over")
+}
+
+func TestApplyClickDeltas(t *testing.T) {
+	ctx := context.Background()
+	last := time.Date(2024, 1, 16, 8, 0, 0, 0, time.UTC)
+
+	backends := map[string]func(t *testing.T) repository.ClickBatcher{
+		"memory":  func(*testing.T) repository.ClickBatcher { return repository.NewMemoryRepository() },
+		"sharded": func(*testing.T) repository.ClickBatcher { return repository.NewShardedMemoryRepository(4, 0) },
+		"sqlite":  func(t *testing.T) repository.ClickBatcher { return newSQLiteRepository(t) },
+	}
+	for name, newRepo := range backends {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo(t)
+			saveClickTestRecord(t, repo, "code0001", 0)
+			saveClickTestRecord(t, repo, "code0002", 0)
+			require.NoError(t, repo.RecordClick(ctx, "code0001", "news.example", last.Add(-48*time.Hour)))
+
+			require.NoError(t, repo.ApplyClickDeltas(ctx, []repository.ClickDelta{
+				{
+					Code:           "code0001",
+					Clicks:         5,
+					ByDay:          map[string]int64{"2024-01-15": 2, "2024-01-16": 3},
+					Referrers:      map[string]int64{"news.example": 1, "blog.example": 2},
+					LastAccessedAt: last,
+				},
+				{Code: "code0002", Clicks: 1, ByDay: map[string]int64{"2024-01-16": 1}, LastAccessedAt: last},
+				{Code: "missing1", Clicks: 7, ByDay: map[string]int64{"2024-01-16": 7}, LastAccessedAt: last},
+			}))
+
+			record, err := repo.FindByShortCode(ctx, "code0001")
+			require.NoError(t, err)
+			assert.EqualValues(t, 6, record.ClickCount)
+			assert.Equal(t, map[string]int64{"2024-01-14": 1, "2024-01-15": 2, "2024-01-16": 3}, record.ClicksByDay)
+			assert.Equal(t, map[string]int64{"news.example": 2, "blog.example": 2}, record.Referrers)
+			assert.True(t, last.Equal(record.LastAccessedAt))
+
+			record, err = repo.FindByShortCode(ctx, "code0002")
+			require.NoError(t, err)
+			assert.EqualValues(t, 1, record.ClickCount)
+
+			_, err = repo.FindByShortCode(ctx, "missing1")
+			assert.ErrorIs(t, err, domain.ErrNotFound, "deltas for missing codes should be skipped")
+		})
+	}
+}
+
+// BenchmarkCoalescingRepository_Parallel resolves links from concurrent
+// goroutines directly and through a CoalescingRepository, reporting the
+// click writes, and so write-lock acquisitions, per resolve.
+func BenchmarkCoalescingRepository_Parallel(b *testing.B) {
+	const seeded = 64
+
+	for _, coalesce := range []bool{false, true} {
+		b.Run(fmt.Sprintf("coalesce=%t", coalesce), func(b *testing.B) {
+			ctx := context.Background()
+			now := time.Now()
+
+			inner := &countingRepository{MemoryRepository: repository.NewMemoryRepository()}
+			for i := range seeded {
+				saveClickTestRecord(b, inner, fmt.Sprintf("seed%04d", i), 0)
+			}
+
+			var repo repository.Repository = inner
+			var coalesced *repository.CoalescingRepository
+			if coalesce {
+				coalesced = repository.NewCoalescingRepository(inner, 10*time.Millisecond)
+				repo = coalesced
+			}
+
+			var next atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					code := fmt.Sprintf("seed%04d", next.Add(1)%seeded)
+					_, _ = repo.ResolveAndIncrement(ctx, code, "", now)
+				}
+			})
+			if coalesced != nil {
+				require.NoError(b, coalesced.Close())
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(inner.writes.Load())/float64(b.N), "writes/op")
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Storage backends accepted in Config.Storage.
+const (
+	StorageMemory = "memory"
+	StorageFile   = "file"
+	StorageSQLite = "sqlite"
+)
+
+// Eviction policies accepted in Config.MemoryEviction.
+const (
+	EvictionReject = "reject"
+	EvictionLRU    = "lru"
+)
+
+// Defaults for the Config fields left zero.
+const (
+	defaultDataFile          = "url-shortener.json"
+	defaultDataFlushInterval = 30 * time.Second
+	defaultDBPath            = "url-shortener.db"
+)
+
+// ErrUnknownStorage is returned by NewFromConfig for an unrecognized
+// Config.Storage.
+var ErrUnknownStorage = errors.New("unknown storage backend")
+
+// Config selects and configures a storage backend for NewFromConfig.
+type Config struct {
+	// Storage names the backend: StorageMemory (the default), StorageFile,
+	// or StorageSQLite.
+	Storage string
+
+	// MemoryMaxRecords caps the records held in memory; zero is unbounded.
+	// MemoryShards, when positive, uses a ShardedMemoryRepository with
+	// that many shards. MemoryEviction decides what a full store does:
+	// EvictionReject (the default) refuses new records, EvictionLRU drops
+	// the least recently used one; see NewLRUMemoryRepository. LRU
+	// eviction can't be combined with shards. All apply to StorageMemory
+	// only.
+	MemoryMaxRecords int
+	MemoryShards     int
+	MemoryEviction   string
+
+	// DataFile and DataFlushInterval configure StorageFile. They default
+	// to "url-shortener.json" and 30s when empty and zero; a negative
+	// DataFlushInterval is an error.
+	DataFile          string
+	DataFlushInterval time.Duration
+
+	// DBPath is the StorageSQLite database file, "url-shortener.db" by
+	// default.
+	DBPath string
+
+	// ClickCoalesceInterval, when positive, wraps the backend in a
+	// CoalescingRepository that applies buffered clicks this often.
+	ClickCoalesceInterval time.Duration
+}
+
+// NewFromConfig creates the repository cfg selects. The returned close
+// func releases whatever the backend holds, such as flushing the data file
+// or closing the database, and must be called once the repository is no
+// longer used.
+func NewFromConfig(cfg Config) (Repository, func() error, error) {
+	repo, closeRepo, err := openBackend(cfg)
+	if err != nil || cfg.ClickCoalesceInterval <= 0 {
+		return repo, closeRepo, err
+	}
+
+	coalesced := NewCoalescingRepository(repo, cfg.ClickCoalesceInterval)
+	return coalesced, func() error {
+		// Apply the last clicks before the backend goes away.
+		return errors.Join(coalesced.Close(), closeRepo())
+	}, nil
+}
+
+// openBackend creates the storage backend cfg selects.
+func openBackend(cfg Config) (ClickBatcher, func() error, error) {
+	switch cfg.Storage {
+	case "", StorageMemory:
+		switch cfg.MemoryEviction {
+		case "", EvictionReject:
+		case EvictionLRU:
+			if cfg.MemoryShards > 0 {
+				return nil, nil, errors.New("LRU eviction is not supported for sharded memory storage")
+			}
+			return NewLRUMemoryRepository(cfg.MemoryMaxRecords), noClose, nil
+		default:
+			return nil, nil, fmt.Errorf("unknown memory eviction policy %q", cfg.MemoryEviction)
+		}
+		if cfg.MemoryShards > 0 {
+			return NewShardedMemoryRepository(cfg.MemoryShards, cfg.MemoryMaxRecords), noClose, nil
+		}
+		return NewMemoryRepositoryWithCapacity(cfg.MemoryMaxRecords), noClose, nil
+	case StorageFile:
+		path := cmp.Or(cfg.DataFile, defaultDataFile)
+		if cfg.DataFlushInterval < 0 {
+			return nil, nil, fmt.Errorf("data flush interval must not be negative, got %v", cfg.DataFlushInterval)
+		}
+		interval := cmp.Or(cfg.DataFlushInterval, defaultDataFlushInterval)
+		repo, err := NewFileRepository(path, interval)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening data file %q: %w", path, err)
+		}
+		return repo, repo.Close, nil
+	case StorageSQLite:
+		path := cmp.Or(cfg.DBPath, defaultDBPath)
+		repo, err := NewSQLiteRepository(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening sqlite database %q: %w", path, err)
+		}
+		return repo, repo.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("%w %q", ErrUnknownStorage, cfg.Storage)
+	}
+}
+
+func noClose() error { return nil }
@@ -0,0 +1,105 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFromConfig_SelectsBackend(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name string
+		cfg  repository.Config
+		want repository.Repository
+	}{
+		{name: "default is memory", cfg: repository.Config{}, want: &repository.MemoryRepository{}},
+		{name: "memory", cfg: repository.Config{Storage: "memory"}, want: &repository.MemoryRepository{}},
+		{name: "sharded memory", cfg: repository.Config{Storage: "memory", MemoryShards: 8}, want: &repository.ShardedMemoryRepository{}},
+		{name: "lru memory", cfg: repository.Config{Storage: "memory", MemoryMaxRecords: 10, MemoryEviction: "lru"}, want: &repository.MemoryRepository{}},
+		{name: "file", cfg: repository.Config{Storage: "file", DataFile: filepath.Join(dir, "data.json")}, want: &repository.FileRepository{}},
+		{name: "sqlite", cfg: repository.Config{Storage: "sqlite", DBPath: filepath.Join(dir, "test.db")}, want: &repository.SQLiteRepository{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, closeRepo, err := repository.NewFromConfig(tt.cfg)
+			require.NoError(t, err)
+
+			assert.IsType(t, tt.want, repo)
+			assert.NoError(t, closeRepo())
+		})
+	}
+}
+
+func TestNewFromConfig_UnknownBackend(t *testing.T) {
+	repo, closeRepo, err := repository.NewFromConfig(repository.Config{Storage: "redis"})
+
+	assert.ErrorIs(t, err, repository.ErrUnknownStorage)
+	assert.Contains(t, err.Error(), `"redis"`)
+	assert.Nil(t, repo)
+	assert.Nil(t, closeRepo)
+}
+
+func TestNewFromConfig_InvalidEviction(t *testing.T) {
+	for _, cfg := range []repository.Config{
+		{MemoryEviction: "fifo"},
+		{MemoryEviction: "lru", MemoryShards: 4, MemoryMaxRecords: 10},
+	} {
+		repo, _, err := repository.NewFromConfig(cfg)
+		assert.Error(t, err)
+		assert.Nil(t, repo)
+	}
+}
+
+func TestNewFromConfig_NegativeDataFlushInterval(t *testing.T) {
+	repo, _, err := repository.NewFromConfig(repository.Config{
+		Storage:           "file",
+		DataFile:          filepath.Join(t.TempDir(), "data.json"),
+		DataFlushInterval: -time.Second,
+	})
+
+	assert.ErrorContains(t, err, "must not be negative")
+	assert.Nil(t, repo)
+}
+
+func TestNewFromConfig_ReportsOpenErrors(t *testing.T) {
+	_, _, err := repository.NewFromConfig(repository.Config{
+		Storage: "sqlite",
+		DBPath:  filepath.Join(t.TempDir(), "missing", "dir", "test.db"),
+	})
+
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, repository.ErrUnknownStorage)
+}
+
+func TestNewFromConfig_ClickCoalescing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	repo, closeRepo, err := repository.NewFromConfig(repository.Config{
+		Storage:               "file",
+		DataFile:              path,
+		ClickCoalesceInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	assert.IsType(t, &repository.CoalescingRepository{}, repo)
+
+	ctx := context.Background()
+	require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345", Enabled: true}))
+	require.NoError(t, repo.IncrementClickCount(ctx, "abc12345", time.Now()))
+	require.NoError(t, closeRepo())
+
+	reopened, err := repository.NewFileRepository(path, time.Hour)
+	require.NoError(t, err)
+	defer reopened.Close()
+	record, err := reopened.FindByShortCode(ctx, "abc12345")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, record.ClickCount, "buffered clicks should be applied before the backend closes")
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// fileFormatVersion is written to data files so the format can evolve.
+const fileFormatVersion = 1
+
+type fileData struct {
+	Version int                 `json:"version"`
+	Records []*domain.URLRecord `json:"records"`
+}
+
+// FileRepository keeps records in memory like MemoryRepository and persists
+// them to a JSON file: periodically, and once more on Close. Writes made
+// since the last flush are lost if the process dies without Close.
+type FileRepository struct {
+	*MemoryRepository
+
+	path string
+
+	// flushMu serializes writers of the data file.
+	flushMu sync.Mutex
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewFileRepository loads records from path and flushes them back every
+// interval. A missing file starts an empty store. A file that can't be
+// loaded, e.g. unreadable, corrupt, or of an unsupported version, is moved
+// aside to path+".corrupt-<timestamp>" with a logged warning, so the next
+// flush can't overwrite the only copy, and the store starts empty; if it
+// can't be moved, NewFileRepository fails instead.
+func NewFileRepository(path string, interval time.Duration) (*FileRepository, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("flush interval must be positive, got %v", interval)
+	}
+
+	r := &FileRepository{
+		MemoryRepository: NewMemoryRepository(),
+		path:             path,
+		stop:             make(chan struct{}),
+		done:             make(chan struct{}),
+	}
+
+	if err := r.load(); errors.Is(err, fs.ErrNotExist) {
+		slog.Info("starting with empty storage", "path", path)
+	} else if err != nil {
+		aside := fmt.Sprintf("%s.corrupt-%s", path, time.Now().UTC().Format("20060102T150405Z"))
+		if renameErr := os.Rename(path, aside); renameErr != nil {
+			return nil, fmt.Errorf("%w; moving the data file aside: %w", err, renameErr)
+		}
+		slog.Warn("starting with empty storage, moved unloadable data file aside",
+			"path", path, "moved_to", aside, "error", err)
+	}
+
+	go r.flushEvery(interval)
+	return r, nil
+}
+
+func (r *FileRepository) load() error {
+	raw, err := os.ReadFile(r.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("data file does not exist yet: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("reading data file: %w", err)
+	}
+
+	var data fileData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("decoding data file: %w", err)
+	}
+	if data.Version != fileFormatVersion {
+		return fmt.Errorf("unsupported data file version %d", data.Version)
+	}
+
+	// Restore into a fresh store, so a failure partway leaves r empty.
+	restored := NewMemoryRepository()
+	ctx := context.Background()
+	for _, record := range data.Records {
+		if err := restored.SaveIfNotExists(ctx, record); err != nil {
+			return fmt.Errorf("restoring record %q: %w", record.ShortCode, err)
+		}
+	}
+
+	r.MemoryRepository = restored
+	return nil
+}
+
+func (r *FileRepository) flushEvery(interval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if err := r.Flush(); err != nil {
+				slog.Error("flushing data file", "path", r.path, "error", err)
+			}
+		}
+	}
+}
+
+// Flush writes all records to the data file. The file is replaced
+// atomically, so a crash mid-write leaves the previous version intact.
+func (r *FileRepository) Flush() error {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+
+	encoded, err := json.Marshal(fileData{
+		Version: fileFormatVersion,
+		Records: r.snapshot(),
+	})
+	if err != nil {
+		return fmt.Errorf("encoding records: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(encoded); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("syncing temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing temp file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("replacing data file: %w", err)
+	}
+	return nil
+}
+
+// Close stops periodic flushing and writes a final snapshot.
+func (r *FileRepository) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		<-r.done
+		err = r.Flush()
+	})
+	return err
+}
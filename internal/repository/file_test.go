@@ -0,0 +1,154 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileRepository_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.json")
+	ctx := context.Background()
+
+	repo, err := repository.NewFileRepository(path, time.Hour)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{
+		ShortCode:         "abc12345",
+		LongURL:           "https://example.com",
+		CreatedAt:         now,
+		ExpiresAt:         now.Add(time.Hour),
+		TTL:               time.Hour,
+		MaxClicks:         10,
+		RedirectPermanent: true,
+		Enabled:           true,
+		History: []domain.HistoryEvent{{
+			Type: domain.EventCreated, At: now, Actor: "anonymous",
+			Details: map[string]string{"ttl_seconds": "3600"},
+		}},
+	}))
+	require.NoError(t, repo.IncrementClickCount(ctx, "abc12345", now.Add(time.Minute)))
+	require.NoError(t, repo.Close())
+
+	reopened, err := repository.NewFileRepository(path, time.Hour)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = reopened.Close() })
+
+	found, err := reopened.FindByShortCode(ctx, "abc12345")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", found.LongURL)
+	assert.True(t, found.ExpiresAt.Equal(now.Add(time.Hour)))
+	assert.Equal(t, time.Hour, found.TTL)
+	assert.Equal(t, int64(1), found.ClickCount)
+	assert.True(t, found.LastAccessedAt.Equal(now.Add(time.Minute)))
+	assert.Equal(t, int64(10), found.MaxClicks)
+	assert.True(t, found.RedirectPermanent)
+	assert.True(t, found.Enabled)
+	require.Len(t, found.History, 1)
+	assert.Equal(t, "3600", found.History[0].Details["ttl_seconds"])
+
+	// The long URL index is rebuilt on load
+	byURL, err := reopened.FindByLongURL(ctx, "https://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "abc12345", byURL.ShortCode)
+}
+
+func TestFileRepository_FlushesPeriodically(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.json")
+
+	repo, err := repository.NewFileRepository(path, 10*time.Millisecond)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = repo.Close() })
+
+	require.NoError(t, repo.SaveIfNotExists(context.Background(), &domain.URLRecord{ShortCode: "abc12345"}))
+
+	assert.Eventually(t, func() bool {
+		raw, err := os.ReadFile(path)
+		return err == nil && strings.Contains(string(raw), "abc12345")
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestFileRepository_MissingOrCorruptFileStartsEmpty(t *testing.T) {
+	dir := t.TempDir()
+	corrupt := filepath.Join(dir, "corrupt.json")
+	require.NoError(t, os.WriteFile(corrupt, []byte("{not json"), 0o600))
+	newer := filepath.Join(dir, "newer.json")
+	require.NoError(t, os.WriteFile(newer, []byte(`{"version": 99, "records": [{"short_code": "abc12345"}]}`), 0o600))
+
+	for _, path := range []string{filepath.Join(dir, "missing.json"), corrupt, newer} {
+		repo, err := repository.NewFileRepository(path, time.Hour)
+		require.NoError(t, err)
+
+		_, total, err := repo.List(context.Background(), 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 0, total)
+
+		require.NoError(t, repo.Close())
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"version": 1, "records": []}`, string(raw))
+	}
+
+	// Bad files are kept aside rather than overwritten by the flush.
+	for path, want := range map[string]string{corrupt: "{not json", newer: `"version": 99`} {
+		aside, err := filepath.Glob(path + ".corrupt-*")
+		require.NoError(t, err)
+		require.Len(t, aside, 1, path)
+		raw, err := os.ReadFile(aside[0])
+		require.NoError(t, err)
+		assert.Contains(t, string(raw), want)
+	}
+}
+
+func TestFileRepository_ConcurrentAccessWhileFlushing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.json")
+	ctx := context.Background()
+
+	repo, err := repository.NewFileRepository(path, time.Millisecond)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345"}))
+
+	const numGoroutines = 20
+	const incrementsPerGoroutine = 50
+
+	var wg sync.WaitGroup
+	wg.Add(numGoroutines + 1)
+
+	for i := 0; i < numGoroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < incrementsPerGoroutine; j++ {
+				assert.NoError(t, repo.IncrementClickCount(ctx, "abc12345", time.Now()))
+			}
+		}()
+	}
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 20; j++ {
+			assert.NoError(t, repo.Flush())
+		}
+	}()
+
+	wg.Wait()
+	require.NoError(t, repo.Close())
+
+	reopened, err := repository.NewFileRepository(path, time.Hour)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = reopened.Close() })
+
+	found, err := reopened.FindByShortCode(ctx, "abc12345")
+	require.NoError(t, err)
+	assert.Equal(t, int64(numGoroutines*incrementsPerGoroutine), found.ClickCount)
+}
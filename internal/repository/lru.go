@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"container/list"
+	"sync"
+)
+
+// NewLRUMemoryRepository creates an in-memory repository holding at most
+// capacity records that, once full, makes room for a new record by evicting
+// the least recently used one instead of failing with
+// domain.ErrCapacityExceeded. Saving, FindByShortCode, and counting a click
+// (which sets LastAccessedAt) mark a record used. A capacity of zero or
+// less means unbounded, so nothing is ever evicted.
+func NewLRUMemoryRepository(capacity int) *MemoryRepository {
+	r := NewMemoryRepositoryWithCapacity(capacity)
+	if r.capacity > 0 {
+		r.recency = newRecencyList()
+	}
+	return r
+}
+
+// recencyList orders short codes from most to least recently used. It has
+// its own lock so lookups holding only MemoryRepository's read lock can
+// still mark a code used; callers take MemoryRepository.mu first.
+type recencyList struct {
+	mu    sync.Mutex
+	order *list.List
+	elems map[string]*list.Element
+}
+
+func newRecencyList() *recencyList {
+	return &recencyList{
+		order: list.New(),
+		elems: make(map[string]*list.Element),
+	}
+}
+
+// touch marks code as the most recently used, adding it if needed.
+func (l *recencyList) touch(code string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.elems[code]; ok {
+		l.order.MoveToFront(elem)
+		return
+	}
+	l.elems[code] = l.order.PushFront(code)
+}
+
+// remove forgets code.
+func (l *recencyList) remove(code string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.elems[code]; ok {
+		l.order.Remove(elem)
+		delete(l.elems, code)
+	}
+}
+
+// oldest returns the least recently used code.
+func (l *recencyList) oldest() (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem := l.order.Back()
+	if elem == nil {
+		return "", false
+	}
+	return elem.Value.(string), true
+}
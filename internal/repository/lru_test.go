@@ -0,0 +1,142 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func saveLRUTestRecord(t *testing.T, repo repository.Repository, code string) {
+	t.Helper()
+	require.NoError(t, repo.SaveIfNotExists(context.Background(), &domain.URLRecord{
+		ShortCode: code,
+		LongURL:   "https://example.com/" + code,
+		ExpiresAt: time.Now().Add(time.Hour),
+		Enabled:   true,
+	}))
+}
+
+func TestLRUMemoryRepository_EvictsLeastRecentlyUsed(t *testing.T) {
+	repo := repository.NewLRUMemoryRepository(3)
+	ctx := context.Background()
+
+	saveLRUTestRecord(t, repo, "code0001")
+	saveLRUTestRecord(t, repo, "code0002")
+	saveLRUTestRecord(t, repo, "code0003")
+
+	// Reading code0001 makes code0002 the least recently used
+	_, err := repo.FindByShortCode(ctx, "code0001")
+	require.NoError(t, err)
+
+	saveLRUTestRecord(t, repo, "code0004")
+
+	_, err = repo.FindByShortCode(ctx, "code0002")
+	assert.ErrorIs(t, err, domain.ErrNotFound, "least recently used code is evicted")
+	for _, code := range []string{"code0001", "code0003", "code0004"} {
+		_, err := repo.FindByShortCode(ctx, code)
+		assert.NoError(t, err, code)
+	}
+	_, err = repo.FindByLongURL(ctx, "https://example.com/code0002")
+	assert.ErrorIs(t, err, domain.ErrNotFound, "evicted code leaves the long URL index")
+}
+
+func TestLRUMemoryRepository_ClicksDriveRecency(t *testing.T) {
+	repo := repository.NewLRUMemoryRepository(3)
+	ctx := context.Background()
+	now := time.Now()
+
+	saveLRUTestRecord(t, repo, "code0001")
+	saveLRUTestRecord(t, repo, "code0002")
+	saveLRUTestRecord(t, repo, "code0003")
+
+	// Clicks update LastAccessedAt and recency alike; code0003 is now the
+	// least recently used.
+	_, err := repo.ResolveAndIncrement(ctx, "code0001", "", now)
+	require.NoError(t, err)
+	require.NoError(t, repo.RecordClick(ctx, "code0002", "", now))
+
+	saveLRUTestRecord(t, repo, "code0004")
+	saveLRUTestRecord(t, repo, "code0005")
+
+	found, err := repo.FindByShortCodes(ctx, []string{"code0001", "code0002", "code0003", "code0004", "code0005"})
+	require.NoError(t, err)
+	assert.NotContains(t, found, "code0003")
+	assert.NotContains(t, found, "code0001", "oldest use after the new saves")
+	assert.Contains(t, found, "code0002")
+	assert.Contains(t, found, "code0004")
+	assert.Contains(t, found, "code0005")
+	assert.Equal(t, now, found["code0002"].LastAccessedAt)
+}
+
+func TestLRUMemoryRepository_DeletedCodesFreeSlots(t *testing.T) {
+	repo := repository.NewLRUMemoryRepository(2)
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{
+		ShortCode: "expired1",
+		LongURL:   "https://example.com/expired",
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}))
+	saveLRUTestRecord(t, repo, "code0001")
+
+	deleted, err := repo.DeleteExpired(ctx, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	// The freed slot is used without evicting code0001
+	saveLRUTestRecord(t, repo, "code0002")
+	_, err = repo.FindByShortCode(ctx, "code0001")
+	assert.NoError(t, err)
+
+	// Further saves evict, and never fail
+	for i := range 10 {
+		saveLRUTestRecord(t, repo, fmt.Sprintf("extra%03d", i))
+	}
+	_, total, err := repo.List(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+}
+
+func TestLRUMemoryRepository_Concurrent(t *testing.T) {
+	repo := repository.NewLRUMemoryRepository(50)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for g := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				code := fmt.Sprintf("g%02di%03d", g, i)
+				assert.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: code, LongURL: "https://example.com", Enabled: true}))
+				_, _ = repo.FindByShortCode(ctx, fmt.Sprintf("g%02di%03d", (g+1)%20, i))
+				_, _ = repo.ResolveAndIncrement(ctx, code, "", time.Now())
+			}
+		}()
+	}
+	wg.Wait()
+
+	_, total, err := repo.List(ctx, 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 50, total)
+}
+
+func TestLRUMemoryRepository_SharedBehavior(t *testing.T) {
+	t.Run("store stats", func(t *testing.T) {
+		assertStoreStats(t, repository.NewLRUMemoryRepository(100))
+	})
+	t.Run("resolve and increment", func(t *testing.T) {
+		assertResolveAndIncrement(t, repository.NewLRUMemoryRepository(100))
+	})
+	t.Run("soft delete", func(t *testing.T) {
+		assertSoftDeleteLifecycle(t, repository.NewLRUMemoryRepository(100))
+	})
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"time"
 
@@ -12,15 +13,36 @@ import (
 type MemoryRepository struct {
 	mu   sync.RWMutex
 	data map[string]*domain.URLRecord
+
+	// byLongURL indexes short codes by long URL, guarded by mu.
+	byLongURL map[string][]string
+
+	// capacity caps the number of stored records; zero means unbounded.
+	capacity int
+
+	// recency is set by NewLRUMemoryRepository, making a full store evict
+	// its least recently used record instead of refusing new ones.
+	recency *recencyList
 }
 
 // NewMemoryRepository creates a new in-memory repository.
 func NewMemoryRepository() *MemoryRepository {
 	return &MemoryRepository{
-		data: make(map[string]*domain.URLRecord),
+		data:      make(map[string]*domain.URLRecord),
+		byLongURL: make(map[string][]string),
 	}
 }
 
+// NewMemoryRepositoryWithCapacity creates an in-memory repository holding at
+// most capacity records, so it can't grow without bound. Once full,
+// SaveIfNotExists fails with domain.ErrCapacityExceeded until DeleteExpired
+// frees space. A capacity of zero or less means unbounded.
+func NewMemoryRepositoryWithCapacity(capacity int) *MemoryRepository {
+	r := NewMemoryRepository()
+	r.capacity = max(capacity, 0)
+	return r
+}
+
 // SaveIfNotExists atomically saves the record only if the short code
 // doesn't already exist.
 func (r *MemoryRepository) SaveIfNotExists(ctx context.Context, record *domain.URLRecord) error {
@@ -36,8 +58,13 @@ func (r *MemoryRepository) SaveIfNotExists(ctx context.Context, record *domain.U
 	if _, exists := r.data[record.ShortCode]; exists {
 		return domain.ErrCodeExists
 	}
+	if r.capacity > 0 && len(r.data) >= r.capacity && !r.evictOldest() {
+		return domain.ErrCapacityExceeded
+	}
 
 	r.data[record.ShortCode] = record.Clone()
+	r.byLongURL[record.LongURL] = append(r.byLongURL[record.LongURL], record.ShortCode)
+	r.touch(record.ShortCode)
 	return nil
 }
 
@@ -52,16 +79,177 @@ func (r *MemoryRepository) FindByShortCode(ctx context.Context, code string) (*d
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	record, exists := r.data[code]
+	record, exists := r.live(code)
 	if !exists {
 		return nil, domain.ErrNotFound
 	}
 
+	r.touch(code)
 	return record.Clone(), nil
 }
 
-// IncrementClickCount atomically increments the click counter.
+// FindByShortCodes retrieves the records for all given codes under a single
+// read lock, so the returned records are mutually consistent.
+func (r *MemoryRepository) FindByShortCodes(ctx context.Context, codes []string) (map[string]*domain.URLRecord, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	found := make(map[string]*domain.URLRecord, len(codes))
+	for _, code := range codes {
+		if record, exists := r.live(code); exists {
+			found[code] = record.Clone()
+		}
+	}
+
+	return found, nil
+}
+
+// FindByLongURL retrieves the record for the given long URL with the latest
+// expiry, using the long URL index.
+func (r *MemoryRepository) FindByLongURL(ctx context.Context, longURL string) (*domain.URLRecord, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *domain.URLRecord
+	for _, code := range r.byLongURL[longURL] {
+		record := r.data[code]
+		if record.IsDeleted() {
+			continue
+		}
+		if latest == nil || expiresLater(record, latest) {
+			latest = record
+		}
+	}
+
+	if latest == nil {
+		return nil, domain.ErrNotFound
+	}
+
+	return latest.Clone(), nil
+}
+
+// expiresLater reports whether a expires after b, counting records that
+// never expire as the latest.
+func expiresLater(a, b *domain.URLRecord) bool {
+	if a.NeverExpires() || b.NeverExpires() {
+		return a.NeverExpires() && !b.NeverExpires()
+	}
+	return a.ExpiresAt.After(b.ExpiresAt)
+}
+
+// List returns a page of records ordered by creation time, then short code.
+// Each call sorts the whole store, which is fine at in-memory scale.
+func (r *MemoryRepository) List(ctx context.Context, offset, limit int) ([]*domain.URLRecord, int, error) {
+	return r.listWhere(ctx, offset, limit, func(*domain.URLRecord) bool { return true })
+}
+
+// ListByTag returns a page of the records labeled with tag, ordered as for
+// List.
+func (r *MemoryRepository) ListByTag(ctx context.Context, tag string, offset, limit int) ([]*domain.URLRecord, int, error) {
+	return r.listWhere(ctx, offset, limit, func(record *domain.URLRecord) bool {
+		return record.HasTag(tag)
+	})
+}
+
+// listWhere returns a page of the live records matching keep.
+func (r *MemoryRepository) listWhere(ctx context.Context, offset, limit int, keep func(*domain.URLRecord) bool) ([]*domain.URLRecord, int, error) {
+	select {
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	default:
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	all := make([]*domain.URLRecord, 0, len(r.data))
+	for _, record := range r.data {
+		if !record.IsDeleted() && keep(record) {
+			all = append(all, record)
+		}
+	}
+	page, total := paginate(all, offset, limit)
+	return page, total, nil
+}
+
+// paginate sorts records by creation time and then short code and returns
+// clones of those in [offset, offset+limit), along with the record count.
+func paginate(records []*domain.URLRecord, offset, limit int) ([]*domain.URLRecord, int) {
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].CreatedAt.Equal(records[j].CreatedAt) {
+			return records[i].CreatedAt.Before(records[j].CreatedAt)
+		}
+		return records[i].ShortCode < records[j].ShortCode
+	})
+
+	total := len(records)
+	if offset >= total {
+		return []*domain.URLRecord{}, total
+	}
+	end := min(offset+limit, total)
+
+	page := make([]*domain.URLRecord, 0, end-offset)
+	for _, record := range records[offset:end] {
+		page = append(page, record.Clone())
+	}
+	return page, total
+}
+
+// Count returns the number of stored records, soft-deleted ones included.
+func (r *MemoryRepository) Count(ctx context.Context) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.data), nil
+}
+
+// Stats summarizes the stored records as of now under one read lock,
+// without copying them.
+func (r *MemoryRepository) Stats(ctx context.Context, now time.Time, top int) (domain.StoreStats, error) {
+	select {
+	case <-ctx.Done():
+		return domain.StoreStats{}, ctx.Err()
+	default:
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var stats domain.StoreStats
+	for _, record := range r.data {
+		if !record.IsDeleted() {
+			stats.Add(record, now, top)
+		}
+	}
+	return stats, nil
+}
+
+// IncrementClickCount atomically increments the click counter, refusing once
+// the record's MaxClicks is reached.
 func (r *MemoryRepository) IncrementClickCount(ctx context.Context, code string, accessTime time.Time) error {
+	return r.RecordClick(ctx, code, "", accessTime)
+}
+
+// RecordClick counts a click like IncrementClickCount and, in the same
+// step, tallies it against referrer unless referrer is empty.
+func (r *MemoryRepository) RecordClick(ctx context.Context, code, referrer string, accessTime time.Time) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -71,34 +259,330 @@ func (r *MemoryRepository) IncrementClickCount(ctx context.Context, code string,
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	record, exists := r.data[code]
+	record, exists := r.live(code)
 	if !exists {
 		return domain.ErrNotFound
 	}
 
+	if record.ClickLimitReached() {
+		return domain.ErrClickLimitReached
+	}
+
+	applyClick(record, referrer, accessTime)
+	r.touch(code)
+	return nil
+}
+
+// ResolveAndIncrement counts a click at now like RecordClick, but only if
+// the record can still be redirected to at now, checked under the same lock.
+func (r *MemoryRepository) ResolveAndIncrement(ctx context.Context, code, referrer string, now time.Time) (*domain.URLRecord, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, exists := r.live(code)
+	if !exists {
+		return nil, domain.ErrNotFound
+	}
+	if record.IsExpired(now) || record.ClickLimitReached() {
+		return nil, domain.ErrExpired
+	}
+	if !record.Enabled {
+		return nil, domain.ErrDisabled
+	}
+
+	applyClick(record, referrer, now)
+	r.touch(code)
+	return record.Clone(), nil
+}
+
+// ApplyClickDeltas adds the buffered clicks of every delta under one lock.
+func (r *MemoryRepository) ApplyClickDeltas(ctx context.Context, deltas []ClickDelta) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range deltas {
+		record, exists := r.live(d.Code)
+		if !exists {
+			continue
+		}
+		applyClickDelta(record, d)
+		r.touch(d.Code)
+	}
+	return nil
+}
+
+// applyClick adds a click at accessTime to record's counts. An empty
+// referrer counts the click without attributing it.
+func applyClick(record *domain.URLRecord, referrer string, accessTime time.Time) {
 	record.ClickCount++
 	record.LastAccessedAt = accessTime
+	record.ClicksByDay = domain.CountDailyClick(record.ClicksByDay, accessTime)
+	if referrer != "" {
+		record.Referrers = domain.CountReferrer(record.Referrers, referrer)
+	}
+}
+
+// UpdateExpiry sets the record's expiry time.
+func (r *MemoryRepository) UpdateExpiry(ctx context.Context, code string, expiresAt time.Time) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, exists := r.live(code)
+	if !exists {
+		return domain.ErrNotFound
+	}
+
+	record.ExpiresAt = expiresAt
+	return nil
+}
+
+// SetEnabled enables or disables the record.
+func (r *MemoryRepository) SetEnabled(ctx context.Context, code string, enabled bool) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, exists := r.live(code)
+	if !exists {
+		return domain.ErrNotFound
+	}
+
+	record.Enabled = enabled
+	return nil
+}
+
+// AppendHistory adds a lifecycle event to the record's bounded history.
+func (r *MemoryRepository) AppendHistory(ctx context.Context, code string, event domain.HistoryEvent) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, exists := r.live(code)
+	if !exists {
+		return domain.ErrNotFound
+	}
+
+	record.History = domain.AppendHistory(record.History, event.Clone())
+	return nil
+}
+
+// SoftDelete marks the record deleted at deletedAt.
+func (r *MemoryRepository) SoftDelete(ctx context.Context, code string, deletedAt time.Time) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, exists := r.live(code)
+	if !exists {
+		return domain.ErrNotFound
+	}
+
+	record.DeletedAt = deletedAt
+	return nil
+}
+
+// Restore undoes SoftDelete for a record deleted at or after deletedSince.
+func (r *MemoryRepository) Restore(ctx context.Context, code string, deletedSince time.Time) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, exists := r.data[code]
+	if !exists || !record.IsDeleted() || record.DeletedAt.Before(deletedSince) {
+		return domain.ErrNotFound
+	}
+
+	record.DeletedAt = time.Time{}
 	return nil
 }
 
+// PurgeDeleted removes records soft-deleted before deletedBefore and
+// returns their codes in sorted order.
+func (r *MemoryRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	return r.deleteWhere(ctx, func(record *domain.URLRecord) bool {
+		return record.IsDeleted() && record.DeletedAt.Before(deletedBefore)
+	})
+}
+
 // DeleteExpired removes all records that have expired before the given time.
 func (r *MemoryRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	codes, err := r.DeleteExpiredCodes(ctx, before)
+	return int64(len(codes)), err
+}
+
+// DeleteExpiredCodes removes all records that have expired before the given
+// time and returns their codes in sorted order.
+func (r *MemoryRepository) DeleteExpiredCodes(ctx context.Context, before time.Time) ([]string, error) {
+	return r.deleteWhere(ctx, func(record *domain.URLRecord) bool {
+		return record.IsExpired(before)
+	})
+}
+
+// SoftDeleteByPrefix marks the live records whose long URL starts with
+// prefix deleted at deletedAt and returns their codes in sorted order.
+func (r *MemoryRepository) SoftDeleteByPrefix(ctx context.Context, prefix string, deletedAt time.Time) ([]string, error) {
+	if prefix == "" {
+		return nil, ErrEmptyPrefix
+	}
+
 	select {
 	case <-ctx.Done():
-		return 0, ctx.Err()
+		return nil, ctx.Err()
 	default:
 	}
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	var deleted int64
+	var deleted []string
 	for code, record := range r.data {
-		if record.ExpiresAt.Before(before) {
-			delete(r.data, code)
-			deleted++
+		if !record.IsDeleted() && hasURLPrefix(record.LongURL, prefix) {
+			record.DeletedAt = deletedAt
+			deleted = append(deleted, code)
 		}
 	}
 
+	sort.Strings(deleted)
 	return deleted, nil
 }
+
+// deleteWhere removes the records matching remove and returns their codes
+// in sorted order.
+func (r *MemoryRepository) deleteWhere(ctx context.Context, remove func(*domain.URLRecord) bool) ([]string, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted []string
+	for code, record := range r.data {
+		if remove(record) {
+			r.drop(code, record)
+			deleted = append(deleted, code)
+		}
+	}
+
+	sort.Strings(deleted)
+	return deleted, nil
+}
+
+// drop removes the record stored under code. Callers must hold mu for
+// writing.
+func (r *MemoryRepository) drop(code string, record *domain.URLRecord) {
+	delete(r.data, code)
+	r.unindexLongURL(record.LongURL, code)
+	if r.recency != nil {
+		r.recency.remove(code)
+	}
+}
+
+// evictOldest drops the least recently used record to make room for a new
+// one, reporting false if the store doesn't evict. Callers must hold mu for
+// writing.
+func (r *MemoryRepository) evictOldest() bool {
+	if r.recency == nil {
+		return false
+	}
+	code, ok := r.recency.oldest()
+	if !ok {
+		return false
+	}
+	r.drop(code, r.data[code])
+	return true
+}
+
+// touch marks code as just used when the store evicts by recency. Callers
+// must hold mu.
+func (r *MemoryRepository) touch(code string) {
+	if r.recency != nil {
+		r.recency.touch(code)
+	}
+}
+
+// live returns the record stored under code unless it is missing or
+// soft-deleted. Callers must hold mu.
+func (r *MemoryRepository) live(code string) (*domain.URLRecord, bool) {
+	record, exists := r.data[code]
+	if !exists || record.IsDeleted() {
+		return nil, false
+	}
+	return record, true
+}
+
+// snapshot returns copies of all records, read under one lock so they are
+// mutually consistent.
+func (r *MemoryRepository) snapshot() []*domain.URLRecord {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	records := make([]*domain.URLRecord, 0, len(r.data))
+	for _, record := range r.data {
+		records = append(records, record.Clone())
+	}
+	return records
+}
+
+// unindexLongURL removes code from the long URL index. Callers must hold mu.
+func (r *MemoryRepository) unindexLongURL(longURL, code string) {
+	codes := r.byLongURL[longURL]
+	for i, c := range codes {
+		if c == code {
+			codes = append(codes[:i], codes[i+1:]...)
+			break
+		}
+	}
+
+	if len(codes) == 0 {
+		delete(r.byLongURL, longURL)
+		return
+	}
+	r.byLongURL[longURL] = codes
+}
+
+// Ping always succeeds unless ctx is done; memory storage can't become
+// unreachable.
+func (r *MemoryRepository) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
@@ -16,293 +16,1144 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func TestMemoryRepository_SaveIfNotExists_Success(t *testing.T) {
-	repo := repository.NewMemoryRepository()
-	ctx := context.Background()
+// memoryRepositoryFactory creates an in-memory repository holding at most
+// capacity records, or unbounded if capacity is zero.
+type memoryRepositoryFactory func(capacity int) repository.Repository
+
+// memoryRepositories lists the in-memory implementations, which must pass
+// the same suite.
+var memoryRepositories = map[string]memoryRepositoryFactory{
+	"single": func(capacity int) repository.Repository {
+		return repository.NewMemoryRepositoryWithCapacity(capacity)
+	},
+	"sharded": func(capacity int) repository.Repository {
+		return repository.NewShardedMemoryRepository(4, capacity)
+	},
+}
 
-	record := &domain.URLRecord{
-		ShortCode: "abc12345",
-		LongURL:   "https://example.com",
-		CreatedAt: time.Now(),
-		ExpiresAt: time.Now().Add(time.Hour),
+// forEachMemoryRepository runs test as a subtest against each in-memory
+// implementation.
+func forEachMemoryRepository(t *testing.T, test func(t *testing.T, newRepo memoryRepositoryFactory)) {
+	for name, newRepo := range memoryRepositories {
+		t.Run(name, func(t *testing.T) {
+			test(t, newRepo)
+		})
 	}
+}
 
-	err := repo.SaveIfNotExists(ctx, record)
-	assert.NoError(t, err)
-
-	// Verify it was saved
-	saved, err := repo.FindByShortCode(ctx, "abc12345")
-	require.NoError(t, err)
-	assert.Equal(t, "https://example.com", saved.LongURL)
+func TestMemoryRepository_SaveIfNotExists_Success(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		record := &domain.URLRecord{
+			ShortCode: "abc12345",
+			LongURL:   "https://example.com",
+			CreatedAt: time.Now(),
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+
+		err := repo.SaveIfNotExists(ctx, record)
+		assert.NoError(t, err)
+
+		// Verify it was saved
+		saved, err := repo.FindByShortCode(ctx, "abc12345")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com", saved.LongURL)
+	})
 }
 
 func TestMemoryRepository_SaveIfNotExists_Duplicate(t *testing.T) {
-	repo := repository.NewMemoryRepository()
-	ctx := context.Background()
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		record := &domain.URLRecord{
+			ShortCode: "abc12345",
+			LongURL:   "https://example.com",
+		}
+
+		// First save succeeds
+		err := repo.SaveIfNotExists(ctx, record)
+		require.NoError(t, err)
+
+		// Second save with same code fails
+		record2 := &domain.URLRecord{
+			ShortCode: "abc12345",
+			LongURL:   "https://different.com",
+		}
+		err = repo.SaveIfNotExists(ctx, record2)
+		assert.ErrorIs(t, err, domain.ErrCodeExists)
+	})
+}
 
-	record := &domain.URLRecord{
-		ShortCode: "abc12345",
-		LongURL:   "https://example.com",
-	}
+func TestMemoryRepository_SaveIfNotExists_StoresClone(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		record := &domain.URLRecord{
+			ShortCode:  "abc12345",
+			LongURL:    "https://example.com",
+			ClickCount: 0,
+		}
+
+		err := repo.SaveIfNotExists(ctx, record)
+		require.NoError(t, err)
+
+		// Modify original after save
+		record.ClickCount = 999
+
+		// Stored record should be unaffected
+		saved, _ := repo.FindByShortCode(ctx, "abc12345")
+		assert.Equal(t, int64(0), saved.ClickCount)
+	})
+}
 
-	// First save succeeds
-	err := repo.SaveIfNotExists(ctx, record)
-	require.NoError(t, err)
+func TestMemoryRepository_FindByShortCode_Success(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		record := &domain.URLRecord{
+			ShortCode:  "abc12345",
+			LongURL:    "https://example.com",
+			ClickCount: 42,
+		}
+		_ = repo.SaveIfNotExists(ctx, record)
+
+		found, err := repo.FindByShortCode(ctx, "abc12345")
+		require.NoError(t, err)
+		assert.Equal(t, "abc12345", found.ShortCode)
+		assert.Equal(t, "https://example.com", found.LongURL)
+		assert.Equal(t, int64(42), found.ClickCount)
+	})
+}
 
-	// Second save with same code fails
-	record2 := &domain.URLRecord{
-		ShortCode: "abc12345",
-		LongURL:   "https://different.com",
-	}
-	err = repo.SaveIfNotExists(ctx, record2)
-	assert.ErrorIs(t, err, domain.ErrCodeExists)
+func TestMemoryRepository_FindByShortCode_NotFound(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		_, err := repo.FindByShortCode(ctx, "notexist")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
 }
 
-func TestMemoryRepository_SaveIfNotExists_StoresClone(t *testing.T) {
-	repo := repository.NewMemoryRepository()
-	ctx := context.Background()
+func TestMemoryRepository_FindByShortCode_ReturnsClone(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		record := &domain.URLRecord{
+			ShortCode:  "abc12345",
+			ClickCount: 10,
+		}
+		_ = repo.SaveIfNotExists(ctx, record)
+
+		// Get record and modify it
+		found, _ := repo.FindByShortCode(ctx, "abc12345")
+		found.ClickCount = 999
+
+		// Original in repo should be unaffected
+		found2, _ := repo.FindByShortCode(ctx, "abc12345")
+		assert.Equal(t, int64(10), found2.ClickCount)
+	})
+}
 
-	record := &domain.URLRecord{
-		ShortCode:  "abc12345",
-		LongURL:    "https://example.com",
-		ClickCount: 0,
-	}
+func TestMemoryRepository_IncrementClickCount_Success(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		record := &domain.URLRecord{
+			ShortCode:  "abc12345",
+			ClickCount: 0,
+		}
+		_ = repo.SaveIfNotExists(ctx, record)
+
+		accessTime := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+		err := repo.IncrementClickCount(ctx, "abc12345", accessTime)
+		require.NoError(t, err)
+
+		found, _ := repo.FindByShortCode(ctx, "abc12345")
+		assert.Equal(t, int64(1), found.ClickCount)
+		assert.Equal(t, accessTime, found.LastAccessedAt)
+	})
+}
 
-	err := repo.SaveIfNotExists(ctx, record)
-	require.NoError(t, err)
+func TestMemoryRepository_IncrementClickCount_NotFound(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
 
-	// Modify original after save
-	record.ClickCount = 999
+		err := repo.IncrementClickCount(ctx, "notexist", time.Now())
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+}
 
-	// Stored record should be unaffected
-	saved, _ := repo.FindByShortCode(ctx, "abc12345")
-	assert.Equal(t, int64(0), saved.ClickCount)
+func TestMemoryRepository_IncrementClickCount_Multiple(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		record := &domain.URLRecord{
+			ShortCode:  "abc12345",
+			ClickCount: 0,
+		}
+		_ = repo.SaveIfNotExists(ctx, record)
+
+		for i := 0; i < 100; i++ {
+			_ = repo.IncrementClickCount(ctx, "abc12345", time.Now())
+		}
+
+		found, _ := repo.FindByShortCode(ctx, "abc12345")
+		assert.Equal(t, int64(100), found.ClickCount)
+	})
 }
 
-func TestMemoryRepository_FindByShortCode_Success(t *testing.T) {
-	repo := repository.NewMemoryRepository()
-	ctx := context.Background()
+func TestMemoryRepository_RecordClick_CountsReferrers(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
 
-	record := &domain.URLRecord{
-		ShortCode:  "abc12345",
-		LongURL:    "https://example.com",
-		ClickCount: 42,
-	}
-	_ = repo.SaveIfNotExists(ctx, record)
+		_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345"})
 
-	found, err := repo.FindByShortCode(ctx, "abc12345")
-	require.NoError(t, err)
-	assert.Equal(t, "abc12345", found.ShortCode)
-	assert.Equal(t, "https://example.com", found.LongURL)
-	assert.Equal(t, int64(42), found.ClickCount)
+		for _, referrer := range []string{"news.example", "news.example", domain.ReferrerDirect, ""} {
+			require.NoError(t, repo.RecordClick(ctx, "abc12345", referrer, time.Now()))
+		}
+
+		found, _ := repo.FindByShortCode(ctx, "abc12345")
+		assert.Equal(t, int64(4), found.ClickCount)
+		assert.Equal(t, map[string]int64{"news.example": 2, domain.ReferrerDirect: 1}, found.Referrers)
+
+		err := repo.RecordClick(ctx, "notexist", "news.example", time.Now())
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
 }
 
-func TestMemoryRepository_FindByShortCode_NotFound(t *testing.T) {
-	repo := repository.NewMemoryRepository()
-	ctx := context.Background()
+func TestMemoryRepository_IncrementClickCount_CountsPerDay(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+		clock := domain.NewMockClock(time.Date(2024, 1, 15, 23, 58, 0, 0, time.UTC))
 
-	_, err := repo.FindByShortCode(ctx, "notexist")
-	assert.ErrorIs(t, err, domain.ErrNotFound)
+		_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345"})
+
+		require.NoError(t, repo.IncrementClickCount(ctx, "abc12345", clock.Now()))
+		clock.Advance(time.Minute)
+		require.NoError(t, repo.RecordClick(ctx, "abc12345", "news.example", clock.Now()))
+		clock.Advance(2 * time.Minute) // past midnight
+		require.NoError(t, repo.IncrementClickCount(ctx, "abc12345", clock.Now()))
+
+		found, _ := repo.FindByShortCode(ctx, "abc12345")
+		assert.Equal(t, map[string]int64{"2024-01-15": 2, "2024-01-16": 1}, found.ClicksByDay)
+	})
 }
 
-func TestMemoryRepository_FindByShortCode_ReturnsClone(t *testing.T) {
-	repo := repository.NewMemoryRepository()
-	ctx := context.Background()
+func TestMemoryRepository_IncrementClickCount_Concurrent(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		record := &domain.URLRecord{
+			ShortCode:  "abc12345",
+			ClickCount: 0,
+		}
+		_ = repo.SaveIfNotExists(ctx, record)
+
+		// 100 goroutines each incrementing 100 times
+		const numGoroutines = 100
+		const incrementsPerGoroutine = 100
+		expectedTotal := int64(numGoroutines * incrementsPerGoroutine)
+
+		var wg sync.WaitGroup
+		wg.Add(numGoroutines)
+
+		for i := 0; i < numGoroutines; i++ {
+			go func() {
+				defer wg.Done()
+				for j := 0; j < incrementsPerGoroutine; j++ {
+					err := repo.IncrementClickCount(ctx, "abc12345", time.Now())
+					assert.NoError(t, err)
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		found, _ := repo.FindByShortCode(ctx, "abc12345")
+		assert.Equal(t, expectedTotal, found.ClickCount,
+			"click count should be exactly %d after concurrent increments", expectedTotal)
+	})
+}
 
-	record := &domain.URLRecord{
-		ShortCode:  "abc12345",
-		ClickCount: 10,
-	}
-	_ = repo.SaveIfNotExists(ctx, record)
+func TestMemoryRepository_IncrementClickCount_StopsAtMaxClicks(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345", MaxClicks: 5})
+
+		const numGoroutines = 50
+		var succeeded atomic.Int64
 
-	// Get record and modify it
-	found, _ := repo.FindByShortCode(ctx, "abc12345")
-	found.ClickCount = 999
+		var wg sync.WaitGroup
+		wg.Add(numGoroutines)
 
-	// Original in repo should be unaffected
-	found2, _ := repo.FindByShortCode(ctx, "abc12345")
-	assert.Equal(t, int64(10), found2.ClickCount)
+		for i := 0; i < numGoroutines; i++ {
+			go func() {
+				defer wg.Done()
+				err := repo.IncrementClickCount(ctx, "abc12345", time.Now())
+				if err == nil {
+					succeeded.Add(1)
+					return
+				}
+				assert.ErrorIs(t, err, domain.ErrClickLimitReached)
+			}()
+		}
+
+		wg.Wait()
+
+		assert.Equal(t, int64(5), succeeded.Load())
+		found, _ := repo.FindByShortCode(ctx, "abc12345")
+		assert.Equal(t, int64(5), found.ClickCount)
+	})
 }
 
-func TestMemoryRepository_IncrementClickCount_Success(t *testing.T) {
-	repo := repository.NewMemoryRepository()
+func TestMemoryRepository_SaveIfNotExists_ConcurrentCollision(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		const numGoroutines = 100
+		code := "samecode"
+
+		var wg sync.WaitGroup
+		wg.Add(numGoroutines)
+
+		var successCount int32
+		var collisionCount int32
+
+		for i := 0; i < numGoroutines; i++ {
+			go func(id int) {
+				defer wg.Done()
+				record := &domain.URLRecord{
+					ShortCode: code,
+					LongURL:   fmt.Sprintf("https://example.com/%d", id),
+				}
+
+				err := repo.SaveIfNotExists(ctx, record)
+				if err == nil {
+					atomic.AddInt32(&successCount, 1)
+				} else if errors.Is(err, domain.ErrCodeExists) {
+					atomic.AddInt32(&collisionCount, 1)
+				}
+			}(i)
+		}
+
+		wg.Wait()
+
+		// Exactly one should succeed
+		assert.Equal(t, int32(1), successCount)
+		assert.Equal(t, int32(numGoroutines-1), collisionCount)
+	})
+}
+
+func TestMemoryRepository_DeleteExpired(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+		// Create records with different expiry times
+		records := []*domain.URLRecord{
+			{ShortCode: "expired1", ExpiresAt: now.Add(-time.Hour)},   // Expired
+			{ShortCode: "expired2", ExpiresAt: now.Add(-time.Minute)}, // Expired
+			{ShortCode: "valid1", ExpiresAt: now.Add(time.Hour)},      // Valid
+			{ShortCode: "valid2", ExpiresAt: now.Add(time.Minute)},    // Valid
+		}
+
+		for _, r := range records {
+			_ = repo.SaveIfNotExists(ctx, r)
+		}
+
+		deleted, err := repo.DeleteExpired(ctx, now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), deleted)
+
+		// Verify expired are gone
+		_, err = repo.FindByShortCode(ctx, "expired1")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+
+		_, err = repo.FindByShortCode(ctx, "expired2")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+
+		// Verify valid still exist
+		_, err = repo.FindByShortCode(ctx, "valid1")
+		assert.NoError(t, err)
+
+		_, err = repo.FindByShortCode(ctx, "valid2")
+		assert.NoError(t, err)
+	})
+}
+
+func TestMemoryRepository_DeleteExpiredCodes(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+		records := []*domain.URLRecord{
+			{ShortCode: "expired2", ExpiresAt: now.Add(-time.Minute)},
+			{ShortCode: "valid1", ExpiresAt: now.Add(time.Hour)},
+			{ShortCode: "expired1", ExpiresAt: now.Add(-time.Hour)},
+		}
+		for _, r := range records {
+			_ = repo.SaveIfNotExists(ctx, r)
+		}
+
+		codes, err := repo.DeleteExpiredCodes(ctx, now)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"expired1", "expired2"}, codes)
+
+		_, err = repo.FindByShortCode(ctx, "expired1")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		_, err = repo.FindByShortCode(ctx, "valid1")
+		assert.NoError(t, err)
+
+		// Nothing left to delete
+		codes, err = repo.DeleteExpiredCodes(ctx, now)
+		require.NoError(t, err)
+		assert.Empty(t, codes)
+	})
+}
+
+func TestMemoryRepository_NeverExpiringRecords(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+		require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "forever1", LongURL: "https://example.com"}))
+		require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "later001", LongURL: "https://example.com", ExpiresAt: now.AddDate(10, 0, 0)}))
+
+		codes, err := repo.DeleteExpiredCodes(ctx, now.AddDate(100, 0, 0))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"later001"}, codes)
+
+		require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "later002", LongURL: "https://example.com", ExpiresAt: now.AddDate(20, 0, 0)}))
+		found, err := repo.FindByLongURL(ctx, "https://example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "forever1", found.ShortCode, "a never-expiring record counts as expiring latest")
+	})
+}
+
+func TestMemoryRepository_Capacity(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(2)
+		ctx := context.Background()
+
+		now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+		require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "expired1", ExpiresAt: now.Add(-time.Hour)}))
+		require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "valid1", ExpiresAt: now.Add(time.Hour)}))
+
+		// Full: new codes are rejected, taken codes still report the collision
+		err := repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "valid2", ExpiresAt: now.Add(time.Hour)})
+		assert.ErrorIs(t, err, domain.ErrCapacityExceeded)
+		err = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "valid1", ExpiresAt: now.Add(time.Hour)})
+		assert.ErrorIs(t, err, domain.ErrCodeExists)
+
+		// Deleting expired records frees space
+		deleted, err := repo.DeleteExpired(ctx, now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+
+		require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "valid2", ExpiresAt: now.Add(time.Hour)}))
+		err = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "valid3", ExpiresAt: now.Add(time.Hour)})
+		assert.ErrorIs(t, err, domain.ErrCapacityExceeded)
+	})
+}
+
+func TestMemoryRepository_ZeroCapacityIsUnbounded(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		for i := 0; i < 100; i++ {
+			require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: fmt.Sprintf("code%d", i)}))
+		}
+	})
+}
+
+func TestMemoryRepository_DeleteExpired_Empty(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		deleted, err := repo.DeleteExpired(ctx, time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), deleted)
+	})
+}
+
+func TestMemoryRepository_RespectsContextCancellation(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel() // Cancel immediately
+
+		record := &domain.URLRecord{ShortCode: "test1234"}
+
+		err := repo.SaveIfNotExists(ctx, record)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = repo.FindByShortCode(ctx, "test1234")
+		assert.ErrorIs(t, err, context.Canceled)
+
+		err = repo.IncrementClickCount(ctx, "test1234", time.Now())
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = repo.FindByShortCodes(ctx, []string{"test1234"})
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = repo.FindByLongURL(ctx, "https://example.com")
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = repo.DeleteExpired(ctx, time.Now())
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestMemoryRepository_RespectsContextDeadline(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+		defer cancel()
+		<-ctx.Done()
+
+		// Request timeouts surface as DeadlineExceeded, which handlers map to 503
+		err := repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "test1234"})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		_, err = repo.FindByShortCode(ctx, "test1234")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestMemoryRepository_FindByShortCodes(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "code0001", ClickCount: 1})
+		_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "code0002", ClickCount: 2})
+
+		found, err := repo.FindByShortCodes(ctx, []string{"code0001", "code0002", "notexist"})
+		require.NoError(t, err)
+
+		assert.Len(t, found, 2)
+		assert.Equal(t, int64(1), found["code0001"].ClickCount)
+		assert.Equal(t, int64(2), found["code0002"].ClickCount)
+		assert.NotContains(t, found, "notexist")
+
+		// Returned records are clones
+		found["code0001"].ClickCount = 999
+		again, _ := repo.FindByShortCode(ctx, "code0001")
+		assert.Equal(t, int64(1), again.ClickCount)
+	})
+}
+
+func TestMemoryRepository_AppendHistory(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{
+			ShortCode: "abc12345",
+			History:   []domain.HistoryEvent{{Type: domain.EventCreated}},
+		})
+
+		err := repo.AppendHistory(ctx, "abc12345", domain.HistoryEvent{Type: domain.EventTTLExtended, Actor: "admin"})
+		require.NoError(t, err)
+
+		found, _ := repo.FindByShortCode(ctx, "abc12345")
+		require.Len(t, found.History, 2)
+		assert.Equal(t, domain.EventCreated, found.History[0].Type)
+		assert.Equal(t, domain.EventTTLExtended, found.History[1].Type)
+
+		err = repo.AppendHistory(ctx, "notexist", domain.HistoryEvent{Type: domain.EventDisabled})
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+}
+
+func TestMemoryRepository_FindByLongURL(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+		now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+		_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "older001", LongURL: "https://example.com", ExpiresAt: now.Add(time.Hour)})
+		_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "later001", LongURL: "https://example.com", ExpiresAt: now.Add(2 * time.Hour)})
+		_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "other001", LongURL: "https://other.com", ExpiresAt: now.Add(3 * time.Hour)})
+
+		found, err := repo.FindByLongURL(ctx, "https://example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "later001", found.ShortCode, "should return the record with the latest expiry")
+
+		_, err = repo.FindByLongURL(ctx, "https://missing.com")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+}
+
+func TestMemoryRepository_FindByLongURL_DeleteExpiredUpdatesIndex(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+		now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+		_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "expired1", LongURL: "https://example.com", ExpiresAt: now.Add(-time.Hour)})
+
+		_, err := repo.DeleteExpired(ctx, now)
+		require.NoError(t, err)
+
+		_, err = repo.FindByLongURL(ctx, "https://example.com")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+}
+
+func TestMemoryRepository_List(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+		base := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+		page, total, err := repo.List(ctx, 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 0, total)
+		assert.Empty(t, page)
+
+		// Saved out of order; two share a creation time and sort by code
+		_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "code0003", CreatedAt: base.Add(2 * time.Minute)})
+		_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "code0002", CreatedAt: base.Add(time.Minute)})
+		_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "code0001", CreatedAt: base.Add(time.Minute)})
+		_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "code0000", CreatedAt: base})
+
+		testCases := []struct {
+			name   string
+			offset int
+			limit  int
+			want   []string
+		}{
+			{name: "first page", offset: 0, limit: 2, want: []string{"code0000", "code0001"}},
+			{name: "partial last page", offset: 3, limit: 2, want: []string{"code0003"}},
+			{name: "exact end", offset: 2, limit: 2, want: []string{"code0002", "code0003"}},
+			{name: "offset at total", offset: 4, limit: 2, want: []string{}},
+			{name: "offset past total", offset: 10, limit: 2, want: []string{}},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				page, total, err := repo.List(ctx, tc.offset, tc.limit)
+				require.NoError(t, err)
+				assert.Equal(t, 4, total)
+
+				codes := make([]string, 0, len(page))
+				for _, record := range page {
+					codes = append(codes, record.ShortCode)
+				}
+				assert.Equal(t, tc.want, codes)
+			})
+		}
+	})
+}
+
+func TestMemoryRepository_UpdateExpiry(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+		now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+		_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345", ExpiresAt: now})
+
+		err := repo.UpdateExpiry(ctx, "abc12345", now.Add(48*time.Hour))
+		require.NoError(t, err)
+
+		found, _ := repo.FindByShortCode(ctx, "abc12345")
+		assert.Equal(t, now.Add(48*time.Hour), found.ExpiresAt)
+
+		err = repo.UpdateExpiry(ctx, "notexist", now)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+}
+
+func TestMemoryRepository_Ping(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+
+		assert.NoError(t, repo.Ping(context.Background()))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.Error(t, repo.Ping(ctx))
+	})
+}
+
+func TestMemoryRepository_SetEnabled(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(0)
+		ctx := context.Background()
+
+		require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345", Enabled: true}))
+
+		require.NoError(t, repo.SetEnabled(ctx, "abc12345", false))
+		found, err := repo.FindByShortCode(ctx, "abc12345")
+		require.NoError(t, err)
+		assert.False(t, found.Enabled)
+
+		require.NoError(t, repo.SetEnabled(ctx, "abc12345", true))
+		found, err = repo.FindByShortCode(ctx, "abc12345")
+		require.NoError(t, err)
+		assert.True(t, found.Enabled)
+
+		assert.ErrorIs(t, repo.SetEnabled(ctx, "notexist", false), domain.ErrNotFound)
+	})
+}
+
+// BenchmarkMemoryRepository_Parallel mixes clicks, lookups, and saves from
+// concurrent goroutines to compare the single-lock repository against the
+// sharded one under contention.
+func BenchmarkMemoryRepository_Parallel(b *testing.B) {
+	const seeded = 1024
+
+	repos := map[string]func() repository.Repository{
+		"single":  func() repository.Repository { return repository.NewMemoryRepository() },
+		"sharded": func() repository.Repository { return repository.NewShardedMemoryRepository(0, 0) },
+	}
+	for name, newRepo := range repos {
+		b.Run(name, func(b *testing.B) {
+			repo := newRepo()
+			ctx := context.Background()
+			now := time.Now()
+
+			for i := range seeded {
+				code := fmt.Sprintf("seed%04d", i)
+				require.NoError(b, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: code, LongURL: "https://example.com/" + code}))
+			}
+
+			var next atomic.Int64
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					n := next.Add(1)
+					code := fmt.Sprintf("seed%04d", n%seeded)
+					switch n % 4 {
+					case 0:
+						_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: fmt.Sprintf("new%d", n)})
+					case 1:
+						_, _ = repo.FindByShortCode(ctx, code)
+					default:
+						_ = repo.IncrementClickCount(ctx, code, now)
+					}
+				}
+			})
+		})
+	}
+}
+
+// assertSoftDeleteLifecycle checks that a soft-deleted record is hidden
+// from every lookup while its code stays taken, and that it can be
+// restored or purged. Every Repository implementation should pass it.
+func assertSoftDeleteLifecycle(t *testing.T, repo repository.Repository) {
+	t.Helper()
 	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
 
-	record := &domain.URLRecord{
-		ShortCode:  "abc12345",
-		ClickCount: 0,
+	for _, code := range []string{"deleted1", "kept0001"} {
+		require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{
+			ShortCode: code,
+			LongURL:   "https://example.com/" + code,
+			CreatedAt: now,
+			ExpiresAt: now.Add(time.Hour),
+			Enabled:   true,
+		}))
 	}
-	_ = repo.SaveIfNotExists(ctx, record)
 
-	accessTime := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
-	err := repo.IncrementClickCount(ctx, "abc12345", accessTime)
+	require.NoError(t, repo.SoftDelete(ctx, "deleted1", now))
+	assert.ErrorIs(t, repo.SoftDelete(ctx, "deleted1", now), domain.ErrNotFound, "already deleted")
+	assert.ErrorIs(t, repo.SoftDelete(ctx, "missing1", now), domain.ErrNotFound)
+
+	_, err := repo.FindByShortCode(ctx, "deleted1")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = repo.FindByLongURL(ctx, "https://example.com/deleted1")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	found, err := repo.FindByShortCodes(ctx, []string{"deleted1", "kept0001"})
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+	assert.Contains(t, found, "kept0001")
+	page, total, err := repo.List(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, page, 1)
+	assert.Equal(t, "kept0001", page[0].ShortCode)
+	assert.ErrorIs(t, repo.RecordClick(ctx, "deleted1", "", now), domain.ErrNotFound)
+	assert.ErrorIs(t, repo.UpdateExpiry(ctx, "deleted1", now.Add(2*time.Hour)), domain.ErrNotFound)
+	assert.ErrorIs(t, repo.SetEnabled(ctx, "deleted1", false), domain.ErrNotFound)
+	assert.ErrorIs(t, repo.AppendHistory(ctx, "deleted1", domain.HistoryEvent{Type: domain.EventEnabled, At: now}), domain.ErrNotFound)
+
+	// The code stays taken so it can be restored.
+	err = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "deleted1", LongURL: "https://other.example", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	assert.ErrorIs(t, err, domain.ErrCodeExists)
+
+	// Deleted before the window opens: not restorable.
+	assert.ErrorIs(t, repo.Restore(ctx, "deleted1", now.Add(time.Second)), domain.ErrNotFound)
+	assert.ErrorIs(t, repo.Restore(ctx, "kept0001", now), domain.ErrNotFound, "not deleted")
+
+	require.NoError(t, repo.Restore(ctx, "deleted1", now.Add(-time.Minute)))
+	restored, err := repo.FindByShortCode(ctx, "deleted1")
 	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/deleted1", restored.LongURL)
+	assert.False(t, restored.IsDeleted())
+	require.NoError(t, repo.RecordClick(ctx, "deleted1", "", now))
+
+	// Purging removes only records deleted before the cutoff.
+	require.NoError(t, repo.SoftDelete(ctx, "deleted1", now))
+	require.NoError(t, repo.SoftDelete(ctx, "kept0001", now.Add(time.Hour)))
+	purged, err := repo.PurgeDeleted(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deleted1"}, purged)
+	assert.ErrorIs(t, repo.Restore(ctx, "deleted1", now.Add(-time.Hour)), domain.ErrNotFound)
+	require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "deleted1", LongURL: "https://other.example", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}),
+		"a purged code is free again")
+	require.NoError(t, repo.Restore(ctx, "kept0001", now))
+}
 
-	found, _ := repo.FindByShortCode(ctx, "abc12345")
-	assert.Equal(t, int64(1), found.ClickCount)
-	assert.Equal(t, accessTime, found.LastAccessedAt)
+func TestMemoryRepository_SoftDelete(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		assertSoftDeleteLifecycle(t, newRepo(0))
+	})
 }
 
-func TestMemoryRepository_IncrementClickCount_NotFound(t *testing.T) {
-	repo := repository.NewMemoryRepository()
-	ctx := context.Background()
+func TestMemoryRepository_PurgeDeleted_FreesCapacity(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		repo := newRepo(1)
+		ctx := context.Background()
+		now := time.Now()
 
-	err := repo.IncrementClickCount(ctx, "notexist", time.Now())
-	assert.ErrorIs(t, err, domain.ErrNotFound)
+		require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "first001", LongURL: "https://example.com", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}))
+		require.NoError(t, repo.SoftDelete(ctx, "first001", now))
+
+		second := &domain.URLRecord{ShortCode: "second01", LongURL: "https://example.com", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+		assert.ErrorIs(t, repo.SaveIfNotExists(ctx, second), domain.ErrCapacityExceeded, "deleted records count until purged")
+
+		_, err := repo.PurgeDeleted(ctx, now.Add(time.Second))
+		require.NoError(t, err)
+		assert.NoError(t, repo.SaveIfNotExists(ctx, second))
+	})
 }
 
-func TestMemoryRepository_IncrementClickCount_Multiple(t *testing.T) {
-	repo := repository.NewMemoryRepository()
+// assertStoreStats seeds a mix of live, expired, used-up, and deleted
+// records and checks the aggregates. Every Repository implementation
+// should pass it.
+func assertStoreStats(t *testing.T, repo repository.Repository) {
+	t.Helper()
 	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
 
-	record := &domain.URLRecord{
-		ShortCode:  "abc12345",
-		ClickCount: 0,
+	seed := []*domain.URLRecord{
+		{ShortCode: "live0001", ExpiresAt: now.Add(time.Hour), ClickCount: 4},
+		{ShortCode: "live0002", ExpiresAt: now.Add(time.Hour), ClickCount: 9},
+		{ShortCode: "forever1", ClickCount: 4},
+		{ShortCode: "expired1", ExpiresAt: now.Add(-time.Hour), ClickCount: 2},
+		{ShortCode: "usedup01", ExpiresAt: now.Add(time.Hour), ClickCount: 1, MaxClicks: 1},
+		{ShortCode: "deleted1", ExpiresAt: now.Add(time.Hour), ClickCount: 100},
 	}
-	_ = repo.SaveIfNotExists(ctx, record)
-
-	for i := 0; i < 100; i++ {
-		_ = repo.IncrementClickCount(ctx, "abc12345", time.Now())
+	for _, record := range seed {
+		record.LongURL = "https://example.com/" + record.ShortCode
+		record.CreatedAt = now.Add(-2 * time.Hour)
+		require.NoError(t, repo.SaveIfNotExists(ctx, record))
 	}
+	require.NoError(t, repo.SoftDelete(ctx, "deleted1", now))
 
-	found, _ := repo.FindByShortCode(ctx, "abc12345")
-	assert.Equal(t, int64(100), found.ClickCount)
+	stats, err := repo.Stats(ctx, now, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), stats.TotalLinks)
+	assert.Equal(t, int64(3), stats.ActiveLinks)
+	assert.Equal(t, int64(2), stats.ExpiredLinks)
+	assert.Equal(t, int64(20), stats.TotalClicks)
+	assert.Equal(t, []domain.LinkClicks{
+		{ShortCode: "live0002", LongURL: "https://example.com/live0002", Clicks: 9},
+		{ShortCode: "forever1", LongURL: "https://example.com/forever1", Clicks: 4},
+		{ShortCode: "live0001", LongURL: "https://example.com/live0001", Clicks: 4},
+	}, stats.TopLinks)
+
+	stats, err = repo.Stats(ctx, now, 0)
+	require.NoError(t, err)
+	assert.Empty(t, stats.TopLinks)
+	assert.Equal(t, int64(5), stats.TotalLinks)
 }
 
-func TestMemoryRepository_IncrementClickCount_Concurrent(t *testing.T) {
-	repo := repository.NewMemoryRepository()
+func TestMemoryRepository_Stats(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		assertStoreStats(t, newRepo(0))
+	})
+}
+
+// assertCount checks that Count tracks saves and removals and includes
+// soft-deleted records. Every Repository implementation should pass it.
+func assertCount(t *testing.T, repo repository.Repository) {
+	t.Helper()
 	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
 
-	record := &domain.URLRecord{
-		ShortCode:  "abc12345",
-		ClickCount: 0,
+	n, err := repo.Count(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	for i := range 3 {
+		require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{
+			ShortCode: fmt.Sprintf("code%04d", i),
+			LongURL:   "https://example.com",
+			ExpiresAt: now.Add(time.Duration(i) * time.Hour),
+			Enabled:   true,
+		}))
 	}
-	_ = repo.SaveIfNotExists(ctx, record)
-
-	// 100 goroutines each incrementing 100 times
-	const numGoroutines = 100
-	const incrementsPerGoroutine = 100
-	expectedTotal := int64(numGoroutines * incrementsPerGoroutine)
+	require.NoError(t, repo.SoftDelete(ctx, "code0002", now))
 
-	var wg sync.WaitGroup
-	wg.Add(numGoroutines)
+	n, err = repo.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n, "soft-deleted codes are still taken")
 
-	for i := 0; i < numGoroutines; i++ {
-		go func() {
-			defer wg.Done()
-			for j := 0; j < incrementsPerGoroutine; j++ {
-				err := repo.IncrementClickCount(ctx, "abc12345", time.Now())
-				assert.NoError(t, err)
-			}
-		}()
-	}
+	_, err = repo.DeleteExpired(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	n, err = repo.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+}
 
-	wg.Wait()
+func TestMemoryRepository_Count(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		assertCount(t, newRepo(0))
+	})
+}
 
-	found, _ := repo.FindByShortCode(ctx, "abc12345")
-	assert.Equal(t, expectedTotal, found.ClickCount,
-		"click count should be exactly %d after concurrent increments", expectedTotal)
+func TestMemoryRepository_Stats_Empty(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		stats, err := newRepo(0).Stats(context.Background(), time.Now(), 10)
+		require.NoError(t, err)
+		assert.Equal(t, domain.StoreStats{}, stats)
+	})
 }
 
-func TestMemoryRepository_SaveIfNotExists_ConcurrentCollision(t *testing.T) {
-	repo := repository.NewMemoryRepository()
+// assertResolveAndIncrement checks that ResolveAndIncrement refuses records
+// that can't be redirected to and that, under concurrency, counts exactly
+// the clicks made before the expiry instant. Every Repository
+// implementation should pass it.
+func assertResolveAndIncrement(t *testing.T, repo repository.Repository) {
+	t.Helper()
 	ctx := context.Background()
+	expiresAt := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
 
-	const numGoroutines = 100
-	code := "samecode"
+	seed := []*domain.URLRecord{
+		{ShortCode: "live0001", ExpiresAt: expiresAt, Enabled: true},
+		{ShortCode: "disabled", ExpiresAt: expiresAt, Enabled: false},
+		{ShortCode: "usedup01", ExpiresAt: expiresAt, Enabled: true, ClickCount: 2, MaxClicks: 2},
+		{ShortCode: "deleted1", ExpiresAt: expiresAt, Enabled: true},
+	}
+	for _, record := range seed {
+		record.LongURL = "https://example.com/" + record.ShortCode
+		record.CreatedAt = expiresAt.Add(-time.Hour)
+		require.NoError(t, repo.SaveIfNotExists(ctx, record))
+	}
+	require.NoError(t, repo.SoftDelete(ctx, "deleted1", expiresAt.Add(-time.Minute)))
 
+	before := expiresAt.Add(-time.Second)
+	_, err := repo.ResolveAndIncrement(ctx, "missing1", "", before)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = repo.ResolveAndIncrement(ctx, "deleted1", "", before)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = repo.ResolveAndIncrement(ctx, "disabled", "", before)
+	assert.ErrorIs(t, err, domain.ErrDisabled)
+	_, err = repo.ResolveAndIncrement(ctx, "usedup01", "", before)
+	assert.ErrorIs(t, err, domain.ErrExpired)
+
+	// Clicks straddling the expiry instant race each other. Only those at or
+	// before it may be counted.
+	const numGoroutines = 50
+	var counted atomic.Int64
 	var wg sync.WaitGroup
 	wg.Add(numGoroutines)
-
-	var successCount int32
-	var collisionCount int32
-
 	for i := 0; i < numGoroutines; i++ {
-		go func(id int) {
+		go func() {
 			defer wg.Done()
-			record := &domain.URLRecord{
-				ShortCode: code,
-				LongURL:   fmt.Sprintf("https://example.com/%d", id),
+			now := expiresAt.Add(-time.Duration(i) * time.Millisecond)
+			if i%2 == 1 {
+				now = expiresAt.Add(time.Duration(i) * time.Millisecond)
 			}
 
-			err := repo.SaveIfNotExists(ctx, record)
-			if err == nil {
-				atomic.AddInt32(&successCount, 1)
-			} else if errors.Is(err, domain.ErrCodeExists) {
-				atomic.AddInt32(&collisionCount, 1)
+			record, err := repo.ResolveAndIncrement(ctx, "live0001", "news.example.com", now)
+			if i%2 == 1 {
+				assert.ErrorIs(t, err, domain.ErrExpired)
+				return
+			}
+			if assert.NoError(t, err) {
+				counted.Add(1)
+				assert.Equal(t, "https://example.com/live0001", record.LongURL)
+				assert.Positive(t, record.ClickCount)
 			}
-		}(i)
+		}()
 	}
-
 	wg.Wait()
 
-	// Exactly one should succeed
-	assert.Equal(t, int32(1), successCount)
-	assert.Equal(t, int32(numGoroutines-1), collisionCount)
+	assert.Equal(t, int64(numGoroutines/2), counted.Load())
+	found, err := repo.FindByShortCode(ctx, "live0001")
+	require.NoError(t, err)
+	assert.Equal(t, int64(numGoroutines/2), found.ClickCount)
+	assert.Equal(t, int64(numGoroutines/2), found.Referrers["news.example.com"])
+
+	unchanged, err := repo.FindByShortCode(ctx, "usedup01")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), unchanged.ClickCount, "refused clicks are not counted")
 }
 
-func TestMemoryRepository_DeleteExpired(t *testing.T) {
-	repo := repository.NewMemoryRepository()
-	ctx := context.Background()
+func TestMemoryRepository_ResolveAndIncrement(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		assertResolveAndIncrement(t, newRepo(0))
+	})
+}
 
+// assertSoftDeleteByPrefix checks that SoftDeleteByPrefix soft-deletes
+// exactly the live records whose long URL starts with the prefix, stops a
+// host prefix at the end of the host, treats LIKE wildcards literally, and
+// refuses an empty prefix. Every Repository implementation should pass it.
+func assertSoftDeleteByPrefix(t *testing.T, repo repository.Repository) {
+	t.Helper()
+	ctx := context.Background()
 	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
-
-	// Create records with different expiry times
-	records := []*domain.URLRecord{
-		{ShortCode: "expired1", ExpiresAt: now.Add(-time.Hour)},   // Expired
-		{ShortCode: "expired2", ExpiresAt: now.Add(-time.Minute)}, // Expired
-		{ShortCode: "valid1", ExpiresAt: now.Add(time.Hour)},      // Valid
-		{ShortCode: "valid2", ExpiresAt: now.Add(time.Minute)},    // Valid
+	deletedAt := now.Add(time.Minute)
+
+	seed := map[string]string{
+		"spring01": "https://example.com/spring/a",
+		"spring02": "https://example.com/spring/b?utm=x",
+		"spring03": "https://example.com/spring/c",
+		"summer01": "https://example.com/summer/a",
+		"other001": "https://other.example/spring/a",
+		"percent1": "https://example.com/100%_off",
+		"lookalik": "https://example.com.evil.example/spring/a",
+		"port8080": "https://example.com:8080/a",
+		"port8081": "https://example.com:80801/a",
+	}
+	for code, longURL := range seed {
+		require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{
+			ShortCode: code,
+			LongURL:   longURL,
+			CreatedAt: now,
+			ExpiresAt: now.Add(time.Hour),
+		}))
 	}
+	require.NoError(t, repo.SoftDelete(ctx, "spring03", now))
+
+	_, err := repo.SoftDeleteByPrefix(ctx, "", deletedAt)
+	assert.ErrorIs(t, err, repository.ErrEmptyPrefix)
+
+	codes, err := repo.SoftDeleteByPrefix(ctx, "https://example.com/spring/", deletedAt)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"spring01", "spring02"}, codes, "already deleted records are left alone")
 
-	for _, r := range records {
-		_ = repo.SaveIfNotExists(ctx, r)
+	for _, code := range codes {
+		_, err := repo.FindByShortCode(ctx, code)
+		assert.ErrorIs(t, err, domain.ErrNotFound, code)
 	}
+	_, err = repo.FindByShortCode(ctx, "other001")
+	assert.NoError(t, err)
+
+	require.NoError(t, repo.Restore(ctx, "spring01", deletedAt), "bulk deleted records can be restored")
+	_, err = repo.FindByShortCode(ctx, "spring01")
+	assert.NoError(t, err)
 
-	deleted, err := repo.DeleteExpired(ctx, now)
+	codes, err = repo.SoftDeleteByPrefix(ctx, "https://example.com/1%", deletedAt)
+	require.NoError(t, err)
+	assert.Empty(t, codes)
+	codes, err = repo.SoftDeleteByPrefix(ctx, "https://example.com/100%_", deletedAt)
 	require.NoError(t, err)
-	assert.Equal(t, int64(2), deleted)
+	assert.Equal(t, []string{"percent1"}, codes)
 
-	// Verify expired are gone
-	_, err = repo.FindByShortCode(ctx, "expired1")
-	assert.ErrorIs(t, err, domain.ErrNotFound)
+	codes, err = repo.SoftDeleteByPrefix(ctx, "https://example.com:8080", deletedAt)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"port8080"}, codes)
 
-	_, err = repo.FindByShortCode(ctx, "expired2")
-	assert.ErrorIs(t, err, domain.ErrNotFound)
+	codes, err = repo.SoftDeleteByPrefix(ctx, "https://example.com", deletedAt)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"port8081", "spring01", "summer01"}, codes, "a host prefix must not match longer hosts")
 
-	// Verify valid still exist
-	_, err = repo.FindByShortCode(ctx, "valid1")
+	_, err = repo.FindByShortCode(ctx, "lookalik")
 	assert.NoError(t, err)
+}
 
-	_, err = repo.FindByShortCode(ctx, "valid2")
-	assert.NoError(t, err)
+func TestMemoryRepository_SoftDeleteByPrefix(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		assertSoftDeleteByPrefix(t, newRepo(0))
+	})
 }
 
-func TestMemoryRepository_DeleteExpired_Empty(t *testing.T) {
-	repo := repository.NewMemoryRepository()
+// assertListByTag checks that ListByTag pages through only the live
+// records carrying the tag, in List order, and that tags survive a round
+// trip. Every Repository implementation should pass it.
+func assertListByTag(t *testing.T, repo repository.Repository) {
+	t.Helper()
 	ctx := context.Background()
+	created := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	seed := []struct {
+		code string
+		tags []string
+	}{
+		{"spring01", []string{"spring", "email"}},
+		{"summer01", []string{"summer"}},
+		{"spring02", []string{"social", "spring"}},
+		{"untagged", nil},
+		{"spring03", []string{"spring"}},
+		{"springer", []string{"springer"}},
+	}
+	for i, s := range seed {
+		require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{
+			ShortCode: s.code,
+			LongURL:   "https://example.com/" + s.code,
+			CreatedAt: created.Add(time.Duration(i) * time.Minute),
+			ExpiresAt: created.Add(time.Hour),
+			Tags:      s.tags,
+		}))
+	}
+	require.NoError(t, repo.SoftDelete(ctx, "spring03", created))
 
-	deleted, err := repo.DeleteExpired(ctx, time.Now())
+	page, total, err := repo.ListByTag(ctx, "spring", 0, 10)
 	require.NoError(t, err)
-	assert.Equal(t, int64(0), deleted)
-}
+	assert.Equal(t, 2, total)
+	require.Len(t, page, 2)
+	assert.Equal(t, "spring01", page[0].ShortCode)
+	assert.Equal(t, []string{"spring", "email"}, page[0].Tags)
+	assert.Equal(t, "spring02", page[1].ShortCode)
 
-func TestMemoryRepository_RespectsContextCancellation(t *testing.T) {
-	repo := repository.NewMemoryRepository()
-	ctx, cancel := context.WithCancel(context.Background())
-	cancel() // Cancel immediately
-
-	record := &domain.URLRecord{ShortCode: "test1234"}
-
-	err := repo.SaveIfNotExists(ctx, record)
-	assert.ErrorIs(t, err, context.Canceled)
+	page, total, err = repo.ListByTag(ctx, "spring", 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	require.Len(t, page, 1)
+	assert.Equal(t, "spring02", page[0].ShortCode)
 
-	_, err = repo.FindByShortCode(ctx, "test1234")
-	assert.ErrorIs(t, err, context.Canceled)
+	page, total, err = repo.ListByTag(ctx, "winter", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, page)
 
-	err = repo.IncrementClickCount(ctx, "test1234", time.Now())
-	assert.ErrorIs(t, err, context.Canceled)
+	found, err := repo.FindByShortCode(ctx, "untagged")
+	require.NoError(t, err)
+	assert.Nil(t, found.Tags)
+}
 
-	_, err = repo.DeleteExpired(ctx, time.Now())
-	assert.ErrorIs(t, err, context.Canceled)
+func TestMemoryRepository_ListByTag(t *testing.T) {
+	forEachMemoryRepository(t, func(t *testing.T, newRepo memoryRepositoryFactory) {
+		assertListByTag(t, newRepo(0))
+	})
 }
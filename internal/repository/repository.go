@@ -2,28 +2,149 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"url-shortener/internal/domain"
 )
 
+// ErrEmptyPrefix is returned by SoftDeleteByPrefix for an empty prefix,
+// which would otherwise match every record.
+var ErrEmptyPrefix = errors.New("prefix must not be empty")
+
 // Repository defines the contract for URL storage operations.
 // All implementations must be thread-safe for concurrent access.
+// Soft-deleted records (see SoftDelete) are reported as domain.ErrNotFound
+// and left out of lookups and listings, but their codes stay taken.
 type Repository interface {
 	// SaveIfNotExists atomically saves the record only if the short code
-	// doesn't already exist. Returns domain.ErrCodeExists if taken.
+	// doesn't already exist. Returns domain.ErrCodeExists if taken, or
+	// domain.ErrCapacityExceeded if the store is full.
 	SaveIfNotExists(ctx context.Context, record *domain.URLRecord) error
 
 	// FindByShortCode retrieves a record by its short code.
 	// Returns domain.ErrNotFound if the code doesn't exist.
 	FindByShortCode(ctx context.Context, code string) (*domain.URLRecord, error)
 
-	// IncrementClickCount atomically increments the click counter
-	// and updates LastAccessedAt timestamp.
-	// Returns domain.ErrNotFound if the code doesn't exist.
+	// FindByShortCodes retrieves the records for all given codes as a single
+	// point-in-time snapshot. Codes that don't exist are omitted from the map.
+	FindByShortCodes(ctx context.Context, codes []string) (map[string]*domain.URLRecord, error)
+
+	// FindByLongURL retrieves the record for the given long URL with the
+	// latest expiry, which is the one most likely still valid. A record
+	// that never expires counts as expiring latest.
+	// Returns domain.ErrNotFound if no record has that long URL.
+	FindByLongURL(ctx context.Context, longURL string) (*domain.URLRecord, error)
+
+	// List returns up to limit records starting at offset, ordered by
+	// creation time and then short code, along with the total record count.
+	List(ctx context.Context, offset, limit int) ([]*domain.URLRecord, int, error)
+
+	// ListByTag is List restricted to records whose Tags include tag; the
+	// total counts only those.
+	ListByTag(ctx context.Context, tag string, offset, limit int) ([]*domain.URLRecord, int, error)
+
+	// Stats summarizes the stored records as of now, listing the top
+	// most-clicked links.
+	Stats(ctx context.Context, now time.Time, top int) (domain.StoreStats, error)
+
+	// Count returns the number of stored records. Soft-deleted records are
+	// included, since their codes stay taken.
+	Count(ctx context.Context) (int, error)
+
+	// IncrementClickCount atomically increments the click counter and the
+	// count for accessTime's day (see domain.CountDailyClick), and updates
+	// LastAccessedAt. The MaxClicks check happens in the same atomic step,
+	// so concurrent callers can't overshoot it.
+	// Returns domain.ErrNotFound if the code doesn't exist, or
+	// domain.ErrClickLimitReached if the record has no clicks left.
 	IncrementClickCount(ctx context.Context, code string, accessTime time.Time) error
 
-	// DeleteExpired removes all records where ExpiresAt < before.
+	// RecordClick is IncrementClickCount that also adds the click to the
+	// record's per-referrer counts (see domain.CountReferrer) atomically.
+	// An empty referrer counts the click without attributing it.
+	RecordClick(ctx context.Context, code, referrer string, accessTime time.Time) error
+
+	// ResolveAndIncrement is RecordClick for a click at now that first
+	// checks, in the same atomic step, that the record can still be
+	// redirected to, and returns the record with the click counted. No
+	// click is counted on failure.
+	// Returns domain.ErrNotFound if the code doesn't exist,
+	// domain.ErrExpired if the record has expired at now or has no clicks
+	// left, or domain.ErrDisabled if it is disabled.
+	ResolveAndIncrement(ctx context.Context, code, referrer string, now time.Time) (*domain.URLRecord, error)
+
+	// UpdateExpiry sets the record's expiry time.
+	// Returns domain.ErrNotFound if the code doesn't exist.
+	UpdateExpiry(ctx context.Context, code string, expiresAt time.Time) error
+
+	// SetEnabled enables or disables the record.
+	// Returns domain.ErrNotFound if the code doesn't exist.
+	SetEnabled(ctx context.Context, code string, enabled bool) error
+
+	// AppendHistory adds a lifecycle event to the record's bounded history.
+	// Returns domain.ErrNotFound if the code doesn't exist.
+	AppendHistory(ctx context.Context, code string, event domain.HistoryEvent) error
+
+	// SoftDelete marks the record deleted at deletedAt, keeping it so it
+	// can be restored. Returns domain.ErrNotFound if the code doesn't exist
+	// or is already deleted.
+	SoftDelete(ctx context.Context, code string, deletedAt time.Time) error
+
+	// Restore undoes SoftDelete for a record deleted at or after
+	// deletedSince. Returns domain.ErrNotFound if the code doesn't exist,
+	// isn't deleted, or was deleted before deletedSince.
+	Restore(ctx context.Context, code string, deletedSince time.Time) error
+
+	// PurgeDeleted removes records soft-deleted before deletedBefore and
+	// returns their codes, sorted.
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) ([]string, error)
+
+	// DeleteExpired removes all records where ExpiresAt < before, keeping
+	// records that never expire. Deleted records are removed too.
 	// Returns the number of deleted records.
 	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+
+	// DeleteExpiredCodes is DeleteExpired that returns the short codes of
+	// the deleted records, sorted, instead of their count.
+	DeleteExpiredCodes(ctx context.Context, before time.Time) ([]string, error)
+
+	// SoftDeleteByPrefix marks every live record whose LongURL starts with
+	// prefix deleted at deletedAt, as SoftDelete does, and returns their
+	// short codes, sorted. A prefix that ends inside the host only matches
+	// at the end of the host, so "https://example.com" doesn't match
+	// "https://example.com.evil.example". Returns ErrEmptyPrefix if prefix
+	// is empty.
+	SoftDeleteByPrefix(ctx context.Context, prefix string, deletedAt time.Time) ([]string, error)
+
+	// Ping reports whether the backend is reachable. It should be cheap
+	// enough to call from a readiness probe.
+	Ping(ctx context.Context) error
+}
+
+// hostBoundaries returns the characters that may follow prefix in a long
+// URL it matches, or "" if any may: a prefix ending inside a URL's host,
+// such as "https://example.com", must be followed by the end of the host.
+func hostBoundaries(prefix string) string {
+	_, rest, ok := strings.Cut(prefix, "://")
+	if !ok || strings.ContainsAny(rest, "/?#") {
+		return ""
+	}
+	if strings.Contains(rest, ":") {
+		// The prefix already has a port, which must end too.
+		return "/?#"
+	}
+	return "/:?#"
+}
+
+// hasURLPrefix reports whether longURL starts with prefix, respecting
+// hostBoundaries.
+func hasURLPrefix(longURL, prefix string) bool {
+	rest, ok := strings.CutPrefix(longURL, prefix)
+	if !ok {
+		return false
+	}
+	boundaries := hostBoundaries(prefix)
+	return boundaries == "" || rest == "" || strings.IndexByte(boundaries, rest[0]) >= 0
 }
@@ -0,0 +1,380 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// DefaultShards is the shard count NewShardedMemoryRepository uses when
+// given zero or less.
+const DefaultShards = 32
+
+// ShardedMemoryRepository is an in-memory repository that spreads records
+// over shards by a hash of the short code, each with its own lock, so
+// writers to different codes rarely contend. It behaves like
+// MemoryRepository; only operations spanning many codes lock every shard.
+type ShardedMemoryRepository struct {
+	shards []*MemoryRepository
+
+	// count tracks the records across all shards when capacity is set,
+	// since no single shard knows the total.
+	count    atomic.Int64
+	capacity int64
+}
+
+// NewShardedMemoryRepository creates a repository with the given number of
+// shards (DefaultShards if zero or less) holding at most capacity records
+// in total, or unbounded if capacity is zero or less.
+func NewShardedMemoryRepository(shards, capacity int) *ShardedMemoryRepository {
+	if shards <= 0 {
+		shards = DefaultShards
+	}
+	r := &ShardedMemoryRepository{
+		shards:   make([]*MemoryRepository, shards),
+		capacity: int64(max(capacity, 0)),
+	}
+	for i := range r.shards {
+		r.shards[i] = NewMemoryRepository()
+	}
+	return r
+}
+
+// shardIndex returns the index of the shard holding code.
+func (r *ShardedMemoryRepository) shardIndex(code string) int {
+	return int(fnv32a(code) % uint32(len(r.shards)))
+}
+
+// fnv32a returns the 32-bit FNV-1a hash of code, computed inline to avoid
+// allocating a hash.Hash per call.
+func fnv32a(code string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(code); i++ {
+		h ^= uint32(code[i])
+		h *= 16777619
+	}
+	return h
+}
+
+func (r *ShardedMemoryRepository) shard(code string) *MemoryRepository {
+	return r.shards[r.shardIndex(code)]
+}
+
+// SaveIfNotExists atomically saves the record only if the short code
+// doesn't already exist.
+func (r *ShardedMemoryRepository) SaveIfNotExists(ctx context.Context, record *domain.URLRecord) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s := r.shard(record.ShortCode)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.data[record.ShortCode]; exists {
+		return domain.ErrCodeExists
+	}
+	if r.capacity > 0 && !r.reserve() {
+		return domain.ErrCapacityExceeded
+	}
+
+	s.data[record.ShortCode] = record.Clone()
+	s.byLongURL[record.LongURL] = append(s.byLongURL[record.LongURL], record.ShortCode)
+	return nil
+}
+
+// reserve claims room for one more record, reporting false when full.
+func (r *ShardedMemoryRepository) reserve() bool {
+	for {
+		n := r.count.Load()
+		if n >= r.capacity {
+			return false
+		}
+		if r.count.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// FindByShortCode retrieves a record by its short code.
+func (r *ShardedMemoryRepository) FindByShortCode(ctx context.Context, code string) (*domain.URLRecord, error) {
+	return r.shard(code).FindByShortCode(ctx, code)
+}
+
+// FindByShortCodes retrieves the records for all given codes, holding the
+// read locks of every shard involved at once so the returned records are
+// mutually consistent.
+func (r *ShardedMemoryRepository) FindByShortCodes(ctx context.Context, codes []string) (map[string]*domain.URLRecord, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	involved := make([]bool, len(r.shards))
+	for _, code := range codes {
+		involved[r.shardIndex(code)] = true
+	}
+	unlock := r.rlockShards(involved)
+	defer unlock()
+
+	found := make(map[string]*domain.URLRecord, len(codes))
+	for _, code := range codes {
+		if record, exists := r.shard(code).live(code); exists {
+			found[code] = record.Clone()
+		}
+	}
+
+	return found, nil
+}
+
+// rlockShards read-locks the shards flagged in which, in index order so
+// concurrent callers can't deadlock, and returns a func releasing them.
+// A nil which locks every shard.
+func (r *ShardedMemoryRepository) rlockShards(which []bool) func() {
+	var locked []*MemoryRepository
+	for i, s := range r.shards {
+		if which == nil || which[i] {
+			s.mu.RLock()
+			locked = append(locked, s)
+		}
+	}
+	return func() {
+		for _, s := range locked {
+			s.mu.RUnlock()
+		}
+	}
+}
+
+// FindByLongURL retrieves the record for the given long URL with the latest
+// expiry. Records for one long URL may live in any shard, so every shard's
+// index is consulted.
+func (r *ShardedMemoryRepository) FindByLongURL(ctx context.Context, longURL string) (*domain.URLRecord, error) {
+	var latest *domain.URLRecord
+	for _, s := range r.shards {
+		record, err := s.FindByLongURL(ctx, longURL)
+		if errors.Is(err, domain.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if latest == nil || expiresLater(record, latest) {
+			latest = record
+		}
+	}
+
+	if latest == nil {
+		return nil, domain.ErrNotFound
+	}
+
+	return latest, nil
+}
+
+// List returns a page of records ordered by creation time, then short code,
+// read with every shard locked so the page and total agree.
+func (r *ShardedMemoryRepository) List(ctx context.Context, offset, limit int) ([]*domain.URLRecord, int, error) {
+	return r.listWhere(ctx, offset, limit, func(*domain.URLRecord) bool { return true })
+}
+
+// ListByTag returns a page of the records labeled with tag across all
+// shards, ordered as for List.
+func (r *ShardedMemoryRepository) ListByTag(ctx context.Context, tag string, offset, limit int) ([]*domain.URLRecord, int, error) {
+	return r.listWhere(ctx, offset, limit, func(record *domain.URLRecord) bool {
+		return record.HasTag(tag)
+	})
+}
+
+// listWhere returns a page of the live records matching keep, read with
+// every shard locked.
+func (r *ShardedMemoryRepository) listWhere(ctx context.Context, offset, limit int, keep func(*domain.URLRecord) bool) ([]*domain.URLRecord, int, error) {
+	select {
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	default:
+	}
+
+	unlock := r.rlockShards(nil)
+	defer unlock()
+
+	var all []*domain.URLRecord
+	for _, s := range r.shards {
+		for _, record := range s.data {
+			if !record.IsDeleted() && keep(record) {
+				all = append(all, record)
+			}
+		}
+	}
+	page, total := paginate(all, offset, limit)
+	return page, total, nil
+}
+
+// Count returns the number of stored records across all shards,
+// soft-deleted ones included.
+func (r *ShardedMemoryRepository) Count(ctx context.Context) (int, error) {
+	total := 0
+	for _, s := range r.shards {
+		n, err := s.Count(ctx)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// Stats summarizes the stored records as of now, read with every shard
+// locked so the numbers agree, without copying them.
+func (r *ShardedMemoryRepository) Stats(ctx context.Context, now time.Time, top int) (domain.StoreStats, error) {
+	select {
+	case <-ctx.Done():
+		return domain.StoreStats{}, ctx.Err()
+	default:
+	}
+
+	unlock := r.rlockShards(nil)
+	defer unlock()
+
+	var stats domain.StoreStats
+	for _, s := range r.shards {
+		for _, record := range s.data {
+			if !record.IsDeleted() {
+				stats.Add(record, now, top)
+			}
+		}
+	}
+	return stats, nil
+}
+
+// IncrementClickCount atomically increments the click counter, refusing once
+// the record's MaxClicks is reached.
+func (r *ShardedMemoryRepository) IncrementClickCount(ctx context.Context, code string, accessTime time.Time) error {
+	return r.shard(code).IncrementClickCount(ctx, code, accessTime)
+}
+
+// RecordClick counts a click like IncrementClickCount and, in the same
+// step, tallies it against referrer unless referrer is empty.
+func (r *ShardedMemoryRepository) RecordClick(ctx context.Context, code, referrer string, accessTime time.Time) error {
+	return r.shard(code).RecordClick(ctx, code, referrer, accessTime)
+}
+
+// ResolveAndIncrement counts a click at now like RecordClick, but only if
+// the record can still be redirected to at now, checked under the shard's
+// lock.
+func (r *ShardedMemoryRepository) ResolveAndIncrement(ctx context.Context, code, referrer string, now time.Time) (*domain.URLRecord, error) {
+	return r.shard(code).ResolveAndIncrement(ctx, code, referrer, now)
+}
+
+// ApplyClickDeltas adds the buffered clicks of every delta, locking each
+// shard they touch once.
+func (r *ShardedMemoryRepository) ApplyClickDeltas(ctx context.Context, deltas []ClickDelta) error {
+	byShard := make(map[int][]ClickDelta)
+	for _, d := range deltas {
+		i := r.shardIndex(d.Code)
+		byShard[i] = append(byShard[i], d)
+	}
+	for i, shardDeltas := range byShard {
+		if err := r.shards[i].ApplyClickDeltas(ctx, shardDeltas); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateExpiry sets the record's expiry time.
+func (r *ShardedMemoryRepository) UpdateExpiry(ctx context.Context, code string, expiresAt time.Time) error {
+	return r.shard(code).UpdateExpiry(ctx, code, expiresAt)
+}
+
+// SetEnabled enables or disables the record.
+func (r *ShardedMemoryRepository) SetEnabled(ctx context.Context, code string, enabled bool) error {
+	return r.shard(code).SetEnabled(ctx, code, enabled)
+}
+
+// AppendHistory adds a lifecycle event to the record's bounded history.
+func (r *ShardedMemoryRepository) AppendHistory(ctx context.Context, code string, event domain.HistoryEvent) error {
+	return r.shard(code).AppendHistory(ctx, code, event)
+}
+
+// SoftDelete marks the record deleted at deletedAt.
+func (r *ShardedMemoryRepository) SoftDelete(ctx context.Context, code string, deletedAt time.Time) error {
+	return r.shard(code).SoftDelete(ctx, code, deletedAt)
+}
+
+// Restore undoes SoftDelete for a record deleted at or after deletedSince.
+func (r *ShardedMemoryRepository) Restore(ctx context.Context, code string, deletedSince time.Time) error {
+	return r.shard(code).Restore(ctx, code, deletedSince)
+}
+
+// PurgeDeleted removes records soft-deleted before deletedBefore from every
+// shard, one shard at a time, and returns their codes in sorted order.
+func (r *ShardedMemoryRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	return r.deleteFromShards(func(s *MemoryRepository) ([]string, error) {
+		return s.PurgeDeleted(ctx, deletedBefore)
+	})
+}
+
+// DeleteExpired removes all records that have expired before the given time.
+func (r *ShardedMemoryRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	codes, err := r.DeleteExpiredCodes(ctx, before)
+	return int64(len(codes)), err
+}
+
+// DeleteExpiredCodes removes all records that have expired before the given
+// time from every shard, one shard at a time, and returns their codes in
+// sorted order.
+func (r *ShardedMemoryRepository) DeleteExpiredCodes(ctx context.Context, before time.Time) ([]string, error) {
+	return r.deleteFromShards(func(s *MemoryRepository) ([]string, error) {
+		return s.DeleteExpiredCodes(ctx, before)
+	})
+}
+
+// SoftDeleteByPrefix marks the live records whose long URL starts with
+// prefix deleted at deletedAt in every shard, one shard at a time, and
+// returns their codes in sorted order.
+func (r *ShardedMemoryRepository) SoftDeleteByPrefix(ctx context.Context, prefix string, deletedAt time.Time) ([]string, error) {
+	if prefix == "" {
+		return nil, ErrEmptyPrefix
+	}
+
+	var deleted []string
+	for _, s := range r.shards {
+		codes, err := s.SoftDeleteByPrefix(ctx, prefix, deletedAt)
+		if err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, codes...)
+	}
+
+	sort.Strings(deleted)
+	return deleted, nil
+}
+
+// deleteFromShards runs remove on each shard, keeping the record count in
+// step, and returns the removed codes in sorted order.
+func (r *ShardedMemoryRepository) deleteFromShards(remove func(*MemoryRepository) ([]string, error)) ([]string, error) {
+	var deleted []string
+	for _, s := range r.shards {
+		codes, err := remove(s)
+		r.count.Add(-int64(len(codes)))
+		deleted = append(deleted, codes...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(deleted)
+	return deleted, nil
+}
+
+// Ping always succeeds unless ctx is done; memory storage can't become
+// unreachable.
+func (r *ShardedMemoryRepository) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
@@ -0,0 +1,767 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"url-shortener/internal/domain"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" database/sql driver
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS url_records (
+	short_code         TEXT PRIMARY KEY,
+	long_url           TEXT NOT NULL,
+	created_at         INTEGER NOT NULL,
+	expires_at         INTEGER NOT NULL,
+	click_count        INTEGER NOT NULL DEFAULT 0,
+	last_accessed_at   INTEGER NOT NULL DEFAULT 0,
+	max_clicks         INTEGER NOT NULL DEFAULT 0,
+	redirect_permanent INTEGER NOT NULL DEFAULT 0,
+	history            TEXT NOT NULL DEFAULT '[]',
+	enabled            INTEGER NOT NULL DEFAULT 1,
+	referrers          TEXT NOT NULL DEFAULT '{}',
+	clicks_by_day      TEXT NOT NULL DEFAULT '{}',
+	deleted_at         INTEGER NOT NULL DEFAULT 0,
+	tags               TEXT NOT NULL DEFAULT '[]',
+	ttl                INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_url_records_long_url ON url_records (long_url, expires_at);
+CREATE INDEX IF NOT EXISTS idx_url_records_expires_at ON url_records (expires_at);
+`
+
+const sqliteColumns = `short_code, long_url, created_at, expires_at, click_count,
+	last_accessed_at, max_clicks, redirect_permanent, history, enabled, referrers,
+	clicks_by_day, tags, ttl`
+
+// SQLiteRepository provides durable storage in a SQLite database.
+// Timestamps are stored as Unix nanoseconds, with 0 meaning the zero time,
+// and durations as nanoseconds.
+// Queries for live records filter on deleted_at = 0.
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository opens (or creates) the SQLite database at path and
+// ensures the schema exists. Use ":memory:" for a throwaway database.
+func NewSQLiteRepository(path string) (*SQLiteRepository, error) {
+	dsn := path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite database: %w", err)
+	}
+
+	// SQLite allows a single writer; serializing through one connection
+	// avoids SQLITE_BUSY under concurrent writes and keeps ":memory:"
+	// databases from being split across connections.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("creating sqlite schema: %w", err)
+	}
+
+	return &SQLiteRepository{db: db}, nil
+}
+
+// Close releases the underlying database.
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+// SaveIfNotExists inserts the record, relying on the primary key constraint
+// to reject an existing short code atomically.
+func (r *SQLiteRepository) SaveIfNotExists(ctx context.Context, record *domain.URLRecord) error {
+	history, err := json.Marshal(record.History)
+	if err != nil {
+		return fmt.Errorf("encoding history: %w", err)
+	}
+	referrers, err := encodeCounts(record.Referrers)
+	if err != nil {
+		return err
+	}
+	clicksByDay, err := encodeCounts(record.ClicksByDay)
+	if err != nil {
+		return err
+	}
+	tags, err := encodeTags(record.Tags)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO url_records (`+sqliteColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (short_code) DO NOTHING`,
+		record.ShortCode,
+		record.LongURL,
+		toUnixNano(record.CreatedAt),
+		toUnixNano(record.ExpiresAt),
+		record.ClickCount,
+		toUnixNano(record.LastAccessedAt),
+		record.MaxClicks,
+		record.RedirectPermanent,
+		string(history),
+		record.Enabled,
+		referrers,
+		clicksByDay,
+		tags,
+		int64(record.TTL),
+	)
+	if err != nil {
+		return fmt.Errorf("inserting record: %w", err)
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("inserting record: %w", err)
+	}
+	if inserted == 0 {
+		return domain.ErrCodeExists
+	}
+
+	return nil
+}
+
+// FindByShortCode retrieves a record by its short code.
+func (r *SQLiteRepository) FindByShortCode(ctx context.Context, code string) (*domain.URLRecord, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+sqliteColumns+` FROM url_records WHERE short_code = ? AND deleted_at = 0`, code)
+
+	return scanRecord(row)
+}
+
+// FindByShortCodes retrieves the records for all given codes in a single
+// query, so the returned records are mutually consistent.
+func (r *SQLiteRepository) FindByShortCodes(ctx context.Context, codes []string) (map[string]*domain.URLRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]*domain.URLRecord, len(codes))
+	if len(codes) == 0 {
+		return found, nil
+	}
+
+	args := make([]any, len(codes))
+	for i, code := range codes {
+		args[i] = code
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(codes)), ",")
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+sqliteColumns+` FROM url_records WHERE short_code IN (`+placeholders+`) AND deleted_at = 0`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying records: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		found[record.ShortCode] = record
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying records: %w", err)
+	}
+
+	return found, nil
+}
+
+// FindByLongURL retrieves the record for the given long URL with the latest
+// expiry.
+func (r *SQLiteRepository) FindByLongURL(ctx context.Context, longURL string) (*domain.URLRecord, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+sqliteColumns+` FROM url_records
+		WHERE long_url = ? AND deleted_at = 0 ORDER BY expires_at = 0 DESC, expires_at DESC LIMIT 1`, longURL)
+
+	return scanRecord(row)
+}
+
+// List returns a page of records ordered by creation time, then short code.
+// The page and total are read in one transaction so they agree.
+func (r *SQLiteRepository) List(ctx context.Context, offset, limit int) ([]*domain.URLRecord, int, error) {
+	return r.listWhere(ctx, offset, limit, `deleted_at = 0`)
+}
+
+// ListByTag returns a page of the records labeled with tag, ordered and
+// read as for List.
+func (r *SQLiteRepository) ListByTag(ctx context.Context, tag string, offset, limit int) ([]*domain.URLRecord, int, error) {
+	return r.listWhere(ctx, offset, limit,
+		`deleted_at = 0 AND EXISTS (SELECT 1 FROM json_each(tags) WHERE value = ?)`, tag)
+}
+
+// listWhere returns a page of the records matching where, along with their
+// total count.
+func (r *SQLiteRepository) listWhere(ctx context.Context, offset, limit int, where string, args ...any) ([]*domain.URLRecord, int, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var total int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM url_records WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("counting records: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT `+sqliteColumns+` FROM url_records WHERE `+where+`
+		ORDER BY created_at, short_code LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing records: %w", err)
+	}
+	defer rows.Close()
+
+	page := []*domain.URLRecord{}
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		page = append(page, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("listing records: %w", err)
+	}
+
+	return page, total, nil
+}
+
+// Count returns the number of stored records, soft-deleted ones included.
+func (r *SQLiteRepository) Count(ctx context.Context) (int, error) {
+	var n int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM url_records`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting records: %w", err)
+	}
+	return n, nil
+}
+
+// Stats summarizes the stored records as of now with aggregate queries,
+// read in one transaction so the numbers agree.
+func (r *SQLiteRepository) Stats(ctx context.Context, now time.Time, top int) (domain.StoreStats, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return domain.StoreStats{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var stats domain.StoreStats
+	err = tx.QueryRowContext(ctx,
+		`SELECT COUNT(*),
+			COALESCE(SUM(CASE WHEN (expires_at != 0 AND expires_at < ?)
+				OR (max_clicks > 0 AND click_count >= max_clicks) THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(click_count), 0)
+		FROM url_records WHERE deleted_at = 0`, toUnixNano(now)).
+		Scan(&stats.TotalLinks, &stats.ExpiredLinks, &stats.TotalClicks)
+	if err != nil {
+		return domain.StoreStats{}, fmt.Errorf("aggregating records: %w", err)
+	}
+	stats.ActiveLinks = stats.TotalLinks - stats.ExpiredLinks
+
+	if top <= 0 {
+		return stats, nil
+	}
+	rows, err := tx.QueryContext(ctx,
+		`SELECT short_code, long_url, click_count FROM url_records WHERE deleted_at = 0
+		ORDER BY click_count DESC, short_code LIMIT ?`, top)
+	if err != nil {
+		return domain.StoreStats{}, fmt.Errorf("querying top links: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link domain.LinkClicks
+		if err := rows.Scan(&link.ShortCode, &link.LongURL, &link.Clicks); err != nil {
+			return domain.StoreStats{}, fmt.Errorf("scanning top link: %w", err)
+		}
+		stats.TopLinks = append(stats.TopLinks, link)
+	}
+	if err := rows.Err(); err != nil {
+		return domain.StoreStats{}, fmt.Errorf("querying top links: %w", err)
+	}
+
+	return stats, nil
+}
+
+// IncrementClickCount atomically increments the click counter.
+func (r *SQLiteRepository) IncrementClickCount(ctx context.Context, code string, accessTime time.Time) error {
+	return r.RecordClick(ctx, code, "", accessTime)
+}
+
+// RecordClick counts a click, tallying it against its day and referrer. The
+// counts are read and rewritten in one transaction that also checks
+// MaxClicks, so concurrent clicks can't overshoot it or lose updates.
+func (r *SQLiteRepository) RecordClick(ctx context.Context, code, referrer string, accessTime time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var (
+		clickCount, maxClicks int64
+		rawReferrers, rawDays string
+	)
+	err = tx.QueryRowContext(ctx,
+		`SELECT click_count, max_clicks, referrers, clicks_by_day FROM url_records WHERE short_code = ? AND deleted_at = 0`, code).
+		Scan(&clickCount, &maxClicks, &rawReferrers, &rawDays)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("reading click count: %w", err)
+	}
+	if maxClicks > 0 && clickCount >= maxClicks {
+		return domain.ErrClickLimitReached
+	}
+
+	referrers, days, err := countClick(rawReferrers, rawDays, referrer, accessTime)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE url_records
+		SET click_count = click_count + 1, last_accessed_at = ?, referrers = ?, clicks_by_day = ?
+		WHERE short_code = ?`,
+		toUnixNano(accessTime), referrers, days, code); err != nil {
+		return fmt.Errorf("recording click: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ResolveAndIncrement counts a click at now like RecordClick, but only if
+// the record can still be redirected to at now. The check and the update
+// share one transaction.
+func (r *SQLiteRepository) ResolveAndIncrement(ctx context.Context, code, referrer string, now time.Time) (*domain.URLRecord, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	record, err := scanRecord(tx.QueryRowContext(ctx,
+		`SELECT `+sqliteColumns+` FROM url_records WHERE short_code = ? AND deleted_at = 0`, code))
+	if err != nil {
+		return nil, err
+	}
+	if record.IsExpired(now) || record.ClickLimitReached() {
+		return nil, domain.ErrExpired
+	}
+	if !record.Enabled {
+		return nil, domain.ErrDisabled
+	}
+
+	applyClick(record, referrer, now)
+	referrers, err := encodeCounts(record.Referrers)
+	if err != nil {
+		return nil, err
+	}
+	days, err := encodeCounts(record.ClicksByDay)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE url_records
+		SET click_count = click_count + 1, last_accessed_at = ?, referrers = ?, clicks_by_day = ?
+		WHERE short_code = ?`,
+		toUnixNano(now), referrers, days, code); err != nil {
+		return nil, fmt.Errorf("recording click: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing click: %w", err)
+	}
+	return record, nil
+}
+
+// ApplyClickDeltas adds the buffered clicks of every delta in one
+// transaction.
+func (r *SQLiteRepository) ApplyClickDeltas(ctx context.Context, deltas []ClickDelta) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, d := range deltas {
+		var rawReferrers, rawDays string
+		err := tx.QueryRowContext(ctx,
+			`SELECT referrers, clicks_by_day FROM url_records WHERE short_code = ? AND deleted_at = 0`, d.Code).
+			Scan(&rawReferrers, &rawDays)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("reading click counts: %w", err)
+		}
+
+		record := &domain.URLRecord{}
+		if record.Referrers, err = decodeCounts(rawReferrers); err != nil {
+			return err
+		}
+		if record.ClicksByDay, err = decodeCounts(rawDays); err != nil {
+			return err
+		}
+		applyClickDelta(record, d)
+
+		referrers, err := encodeCounts(record.Referrers)
+		if err != nil {
+			return err
+		}
+		days, err := encodeCounts(record.ClicksByDay)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE url_records
+			SET click_count = click_count + ?, last_accessed_at = MAX(last_accessed_at, ?), referrers = ?, clicks_by_day = ?
+			WHERE short_code = ?`,
+			d.Clicks, toUnixNano(d.LastAccessedAt), referrers, days, d.Code); err != nil {
+			return fmt.Errorf("applying clicks: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// countClick adds a click to the stored referrer and daily counts, returning
+// them re-encoded. An empty referrer leaves the referrer counts unchanged.
+func countClick(rawReferrers, rawDays, referrer string, accessTime time.Time) (referrers, days string, err error) {
+	referrerCounts, err := decodeCounts(rawReferrers)
+	if err != nil {
+		return "", "", err
+	}
+	if referrer != "" {
+		referrerCounts = domain.CountReferrer(referrerCounts, referrer)
+	}
+	if referrers, err = encodeCounts(referrerCounts); err != nil {
+		return "", "", err
+	}
+
+	dayCounts, err := decodeCounts(rawDays)
+	if err != nil {
+		return "", "", err
+	}
+	if days, err = encodeCounts(domain.CountDailyClick(dayCounts, accessTime)); err != nil {
+		return "", "", err
+	}
+
+	return referrers, days, nil
+}
+
+// UpdateExpiry sets the record's expiry time.
+func (r *SQLiteRepository) UpdateExpiry(ctx context.Context, code string, expiresAt time.Time) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE url_records SET expires_at = ? WHERE short_code = ? AND deleted_at = 0`,
+		toUnixNano(expiresAt), code)
+	if err != nil {
+		return fmt.Errorf("updating expiry: %w", err)
+	}
+
+	return requireAffected(result)
+}
+
+// SetEnabled enables or disables the record.
+func (r *SQLiteRepository) SetEnabled(ctx context.Context, code string, enabled bool) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE url_records SET enabled = ? WHERE short_code = ? AND deleted_at = 0`, enabled, code)
+	if err != nil {
+		return fmt.Errorf("updating enabled: %w", err)
+	}
+
+	return requireAffected(result)
+}
+
+// AppendHistory adds a lifecycle event to the record's bounded history.
+func (r *SQLiteRepository) AppendHistory(ctx context.Context, code string, event domain.HistoryEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var raw string
+	err = tx.QueryRowContext(ctx, `SELECT history FROM url_records WHERE short_code = ? AND deleted_at = 0`, code).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+
+	var history []domain.HistoryEvent
+	if err := json.Unmarshal([]byte(raw), &history); err != nil {
+		return fmt.Errorf("decoding history: %w", err)
+	}
+
+	encoded, err := json.Marshal(domain.AppendHistory(history, event.Clone()))
+	if err != nil {
+		return fmt.Errorf("encoding history: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE url_records SET history = ? WHERE short_code = ?`, string(encoded), code); err != nil {
+		return fmt.Errorf("writing history: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// SoftDelete marks the record deleted at deletedAt.
+func (r *SQLiteRepository) SoftDelete(ctx context.Context, code string, deletedAt time.Time) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE url_records SET deleted_at = ? WHERE short_code = ? AND deleted_at = 0`,
+		toUnixNano(deletedAt), code)
+	if err != nil {
+		return fmt.Errorf("deleting record: %w", err)
+	}
+
+	return requireAffected(result)
+}
+
+// Restore undoes SoftDelete for a record deleted at or after deletedSince.
+func (r *SQLiteRepository) Restore(ctx context.Context, code string, deletedSince time.Time) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE url_records SET deleted_at = 0 WHERE short_code = ? AND deleted_at != 0 AND deleted_at >= ?`,
+		code, toUnixNano(deletedSince))
+	if err != nil {
+		return fmt.Errorf("restoring record: %w", err)
+	}
+
+	return requireAffected(result)
+}
+
+// PurgeDeleted removes records soft-deleted before deletedBefore and
+// returns their codes in sorted order.
+func (r *SQLiteRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	codes, err := r.deleteReturningCodes(ctx,
+		`deleted_at != 0 AND deleted_at < ?`, toUnixNano(deletedBefore))
+	if err != nil {
+		return nil, fmt.Errorf("purging deleted records: %w", err)
+	}
+	return codes, nil
+}
+
+// DeleteExpired removes all records that have expired before the given time.
+func (r *SQLiteRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM url_records WHERE expires_at != 0 AND expires_at < ?`, toUnixNano(before))
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired records: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteExpiredCodes removes all records that have expired before the given
+// time and returns their codes in sorted order.
+func (r *SQLiteRepository) DeleteExpiredCodes(ctx context.Context, before time.Time) ([]string, error) {
+	codes, err := r.deleteReturningCodes(ctx,
+		`expires_at != 0 AND expires_at < ?`, toUnixNano(before))
+	if err != nil {
+		return nil, fmt.Errorf("deleting expired records: %w", err)
+	}
+	return codes, nil
+}
+
+// SoftDeleteByPrefix marks the live records whose long URL starts with
+// prefix deleted at deletedAt and returns their codes in sorted order.
+// instr is used rather than LIKE so that '%' and '_' in the prefix match
+// literally. length and substr count characters, as does the prefix length
+// passed in.
+func (r *SQLiteRepository) SoftDeleteByPrefix(ctx context.Context, prefix string, deletedAt time.Time) ([]string, error) {
+	if prefix == "" {
+		return nil, ErrEmptyPrefix
+	}
+	boundaries, n := hostBoundaries(prefix), utf8.RuneCountInString(prefix)
+	codes, err := r.codesReturnedBy(ctx,
+		`UPDATE url_records SET deleted_at = ? WHERE deleted_at = 0 AND instr(long_url, ?) = 1
+		AND (? = '' OR length(long_url) = ? OR instr(?, substr(long_url, ? + 1, 1)) > 0)
+		RETURNING short_code`,
+		toUnixNano(deletedAt), prefix, boundaries, n, boundaries, n)
+	if err != nil {
+		return nil, fmt.Errorf("deleting records by prefix: %w", err)
+	}
+	return codes, nil
+}
+
+// deleteReturningCodes deletes the rows matching where and returns their
+// codes in sorted order.
+func (r *SQLiteRepository) deleteReturningCodes(ctx context.Context, where string, args ...any) ([]string, error) {
+	return r.codesReturnedBy(ctx, `DELETE FROM url_records WHERE `+where+` RETURNING short_code`, args...)
+}
+
+// codesReturnedBy runs query, which must return the short codes of the rows
+// it changes, and returns them in sorted order.
+func (r *SQLiteRepository) codesReturnedBy(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deleted []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, fmt.Errorf("scanning deleted code: %w", err)
+		}
+		deleted = append(deleted, code)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(deleted)
+	return deleted, nil
+}
+
+// Ping checks that the database connection is usable.
+func (r *SQLiteRepository) Ping(ctx context.Context) error {
+	if err := r.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("pinging sqlite database: %w", err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRecord(row rowScanner) (*domain.URLRecord, error) {
+	var (
+		record                               domain.URLRecord
+		createdAt, expiresAt, lastAccessedAt int64
+		history, referrers, clicksByDay      string
+		tags                                 string
+		ttl                                  int64
+	)
+
+	err := row.Scan(
+		&record.ShortCode,
+		&record.LongURL,
+		&createdAt,
+		&expiresAt,
+		&record.ClickCount,
+		&lastAccessedAt,
+		&record.MaxClicks,
+		&record.RedirectPermanent,
+		&history,
+		&record.Enabled,
+		&referrers,
+		&clicksByDay,
+		&tags,
+		&ttl,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning record: %w", err)
+	}
+
+	record.CreatedAt = fromUnixNano(createdAt)
+	record.ExpiresAt = fromUnixNano(expiresAt)
+	record.LastAccessedAt = fromUnixNano(lastAccessedAt)
+	record.TTL = time.Duration(ttl)
+
+	if err := json.Unmarshal([]byte(history), &record.History); err != nil {
+		return nil, fmt.Errorf("decoding history: %w", err)
+	}
+	if len(record.History) == 0 {
+		record.History = nil
+	}
+
+	if record.Referrers, err = decodeCounts(referrers); err != nil {
+		return nil, err
+	}
+	if record.ClicksByDay, err = decodeCounts(clicksByDay); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(tags), &record.Tags); err != nil {
+		return nil, fmt.Errorf("decoding tags: %w", err)
+	}
+	if len(record.Tags) == 0 {
+		record.Tags = nil
+	}
+
+	return &record, nil
+}
+
+// encodeTags stores tags as a JSON array, which ListByTag searches with
+// json_each.
+func encodeTags(tags []string) (string, error) {
+	if len(tags) == 0 {
+		return "[]", nil
+	}
+	encoded, err := json.Marshal(tags)
+	if err != nil {
+		return "", fmt.Errorf("encoding tags: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// encodeCounts stores a map of counts as a JSON object.
+func encodeCounts(counts map[string]int64) (string, error) {
+	if len(counts) == 0 {
+		return "{}", nil
+	}
+	encoded, err := json.Marshal(counts)
+	if err != nil {
+		return "", fmt.Errorf("encoding counts: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// decodeCounts maps an empty object to nil, matching new records.
+func decodeCounts(raw string) (map[string]int64, error) {
+	var counts map[string]int64
+	if err := json.Unmarshal([]byte(raw), &counts); err != nil {
+		return nil, fmt.Errorf("decoding counts: %w", err)
+	}
+	if len(counts) == 0 {
+		return nil, nil
+	}
+	return counts, nil
+}
+
+func requireAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// toUnixNano maps the zero time to 0, since time.Time{}.UnixNano overflows.
+func toUnixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
@@ -0,0 +1,541 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQLiteRepository(t *testing.T) *repository.SQLiteRepository {
+	t.Helper()
+
+	repo, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "urls.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = repo.Close() })
+
+	return repo
+}
+
+func TestSQLiteRepository_SaveIfNotExists_Success(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	record := &domain.URLRecord{
+		ShortCode:         "abc12345",
+		LongURL:           "https://example.com",
+		CreatedAt:         now,
+		ExpiresAt:         now.Add(time.Hour),
+		TTL:               time.Hour,
+		MaxClicks:         10,
+		RedirectPermanent: true,
+		History: []domain.HistoryEvent{
+			{Type: domain.EventCreated, At: now, Actor: "anonymous", Details: map[string]string{"ttl_seconds": "3600"}},
+		},
+	}
+
+	err := repo.SaveIfNotExists(ctx, record)
+	assert.NoError(t, err)
+
+	saved, err := repo.FindByShortCode(ctx, "abc12345")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", saved.LongURL)
+	assert.True(t, saved.CreatedAt.Equal(now))
+	assert.True(t, saved.ExpiresAt.Equal(now.Add(time.Hour)))
+	assert.Equal(t, time.Hour, saved.TTL)
+	assert.True(t, saved.LastAccessedAt.IsZero())
+	assert.Equal(t, int64(10), saved.MaxClicks)
+	assert.True(t, saved.RedirectPermanent)
+	require.Len(t, saved.History, 1)
+	assert.Equal(t, domain.EventCreated, saved.History[0].Type)
+	assert.Equal(t, "3600", saved.History[0].Details["ttl_seconds"])
+}
+
+func TestSQLiteRepository_SaveIfNotExists_Duplicate(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	err := repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345", LongURL: "https://example.com"})
+	require.NoError(t, err)
+
+	err = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345", LongURL: "https://different.com"})
+	assert.ErrorIs(t, err, domain.ErrCodeExists)
+
+	saved, err := repo.FindByShortCode(ctx, "abc12345")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", saved.LongURL, "original record should be untouched")
+}
+
+func TestSQLiteRepository_FindByShortCode_NotFound(t *testing.T) {
+	repo := newSQLiteRepository(t)
+
+	_, err := repo.FindByShortCode(context.Background(), "notexist")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestSQLiteRepository_IncrementClickCount_Success(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345"})
+
+	accessTime := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	err := repo.IncrementClickCount(ctx, "abc12345", accessTime)
+	require.NoError(t, err)
+
+	found, _ := repo.FindByShortCode(ctx, "abc12345")
+	assert.Equal(t, int64(1), found.ClickCount)
+	assert.True(t, found.LastAccessedAt.Equal(accessTime))
+}
+
+func TestSQLiteRepository_IncrementClickCount_NotFound(t *testing.T) {
+	repo := newSQLiteRepository(t)
+
+	err := repo.IncrementClickCount(context.Background(), "notexist", time.Now())
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestSQLiteRepository_IncrementClickCount_Concurrent(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345"})
+
+	const numGoroutines = 20
+	const incrementsPerGoroutine = 20
+	expectedTotal := int64(numGoroutines * incrementsPerGoroutine)
+
+	var wg sync.WaitGroup
+	wg.Add(numGoroutines)
+
+	for i := 0; i < numGoroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < incrementsPerGoroutine; j++ {
+				err := repo.IncrementClickCount(ctx, "abc12345", time.Now())
+				assert.NoError(t, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	found, _ := repo.FindByShortCode(ctx, "abc12345")
+	assert.Equal(t, expectedTotal, found.ClickCount)
+}
+
+func TestSQLiteRepository_IncrementClickCount_StopsAtMaxClicks(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345", MaxClicks: 5})
+
+	const numGoroutines = 20
+	var succeeded atomic.Int64
+
+	var wg sync.WaitGroup
+	wg.Add(numGoroutines)
+
+	for i := 0; i < numGoroutines; i++ {
+		go func() {
+			defer wg.Done()
+			err := repo.IncrementClickCount(ctx, "abc12345", time.Now())
+			if err == nil {
+				succeeded.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, domain.ErrClickLimitReached)
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int64(5), succeeded.Load())
+	found, _ := repo.FindByShortCode(ctx, "abc12345")
+	assert.Equal(t, int64(5), found.ClickCount)
+
+	err := repo.IncrementClickCount(ctx, "notexist", time.Now())
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestSQLiteRepository_RecordClick_CountsReferrers(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345", MaxClicks: 4})
+
+	for _, referrer := range []string{"news.example", "news.example", domain.ReferrerDirect, ""} {
+		require.NoError(t, repo.RecordClick(ctx, "abc12345", referrer, time.Now()))
+	}
+
+	found, err := repo.FindByShortCode(ctx, "abc12345")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), found.ClickCount)
+	assert.Equal(t, map[string]int64{"news.example": 2, domain.ReferrerDirect: 1}, found.Referrers)
+
+	err = repo.RecordClick(ctx, "abc12345", "news.example", time.Now())
+	assert.ErrorIs(t, err, domain.ErrClickLimitReached)
+
+	err = repo.RecordClick(ctx, "notexist", "news.example", time.Now())
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestSQLiteRepository_IncrementClickCount_CountsPerDay(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 23, 58, 0, 0, time.UTC))
+
+	_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345"})
+
+	require.NoError(t, repo.IncrementClickCount(ctx, "abc12345", clock.Now()))
+	clock.Advance(time.Minute)
+	require.NoError(t, repo.RecordClick(ctx, "abc12345", "news.example", clock.Now()))
+	clock.Advance(2 * time.Minute) // past midnight
+	require.NoError(t, repo.IncrementClickCount(ctx, "abc12345", clock.Now()))
+
+	found, err := repo.FindByShortCode(ctx, "abc12345")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"2024-01-15": 2, "2024-01-16": 1}, found.ClicksByDay)
+	assert.Equal(t, map[string]int64{"news.example": 1}, found.Referrers)
+}
+
+func TestSQLiteRepository_RecordClick_Concurrent(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345"})
+
+	const numGoroutines = 20
+	var wg sync.WaitGroup
+	wg.Add(numGoroutines)
+	for i := 0; i < numGoroutines; i++ {
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, repo.RecordClick(ctx, "abc12345", "news.example", time.Now()))
+		}()
+	}
+	wg.Wait()
+
+	found, _ := repo.FindByShortCode(ctx, "abc12345")
+	assert.Equal(t, int64(numGoroutines), found.ClickCount)
+	assert.Equal(t, int64(numGoroutines), found.Referrers["news.example"])
+}
+
+func TestSQLiteRepository_SaveIfNotExists_ConcurrentCollision(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	const numGoroutines = 20
+
+	var wg sync.WaitGroup
+	wg.Add(numGoroutines)
+
+	var successCount int32
+	var collisionCount int32
+
+	for i := 0; i < numGoroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			err := repo.SaveIfNotExists(ctx, &domain.URLRecord{
+				ShortCode: "samecode",
+				LongURL:   fmt.Sprintf("https://example.com/%d", id),
+			})
+			if err == nil {
+				atomic.AddInt32(&successCount, 1)
+			} else if errors.Is(err, domain.ErrCodeExists) {
+				atomic.AddInt32(&collisionCount, 1)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int32(1), successCount)
+	assert.Equal(t, int32(numGoroutines-1), collisionCount)
+}
+
+func TestSQLiteRepository_DeleteExpired(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	records := []*domain.URLRecord{
+		{ShortCode: "expired1", ExpiresAt: now.Add(-time.Hour)},
+		{ShortCode: "expired2", ExpiresAt: now.Add(-time.Minute)},
+		{ShortCode: "valid1", ExpiresAt: now.Add(time.Hour)},
+		{ShortCode: "valid2", ExpiresAt: now.Add(time.Minute)},
+	}
+	for _, r := range records {
+		_ = repo.SaveIfNotExists(ctx, r)
+	}
+
+	deleted, err := repo.DeleteExpired(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	_, err = repo.FindByShortCode(ctx, "expired1")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	_, err = repo.FindByShortCode(ctx, "valid1")
+	assert.NoError(t, err)
+}
+
+func TestSQLiteRepository_DeleteExpiredCodes(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	records := []*domain.URLRecord{
+		{ShortCode: "expired2", ExpiresAt: now.Add(-time.Minute)},
+		{ShortCode: "valid1", ExpiresAt: now.Add(time.Hour)},
+		{ShortCode: "expired1", ExpiresAt: now.Add(-time.Hour)},
+	}
+	for _, r := range records {
+		_ = repo.SaveIfNotExists(ctx, r)
+	}
+
+	codes, err := repo.DeleteExpiredCodes(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"expired1", "expired2"}, codes)
+
+	_, err = repo.FindByShortCode(ctx, "expired1")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = repo.FindByShortCode(ctx, "valid1")
+	assert.NoError(t, err)
+}
+
+func TestSQLiteRepository_NeverExpiringRecords(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "forever1", LongURL: "https://example.com"}))
+	require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "later001", LongURL: "https://example.com", ExpiresAt: now.AddDate(10, 0, 0)}))
+
+	codes, err := repo.DeleteExpiredCodes(ctx, now.AddDate(100, 0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"later001"}, codes)
+
+	require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "later002", LongURL: "https://example.com", ExpiresAt: now.AddDate(20, 0, 0)}))
+	found, err := repo.FindByLongURL(ctx, "https://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "forever1", found.ShortCode)
+	assert.True(t, found.ExpiresAt.IsZero())
+}
+
+func TestSQLiteRepository_FindByShortCodes(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "code0001", ClickCount: 1})
+	_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "code0002", ClickCount: 2})
+
+	found, err := repo.FindByShortCodes(ctx, []string{"code0001", "code0002", "missing1"})
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+	assert.Equal(t, int64(2), found["code0002"].ClickCount)
+
+	empty, err := repo.FindByShortCodes(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestSQLiteRepository_FindByLongURL(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "older001", LongURL: "https://example.com", ExpiresAt: now.Add(time.Hour)})
+	_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "later001", LongURL: "https://example.com", ExpiresAt: now.Add(2 * time.Hour)})
+
+	found, err := repo.FindByLongURL(ctx, "https://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "later001", found.ShortCode)
+
+	_, err = repo.FindByLongURL(ctx, "https://missing.com")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestSQLiteRepository_AppendHistory(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345"})
+
+	for i := 0; i < domain.MaxHistoryEvents+5; i++ {
+		err := repo.AppendHistory(ctx, "abc12345", domain.HistoryEvent{
+			Type:  domain.EventTTLExtended,
+			Actor: fmt.Sprintf("actor-%d", i),
+		})
+		require.NoError(t, err)
+	}
+
+	found, err := repo.FindByShortCode(ctx, "abc12345")
+	require.NoError(t, err)
+	require.Len(t, found.History, domain.MaxHistoryEvents)
+	assert.Equal(t, "actor-5", found.History[0].Actor, "oldest events should be dropped first")
+
+	err = repo.AppendHistory(ctx, "notexist", domain.HistoryEvent{Type: domain.EventCreated})
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestSQLiteRepository_PersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.db")
+	ctx := context.Background()
+
+	repo, err := repository.NewSQLiteRepository(path)
+	require.NoError(t, err)
+	require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345", LongURL: "https://example.com"}))
+	require.NoError(t, repo.Close())
+
+	reopened, err := repository.NewSQLiteRepository(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	found, err := reopened.FindByShortCode(ctx, "abc12345")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", found.LongURL)
+}
+
+func TestSQLiteRepository_RespectsContextCancellation(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "test1234"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = repo.FindByShortCode(ctx, "test1234")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = repo.FindByShortCodes(ctx, []string{"test1234"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = repo.FindByLongURL(ctx, "https://example.com")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	err = repo.IncrementClickCount(ctx, "test1234", time.Now())
+	assert.ErrorIs(t, err, context.Canceled)
+
+	err = repo.AppendHistory(ctx, "test1234", domain.HistoryEvent{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = repo.DeleteExpired(ctx, time.Now())
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSQLiteRepository_List(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+	base := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	page, total, err := repo.List(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, page)
+
+	_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "code0003", CreatedAt: base.Add(2 * time.Minute)})
+	_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "code0002", CreatedAt: base.Add(time.Minute)})
+	_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "code0001", CreatedAt: base.Add(time.Minute)})
+	_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "code0000", CreatedAt: base})
+
+	page, total, err = repo.List(ctx, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 4, total)
+	require.Len(t, page, 2)
+	assert.Equal(t, "code0001", page[0].ShortCode)
+	assert.Equal(t, "code0002", page[1].ShortCode)
+
+	page, _, err = repo.List(ctx, 4, 2)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+}
+
+func TestSQLiteRepository_UpdateExpiry(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	_ = repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345", ExpiresAt: now})
+
+	err := repo.UpdateExpiry(ctx, "abc12345", now.Add(48*time.Hour))
+	require.NoError(t, err)
+
+	found, _ := repo.FindByShortCode(ctx, "abc12345")
+	assert.True(t, found.ExpiresAt.Equal(now.Add(48*time.Hour)))
+
+	err = repo.UpdateExpiry(ctx, "notexist", now)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestSQLiteRepository_Ping(t *testing.T) {
+	repo := newSQLiteRepository(t)
+
+	assert.NoError(t, repo.Ping(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, repo.Ping(ctx))
+}
+
+func TestSQLiteRepository_SetEnabled(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "abc12345", Enabled: true}))
+
+	require.NoError(t, repo.SetEnabled(ctx, "abc12345", false))
+	found, err := repo.FindByShortCode(ctx, "abc12345")
+	require.NoError(t, err)
+	assert.False(t, found.Enabled)
+
+	require.NoError(t, repo.SetEnabled(ctx, "abc12345", true))
+	found, err = repo.FindByShortCode(ctx, "abc12345")
+	require.NoError(t, err)
+	assert.True(t, found.Enabled)
+
+	assert.ErrorIs(t, repo.SetEnabled(ctx, "notexist", false), domain.ErrNotFound)
+}
+
+func TestSQLiteRepository_SoftDelete(t *testing.T) {
+	assertSoftDeleteLifecycle(t, newSQLiteRepository(t))
+}
+
+func TestSQLiteRepository_Stats(t *testing.T) {
+	assertStoreStats(t, newSQLiteRepository(t))
+}
+
+func TestSQLiteRepository_Count(t *testing.T) {
+	assertCount(t, newSQLiteRepository(t))
+}
+
+func TestSQLiteRepository_ListByTag(t *testing.T) {
+	assertListByTag(t, newSQLiteRepository(t))
+}
+
+func TestSQLiteRepository_SoftDeleteByPrefix(t *testing.T) {
+	assertSoftDeleteByPrefix(t, newSQLiteRepository(t))
+}
+
+func TestSQLiteRepository_Stats_Empty(t *testing.T) {
+	stats, err := newSQLiteRepository(t).Stats(context.Background(), time.Now(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StoreStats{}, stats)
+}
+
+func TestSQLiteRepository_ResolveAndIncrement(t *testing.T) {
+	assertResolveAndIncrement(t, newSQLiteRepository(t))
+}
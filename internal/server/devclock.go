@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"url-shortener/internal/handler"
+)
+
+// Dev clock endpoints let load and expiry tests fast-forward the service clock.
+// They are only registered when Config.DevClock is set, which main.go refuses
+// to do outside development and test environments, and require the admin
+// key when Config.AdminAPIKey is set.
+
+type devClockResponse struct {
+	Now           string  `json:"now"`
+	OffsetSeconds float64 `json:"offset_seconds"`
+}
+
+type devClockAdvanceRequest struct {
+	Duration string `json:"duration"`
+}
+
+type devClockSetRequest struct {
+	Time string `json:"time"`
+}
+
+func (s *Server) registerDevClockRoutes() {
+	get, advance, set := s.handleDevClockGet, s.handleDevClockAdvance, s.handleDevClockSet
+	if s.cfg.AdminAPIKey != "" {
+		get, advance, set = s.requireAdminKey(get), s.requireAdminKey(advance), s.requireAdminKey(set)
+	}
+	s.mux.HandleFunc("GET /admin/clock", get)
+	s.mux.HandleFunc("POST /admin/clock/advance", advance)
+	s.mux.HandleFunc("POST /admin/clock/set", set)
+}
+
+func (s *Server) handleDevClockGet(w http.ResponseWriter, _ *http.Request) {
+	s.writeDevClock(w)
+}
+
+func (s *Server) handleDevClockAdvance(w http.ResponseWriter, r *http.Request) {
+	var req devClockAdvanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, handler.ErrorResponse{Error: "invalid_json", Message: "invalid JSON body"})
+		return
+	}
+
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil || d < 0 {
+		writeJSON(w, http.StatusBadRequest, handler.ErrorResponse{
+			Error:   "validation_error",
+			Message: "duration must be a non-negative Go duration such as 90s or 24h",
+		})
+		return
+	}
+
+	s.cfg.DevClock.Advance(d)
+	s.writeDevClock(w)
+}
+
+func (s *Server) handleDevClockSet(w http.ResponseWriter, r *http.Request) {
+	var req devClockSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, handler.ErrorResponse{Error: "invalid_json", Message: "invalid JSON body"})
+		return
+	}
+
+	t, err := time.Parse(time.RFC3339, req.Time)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, handler.ErrorResponse{
+			Error:   "validation_error",
+			Message: "time must be an RFC3339 timestamp",
+		})
+		return
+	}
+
+	s.cfg.DevClock.Set(t)
+	s.writeDevClock(w)
+}
+
+func (s *Server) writeDevClock(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, devClockResponse{
+		Now:           s.cfg.DevClock.Now().UTC().Format(time.RFC3339),
+		OffsetSeconds: s.cfg.DevClock.Offset().Seconds(),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
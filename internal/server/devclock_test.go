@@ -0,0 +1,149 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_DevClock_AdvanceAndSet(t *testing.T) {
+	base := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	devClock := domain.NewAdjustableClock(base)
+
+	cfg := server.Config{
+		Port:            18100,
+		ShutdownTimeout: 5 * time.Second,
+		DevClock:        devClock,
+	}
+	srv := server.New(cfg)
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	baseURL := "http://localhost:18100"
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	t.Run("advance moves clock forward", func(t *testing.T) {
+		resp, err := http.Post(baseURL+"/admin/clock/advance", "application/json",
+			bytes.NewBufferString(`{"duration": "25h"}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, time.Date(2024, 1, 16, 13, 0, 0, 0, time.UTC), devClock.Now())
+	})
+
+	t.Run("set jumps to time", func(t *testing.T) {
+		resp, err := http.Post(baseURL+"/admin/clock/set", "application/json",
+			bytes.NewBufferString(`{"time": "2025-06-01T00:00:00Z"}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "2025-06-01T00:00:00Z", body["now"])
+	})
+
+	t.Run("negative duration is rejected", func(t *testing.T) {
+		resp, err := http.Post(baseURL+"/admin/clock/advance", "application/json",
+			bytes.NewBufferString(`{"duration": "-1h"}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestServer_DevClock_NotRegisteredByDefault(t *testing.T) {
+	cfg := server.Config{
+		Port:            18101,
+		ShutdownTimeout: 5 * time.Second,
+	}
+	srv := server.New(cfg)
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	baseURL := "http://localhost:18101"
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	resp, err := http.Post(baseURL+"/admin/clock/advance", "application/json",
+		bytes.NewBufferString(`{"duration": "1h"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServer_DevClock_RequiresAdminKey(t *testing.T) {
+	base := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	devClock := domain.NewAdjustableClock(base)
+
+	cfg := server.Config{
+		Port:            18118,
+		ShutdownTimeout: 5 * time.Second,
+		DevClock:        devClock,
+		AdminAPIKey:     "admin-secret",
+	}
+	srv := server.New(cfg)
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	baseURL := "http://localhost:18118"
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	advance := func(key string) int {
+		req, err := http.NewRequest(http.MethodPost, baseURL+"/admin/clock/advance",
+			bytes.NewBufferString(`{"duration": "1h"}`))
+		require.NoError(t, err)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, advance(""))
+	assert.Equal(t, http.StatusUnauthorized, advance("wrong"))
+	assert.Equal(t, base.Now(), devClock.Now(), "rejected requests must not move the clock")
+
+	resp, err := http.Get(baseURL + "/admin/clock")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	assert.Equal(t, http.StatusOK, advance("admin-secret"))
+	assert.Equal(t, base.Now().Add(time.Hour), devClock.Now())
+}
@@ -0,0 +1,8 @@
+package server
+
+import "net/http"
+
+// HTTPServer exposes the underlying http.Server to tests.
+func HTTPServer(s *Server) *http.Server {
+	return s.httpServer
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+
+	"url-shortener/internal/handler"
+)
+
+// jsonFallback serves mux, replacing the plain-text 404 and 405 responses
+// the mux writes for requests no pattern matches with a JSON ErrorResponse
+// like every other error the API returns. The mux's Allow header is kept on
+// 405s. Matched requests are passed straight through.
+func jsonFallback(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		capture := newCapturedResponse()
+		mux.ServeHTTP(capture, r)
+
+		switch capture.status {
+		case http.StatusNotFound:
+			writeJSON(w, http.StatusNotFound, handler.ErrorResponse{
+				Error:   "not_found",
+				Message: "route not found",
+			})
+		case http.StatusMethodNotAllowed:
+			w.Header().Set("Allow", capture.header.Get("Allow"))
+			writeJSON(w, http.StatusMethodNotAllowed, handler.ErrorResponse{
+				Error:   "method_not_allowed",
+				Message: "method " + r.Method + " not allowed",
+			})
+		default:
+			capture.replay(w)
+		}
+	})
+}
+
+// capturedResponse buffers a response so it can be inspected before being
+// sent on or replaced.
+type capturedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newCapturedResponse() *capturedResponse {
+	return &capturedResponse{header: make(http.Header), status: http.StatusOK}
+}
+
+func (c *capturedResponse) Header() http.Header { return c.header }
+
+func (c *capturedResponse) WriteHeader(status int) { c.status = status }
+
+func (c *capturedResponse) Write(b []byte) (int, error) { return c.body.Write(b) }
+
+// replay sends the captured response to w unchanged.
+func (c *capturedResponse) replay(w http.ResponseWriter) {
+	for key, values := range c.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(c.status)
+	_, _ = w.Write(c.body.Bytes())
+}
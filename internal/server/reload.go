@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+)
+
+// reloadFile is the JSON document read from Config.ReloadFile. Settings it
+// leaves out keep their current values.
+type reloadFile struct {
+	BlockedHosts *[]string `json:"blocked_hosts"`
+
+	RateLimit *struct {
+		RPS   float64 `json:"rps"`
+		Burst int     `json:"burst"`
+	} `json:"rate_limit"`
+
+	TTL *struct {
+		Default   *string `json:"default"`
+		Min       *string `json:"min"`
+		Max       *string `json:"max"`
+		MaxExpiry *string `json:"max_expiry"`
+	} `json:"ttl"`
+}
+
+// ttlPolicySetter is implemented by services whose TTL policy can change
+// while serving, such as *service.URLService.
+type ttlPolicySetter interface {
+	SetTTLPolicy(policy domain.TTLPolicy)
+}
+
+// Reload re-reads Config.ReloadFile and applies its host blocklist, shorten
+// rate limit, and TTL policy. Each takes effect in one step, so requests in
+// flight finish with the settings they started with. A file that fails to
+// parse or validate changes nothing. Run calls it on SIGHUP.
+func (s *Server) Reload() error {
+	if s.cfg.ReloadFile == "" {
+		return errors.New("no reload file configured")
+	}
+
+	raw, err := os.ReadFile(s.cfg.ReloadFile)
+	if err != nil {
+		return fmt.Errorf("reading %q: %w", s.cfg.ReloadFile, err)
+	}
+	var file reloadFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return fmt.Errorf("parsing %q: %w", s.cfg.ReloadFile, err)
+	}
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	blocked := s.cfg.BlockedHosts
+	if file.BlockedHosts != nil {
+		blocked = *file.BlockedHosts
+	}
+
+	rateLimit := s.cfg.ShortenRateLimit
+	if file.RateLimit != nil {
+		if file.RateLimit.RPS < 0 || file.RateLimit.Burst < 0 {
+			return errors.New("rate_limit rps and burst must not be negative")
+		}
+		rateLimit.Rate = file.RateLimit.RPS
+		rateLimit.Burst = file.RateLimit.Burst
+	}
+
+	policy := s.cfg.TTL
+	if policy == (domain.TTLPolicy{}) {
+		policy = domain.DefaultTTLPolicy()
+	}
+	if ttl := file.TTL; ttl != nil {
+		for _, field := range []struct {
+			name string
+			raw  *string
+			dst  *time.Duration
+		}{
+			{"ttl.default", ttl.Default, &policy.Default},
+			{"ttl.min", ttl.Min, &policy.Min},
+			{"ttl.max", ttl.Max, &policy.Max},
+		} {
+			if field.raw == nil {
+				continue
+			}
+			if *field.dst, err = time.ParseDuration(*field.raw); err != nil {
+				return fmt.Errorf("%s: %w", field.name, err)
+			}
+		}
+		if ttl.MaxExpiry != nil {
+			policy.MaxExpiryAbsolute = time.Time{}
+			if *ttl.MaxExpiry != "" {
+				if policy.MaxExpiryAbsolute, err = time.Parse(time.RFC3339, *ttl.MaxExpiry); err != nil {
+					return fmt.Errorf("ttl.max_expiry must be an RFC 3339 time: %w", err)
+				}
+			}
+		}
+	}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	if s.handler != nil {
+		s.handler.SetLimits(handler.Limits{BlockedHosts: blocked, TTL: policy})
+	}
+	if setter, ok := s.service.(ttlPolicySetter); ok {
+		setter.SetTTLPolicy(policy)
+	}
+	if s.limiter != nil {
+		s.limiter.SetRate(rateLimit.Rate, rateLimit.Burst)
+	}
+
+	s.cfg.BlockedHosts = blocked
+	s.cfg.ShortenRateLimit = rateLimit
+	s.cfg.TTL = policy
+	return nil
+}
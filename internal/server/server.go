@@ -2,14 +2,21 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html/template"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"url-shortener/internal/clientip"
+	"url-shortener/internal/domain"
 	"url-shortener/internal/handler"
 	"url-shortener/internal/middleware"
 )
@@ -19,36 +26,328 @@ type Config struct {
 	Port            int
 	ShutdownTimeout time.Duration
 	BaseURL         string
+
+	// DrainDelay keeps accepting connections for this long after shutdown
+	// starts, answering them with 503 shutting_down, before the listener
+	// closes. It counts towards ShutdownTimeout.
+	DrainDelay time.Duration
+
+	// TLSCertFile and TLSKeyFile are PEM files. When both are set the
+	// server serves HTTPS; when neither is, plain HTTP.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// BaseURLFunc optionally derives the short URL base per request, for
+	// deployments serving several short domains. BaseURL is the fallback.
+	BaseURLFunc func(*http.Request) string
+
+	// ForwardedBaseURL builds short URLs from the scheme and host that
+	// proxies trusted by ClientIP report in Forwarded or
+	// X-Forwarded-Proto and X-Forwarded-Host.
+	ForwardedBaseURL bool
+
+	// APIKeys, when set, restricts POST /shorten and POST /shorten/batch
+	// to clients presenting one of them, or, with AnonymousMaxTTL, to
+	// clients presenting none of them or a valid one. Redirects and stats
+	// stay public.
+	APIKeys []string
+
+	// APIKeyCodePrefixes maps API keys to the prefix of the codes they
+	// create, e.g. "t1-", to namespace each tenant's links.
+	APIKeyCodePrefixes map[string]string
+
+	// AdminAPIKey gates admin-only endpoints such as link history.
+	// Those endpoints are not registered when it is empty.
+	AdminAPIKey string
+
+	// Clock supplies the time and uptime reported by the health
+	// endpoints. It defaults to domain.RealClock.
+	Clock domain.Clock
+
+	// Version is the build version reported by the health endpoints.
+	Version string
+
+	// DevClock, when set, exposes /admin/clock endpoints that can fast-forward
+	// the service clock. It must never be set in production.
+	DevClock *domain.AdjustableClock
+
+	// RequestTimeout bounds how long a request's context stays live, so
+	// stuck storage calls are cancelled and answered with 503 "timeout".
+	// Zero disables it.
+	RequestTimeout time.Duration
+
+	// ReadTimeout, ReadHeaderTimeout, WriteTimeout, and IdleTimeout set the
+	// matching http.Server limits. Zero uses the defaults of 10s, 5s, 10s,
+	// and 60s. ReadHeaderTimeout guards against slowloris clients that
+	// trickle in headers.
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// MaxInFlight caps how many requests are served at once; requests
+	// over it get 503 server_busy. Zero means no cap.
+	MaxInFlight int
+
+	// Metrics, when set, records per-route request metrics and is served
+	// at GET /metrics.
+	Metrics *middleware.Metrics
+
+	// Latency, when set, records request durations and serves their
+	// percentiles at GET /debug/latency.
+	Latency *middleware.LatencyRecorder
+
+	// BlockPrivateURLs rejects long URLs pointing at localhost or private,
+	// loopback, link-local, or unspecified addresses.
+	BlockPrivateURLs bool
+
+	// JSONCase spells the keys of API responses in snake_case (the
+	// default) or camelCase. Health and readiness responses keep
+	// snake_case.
+	JSONCase handler.JSONCase
+
+	// VerifyDestinations lets create requests set verify_destination to
+	// have the long URL checked with a HEAD request first. It implies
+	// BlockPrivateURLs, so the server never fetches internal hosts.
+	VerifyDestinations bool
+
+	// ValidateRequestSchema checks POST /shorten bodies against the
+	// embedded JSON Schema before decoding them, rejecting wrong types and
+	// unknown fields with every failing field listed.
+	ValidateRequestSchema bool
+
+	// ShortenRateLimit limits POST /shorten and POST /shorten/batch per
+	// client IP, sharing one budget. A zero Rate disables limiting.
+	ShortenRateLimit middleware.RateLimitConfig
+
+	// LogSampling thins out request logs of successful requests. The zero
+	// value logs every request.
+	LogSampling middleware.LogSampling
+
+	// ClientIP resolves client addresses behind trusted proxies for
+	// request logs and, unless ShortenRateLimit sets its own, rate
+	// limiting. When nil the direct peer's address is used.
+	ClientIP *clientip.Resolver
+
+	// BlockedHosts lists destination domains, including their subdomains,
+	// that long URLs may not point to.
+	BlockedHosts []string
+
+	// URLSchemes lists the schemes long URLs may use. Empty means
+	// handler.DefaultURLSchemes.
+	URLSchemes []string
+
+	// MinURLLength rejects shorter long URLs. Zero means no minimum.
+	MinURLLength int
+
+	// CodeAlphabet is the alphabet of the service's generator, which custom
+	// aliases are checked against. Empty means shortcode.Alphabet.
+	CodeAlphabet string
+
+	// SortQueryParams sorts the query parameters of long URLs by key when
+	// normalizing them, so links differing only in parameter order share
+	// a record.
+	SortQueryParams bool
+
+	// TTL sets the default lifetime of new links and the ttl_seconds range
+	// clients may request. The zero value uses domain.DefaultTTLPolicy.
+	TTL domain.TTLPolicy
+
+	// AnonymousMaxTTL, when positive, caps the TTL of links created
+	// without an API key, while callers with one keep TTL.Max. With
+	// APIKeys set it also lets creates without a key through instead of
+	// refusing them.
+	AnonymousMaxTTL time.Duration
+
+	// GoneForExpired answers redirects to expired links with 410 Gone
+	// instead of 404 Not Found.
+	GoneForExpired bool
+
+	// RedirectErrorTemplate, when set, replaces the built-in HTML page
+	// browsers get when a redirect fails; see
+	// handler.WithRedirectErrorTemplate.
+	RedirectErrorTemplate *template.Template
+
+	// TrimCodeSuffixes lets redirects tolerate a trailing slash or a
+	// ".html" or ".htm" extension pasted onto a short link.
+	TrimCodeSuffixes bool
+
+	// RootRedirectURL, when set, makes GET / redirect to a landing page
+	// instead of describing the service as JSON.
+	RootRedirectURL string
+
+	// CORS enables cross-origin requests from browsers. It is disabled
+	// when AllowedOrigins is empty.
+	CORS middleware.CORSConfig
+
+	// Readiness is pinged by GET /health/ready, typically the repository.
+	// When nil the server reports ready as soon as it is up.
+	Readiness Pinger
+
+	// ReloadFile is a JSON file whose blocklist, rate limit, and TTL
+	// settings replace BlockedHosts, ShortenRateLimit, and TTL when the
+	// process receives SIGHUP. See Reload.
+	ReloadFile string
+}
+
+// Pinger reports whether a dependency is reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
 }
 
+// Defaults for the http.Server timeouts left zero in Config.
+const (
+	defaultReadTimeout       = 10 * time.Second
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultWriteTimeout      = 10 * time.Second
+	defaultIdleTimeout       = 60 * time.Second
+)
+
+// drainLogInterval is how often Shutdown logs the requests still draining.
+const drainLogInterval = time.Second
+
+// readinessTimeout bounds a readiness check so a hung backend fails the
+// probe instead of stalling it.
+const readinessTimeout = 2 * time.Second
+
 // Server represents the HTTP server.
 type Server struct {
 	cfg        Config
 	httpServer *http.Server
 	mux        *http.ServeMux
 	handler    *handler.Handler
+	service    handler.URLService
+	inFlight   *middleware.InFlight
+	drain      *middleware.Drain
+
+	// startedAt is when New ran, by cfg.Clock, for reporting uptime.
+	startedAt time.Time
+
+	// limiter is the shorten rate limiter, kept so Reload can adjust it.
+	limiter *middleware.RateLimiter
+
+	// reloadMu serializes Reload, which updates the reloadable cfg fields.
+	reloadMu sync.Mutex
 }
 
 // New creates a new Server with the given configuration.
 // Optional urlService can be passed to enable URL shortening endpoints.
 func New(cfg Config, urlService ...handler.URLService) *Server {
+	if cfg.Clock == nil {
+		cfg.Clock = domain.RealClock{}
+	}
+	if cfg.ReadTimeout == 0 {
+		cfg.ReadTimeout = defaultReadTimeout
+	}
+	if cfg.ReadHeaderTimeout == 0 {
+		cfg.ReadHeaderTimeout = defaultReadHeaderTimeout
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = defaultWriteTimeout
+	}
+	if cfg.IdleTimeout == 0 {
+		cfg.IdleTimeout = defaultIdleTimeout
+	}
+	if cfg.ShortenRateLimit.ClientIP == nil {
+		cfg.ShortenRateLimit.ClientIP = cfg.ClientIP
+	}
+
 	mux := http.NewServeMux()
 
+	var root http.Handler = jsonFallback(mux)
+	if cfg.Metrics != nil {
+		root = cfg.Metrics.Middleware(root)
+	}
+	root = middleware.Timeout(cfg.RequestTimeout)(root)
+	root = middleware.Gzip(root)
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		root = middleware.CORS(cfg.CORS)(root)
+	}
+	root = middleware.MaxInFlight(cfg.MaxInFlight)(root)
+
+	drain := &middleware.Drain{}
+	root = drain.Middleware(root)
+
+	inFlight := &middleware.InFlight{}
+
+	timing := middleware.Timing
+	if cfg.Latency != nil {
+		timing = middleware.TimingWithRecorder(cfg.Latency)
+	}
+
 	s := &Server{
-		cfg: cfg,
-		mux: mux,
+		cfg:       cfg,
+		mux:       mux,
+		inFlight:  inFlight,
+		drain:     drain,
+		startedAt: cfg.Clock.Now(),
 		httpServer: &http.Server{
-			Addr:         fmt.Sprintf(":%d", cfg.Port),
-			Handler:      middleware.Timing(mux), // Wrap with timing middleware
-			ReadTimeout:  10 * time.Second,
-			WriteTimeout: 10 * time.Second,
-			IdleTimeout:  60 * time.Second,
+			Addr:              fmt.Sprintf(":%d", cfg.Port),
+			Handler:           inFlight.Middleware(middleware.RequestID(timing(middleware.SampledLogger(slog.Default(), cfg.ClientIP, cfg.LogSampling)(root)))),
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
 		},
 	}
 
 	// If URLService is provided, create handler
 	if len(urlService) > 0 && urlService[0] != nil {
-		s.handler = handler.New(urlService[0], cfg.BaseURL)
+		var opts []handler.Option
+		if cfg.BaseURLFunc != nil {
+			opts = append(opts, handler.WithBaseURLFunc(cfg.BaseURLFunc))
+		}
+		if cfg.ForwardedBaseURL {
+			opts = append(opts, handler.WithForwardedOrigin(cfg.ClientIP))
+		}
+		if cfg.ClientIP != nil {
+			opts = append(opts, handler.WithClientIPResolver(cfg.ClientIP))
+		}
+		if cfg.BlockPrivateURLs || cfg.VerifyDestinations {
+			opts = append(opts, handler.WithPrivateURLBlocking(nil))
+		}
+		if cfg.JSONCase != "" {
+			opts = append(opts, handler.WithJSONCase(cfg.JSONCase))
+		}
+		if cfg.ValidateRequestSchema {
+			opts = append(opts, handler.WithSchemaValidation())
+		}
+		if cfg.VerifyDestinations {
+			opts = append(opts, handler.WithDestinationVerification(nil))
+		}
+		if len(cfg.BlockedHosts) > 0 {
+			opts = append(opts, handler.WithHostBlocklist(cfg.BlockedHosts))
+		}
+		if len(cfg.URLSchemes) > 0 {
+			opts = append(opts, handler.WithURLSchemes(cfg.URLSchemes))
+		}
+		if cfg.MinURLLength > 0 {
+			opts = append(opts, handler.WithMinURLLength(cfg.MinURLLength))
+		}
+		if cfg.CodeAlphabet != "" {
+			opts = append(opts, handler.WithCodeAlphabet(cfg.CodeAlphabet))
+		}
+		if cfg.SortQueryParams {
+			opts = append(opts, handler.WithSortedQueryParams())
+		}
+		if cfg.GoneForExpired {
+			opts = append(opts, handler.WithGoneForExpired())
+		}
+		if cfg.RedirectErrorTemplate != nil {
+			opts = append(opts, handler.WithRedirectErrorTemplate(cfg.RedirectErrorTemplate))
+		}
+		if cfg.TrimCodeSuffixes {
+			opts = append(opts, handler.WithCodeSuffixTrimming())
+		}
+		if cfg.TTL != (domain.TTLPolicy{}) {
+			opts = append(opts, handler.WithTTLPolicy(cfg.TTL))
+		}
+		if cfg.AnonymousMaxTTL > 0 {
+			opts = append(opts, handler.WithAnonymousMaxTTL(cfg.AnonymousMaxTTL))
+		}
+		opts = append(opts, handler.WithClock(cfg.Clock))
+		s.handler = handler.New(urlService[0], cfg.BaseURL, opts...)
+		s.service = urlService[0]
 	}
 
 	s.registerRoutes()
@@ -56,38 +355,237 @@ func New(cfg Config, urlService ...handler.URLService) *Server {
 }
 
 func (s *Server) registerRoutes() {
+	s.mux.HandleFunc("GET /{$}", s.handleRoot)
 	s.mux.HandleFunc("GET /health", s.handleHealth)
+	s.mux.HandleFunc("GET /health/live", s.handleHealth)
+	s.mux.HandleFunc("GET /health/ready", s.handleReady)
+
+	if s.cfg.Metrics != nil {
+		s.mux.Handle("GET /metrics", s.cfg.Metrics.Handler())
+	}
+	if s.cfg.Latency != nil {
+		s.mux.Handle("GET /debug/latency", s.cfg.Latency.Handler())
+	}
+
+	if s.cfg.DevClock != nil {
+		s.registerDevClockRoutes()
+	}
 
 	// Register URL shortening routes if handler is available
 	if s.handler != nil {
-		s.mux.HandleFunc("POST /shorten", s.handler.Create)
+		var create http.Handler = http.HandlerFunc(s.handler.Create)
+		var createBatch http.Handler = http.HandlerFunc(s.handler.CreateBatch)
+		if len(s.cfg.APIKeyCodePrefixes) > 0 {
+			prefixes := middleware.APIKeyCodePrefixes(s.cfg.APIKeyCodePrefixes)
+			create = prefixes(create)
+			createBatch = prefixes(createBatch)
+		}
+		if len(s.cfg.APIKeys) > 0 {
+			auth := middleware.APIKeyAuth(s.cfg.APIKeys)
+			if s.cfg.AnonymousMaxTTL > 0 {
+				auth = middleware.OptionalAPIKeyAuth(s.cfg.APIKeys)
+			}
+			create = auth(create)
+			createBatch = auth(createBatch)
+		}
+		// A reload may turn rate limiting on, so it needs a limiter ready.
+		if s.cfg.ShortenRateLimit.Rate > 0 || s.cfg.ReloadFile != "" {
+			// One limiter for both routes, so batches can't dodge the limit.
+			s.limiter = middleware.NewRateLimiter(s.cfg.ShortenRateLimit)
+			create = s.limiter.Middleware(create)
+			createBatch = s.limiter.Middleware(createBatch)
+		}
+		s.mux.Handle("POST /shorten", create)
+		s.mux.Handle("POST /shorten/batch", createBatch)
 		s.mux.HandleFunc("GET /s/{code}", s.handler.Redirect)
+		s.mux.HandleFunc("HEAD /s/{code}", s.handler.RedirectHead)
+		if s.cfg.TrimCodeSuffixes {
+			s.mux.HandleFunc("GET /s/{code}/{$}", s.handler.Redirect)
+			s.mux.HandleFunc("HEAD /s/{code}/{$}", s.handler.RedirectHead)
+		}
+		s.mux.HandleFunc("GET /s/{code}/qr", s.handler.QR)
+		s.mux.HandleFunc("GET /s/{code}/info", s.handler.Info)
+		s.mux.HandleFunc("GET /stats", s.handler.StatsBatch)
 		s.mux.HandleFunc("GET /stats/{code}", s.handler.Stats)
+		s.mux.HandleFunc("GET /stats/{code}/referrers", s.handler.Referrers)
+		s.mux.HandleFunc("GET /stats/{code}/timeseries", s.handler.Timeseries)
+		s.mux.HandleFunc("GET /openapi.json", s.handler.OpenAPI)
+
+		if s.cfg.AdminAPIKey != "" {
+			s.mux.HandleFunc("GET /s/{code}/history", s.requireAdminKey(s.handler.History))
+			s.mux.HandleFunc("GET /urls", s.requireAdminKey(s.handler.List))
+			s.mux.HandleFunc("PATCH /s/{code}", s.requireAdminKey(s.handler.UpdateTTL))
+			s.mux.HandleFunc("PATCH /s/{code}/status", s.requireAdminKey(s.handler.UpdateStatus))
+			s.mux.HandleFunc("DELETE /s/{code}", s.requireAdminKey(s.handler.Delete))
+			s.mux.HandleFunc("POST /s/{code}/restore", s.requireAdminKey(s.handler.Restore))
+			s.mux.HandleFunc("POST /admin/reap", s.requireAdminKey(s.handler.Reap))
+			s.mux.HandleFunc("POST /admin/bulk-delete", s.requireAdminKey(s.handler.BulkDelete))
+			s.mux.HandleFunc("GET /admin/stats", s.requireAdminKey(s.handler.StoreStats))
+		}
+	}
+}
+
+// requireAdminKey rejects requests that don't present the configured admin
+// key via "Authorization: Bearer <key>" or "X-API-Key", and attributes the
+// rest to the "admin" actor.
+func (s *Server) requireAdminKey(next http.HandlerFunc) http.HandlerFunc {
+	asAdmin := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next(w, r.WithContext(domain.WithActor(r.Context(), "admin")))
+	})
+	return middleware.APIKeyAuth([]string{s.cfg.AdminAPIKey})(asAdmin).ServeHTTP
+}
+
+// healthResponse reports status along with the time, version, and uptime.
+func (s *Server) healthResponse(status string) handler.HealthResponse {
+	now := s.cfg.Clock.Now()
+	return handler.HealthResponse{
+		Status:        status,
+		Timestamp:     now.UTC().Format(time.RFC3339),
+		Version:       s.cfg.Version,
+		UptimeSeconds: int64(now.Sub(s.startedAt) / time.Second),
 	}
 }
 
-type healthResponse struct {
-	Status    string `json:"status"`
-	Timestamp string `json:"timestamp"`
+// serviceName is reported by GET /.
+const serviceName = "url-shortener"
+
+// handleRoot answers GET / so a quick browser check of the bare host isn't
+// a 404: it redirects to RootRedirectURL when set, and otherwise describes
+// the service and its public endpoints.
+func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.RootRedirectURL != "" {
+		http.Redirect(w, r, s.cfg.RootRedirectURL, http.StatusFound)
+		return
+	}
+
+	endpoints := []string{"GET /health", "GET /health/live", "GET /health/ready"}
+	if s.handler != nil {
+		endpoints = append(endpoints,
+			"POST /shorten",
+			"POST /shorten/batch",
+			"GET /s/{code}",
+			"GET /stats/{code}",
+			"GET /openapi.json",
+		)
+	}
+	if s.cfg.Metrics != nil {
+		endpoints = append(endpoints, "GET /metrics")
+	}
+
+	writeJSON(w, http.StatusOK, handler.ServiceInfoResponse{
+		Name:      serviceName,
+		Version:   s.cfg.Version,
+		Endpoints: endpoints,
+	})
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(healthResponse{
-		Status:    "healthy",
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-	})
+	_ = json.NewEncoder(w).Encode(s.healthResponse("healthy"))
+}
+
+// handleReady reports whether the server's dependencies are reachable, so
+// orchestrators can hold traffic back without restarting the process.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Readiness != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		if err := s.cfg.Readiness.Ping(ctx); err != nil {
+			slog.Warn("readiness check failed", "error", err)
+			writeJSON(w, http.StatusServiceUnavailable, handler.ErrorResponse{
+				Error:   "not_ready",
+				Message: "storage backend unavailable",
+			})
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, s.healthResponse("ready"))
 }
 
-// Start starts the HTTP server. This method blocks until the server is stopped.
+// ErrTLSConfig is returned by Start and Run when the TLS settings are
+// incomplete or the certificate can't be loaded.
+var ErrTLSConfig = errors.New("invalid TLS configuration")
+
+// Start starts the HTTP server, or the HTTPS server when TLS is configured.
+// This method blocks until the server is stopped.
 func (s *Server) Start() error {
-	return s.httpServer.ListenAndServe()
+	certFile, keyFile := s.cfg.TLSCertFile, s.cfg.TLSKeyFile
+	if certFile == "" && keyFile == "" {
+		return s.httpServer.ListenAndServe()
+	}
+	if certFile == "" || keyFile == "" {
+		return fmt.Errorf("%w: both TLSCertFile and TLSKeyFile must be set", ErrTLSConfig)
+	}
+
+	// Loading up front reports bad files before the port is bound.
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrTLSConfig, err)
+	}
+	s.httpServer.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	return s.httpServer.ListenAndServeTLS("", "")
 }
 
-// Shutdown gracefully shuts down the server.
+// Shutdown gracefully shuts down the server, logging how many requests are
+// in flight when it starts and, periodically, while they drain. From the
+// start, new requests are answered with 503 shutting_down; with DrainDelay
+// the listener stays open that long first, so load balancers see the 503s
+// and stop sending traffic before connections are refused.
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+	s.drain.Start()
+	slog.Info("shutting down", "in_flight", s.InFlight(), "drain_delay", s.cfg.DrainDelay)
+
+	done := make(chan struct{})
+	go s.logDrain(done)
+
+	if s.cfg.DrainDelay > 0 {
+		timer := time.NewTimer(s.cfg.DrainDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	err := s.httpServer.Shutdown(ctx)
+	close(done)
+
+	if err != nil {
+		slog.Warn("shutdown did not finish draining", "in_flight", s.InFlight(), "error", err)
+		return err
+	}
+	slog.Info("shutdown complete")
+	return nil
+}
+
+// logDrain logs the in-flight request count every drainLogInterval until
+// done is closed.
+func (s *Server) logDrain(done <-chan struct{}) {
+	ticker := time.NewTicker(drainLogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if n := s.InFlight(); n > 0 {
+				slog.Info("draining requests", "in_flight", n)
+			}
+		}
+	}
+}
+
+// InFlight returns the number of requests currently being served.
+func (s *Server) InFlight() int64 {
+	return s.inFlight.Count()
 }
 
 // HandleFunc registers a handler function for the given pattern.
@@ -97,7 +595,8 @@ func (s *Server) HandleFunc(pattern string, handler http.HandlerFunc) {
 }
 
 // Run starts the server and blocks until a shutdown signal is received.
-// It handles SIGINT and SIGTERM for graceful shutdown.
+// It handles SIGINT and SIGTERM for graceful shutdown, and SIGHUP by
+// calling Reload when ReloadFile is set.
 // The provided context can also be used to trigger shutdown.
 func (s *Server) Run(ctx context.Context) error {
 	// Channel for shutdown signals
@@ -105,24 +604,46 @@ func (s *Server) Run(ctx context.Context) error {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	defer signal.Stop(sigChan)
 
+	// A nil channel never receives, so SIGHUP is left alone without a file
+	var hupChan chan os.Signal
+	if s.cfg.ReloadFile != "" {
+		hupChan = make(chan os.Signal, 1)
+		signal.Notify(hupChan, syscall.SIGHUP)
+		defer signal.Stop(hupChan)
+	}
+
 	// Channel for server errors
 	errChan := make(chan error, 1)
 
 	// Start server
 	go func() {
-		if err := s.Start(); err != nil && err != http.ErrServerClosed {
+		if err := s.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			errChan <- err
 		}
 	}()
 
 	// Wait for shutdown signal, context cancellation, or server error
-	select {
-	case <-sigChan:
-		// Received OS signal
-	case <-ctx.Done():
-		// Context cancelled
-	case err := <-errChan:
-		return fmt.Errorf("server error: %w", err)
+wait:
+	for {
+		select {
+		case <-hupChan:
+			if err := s.Reload(); err != nil {
+				slog.Error("config reload failed, keeping current settings", "path", s.cfg.ReloadFile, "error", err)
+				continue
+			}
+			slog.Info("config reloaded", "path", s.cfg.ReloadFile)
+		case <-sigChan:
+			// Received OS signal
+			break wait
+		case <-ctx.Done():
+			// Context cancelled
+			break wait
+		case err := <-errChan:
+			if errors.Is(err, ErrTLSConfig) {
+				return err
+			}
+			return fmt.Errorf("server error: %w", err)
+		}
 	}
 
 	// Graceful shutdown
@@ -5,14 +5,25 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 
 	"url-shortener/internal/domain"
 	"url-shortener/internal/handler"
+	"url-shortener/internal/middleware"
+	"url-shortener/internal/repository"
 	"url-shortener/internal/server"
+	"url-shortener/internal/service"
+	"url-shortener/internal/shortcode"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -20,41 +31,81 @@ import (
 // StubURLService is a simple stub implementation for integration testing
 type StubURLService struct {
 	records map[string]*domain.URLRecord
+	deleted map[string]*domain.URLRecord
 	counter int
 }
 
 func NewStubURLService() *StubURLService {
 	return &StubURLService{
 		records: make(map[string]*domain.URLRecord),
+		deleted: make(map[string]*domain.URLRecord),
 		counter: 0,
 	}
 }
 
-func (s *StubURLService) Create(ctx context.Context, longURL string, ttl time.Duration) (*domain.URLRecord, error) {
+func (s *StubURLService) Create(ctx context.Context, params domain.CreateParams) (*domain.URLRecord, int, error) {
 	s.counter++
 	shortCode := fmt.Sprintf("code%04d", s.counter)
+	if params.CustomAlias != "" {
+		if _, taken := s.records[params.CustomAlias]; taken {
+			return nil, 0, domain.ErrAliasTaken
+		}
+		shortCode = params.CustomAlias
+	}
 	record := &domain.URLRecord{
 		ShortCode:  shortCode,
-		LongURL:    longURL,
+		LongURL:    params.LongURL,
 		CreatedAt:  time.Now().UTC(),
-		ExpiresAt:  time.Now().UTC().Add(ttl),
+		ExpiresAt:  time.Now().UTC().Add(params.TTL),
 		ClickCount: 0,
+		Enabled:    true,
 	}
 	s.records[record.ShortCode] = record
-	return record, nil
+	return record, 1, nil
+}
+
+func (s *StubURLService) CreateIdempotent(ctx context.Context, key string, params domain.CreateParams) (*domain.URLRecord, int, bool, error) {
+	record, attempts, err := s.Create(ctx, params)
+	return record, attempts, false, err
+}
+
+func (s *StubURLService) CreateBatch(ctx context.Context, items []domain.CreateParams) []domain.CreateResult {
+	results := make([]domain.CreateResult, len(items))
+	for i, params := range items {
+		record, _, err := s.Create(ctx, params)
+		results[i] = domain.CreateResult{Record: record, Err: err}
+	}
+	return results
 }
 
-func (s *StubURLService) Resolve(ctx context.Context, shortCode string) (string, error) {
+func (s *StubURLService) Resolve(ctx context.Context, shortCode string, visit domain.Visit) (*domain.URLRecord, error) {
 	record, ok := s.records[shortCode]
 	if !ok {
-		return "", domain.ErrNotFound
+		return nil, domain.ErrNotFound
 	}
 	if time.Now().After(record.ExpiresAt) {
-		return "", domain.ErrExpired
+		return nil, domain.ErrExpired
+	}
+	if !record.Enabled {
+		return nil, domain.ErrDisabled
 	}
 	record.ClickCount++
 	record.LastAccessedAt = time.Now().UTC()
-	return record.LongURL, nil
+	return record.Clone(), nil
+}
+
+func (s *StubURLService) Lookup(ctx context.Context, shortCode string) (*domain.URLRecord, error) {
+	record, ok := s.records[shortCode]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return nil, domain.ErrExpired
+	}
+	if !record.Enabled {
+		return nil, domain.ErrDisabled
+	}
+	return record.Clone(), nil
 }
 
 func (s *StubURLService) GetStats(ctx context.Context, shortCode string) (*domain.URLRecord, error) {
@@ -65,6 +116,123 @@ func (s *StubURLService) GetStats(ctx context.Context, shortCode string) (*domai
 	return record, nil
 }
 
+func (s *StubURLService) GetStatsIncludingExpired(ctx context.Context, shortCode string) (*domain.URLRecord, error) {
+	return s.GetStats(ctx, shortCode)
+}
+
+func (s *StubURLService) GetClickSeries(ctx context.Context, shortCode string, from, to time.Time) ([]domain.DayClicks, error) {
+	record, ok := s.records[shortCode]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return domain.ClickSeries(record.ClicksByDay, from, to), nil
+}
+
+func (s *StubURLService) GetReferrers(ctx context.Context, shortCode string, limit int) ([]domain.ReferrerCount, error) {
+	record, ok := s.records[shortCode]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return domain.TopReferrers(record.Referrers, limit), nil
+}
+
+func (s *StubURLService) GetStatsBatch(ctx context.Context, shortCodes []string, consistent bool) ([]*domain.URLRecord, error) {
+	var records []*domain.URLRecord
+	for _, code := range shortCodes {
+		if record, ok := s.records[code]; ok {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func (s *StubURLService) GetHistory(ctx context.Context, shortCode string) ([]domain.HistoryEvent, error) {
+	record, ok := s.records[shortCode]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return record.History, nil
+}
+
+func (s *StubURLService) UpdateTTL(ctx context.Context, shortCode string, ttl time.Duration) (*domain.URLRecord, error) {
+	record, ok := s.records[shortCode]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return nil, domain.ErrExpired
+	}
+	record.ExpiresAt = time.Now().UTC().Add(ttl)
+	return record.Clone(), nil
+}
+
+func (s *StubURLService) SetEnabled(ctx context.Context, shortCode string, enabled bool) (*domain.URLRecord, error) {
+	record, ok := s.records[shortCode]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	record.Enabled = enabled
+	return record.Clone(), nil
+}
+
+func (s *StubURLService) Delete(ctx context.Context, shortCode string) error {
+	record, ok := s.records[shortCode]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	delete(s.records, shortCode)
+	s.deleted[shortCode] = record
+	return nil
+}
+
+func (s *StubURLService) Restore(ctx context.Context, shortCode string) (*domain.URLRecord, error) {
+	record, ok := s.deleted[shortCode]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	delete(s.deleted, shortCode)
+	s.records[shortCode] = record
+	return record.Clone(), nil
+}
+
+func (s *StubURLService) GetStoreStats(ctx context.Context, top int) (domain.StoreStats, error) {
+	var stats domain.StoreStats
+	for _, record := range s.records {
+		stats.Add(record, time.Now(), top)
+	}
+	return stats, nil
+}
+
+func (s *StubURLService) PurgeExpired(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (s *StubURLService) DeleteByPrefix(ctx context.Context, prefix string) ([]string, error) {
+	return nil, nil
+}
+
+func (s *StubURLService) DeleteExpiringBefore(ctx context.Context, before time.Time) ([]string, error) {
+	return nil, nil
+}
+
+func (s *StubURLService) List(ctx context.Context, offset, limit int) ([]*domain.URLRecord, int, error) {
+	codes := make([]string, 0, len(s.records))
+	for code := range s.records {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	records := []*domain.URLRecord{}
+	for i := offset; i < len(codes) && i < offset+limit; i++ {
+		records = append(records, s.records[codes[i]])
+	}
+	return records, len(codes), nil
+}
+
+func (s *StubURLService) ListByTag(ctx context.Context, tag string, offset, limit int) ([]*domain.URLRecord, int, error) {
+	return []*domain.URLRecord{}, 0, nil
+}
+
 func TestIntegration_FullWorkflow(t *testing.T) {
 	// Setup
 	stubService := NewStubURLService()
@@ -349,3 +517,587 @@ func TestIntegration_ContentTypeJSON(t *testing.T) {
 		assert.Equal(t, "application/json", contentType)
 	})
 }
+
+func TestIntegration_HistoryRequiresAdminKey(t *testing.T) {
+	stubService := NewStubURLService()
+	cfg := server.Config{
+		Port:            18093,
+		ShutdownTimeout: 5 * time.Second,
+		BaseURL:         "http://localhost:18093",
+		AdminAPIKey:     "s3cret",
+	}
+	srv := server.New(cfg, stubService)
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	baseURL := "http://localhost:18093"
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	created, _, _ := stubService.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	historyURL := baseURL + "/s/" + created.ShortCode + "/history"
+
+	testCases := []struct {
+		name       string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{name: "missing key", wantStatus: http.StatusUnauthorized},
+		{name: "wrong key", header: "X-API-Key", value: "nope", wantStatus: http.StatusUnauthorized},
+		{name: "bearer key", header: "Authorization", value: "Bearer s3cret", wantStatus: http.StatusOK},
+		{name: "api key header", header: "X-API-Key", value: "s3cret", wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, historyURL, nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestIntegration_MetricsEndpoint(t *testing.T) {
+	stubService := NewStubURLService()
+	cfg := server.Config{
+		Port:            18094,
+		ShutdownTimeout: 5 * time.Second,
+		BaseURL:         "http://localhost:18094",
+		Metrics:         middleware.NewMetrics(prometheus.NewRegistry()),
+	}
+	srv := server.New(cfg, stubService)
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	baseURL := "http://localhost:18094"
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	resp, err := http.Get(baseURL + "/stats/missing1")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = http.Get(baseURL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `http_requests_total{method="GET",route="GET /stats/{code}",status="404"} 1`)
+	assert.Contains(t, string(body), `http_request_duration_seconds_count{method="GET",route="GET /health"}`)
+}
+
+func TestIntegration_ShortenRateLimit(t *testing.T) {
+	stubService := NewStubURLService()
+	cfg := server.Config{
+		Port:             18095,
+		ShutdownTimeout:  5 * time.Second,
+		BaseURL:          "http://localhost:18095",
+		ShortenRateLimit: middleware.RateLimitConfig{Rate: 0.01, Burst: 2},
+	}
+	srv := server.New(cfg, stubService)
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	baseURL := "http://localhost:18095"
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	shorten := func() *http.Response {
+		resp, err := http.Post(baseURL+"/shorten", "application/json",
+			bytes.NewBufferString(`{"long_url":"https://example.com"}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	assert.Equal(t, http.StatusCreated, shorten().StatusCode)
+	assert.Equal(t, http.StatusCreated, shorten().StatusCode)
+
+	limited := shorten()
+	assert.Equal(t, http.StatusTooManyRequests, limited.StatusCode)
+	assert.NotEmpty(t, limited.Header.Get("Retry-After"))
+
+	// Other routes are not limited
+	resp, err := http.Get(baseURL + "/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestIntegration_ShortenRateLimit_ChargesBatchItems(t *testing.T) {
+	stubService := NewStubURLService()
+	cfg := server.Config{
+		Port:             18117,
+		ShutdownTimeout:  5 * time.Second,
+		BaseURL:          "http://localhost:18117",
+		ShortenRateLimit: middleware.RateLimitConfig{Rate: 0.01, Burst: 3},
+	}
+	srv := server.New(cfg, stubService)
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	baseURL := "http://localhost:18117"
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	batch := func(n int) *http.Response {
+		items := strings.Repeat(`{"long_url":"https://example.com"},`, n)
+		resp, err := http.Post(baseURL+"/shorten/batch", "application/json",
+			bytes.NewBufferString(`{"urls":[`+strings.TrimSuffix(items, ",")+`]}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	limited := batch(4)
+	assert.Equal(t, http.StatusTooManyRequests, limited.StatusCode, "a batch larger than the burst must be rejected")
+	assert.NotEmpty(t, limited.Header.Get("Retry-After"))
+	assert.Empty(t, stubService.records, "a rejected batch must not create anything")
+
+	// The rejected batch took one token, leaving room for two items.
+	assert.Equal(t, http.StatusOK, batch(2).StatusCode)
+	assert.Equal(t, http.StatusTooManyRequests, batch(1).StatusCode)
+}
+
+func TestIntegration_GoneForExpired(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		port       int
+		gone       bool
+		wantStatus int
+		wantError  string
+	}{
+		{name: "enabled", port: 18097, gone: true, wantStatus: http.StatusGone, wantError: "expired"},
+		{name: "disabled by default", port: 18098, gone: false, wantStatus: http.StatusNotFound, wantError: "not_found"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stubService := NewStubURLService()
+			stubService.records["expired1"] = &domain.URLRecord{
+				ShortCode: "expired1",
+				LongURL:   "https://example.com",
+				ExpiresAt: time.Now().Add(-time.Hour),
+				Enabled:   true,
+			}
+
+			baseURL := fmt.Sprintf("http://localhost:%d", tc.port)
+			srv := server.New(server.Config{
+				Port:            tc.port,
+				ShutdownTimeout: 5 * time.Second,
+				BaseURL:         baseURL,
+				GoneForExpired:  tc.gone,
+			}, stubService)
+
+			go func() {
+				_ = srv.Start()
+			}()
+
+			waitForServer(t, baseURL+"/health", 2*time.Second)
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				srv.Shutdown(ctx)
+			}()
+
+			resp, err := http.Get(baseURL + "/s/expired1")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+			var errResp handler.ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+			assert.Equal(t, tc.wantError, errResp.Error)
+		})
+	}
+}
+
+func TestIntegration_HeadRedirectDoesNotCountClick(t *testing.T) {
+	stubService := NewStubURLService()
+	stubService.records["Ab2CdE3F"] = &domain.URLRecord{
+		ShortCode: "Ab2CdE3F",
+		LongURL:   "https://example.com/destination",
+		ExpiresAt: time.Now().Add(time.Hour),
+		Enabled:   true,
+	}
+
+	baseURL := "http://localhost:18105"
+	srv := server.New(server.Config{
+		Port:            18105,
+		ShutdownTimeout: 5 * time.Second,
+		BaseURL:         baseURL,
+	}, stubService)
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	req, err := http.NewRequest(http.MethodHead, baseURL+"/s/Ab2CdE3F", nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "https://example.com/destination", resp.Header.Get("Location"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Empty(t, body)
+
+	assert.Equal(t, int64(0), stubService.records["Ab2CdE3F"].ClickCount)
+}
+
+func TestIntegration_UnmatchedRouteReturnsJSON404(t *testing.T) {
+	baseURL := "http://localhost:18106"
+	srv := server.New(server.Config{
+		Port:            18106,
+		ShutdownTimeout: 5 * time.Second,
+		BaseURL:         baseURL,
+	}, NewStubURLService())
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	resp, err := http.Get(baseURL + "/does-not-exist")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var errResp handler.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, "not_found", errResp.Error)
+	assert.Equal(t, "route not found", errResp.Message)
+}
+
+func TestIntegration_WrongMethodReturnsJSON405(t *testing.T) {
+	baseURL := "http://localhost:18107"
+	srv := server.New(server.Config{
+		Port:            18107,
+		ShutdownTimeout: 5 * time.Second,
+		BaseURL:         baseURL,
+	}, NewStubURLService())
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		wantAllow []string
+	}{
+		{"GET on /shorten", http.MethodGet, "/shorten", []string{"POST"}},
+		{"POST on /s/{code}", http.MethodPost, "/s/Ab2CdE3F", []string{"GET", "HEAD"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, baseURL+tt.path, nil)
+			require.NoError(t, err)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			for _, method := range tt.wantAllow {
+				assert.Contains(t, resp.Header.Get("Allow"), method)
+			}
+
+			var errResp handler.ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+			assert.Equal(t, "method_not_allowed", errResp.Error)
+		})
+	}
+}
+
+func TestIntegration_AdminReapDeletesOnlyExpired(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock)
+
+	ctx := context.Background()
+	expired, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com/old", TTL: time.Minute})
+	require.NoError(t, err)
+	valid, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com/new", TTL: 24 * time.Hour})
+	require.NoError(t, err)
+	clock.Advance(time.Hour)
+
+	baseURL := "http://localhost:18109"
+	srv := server.New(server.Config{
+		Port:            18109,
+		ShutdownTimeout: 5 * time.Second,
+		BaseURL:         baseURL,
+		AdminAPIKey:     "s3cret",
+		Clock:           clock,
+	}, svc)
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	resp, err := http.Post(baseURL+"/admin/reap", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	_, err = repo.FindByShortCode(ctx, expired.ShortCode)
+	require.NoError(t, err, "unauthorized reap must not delete anything")
+
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/admin/reap", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-API-Key", "s3cret")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var reaped handler.ReapResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&reaped))
+	assert.Equal(t, 1, reaped.Deleted)
+
+	_, err = repo.FindByShortCode(ctx, expired.ShortCode)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = repo.FindByShortCode(ctx, valid.ShortCode)
+	assert.NoError(t, err)
+}
+
+func TestIntegration_APIKeysGateShortenButNotRedirects(t *testing.T) {
+	stubService := NewStubURLService()
+	stubService.records["Ab2CdE3F"] = &domain.URLRecord{
+		ShortCode: "Ab2CdE3F",
+		LongURL:   "https://example.com/destination",
+		ExpiresAt: time.Now().Add(time.Hour),
+		Enabled:   true,
+	}
+
+	baseURL := "http://localhost:18110"
+	srv := server.New(server.Config{
+		Port:            18110,
+		ShutdownTimeout: 5 * time.Second,
+		BaseURL:         baseURL,
+		APIKeys:         []string{"writer-key"},
+	}, stubService)
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	shorten := func(key string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, baseURL+"/shorten",
+			bytes.NewBufferString(`{"long_url": "https://example.com"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, shorten("").StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, shorten("wrong-key").StatusCode)
+	assert.Equal(t, http.StatusCreated, shorten("writer-key").StatusCode)
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(baseURL + "/s/Ab2CdE3F")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+}
+
+func TestIntegration_SIGHUPReloadsConfig(t *testing.T) {
+	reloadFile := filepath.Join(t.TempDir(), "reload.json")
+	require.NoError(t, os.WriteFile(reloadFile, []byte(`{}`), 0o600))
+
+	baseURL := "http://localhost:18111"
+	srv := server.New(server.Config{
+		Port:            18111,
+		ShutdownTimeout: 5 * time.Second,
+		BaseURL:         baseURL,
+		ReloadFile:      reloadFile,
+	}, NewStubURLService())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		require.NoError(t, <-done)
+	}()
+
+	// Run has subscribed to SIGHUP by the time the server answers
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+
+	shorten := func(body string) int {
+		resp, err := http.Post(baseURL+"/shorten", "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	require.Equal(t, http.StatusCreated, shorten(`{"long_url": "https://evil.com/", "ttl_seconds": 60}`))
+
+	require.NoError(t, os.WriteFile(reloadFile, []byte(`{
+		"blocked_hosts": ["evil.com"],
+		"rate_limit": {"rps": 0.001, "burst": 2},
+		"ttl": {"min": "2m"}
+	}`), 0o600))
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	require.Eventually(t, func() bool {
+		return shorten(`{"long_url": "https://evil.com/"}`) == http.StatusBadRequest
+	}, 2*time.Second, 10*time.Millisecond, "new blocklist should apply after SIGHUP")
+
+	// Rejected requests spend tokens too, leaving none of the burst of 2
+	assert.Equal(t, http.StatusBadRequest, shorten(`{"long_url": "https://example.com/", "ttl_seconds": 60}`), "new TTL minimum applies")
+	assert.Equal(t, http.StatusTooManyRequests, shorten(`{"long_url": "https://example.com/"}`), "new rate limit applies")
+
+	// A broken file leaves the current settings in place
+	require.NoError(t, os.WriteFile(reloadFile, []byte(`{"ttl": {"min": "soon"}}`), 0o600))
+	assert.Error(t, srv.Reload())
+	require.NoError(t, os.WriteFile(reloadFile, []byte(`{"rate_limit": {"rps": 0}}`), 0o600))
+	require.NoError(t, srv.Reload())
+	assert.Equal(t, http.StatusBadRequest, shorten(`{"long_url": "https://evil.com/"}`), "blocklist kept when the file omits it")
+	assert.Equal(t, http.StatusBadRequest, shorten(`{"long_url": "https://example.com/", "ttl_seconds": 60}`))
+	assert.Equal(t, http.StatusCreated, shorten(`{"long_url": "https://example.com/"}`), "rate limiting turned off")
+}
+
+func TestIntegration_TrimCodeSuffixes(t *testing.T) {
+	stubService := NewStubURLService()
+	stubService.records["Ab2CdE3F"] = &domain.URLRecord{
+		ShortCode: "Ab2CdE3F",
+		LongURL:   "https://example.com/destination",
+		ExpiresAt: time.Now().Add(time.Hour),
+		Enabled:   true,
+	}
+
+	baseURL := "http://localhost:18112"
+	srv := server.New(server.Config{
+		Port:             18112,
+		ShutdownTimeout:  5 * time.Second,
+		BaseURL:          baseURL,
+		TrimCodeSuffixes: true,
+	}, stubService)
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	for _, path := range []string{"/s/Ab2CdE3F/", "/s/Ab2CdE3F.html", "/s/Ab2CdE3F.htm/"} {
+		resp, err := client.Get(baseURL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusFound, resp.StatusCode, path)
+		assert.Equal(t, "https://example.com/destination", resp.Header.Get("Location"), path)
+	}
+
+	for _, path := range []string{"/s/unknown1/", "/s/unknown1.html", "/s/Ab2CdE3F.pdf"} {
+		resp, err := client.Get(baseURL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, path)
+	}
+}
@@ -1,13 +1,27 @@
 package server_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"net/http/httptest"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"url-shortener/internal/clientip"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/handler"
+	"url-shortener/internal/repository"
 	"url-shortener/internal/server"
+	"url-shortener/internal/service"
+	"url-shortener/internal/shortcode"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -94,6 +108,66 @@ func TestServer_GracefulShutdown_WaitsForInFlightRequests(t *testing.T) {
 	}
 }
 
+func TestServer_Shutdown_RejectsNewRequestsWhileDraining(t *testing.T) {
+	cfg := server.Config{
+		Port:            18115,
+		ShutdownTimeout: 5 * time.Second,
+		DrainDelay:      500 * time.Millisecond,
+	}
+	srv := server.New(cfg)
+
+	started := make(chan struct{})
+	srv.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+
+	go func() {
+		_ = srv.Start()
+	}()
+	waitForServer(t, "http://localhost:18115/health", 2*time.Second)
+
+	slowStatus := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://localhost:18115/slow")
+		if err != nil {
+			slowStatus <- 0
+			return
+		}
+		resp.Body.Close()
+		slowStatus <- resp.StatusCode
+	}()
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdownErr <- srv.Shutdown(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// A new request during the drain delay is turned away.
+	resp, err := http.Get("http://localhost:18115/health")
+	require.NoError(t, err)
+	var body handler.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "shutting_down", body.Error)
+	assert.True(t, resp.Close, "response should close the connection")
+
+	// The request that started before shutdown completes normally.
+	select {
+	case status := <-slowStatus:
+		assert.Equal(t, http.StatusOK, status)
+	case <-time.After(2 * time.Second):
+		t.Fatal("in-flight request did not complete")
+	}
+	assert.NoError(t, <-shutdownErr)
+}
+
 func TestServer_Run_ShutdownOnContextCancel(t *testing.T) {
 	cfg := server.Config{
 		Port:            18084,
@@ -250,6 +324,128 @@ func TestServer_AddsProcessingTimeHeader(t *testing.T) {
 	assert.NoError(t, err, "header should be a valid integer")
 }
 
+func TestServer_Shutdown_LogsInFlightRequests(t *testing.T) {
+	var logs syncBuffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	cfg := server.Config{
+		Port:            18099,
+		ShutdownTimeout: 5 * time.Second,
+	}
+	srv := server.New(cfg)
+
+	started := make(chan struct{})
+	srv.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	waitForServer(t, "http://localhost:18099/health", 2*time.Second)
+	assert.Equal(t, int64(0), srv.InFlight())
+
+	go func() {
+		resp, err := http.Get("http://localhost:18099/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	<-started
+	assert.Equal(t, int64(1), srv.InFlight())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, srv.Shutdown(ctx))
+
+	assert.Equal(t, int64(0), srv.InFlight())
+	assert.Contains(t, logs.String(), `"msg":"shutting down","in_flight":1`)
+	assert.Contains(t, logs.String(), `"msg":"shutdown complete"`)
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writers.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// stubPinger fails its Ping while failing is set.
+type stubPinger struct {
+	failing atomic.Bool
+}
+
+func (p *stubPinger) Ping(_ context.Context) error {
+	if p.failing.Load() {
+		return errors.New("backend unavailable")
+	}
+	return nil
+}
+
+func TestServer_ReadinessReflectsPinger(t *testing.T) {
+	pinger := &stubPinger{}
+	cfg := server.Config{
+		Port:            18096,
+		ShutdownTimeout: 5 * time.Second,
+		Readiness:       pinger,
+	}
+	srv := server.New(cfg)
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	baseURL := "http://localhost:18096"
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	getStatus := func(path string) int {
+		resp, err := http.Get(baseURL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, getStatus("/health/ready"))
+	assert.Equal(t, http.StatusOK, getStatus("/health/live"))
+
+	pinger.failing.Store(true)
+
+	resp, err := http.Get(baseURL + "/health/ready")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	var errResp handler.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, "not_ready", errResp.Error)
+
+	// Liveness is unaffected by dependencies
+	assert.Equal(t, http.StatusOK, getStatus("/health/live"))
+	assert.Equal(t, http.StatusOK, getStatus("/health"))
+}
+
 func waitForServer(t *testing.T, url string, timeout time.Duration) {
 	t.Helper()
 	deadline := time.Now().Add(timeout)
@@ -263,3 +459,207 @@ func waitForServer(t *testing.T, url string, timeout time.Duration) {
 	}
 	t.Fatalf("server did not start within %v", timeout)
 }
+
+func TestServer_HealthTimestampUsesInjectedClock(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	cfg := server.Config{
+		Port:            18102,
+		ShutdownTimeout: 5 * time.Second,
+		Clock:           clock,
+	}
+	srv := server.New(cfg)
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	baseURL := "http://localhost:18102"
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	for _, path := range []string{"/health", "/health/ready"} {
+		resp, err := http.Get(baseURL + path)
+		require.NoError(t, err)
+
+		var health handler.HealthResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+		resp.Body.Close()
+		assert.Equal(t, "2024-01-15T12:00:00Z", health.Timestamp, path)
+	}
+
+	clock.Advance(90 * time.Second)
+
+	resp, err := http.Get(baseURL + "/health")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var health handler.HealthResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+	assert.Equal(t, "2024-01-15T12:01:30Z", health.Timestamp)
+}
+
+func TestServer_HealthReportsVersionAndUptime(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	srv := server.New(server.Config{
+		Port:            18113,
+		ShutdownTimeout: 5 * time.Second,
+		Clock:           clock,
+		Version:         "v1.4.2",
+	})
+
+	go func() {
+		_ = srv.Start()
+	}()
+
+	baseURL := "http://localhost:18113"
+	waitForServer(t, baseURL+"/health", 2*time.Second)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	health := func(path string) map[string]any {
+		resp, err := http.Get(baseURL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return body
+	}
+
+	for _, path := range []string{"/health", "/health/ready"} {
+		body := health(path)
+		assert.Equal(t, "v1.4.2", body["version"], path)
+		assert.Equal(t, float64(0), body["uptime_seconds"], path)
+	}
+
+	clock.Advance(90*time.Second + 500*time.Millisecond)
+
+	for _, path := range []string{"/health", "/health/ready"} {
+		assert.Equal(t, float64(90), health(path)["uptime_seconds"], path)
+	}
+}
+
+func TestServer_AppliesConfiguredTimeouts(t *testing.T) {
+	srv := server.New(server.Config{
+		Port:              18108,
+		ReadTimeout:       30 * time.Second,
+		ReadHeaderTimeout: 2 * time.Second,
+		WriteTimeout:      45 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	})
+
+	httpServer := server.HTTPServer(srv)
+	assert.Equal(t, 30*time.Second, httpServer.ReadTimeout)
+	assert.Equal(t, 2*time.Second, httpServer.ReadHeaderTimeout)
+	assert.Equal(t, 45*time.Second, httpServer.WriteTimeout)
+	assert.Equal(t, 2*time.Minute, httpServer.IdleTimeout)
+}
+
+func TestServer_DefaultTimeouts(t *testing.T) {
+	httpServer := server.HTTPServer(server.New(server.Config{Port: 18108}))
+
+	assert.Equal(t, 10*time.Second, httpServer.ReadTimeout)
+	assert.Equal(t, 5*time.Second, httpServer.ReadHeaderTimeout)
+	assert.Equal(t, 10*time.Second, httpServer.WriteTimeout)
+	assert.Equal(t, 60*time.Second, httpServer.IdleTimeout)
+}
+
+func TestServer_RootDescribesService(t *testing.T) {
+	srv := server.New(server.Config{Port: 18114, Version: "v1.4.2"}, NewStubURLService())
+	rec := httptest.NewRecorder()
+
+	server.HTTPServer(srv).Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var info handler.ServiceInfoResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	assert.Equal(t, "url-shortener", info.Name)
+	assert.Equal(t, "v1.4.2", info.Version)
+	assert.Contains(t, info.Endpoints, "POST /shorten")
+	assert.Contains(t, info.Endpoints, "GET /s/{code}")
+	assert.Contains(t, info.Endpoints, "GET /health")
+}
+
+func TestServer_RootRedirectsWhenConfigured(t *testing.T) {
+	srv := server.New(server.Config{Port: 18114, RootRedirectURL: "https://www.example.com/"}, NewStubURLService())
+	rec := httptest.NewRecorder()
+
+	server.HTTPServer(srv).Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "https://www.example.com/", rec.Header().Get("Location"))
+}
+
+func TestServer_RootDoesNotShadowOtherPaths(t *testing.T) {
+	srv := server.New(server.Config{Port: 18114, RootRedirectURL: "https://www.example.com/"}, NewStubURLService())
+	rec := httptest.NewRecorder()
+
+	server.HTTPServer(srv).Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), "route not found")
+}
+
+func TestServer_ForwardedBaseURL(t *testing.T) {
+	proxies, err := clientip.NewResolver([]string{"192.0.2.0/24"})
+	require.NoError(t, err)
+	srv := server.New(server.Config{
+		Port:             18114,
+		BaseURL:          "http://localhost:18114",
+		ClientIP:         proxies,
+		ForwardedBaseURL: true,
+	}, NewStubURLService())
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"long_url": "https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "short.example.com")
+	rec := httptest.NewRecorder()
+
+	server.HTTPServer(srv).Handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var resp handler.CreateResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.True(t, strings.HasPrefix(resp.ShortURL, "https://short.example.com/s/"), resp.ShortURL)
+}
+
+func TestServer_APIKeyCodePrefixes(t *testing.T) {
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), domain.RealClock{})
+	srv := server.New(server.Config{
+		Port:               18116,
+		BaseURL:            "http://localhost:18116",
+		APIKeys:            []string{"tenant-one", "plain"},
+		APIKeyCodePrefixes: map[string]string{"tenant-one": "t1-"},
+	}, svc)
+	h := server.HTTPServer(srv).Handler
+
+	create := func(key string) string {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"long_url": "https://example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var resp handler.CreateResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp.ShortCode
+	}
+
+	code := create("tenant-one")
+	assert.True(t, strings.HasPrefix(code, "t1-"), code)
+	assert.False(t, strings.HasPrefix(create("plain"), "t1-"))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/s/"+code, nil))
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "https://example.com", rec.Header().Get("Location"))
+}
@@ -0,0 +1,62 @@
+package service
+
+import "strings"
+
+// DefaultBotPatterns match the User-Agents of common crawlers, link
+// unfurlers, and scripted HTTP clients.
+var DefaultBotPatterns = []string{
+	"bot",
+	"crawl",
+	"spider",
+	"slurp",
+	"facebookexternalhit",
+	"embedly",
+	"headlesschrome",
+	"curl/",
+	"wget/",
+	"python-requests",
+	"go-http-client",
+}
+
+// WithBotFilter stops Resolve from counting clicks whose User-Agent contains
+// any of patterns, compared case-insensitively, or is missing. Bots are
+// still redirected. Clicks on links with MaxClicks are always counted, so a
+// bot User-Agent can't get around the limit. Empty patterns means
+// DefaultBotPatterns.
+func WithBotFilter(patterns []string) Option {
+	return func(s *URLService) {
+		if len(patterns) == 0 {
+			patterns = DefaultBotPatterns
+		}
+		s.bots = newBotFilter(patterns)
+	}
+}
+
+// botFilter holds lowercased User-Agent substrings.
+type botFilter struct {
+	patterns []string
+}
+
+func newBotFilter(patterns []string) *botFilter {
+	f := &botFilter{patterns: make([]string, 0, len(patterns))}
+	for _, p := range patterns {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			f.patterns = append(f.patterns, p)
+		}
+	}
+	return f
+}
+
+// isBot reports whether userAgent is missing or matches a pattern.
+func (f *botFilter) isBot(userAgent string) bool {
+	if strings.TrimSpace(userAgent) == "" {
+		return true
+	}
+	ua := strings.ToLower(userAgent)
+	for _, p := range f.patterns {
+		if strings.Contains(ua, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// clickDeduper remembers when each (code, client IP) pair last counted a
+// click, so repeats inside the window can be ignored. Entries older than the
+// window are swept at most once per window to keep memory bounded.
+type clickDeduper struct {
+	mu        sync.Mutex
+	window    time.Duration
+	lastSeen  map[clickKey]time.Time
+	lastSweep time.Time
+}
+
+type clickKey struct {
+	code string
+	ip   string
+}
+
+func newClickDeduper(window time.Duration) *clickDeduper {
+	return &clickDeduper{
+		window:   window,
+		lastSeen: make(map[clickKey]time.Time),
+	}
+}
+
+// seenRecently reports whether the pair already counted a click within the
+// window ending at now, and records now as the pair's latest counted click
+// otherwise.
+func (d *clickDeduper) seenRecently(code, ip string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if now.Sub(d.lastSweep) >= d.window {
+		d.sweep(now)
+	}
+
+	key := clickKey{code: code, ip: ip}
+	if last, ok := d.lastSeen[key]; ok && now.Sub(last) < d.window {
+		return true
+	}
+
+	d.lastSeen[key] = now
+	return false
+}
+
+func (d *clickDeduper) sweep(now time.Time) {
+	for key, last := range d.lastSeen {
+		if now.Sub(last) >= d.window {
+			delete(d.lastSeen, key)
+		}
+	}
+	d.lastSweep = now
+}
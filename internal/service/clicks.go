@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/repository"
+)
+
+// maxClickBatch caps how many buffered clicks are written in one pass, so a
+// steady stream of clicks still reaches the repository promptly.
+const maxClickBatch = 256
+
+// WithAsyncClicks takes click counting off the redirect path: Resolve queues
+// each click in a buffer of the given size and a background goroutine writes
+// them to the repository in batches, at least every flushInterval. Counts
+// are eventually consistent. Clicks on links with MaxClicks are still
+// counted by Resolve itself, so the limit stays exact. When the buffer is
+// full, Resolve records the click itself rather than drop it. Call Close on shutdown so
+// no queued clicks are lost. A non-positive buffer disables it.
+func WithAsyncClicks(buffer int, flushInterval time.Duration) Option {
+	return func(s *URLService) {
+		if buffer > 0 {
+			s.clicks = newClickRecorder(s.repo, buffer, flushInterval)
+		}
+	}
+}
+
+// click is one counted resolve waiting to be written.
+type click struct {
+	code     string
+	referrer string
+	at       time.Time
+}
+
+// clickRecorder buffers clicks on a channel drained by a single writer
+// goroutine.
+type clickRecorder struct {
+	repo     repository.Repository
+	interval time.Duration
+
+	// mu guards closed, so enqueue never sends on the closed channel.
+	mu      sync.RWMutex
+	closed  bool
+	pending chan click
+
+	flushes chan chan struct{}
+	done    chan struct{}
+}
+
+func newClickRecorder(repo repository.Repository, buffer int, interval time.Duration) *clickRecorder {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	c := &clickRecorder{
+		repo:     repo,
+		interval: interval,
+		pending:  make(chan click, buffer),
+		flushes:  make(chan chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+// enqueue queues the click, reporting false if the buffer is full or the
+// recorder is closed and the caller must record it itself.
+func (c *clickRecorder) enqueue(cl click) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.closed {
+		return false
+	}
+	select {
+	case c.pending <- cl:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *clickRecorder) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	batch := make([]click, 0, maxClickBatch)
+	for {
+		select {
+		case cl, ok := <-c.pending:
+			if !ok {
+				c.write(batch)
+				return
+			}
+			batch = append(batch, cl)
+			if len(batch) >= maxClickBatch {
+				c.write(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			c.write(batch)
+			batch = batch[:0]
+		case flushed := <-c.flushes:
+			open := true
+			for open {
+				batch, open = c.drain(batch)
+				c.write(batch)
+				batch = batch[:0]
+			}
+			close(flushed)
+		}
+	}
+}
+
+// drain moves queued clicks into batch until the channel is empty or batch
+// is full. open is true if more clicks may be queued, false once the
+// channel is empty or closed.
+func (c *clickRecorder) drain(batch []click) (_ []click, open bool) {
+	for len(batch) < maxClickBatch {
+		select {
+		case cl, ok := <-c.pending:
+			if !ok {
+				return batch, false
+			}
+			batch = append(batch, cl)
+		default:
+			return batch, false
+		}
+	}
+	return batch, true
+}
+
+// write records each click in the batch. Failures are logged and the click
+// dropped; a click over the limit is simply not counted, as in Resolve.
+func (c *clickRecorder) write(batch []click) {
+	for _, cl := range batch {
+		err := c.repo.RecordClick(context.Background(), cl.code, cl.referrer, cl.at)
+		if err != nil && !errors.Is(err, domain.ErrClickLimitReached) && !errors.Is(err, domain.ErrNotFound) {
+			slog.Warn("recording buffered click", "code", cl.code, "error", err)
+		}
+	}
+}
+
+// flush waits until every click queued before the call is written.
+func (c *clickRecorder) flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case c.flushes <- flushed:
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close stops queueing, so later clicks are recorded synchronously, and
+// waits for the queued ones to be written.
+func (c *clickRecorder) close(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.pending)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush waits until every click Resolve queued before the call has been
+// written to the repository. It returns at once unless WithAsyncClicks is
+// set.
+func (s *URLService) Flush(ctx context.Context) error {
+	if s.clicks == nil {
+		return nil
+	}
+	return s.clicks.flush(ctx)
+}
+
+// Close writes any queued clicks, stops the background writer, and waits
+// for queued notifier calls, canceling them if ctx is done first. Resolve
+// keeps working afterwards, counting clicks synchronously; later notifier
+// events are dropped.
+func (s *URLService) Close(ctx context.Context) error {
+	if s.clicks != nil {
+		if err := s.clicks.close(ctx); err != nil {
+			return err
+		}
+	}
+	if s.pending != nil {
+		return s.pending.close(ctx)
+	}
+	return nil
+}
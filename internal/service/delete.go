@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// DefaultDeleteRetention is how long a deleted link can be restored before
+// PurgeExpired removes it for good.
+const DefaultDeleteRetention = 30 * 24 * time.Hour
+
+// WithDeleteRetention replaces DefaultDeleteRetention. Zero or less keeps
+// the default.
+func WithDeleteRetention(retention time.Duration) Option {
+	return func(s *URLService) {
+		if retention > 0 {
+			s.retention = retention
+		}
+	}
+}
+
+// Delete soft-deletes the given short code. It stops resolving and is
+// reported as not found everywhere, but keeps its code and stats so Restore
+// can bring it back within the delete retention.
+// Returns domain.ErrNotFound if not found or already deleted.
+func (s *URLService) Delete(ctx context.Context, shortCode string) error {
+	now := s.clock.Now()
+	event := domain.HistoryEvent{
+		Type:  domain.EventDeleted,
+		At:    now,
+		Actor: domain.ActorFromContext(ctx),
+	}
+	// Recorded first, since a deleted record can't be updated.
+	if err := s.repo.AppendHistory(ctx, shortCode, event); err != nil {
+		return fmt.Errorf("recording history: %w", err)
+	}
+
+	return s.repo.SoftDelete(ctx, shortCode, now)
+}
+
+// Restore undoes Delete and returns the restored record.
+// Returns domain.ErrNotFound if the code isn't deleted, or was deleted
+// longer ago than the delete retention.
+func (s *URLService) Restore(ctx context.Context, shortCode string) (*domain.URLRecord, error) {
+	now := s.clock.Now()
+	if err := s.repo.Restore(ctx, shortCode, now.Add(-s.retention)); err != nil {
+		return nil, err
+	}
+
+	event := domain.HistoryEvent{
+		Type:  domain.EventRestored,
+		At:    now,
+		Actor: domain.ActorFromContext(ctx),
+	}
+	if err := s.repo.AppendHistory(ctx, shortCode, event); err != nil {
+		return nil, fmt.Errorf("recording history: %w", err)
+	}
+
+	return s.repo.FindByShortCode(ctx, shortCode)
+}
+
+// DeleteByPrefix soft-deletes every link whose long URL starts with prefix,
+// such as the links of one campaign, and returns their codes, sorted. Like
+// Delete, each can be brought back with Restore within the delete
+// retention. Returns repository.ErrEmptyPrefix for an empty prefix.
+func (s *URLService) DeleteByPrefix(ctx context.Context, prefix string) ([]string, error) {
+	codes, err := s.repo.SoftDeleteByPrefix(ctx, prefix, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("bulk deleted links by prefix", "prefix", prefix, "count", len(codes), "actor", domain.ActorFromContext(ctx))
+	return codes, nil
+}
+
+// DeleteExpiringBefore removes every link that expires before the given
+// time, which may be in the future, and returns their codes, sorted. Links
+// that never expire are kept. Removal is permanent.
+func (s *URLService) DeleteExpiringBefore(ctx context.Context, before time.Time) ([]string, error) {
+	codes, err := s.repo.DeleteExpiredCodes(ctx, before)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("bulk deleted links by expiry", "before", before, "count", len(codes), "actor", domain.ActorFromContext(ctx))
+	return codes, nil
+}
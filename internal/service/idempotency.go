@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// idempotencyStore maps idempotency keys to the codes created for them, so
+// a retried create can be answered with the original record. A key whose
+// create is still running is held by an unfinished entry that concurrent
+// retries wait on. Finished entries older than the window are swept at most
+// once per window to keep memory bounded.
+type idempotencyStore struct {
+	mu        sync.Mutex
+	window    time.Duration
+	entries   map[string]*idempotencyEntry
+	lastSweep time.Time
+}
+
+type idempotencyEntry struct {
+	// done is closed once the create finishes; code is empty if it failed.
+	done      chan struct{}
+	code      string
+	createdAt time.Time
+
+	// params is the paramsDigest of the create that claimed the key.
+	params string
+}
+
+func newIdempotencyStore(window time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		window:  window,
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+// claim returns the live entry for key. owner is true when the caller has
+// just created the entry, for a create with the given paramsDigest, and
+// must finish it with complete or release.
+func (st *idempotencyStore) claim(key, params string, now time.Time) (entry *idempotencyEntry, owner bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if now.Sub(st.lastSweep) >= st.window {
+		st.sweep(now)
+	}
+
+	if entry, ok := st.entries[key]; ok && !st.stale(entry, now) {
+		return entry, false
+	}
+
+	entry = &idempotencyEntry{done: make(chan struct{}), createdAt: now, params: params}
+	st.entries[key] = entry
+	return entry, true
+}
+
+// complete records the code created for entry's key and wakes any waiters.
+func (st *idempotencyStore) complete(entry *idempotencyEntry, code string) {
+	st.mu.Lock()
+	entry.code = code
+	st.mu.Unlock()
+	close(entry.done)
+}
+
+// release forgets key after a failed create, so a retry can try again.
+func (st *idempotencyStore) release(key string, entry *idempotencyEntry) {
+	st.mu.Lock()
+	if st.entries[key] == entry {
+		delete(st.entries, key)
+	}
+	st.mu.Unlock()
+	close(entry.done)
+}
+
+// wait blocks until entry's create finishes and returns its code, or "" if
+// it failed.
+func (st *idempotencyStore) wait(ctx context.Context, entry *idempotencyEntry) (string, error) {
+	select {
+	case <-entry.done:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return entry.code, nil
+}
+
+// stale reports whether a finished entry has outlived the window. Running
+// creates are never stale.
+func (st *idempotencyStore) stale(entry *idempotencyEntry, now time.Time) bool {
+	select {
+	case <-entry.done:
+		return now.Sub(entry.createdAt) >= st.window
+	default:
+		return false
+	}
+}
+
+// paramsDigest condenses params, so a repeated key can be checked against
+// the create it was first sent with without keeping the URL around.
+func paramsDigest(params domain.CreateParams) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q %d %t %q %t %d %q", params.LongURL, params.TTL, params.NoExpiry,
+		params.CustomAlias, params.Permanent, params.MaxClicks, params.Tags)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (st *idempotencyStore) sweep(now time.Time) {
+	for key, entry := range st.entries {
+		if st.stale(entry, now) {
+			delete(st.entries, key)
+		}
+	}
+	st.lastSweep = now
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"url-shortener/internal/domain"
+)
+
+// ResolveOutcome classifies the result of a Resolve call for metrics.
+type ResolveOutcome string
+
+// Resolve outcomes reported to Metrics.
+const (
+	ResolveOK          ResolveOutcome = "ok"
+	ResolveNotFound    ResolveOutcome = "not_found"
+	ResolveExpired     ResolveOutcome = "expired"
+	ResolveDisabled    ResolveOutcome = "disabled"
+	ResolveInvalidCode ResolveOutcome = "invalid_code"
+	ResolveError       ResolveOutcome = "error"
+)
+
+// Metrics receives business events from URLService. Implementations must
+// be safe for concurrent use.
+type Metrics interface {
+	// CodeCreated is called once per newly stored short code. Reused codes
+	// are not reported.
+	CodeCreated()
+
+	// Resolved is called once per Resolve call with its outcome.
+	Resolved(outcome ResolveOutcome)
+
+	// CodeCollided is called each time a generated code turns out to be
+	// taken, whether or not another is tried. A derived code taken by the
+	// same URL is reused rather than counted.
+	CodeCollided()
+
+	// NotificationDropped is called for each notifier event dropped because
+	// the queue was full or the service closed.
+	NotificationDropped()
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) CodeCreated()              {}
+func (noopMetrics) Resolved(_ ResolveOutcome) {}
+func (noopMetrics) CodeCollided()             {}
+func (noopMetrics) NotificationDropped()      {}
+
+// WithMetrics reports business events such as created codes and resolves.
+func WithMetrics(m Metrics) Option {
+	return func(s *URLService) {
+		s.metrics = m
+	}
+}
+
+// ErrUnknownCodeSpace is returned by CodeSpaceSaturation when the generator
+// doesn't implement CodeSpacer.
+var ErrUnknownCodeSpace = errors.New("generator does not report its code space")
+
+// CodeSpaceKnown reports whether the generator implements CodeSpacer, so
+// CodeSpaceSaturation can estimate saturation.
+func (s *URLService) CodeSpaceKnown() bool {
+	_, ok := s.generator.(CodeSpacer)
+	return ok
+}
+
+// CodeSpaceSaturation estimates how much of the generator's code space is
+// taken: stored records divided by CodeSpace. It overstates saturation when
+// custom aliases or prefixed codes are stored, which don't draw from that
+// space, so it is a signal for when to lengthen codes rather than an exact
+// figure.
+func (s *URLService) CodeSpaceSaturation(ctx context.Context) (float64, error) {
+	spacer, ok := s.generator.(CodeSpacer)
+	if !ok {
+		return 0, ErrUnknownCodeSpace
+	}
+	n, err := s.repo.Count(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return float64(n) / spacer.CodeSpace(), nil
+}
+
+func resolveOutcome(err error) ResolveOutcome {
+	switch {
+	case err == nil:
+		return ResolveOK
+	case errors.Is(err, domain.ErrNotFound):
+		return ResolveNotFound
+	case errors.Is(err, domain.ErrExpired):
+		return ResolveExpired
+	case errors.Is(err, domain.ErrDisabled):
+		return ResolveDisabled
+	case errors.Is(err, domain.ErrInvalidChecksum):
+		return ResolveInvalidCode
+	default:
+		return ResolveError
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"url-shortener/internal/domain"
+)
+
+// Defaults for WithNotifierQueue.
+const (
+	DefaultNotifierQueueSize = 1024
+	DefaultNotifierWorkers   = 4
+)
+
+// Notifier is told about link lifecycle events, e.g. to forward them to a
+// webhook. URLService queues the calls for a fixed pool of worker
+// goroutines so a slow receiver never holds up a request. ctx is canceled
+// when Close stops waiting for the queue to drain. Implementations must be
+// safe for concurrent use.
+type Notifier interface {
+	// OnCreated is called once per newly stored record. Existing records
+	// handed back for a repeated long URL are not reported.
+	OnCreated(ctx context.Context, record *domain.URLRecord)
+
+	// OnExpired is called with the codes each purge of expired records
+	// deleted, when it deleted any.
+	OnExpired(ctx context.Context, codes []string)
+}
+
+// WithNotifier reports created and purged links to n.
+func WithNotifier(n Notifier) Option {
+	return func(s *URLService) {
+		s.notifier = n
+	}
+}
+
+// WithNotifierQueue sets how many notifier calls may wait, and how many
+// workers make them; DefaultNotifierQueueSize and DefaultNotifierWorkers
+// otherwise. Events arriving while the queue is full are dropped and
+// reported to Metrics.NotificationDropped. Values below one keep the
+// default.
+func WithNotifierQueue(size, workers int) Option {
+	return func(s *URLService) {
+		s.notifierQueue, s.notifierWorkers = size, workers
+	}
+}
+
+// notifications queues notifier calls on a channel drained by a fixed pool
+// of workers.
+type notifications struct {
+	// mu guards closed, so enqueue never sends on the closed channel.
+	mu     sync.RWMutex
+	closed bool
+	queue  chan func(context.Context)
+
+	// ctx is passed to every call and canceled when close gives up.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newNotifications(size, workers int) *notifications {
+	if size < 1 {
+		size = DefaultNotifierQueueSize
+	}
+	if workers < 1 {
+		workers = DefaultNotifierWorkers
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &notifications{
+		queue:  make(chan func(context.Context), size),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	n.wg.Add(workers)
+	for range workers {
+		go n.work()
+	}
+	return n
+}
+
+func (n *notifications) work() {
+	defer n.wg.Done()
+	for call := range n.queue {
+		call(n.ctx)
+	}
+}
+
+// enqueue queues call, reporting false if the queue is full or closed.
+func (n *notifications) enqueue(call func(context.Context)) bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.closed {
+		return false
+	}
+	select {
+	case n.queue <- call:
+		return true
+	default:
+		return false
+	}
+}
+
+// close stops queueing and waits for the queued calls to finish. If ctx is
+// done first, the calls still running or queued are canceled.
+func (n *notifications) close(ctx context.Context) error {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		n.cancel()
+		return ctx.Err()
+	}
+}
+
+// notify queues call for the notifier, dropping it if the queue is full.
+func (s *URLService) notify(event string, call func(context.Context)) {
+	if !s.pending.enqueue(call) {
+		s.metrics.NotificationDropped()
+		slog.Warn("notifier queue full, dropping event", "event", event)
+	}
+}
+
+// linkCreated reports a newly stored record to metrics and the notifier.
+func (s *URLService) linkCreated(record *domain.URLRecord) {
+	s.metrics.CodeCreated()
+	if s.notifier != nil {
+		created := record.Clone()
+		s.notify("created", func(ctx context.Context) { s.notifier.OnCreated(ctx, created) })
+	}
+}
+
+// linksExpired reports the codes of purged records to the notifier.
+func (s *URLService) linksExpired(codes []string) {
+	if s.notifier != nil && len(codes) > 0 {
+		s.notify("expired", func(ctx context.Context) { s.notifier.OnExpired(ctx, codes) })
+	}
+}
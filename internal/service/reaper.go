@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// PurgeExpired deletes every record that expired before now, passing their
+// codes to the notifier, along with records deleted longer ago than the
+// delete retention. It returns the codes of both, sorted.
+func (s *URLService) PurgeExpired(ctx context.Context) ([]string, error) {
+	now := s.clock.Now()
+	codes, err := s.repo.DeleteExpiredCodes(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	s.linksExpired(codes)
+
+	purged, err := s.repo.PurgeDeleted(ctx, now.Add(-s.retention))
+	if err != nil {
+		return nil, err
+	}
+	if len(purged) > 0 {
+		codes = append(codes, purged...)
+		slices.Sort(codes)
+	}
+	return codes, nil
+}
+
+// RunReaper calls PurgeExpired every interval until ctx is done, logging
+// the purged codes at debug level. A non-positive interval returns at once.
+func (s *URLService) RunReaper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			codes, err := s.PurgeExpired(ctx)
+			if err != nil {
+				slog.Error("purging expired links", "error", err)
+				continue
+			}
+			if len(codes) > 0 {
+				slog.Info("purged expired links", "count", len(codes))
+				slog.Debug("purged expired link codes", "codes", codes)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// Defaults for SaveRetryPolicy fields left zero.
+const (
+	DefaultSaveRetryBackoff    = 50 * time.Millisecond
+	DefaultSaveRetryMaxBackoff = time.Second
+)
+
+// SaveRetryPolicy bounds how Create retries a save that failed with
+// something other than a taken code, such as a database that is briefly
+// unavailable. Taken codes keep their own immediate retry with a new code.
+type SaveRetryPolicy struct {
+	// Retries is how many times a failed save is tried again. Zero
+	// disables retrying.
+	Retries int
+
+	// InitialBackoff is the wait before the first retry. It doubles after
+	// each retry up to MaxBackoff, and each wait is jittered down by up to
+	// half so that clients failing together don't retry together.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Sleeper waits for d, returning early with ctx's error if ctx is done.
+type Sleeper func(ctx context.Context, d time.Duration) error
+
+// WithSaveRetries makes Create retry failed saves as policy describes.
+func WithSaveRetries(policy SaveRetryPolicy) Option {
+	return func(s *URLService) {
+		if policy.InitialBackoff <= 0 {
+			policy.InitialBackoff = DefaultSaveRetryBackoff
+		}
+		if policy.MaxBackoff <= 0 {
+			policy.MaxBackoff = DefaultSaveRetryMaxBackoff
+		}
+		s.saveRetry = policy
+	}
+}
+
+// WithSleeper replaces how the service waits between save retries, letting
+// tests run without real delays.
+func WithSleeper(sleep Sleeper) Option {
+	return func(s *URLService) {
+		s.sleep = sleep
+	}
+}
+
+// sleepContext is the default Sleeper.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// save stores record with SaveIfNotExists, retrying transient failures as
+// the save retry policy allows. It returns the last error.
+func (s *URLService) save(ctx context.Context, record *domain.URLRecord) error {
+	err := s.repo.SaveIfNotExists(ctx, record)
+	backoff := s.saveRetry.InitialBackoff
+	for retry := 1; retry <= s.saveRetry.Retries && retryableSaveError(err); retry++ {
+		wait := backoff/2 + rand.N(backoff/2+1)
+		if s.sleep(ctx, wait) != nil {
+			return err
+		}
+		backoff = min(backoff*2, s.saveRetry.MaxBackoff)
+		err = s.repo.SaveIfNotExists(ctx, record)
+	}
+	return err
+}
+
+// retryableSaveError reports whether a save failing with err may succeed if
+// tried again unchanged. Taken codes and a full store won't, and neither
+// will a save whose context is done.
+func retryableSaveError(err error) bool {
+	return err != nil &&
+		!errors.Is(err, domain.ErrCodeExists) &&
+		!errors.Is(err, domain.ErrCapacityExceeded) &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded)
+}
@@ -4,6 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"url-shortener/internal/domain"
@@ -11,102 +17,628 @@ import (
 	"url-shortener/internal/shortcode"
 )
 
-const (
-	maxRetries = 5
-	defaultTTL = 24 * time.Hour
-)
+// DefaultMaxRetries is how many generated codes Create tries before giving
+// up, unless changed with WithMaxRetries.
+const DefaultMaxRetries = 5
 
 // CodeGenerator defines the interface for short code generation.
 type CodeGenerator interface {
 	Generate() string
 }
 
+// URLCodeGenerator is implemented by generators that derive the code from
+// the long URL. Create prefers GenerateFor over Generate when available.
+type URLCodeGenerator interface {
+	GenerateFor(longURL string) string
+}
+
+// ChecksumVerifier is implemented by generators whose codes carry a check
+// character, letting Resolve reject typos without a store lookup.
+type ChecksumVerifier interface {
+	VerifyChecksum(code string) bool
+	Suggest(code string) string
+}
+
+// CodeSpacer is implemented by generators that know how many distinct codes
+// they can produce, letting CodeSpaceSaturation estimate how full it is.
+type CodeSpacer interface {
+	CodeSpace() float64
+}
+
+// CollisionStrategy decides the next candidate code after SaveIfNotExists
+// reports that collided is already taken. attempt counts the collisions so
+// far, starting at 1. Returning ok=false stops retrying.
+type CollisionStrategy interface {
+	Next(collided string, attempt int) (code string, ok bool)
+}
+
+// regenerateStrategy asks the generator for a fresh code on every collision.
+type regenerateStrategy struct {
+	generator CodeGenerator
+}
+
+func (r regenerateStrategy) Next(_ string, _ int) (string, bool) {
+	return r.generator.Generate(), true
+}
+
 // URLService handles URL shortening business logic.
 type URLService struct {
-	repo      repository.Repository
-	generator CodeGenerator
-	clock     domain.Clock
+	repo       repository.Repository
+	generator  CodeGenerator
+	clock      domain.Clock
+	collisions CollisionStrategy
+	dedup      *clickDeduper
+	bots       *botFilter
+	clicks     *clickRecorder
+	idempotent *idempotencyStore
+	reuseCodes bool
+	metrics    Metrics
+	notifier   Notifier
+	pending    *notifications
+	saveRetry  SaveRetryPolicy
+	sleep      Sleeper
+	retention  time.Duration
+	maxRetries int
+	codePrefix string
+
+	// notifierQueue and notifierWorkers size pending; see
+	// WithNotifierQueue.
+	notifierQueue, notifierWorkers int
+
+	// tenantPrefixes are the code prefixes of every tenant, set with
+	// WithTenantPrefixes.
+	tenantPrefixes []string
+
+	// destinationAllowed vets long URLs before they are resolved; it is
+	// safeDestination unless replaced with WithDestinationCheck.
+	destinationAllowed func(longURL string) bool
+
+	// ttl is swapped by SetTTLPolicy; each call loads it once.
+	ttl atomic.Pointer[domain.TTLPolicy]
 }
 
-// NewURLService creates a new URLService with the default generator.
-func NewURLService(repo repository.Repository, generator *shortcode.Generator, clock domain.Clock) *URLService {
-	return &URLService{
-		repo:      repo,
-		generator: generator,
-		clock:     clock,
+// Option configures optional URLService behavior.
+type Option func(*URLService)
+
+// WithCollisionStrategy replaces the default collision handling, which asks
+// the generator for a new code, e.g. to bump a counter for sequential codes.
+func WithCollisionStrategy(strategy CollisionStrategy) Option {
+	return func(s *URLService) {
+		s.collisions = strategy
 	}
 }
 
+// WithMaxRetries sets how many generated codes Create tries, counting the
+// first, before failing. Raise it for nearly full code spaces, or lower it
+// to fail fast. Zero or less keeps DefaultMaxRetries.
+func WithMaxRetries(n int) Option {
+	return func(s *URLService) {
+		if n > 0 {
+			s.maxRetries = n
+		}
+	}
+}
+
+// WithClickDedupWindow stops repeated clicks from the same client IP on the
+// same code from being counted more than once per window. The redirect still
+// succeeds; only the increment is suppressed. Clicks on links with MaxClicks
+// are always counted, so repeats can't get around the limit. A zero window
+// disables it.
+func WithClickDedupWindow(window time.Duration) Option {
+	return func(s *URLService) {
+		if window > 0 {
+			s.dedup = newClickDeduper(window)
+		}
+	}
+}
+
+// WithIdempotencyWindow makes CreateIdempotent remember the code created for
+// each idempotency key for window, answering repeats of the key with that
+// record. A zero window disables it, so keys are ignored.
+func WithIdempotencyWindow(window time.Duration) Option {
+	return func(s *URLService) {
+		if window > 0 {
+			s.idempotent = newIdempotencyStore(window)
+		}
+	}
+}
+
+// WithTTLPolicy replaces domain.DefaultTTLPolicy. Create applies its
+// default to params without a TTL, and Create and UpdateTTL refuse expiries
+// after its MaxExpiryAbsolute. Min and Max are enforced by callers, such as
+// the HTTP handlers, that accept TTLs from users.
+func WithTTLPolicy(policy domain.TTLPolicy) Option {
+	return func(s *URLService) {
+		s.ttl.Store(&policy)
+	}
+}
+
+// SetTTLPolicy replaces the TTL policy while the service is in use, e.g. on
+// a config reload. Calls already in progress keep the policy they started
+// with.
+func (s *URLService) SetTTLPolicy(policy domain.TTLPolicy) {
+	s.ttl.Store(&policy)
+}
+
+// WithLongURLReuse makes Create return the existing record when the same
+// long URL is shortened again, instead of generating a new code, as long as
+// that record still redirects: enabled, not expired, and with clicks left.
+// Only a record created with the same settings is returned (see
+// reusable), and it keeps its original expiry. Requests with a custom
+// alias or a click limit always create a new record.
+func WithLongURLReuse() Option {
+	return func(s *URLService) {
+		s.reuseCodes = true
+	}
+}
+
+// WithCodePrefix starts every generated code with prefix, e.g. "t1-", to
+// namespace a deployment's links. A prefix set on the create's context with
+// domain.WithCodePrefix takes precedence. Custom aliases are stored as
+// given. Prefixed codes skip the generator's checksum.
+func WithCodePrefix(prefix string) Option {
+	return func(s *URLService) {
+		s.codePrefix = prefix
+	}
+}
+
+// WithTenantPrefixes lists the code prefixes handed to tenants through
+// domain.WithCodePrefix, so a caller can't reach into another tenant's
+// namespace: custom aliases starting with another tenant's prefix are
+// refused with domain.ErrInvalidAlias, and WithLongURLReuse doesn't hand
+// out links from another tenant's namespace, including to callers without
+// a prefix.
+func WithTenantPrefixes(prefixes ...string) Option {
+	return func(s *URLService) {
+		s.tenantPrefixes = prefixes
+	}
+}
+
+// WithDestinationCheck makes Resolve and Lookup refuse records whose long
+// URL allowed rejects with domain.ErrUnsafeDestination, without counting a
+// click. Create already vets long URLs, but records written by imports or
+// other backends could still hold a javascript: or data: URL. allowed
+// replaces safeDestination, so it should refuse those schemes too; a nil
+// allowed keeps safeDestination.
+func WithDestinationCheck(allowed func(longURL string) bool) Option {
+	return func(s *URLService) {
+		if allowed != nil {
+			s.destinationAllowed = allowed
+		}
+	}
+}
+
+// unsafeSchemes run content in the browser instead of navigating.
+var unsafeSchemes = map[string]bool{
+	"javascript": true,
+	"data":       true,
+	"vbscript":   true,
+}
+
+// safeDestination is the destination check used without
+// WithDestinationCheck: it refuses long URLs that don't parse or whose
+// scheme is javascript, data, or vbscript.
+func safeDestination(longURL string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(longURL))
+	return err == nil && !unsafeSchemes[strings.ToLower(parsed.Scheme)]
+}
+
+// NewURLService creates a new URLService with the default generator.
+func NewURLService(repo repository.Repository, generator *shortcode.Generator, clock domain.Clock, opts ...Option) *URLService {
+	return newURLService(repo, generator, clock, opts)
+}
+
 // NewURLServiceWithGenerator creates a URLService with a custom generator (for testing).
-func NewURLServiceWithGenerator(repo repository.Repository, generator CodeGenerator, clock domain.Clock) *URLService {
-	return &URLService{
-		repo:      repo,
-		generator: generator,
-		clock:     clock,
+func NewURLServiceWithGenerator(repo repository.Repository, generator CodeGenerator, clock domain.Clock, opts ...Option) *URLService {
+	return newURLService(repo, generator, clock, opts)
+}
+
+func newURLService(repo repository.Repository, generator CodeGenerator, clock domain.Clock, opts []Option) *URLService {
+	s := &URLService{
+		repo:       repo,
+		generator:  generator,
+		clock:      clock,
+		collisions: regenerateStrategy{generator: generator},
+		metrics:    noopMetrics{},
+		sleep:      sleepContext,
+		retention:  DefaultDeleteRetention,
+		maxRetries: DefaultMaxRetries,
+
+		destinationAllowed: safeDestination,
 	}
+	defaultPolicy := domain.DefaultTTLPolicy()
+	s.ttl.Store(&defaultPolicy)
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.notifier != nil {
+		s.pending = newNotifications(s.notifierQueue, s.notifierWorkers)
+	}
+	return s
 }
 
-// Create creates a new shortened URL with the given TTL.
-// If ttl is 0, the default TTL (24 hours) is used.
-// Returns the created record or an error if max retries exceeded.
-func (s *URLService) Create(ctx context.Context, longURL string, ttl time.Duration) (*domain.URLRecord, error) {
-	if ttl == 0 {
-		ttl = defaultTTL
+// Create creates a new shortened URL.
+// If params.TTL is 0, the policy's default TTL (24 hours unless set with
+// WithTTLPolicy) is used; with params.NoExpiry the link never expires. A
+// link that would expire after the policy's MaxExpiryAbsolute, including
+// one that never expires, is refused with domain.ErrExpiryTooLate.
+// If params.CustomAlias is set, exactly that code is saved, returning
+// domain.ErrAliasTaken if it is in use; otherwise a code is generated.
+// With a URLCodeGenerator, creating the same URL again returns the record
+// already stored under its derived code if it is reusable for params.
+// Returns the created record and how many generated codes were tried to
+// find a free one, or an error if max retries exceeded. attempts is 0 when
+// no code was generated: for aliases and for existing records handed back.
+func (s *URLService) Create(ctx context.Context, params domain.CreateParams) (*domain.URLRecord, int, error) {
+	policy := s.ttl.Load()
+	if params.NoExpiry {
+		params.TTL = 0
+	} else if params.TTL == 0 {
+		params.TTL = policy.Default
 	}
 
 	now := s.clock.Now()
+	if err := policy.CheckExpiry(expiresAt(params, now)); err != nil {
+		return nil, 0, err
+	}
+
+	if params.CustomAlias != "" {
+		record, err := s.createWithAlias(ctx, params, now)
+		return record, 0, err
+	}
+
+	if s.reuseCodes {
+		// A link in another namespace is not this caller's to hand out.
+		existing, err := s.repo.FindByLongURL(ctx, params.LongURL)
+		if err == nil && reusable(existing, params, now) && strings.HasPrefix(existing.ShortCode, s.prefixFor(ctx)) &&
+			!s.inOtherNamespace(ctx, existing.ShortCode) {
+			return existing, 0, nil
+		}
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return nil, 0, fmt.Errorf("finding existing record: %w", err)
+		}
+	}
+
+	return s.createWithGeneratedCode(ctx, params, now)
+}
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		code := s.generator.Generate()
+// prefixFor returns the prefix of codes generated for ctx.
+func (s *URLService) prefixFor(ctx context.Context) string {
+	if prefix, ok := domain.CodePrefixFromContext(ctx); ok {
+		return prefix
+	}
+	return s.codePrefix
+}
 
-		record := &domain.URLRecord{
-			ShortCode:      code,
-			LongURL:        longURL,
-			CreatedAt:      now,
-			ExpiresAt:      now.Add(ttl),
-			ClickCount:     0,
-			LastAccessedAt: time.Time{},
+// inOtherNamespace reports whether code starts with the prefix of a tenant
+// other than ctx's.
+func (s *URLService) inOtherNamespace(ctx context.Context, code string) bool {
+	own := s.prefixFor(ctx)
+	for _, prefix := range s.tenantPrefixes {
+		if prefix != own && strings.HasPrefix(code, prefix) {
+			return true
 		}
+	}
+	return false
+}
+
+// createWithGeneratedCode saves the record under a generated code, asking
+// the collision strategy for another candidate while codes are taken.
+// attempts counts the codes tried, so it is 1 when nothing collided. The
+// code prefix is added to each candidate and hidden from the strategy.
+func (s *URLService) createWithGeneratedCode(ctx context.Context, params domain.CreateParams, now time.Time) (*domain.URLRecord, int, error) {
+	prefix := s.prefixFor(ctx)
 
-		err := s.repo.SaveIfNotExists(ctx, record)
+	var code string
+	urlGen, deterministic := s.generator.(URLCodeGenerator)
+	if deterministic {
+		code = prefix + urlGen.GenerateFor(params.LongURL)
+	} else {
+		code = prefix + s.generator.Generate()
+	}
+
+	for attempt := 1; attempt <= s.maxRetries; attempt++ {
+		record := s.newRecord(ctx, code, params, now)
+
+		err := s.save(ctx, record)
 		if err == nil {
-			return record, nil
+			s.linkCreated(record)
+			return record, attempt, nil
 		}
 
 		if errors.Is(err, domain.ErrCodeExists) {
-			continue // Collision, retry with new code
+			// A derived code that is already taken usually means the URL
+			// was shortened before; hand back that link.
+			if deterministic && attempt == 1 {
+				if existing, ok := s.existingLink(ctx, code, params, now); ok {
+					return existing, 0, nil
+				}
+			}
+			s.metrics.CodeCollided()
+			if attempt == s.maxRetries {
+				break
+			}
+			next, ok := s.collisions.Next(strings.TrimPrefix(code, prefix), attempt)
+			if !ok {
+				return nil, attempt, fmt.Errorf("collision strategy gave up after %d attempts: %w", attempt, err)
+			}
+			code = prefix + next
+			continue // Collision, retry with next candidate
 		}
 
+		return nil, attempt, fmt.Errorf("saving record: %w", err)
+	}
+
+	return nil, s.maxRetries, errors.New("max retries exceeded: unable to generate unique code")
+}
+
+// existingLink returns the record stored under code if it points at
+// params.LongURL and is reusable for params.
+func (s *URLService) existingLink(ctx context.Context, code string, params domain.CreateParams, now time.Time) (*domain.URLRecord, bool) {
+	existing, err := s.repo.FindByShortCode(ctx, code)
+	if err != nil || existing.LongURL != params.LongURL || !reusable(existing, params, now) {
+		return nil, false
+	}
+	return existing, true
+}
+
+// reusable reports whether existing can be handed back for a create with
+// params instead of a new link: it must still redirect, behave as params
+// ask, and expire no later than a new link would, so reusing another
+// caller's link can't get around a TTL cap. Click-limited links are never
+// shared, as one caller's clicks would use up another's.
+func reusable(existing *domain.URLRecord, params domain.CreateParams, now time.Time) bool {
+	if !redirectable(existing, now) || existing.MaxClicks > 0 || params.MaxClicks > 0 {
+		return false
+	}
+	if existing.RedirectPermanent != params.Permanent || existing.NeverExpires() != params.NoExpiry {
+		return false
+	}
+	if !params.NoExpiry && existing.ExpiresAt.After(expiresAt(params, now)) {
+		return false
+	}
+	return sameTags(existing.Tags, params.Tags)
+}
+
+// sameTags reports whether a and b hold the same tags in any order.
+func sameTags(a, b []string) bool {
+	return slices.Equal(slices.Sorted(slices.Values(a)), slices.Sorted(slices.Values(b)))
+}
+
+// redirectable reports whether an existing record can still be handed back
+// to a caller as a working link at now.
+func redirectable(record *domain.URLRecord, now time.Time) bool {
+	return record.Enabled && !record.IsExpired(now) && !record.ClickLimitReached()
+}
+
+// CreateBatch creates each item with Create, collecting per-item results in
+// order. A failed item does not stop the rest of the batch.
+func (s *URLService) CreateBatch(ctx context.Context, items []domain.CreateParams) []domain.CreateResult {
+	results := make([]domain.CreateResult, len(items))
+	for i, params := range items {
+		record, _, err := s.Create(ctx, params)
+		results[i] = domain.CreateResult{Record: record, Err: err}
+	}
+	return results
+}
+
+// CreateIdempotent is Create for clients that retry on network errors. The
+// first call with a given key creates a record; repeats of the key within
+// the idempotency window return that record with replayed set instead of
+// creating another, waiting for the first call if it is still running. A
+// failed create does not use up the key. A repeat with different params
+// returns domain.ErrIdempotencyKeyReused. attempts is as for Create, and 0
+// for replays. Keys are scoped to the caller's code prefix and
+// domain.ClientIDFromContext, so clients can't replay each other's links.
+// Without WithIdempotencyWindow, or with an empty key, it behaves like
+// Create.
+func (s *URLService) CreateIdempotent(ctx context.Context, key string, params domain.CreateParams) (record *domain.URLRecord, attempts int, replayed bool, err error) {
+	if s.idempotent == nil || key == "" {
+		record, attempts, err = s.Create(ctx, params)
+		return record, attempts, false, err
+	}
+	return s.createIdempotent(ctx, s.prefixFor(ctx)+"\x00"+domain.ClientIDFromContext(ctx)+"\x00"+key, params)
+}
+
+// createIdempotent is CreateIdempotent for a key already scoped to the
+// caller.
+func (s *URLService) createIdempotent(ctx context.Context, key string, params domain.CreateParams) (record *domain.URLRecord, attempts int, replayed bool, err error) {
+	digest := paramsDigest(params)
+	entry, owner := s.idempotent.claim(key, digest, s.clock.Now())
+	if !owner && entry.params != digest {
+		return nil, 0, false, domain.ErrIdempotencyKeyReused
+	}
+	if owner {
+		record, attempts, err = s.Create(ctx, params)
+		if err != nil {
+			s.idempotent.release(key, entry)
+			return nil, attempts, false, err
+		}
+		s.idempotent.complete(entry, record.ShortCode)
+		return record, attempts, false, nil
+	}
+
+	code, err := s.idempotent.wait(ctx, entry)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if code == "" {
+		// The first call failed and released the key; try again as if new.
+		return s.createIdempotent(ctx, key, params)
+	}
+
+	record, err = s.repo.FindByShortCode(ctx, code)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("finding record for idempotency key: %w", err)
+	}
+	return record, 0, true, nil
+}
+
+// createWithAlias saves the record under the requested alias, without retries.
+func (s *URLService) createWithAlias(ctx context.Context, params domain.CreateParams, now time.Time) (*domain.URLRecord, error) {
+	// An alias shaped like a generated code must pass the checksum,
+	// otherwise Resolve would reject it before looking it up.
+	if v, ok := s.generator.(ChecksumVerifier); ok && !v.VerifyChecksum(params.CustomAlias) {
+		return nil, domain.ErrInvalidAlias
+	}
+	if s.inOtherNamespace(ctx, params.CustomAlias) {
+		return nil, domain.ErrInvalidAlias
+	}
+
+	record := s.newRecord(ctx, params.CustomAlias, params, now)
+
+	err := s.save(ctx, record)
+	if errors.Is(err, domain.ErrCodeExists) {
+		return nil, domain.ErrAliasTaken
+	}
+	if err != nil {
 		return nil, fmt.Errorf("saving record: %w", err)
 	}
 
-	return nil, errors.New("max retries exceeded: unable to generate unique code")
+	s.linkCreated(record)
+	return record, nil
 }
 
-// Resolve returns the long URL for the given short code.
-// It increments the click count and updates LastAccessedAt.
-// Returns domain.ErrNotFound if not found, domain.ErrExpired if expired.
-func (s *URLService) Resolve(ctx context.Context, shortCode string) (string, error) {
+// expiresAt returns when a link created now with params expires, or the
+// zero time if it never does.
+func expiresAt(params domain.CreateParams, now time.Time) time.Time {
+	if params.NoExpiry {
+		return time.Time{}
+	}
+	return now.Add(params.TTL)
+}
+
+func (s *URLService) newRecord(ctx context.Context, code string, params domain.CreateParams, now time.Time) *domain.URLRecord {
+	details := map[string]string{
+		"long_url":    domain.RedactURL(params.LongURL),
+		"ttl_seconds": strconv.FormatInt(int64(params.TTL/time.Second), 10),
+	}
+	if params.NoExpiry {
+		delete(details, "ttl_seconds")
+		details["no_expiry"] = "true"
+	}
+	if len(params.Tags) > 0 {
+		details["tags"] = strings.Join(params.Tags, ",")
+	}
+
+	return &domain.URLRecord{
+		ShortCode:         code,
+		LongURL:           params.LongURL,
+		CreatedAt:         now,
+		ExpiresAt:         expiresAt(params, now),
+		TTL:               params.TTL,
+		ClickCount:        0,
+		LastAccessedAt:    time.Time{},
+		MaxClicks:         params.MaxClicks,
+		RedirectPermanent: params.Permanent,
+		Enabled:           true,
+		Tags:              slices.Clone(params.Tags),
+		History: []domain.HistoryEvent{{
+			Type:    domain.EventCreated,
+			At:      now,
+			Actor:   domain.ActorFromContext(ctx),
+			Details: details,
+		}},
+	}
+}
+
+// Resolve returns the record for the given short code, reflecting the click
+// it counts: the click count is incremented and LastAccessedAt updated,
+// unless click deduplication suppresses a repeat from the same visitor.
+// With WithAsyncClicks the increment reaches the repository later.
+// Returns domain.ErrNotFound if not found, domain.ErrExpired if expired,
+// domain.ErrDisabled if disabled, or a *domain.ChecksumError if the
+// generator checksums codes and it fails.
+func (s *URLService) Resolve(ctx context.Context, shortCode string, visit domain.Visit) (*domain.URLRecord, error) {
+	record, err := s.resolve(ctx, shortCode, visit)
+	s.metrics.Resolved(resolveOutcome(err))
+	return record, err
+}
+
+func (s *URLService) resolve(ctx context.Context, shortCode string, visit domain.Visit) (*domain.URLRecord, error) {
+	now := s.clock.Now()
+	record, err := s.lookup(ctx, shortCode, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bots are redirected without counting a click. Links with a click
+	// limit still count them: the User-Agent is the client's to choose, so
+	// skipping the limit check for bots would let anyone follow the link
+	// without limit.
+	if s.bots != nil && record.MaxClicks == 0 && s.bots.isBot(visit.UserAgent) {
+		return record, nil
+	}
+
+	// Skip counting rapid repeats from the same client. Links with a click
+	// limit count every click, for the same reason as above.
+	if s.dedup != nil && record.MaxClicks == 0 && visit.ClientIP != "" && s.dedup.seenRecently(shortCode, visit.ClientIP, now) {
+		return record, nil
+	}
+
+	referrer := domain.ReferrerKey(visit.Referer)
+	if s.clicks != nil && record.MaxClicks == 0 && s.clicks.enqueue(click{code: shortCode, referrer: referrer, at: now}) {
+		record.ClickCount++
+		record.LastAccessedAt = now
+		return record, nil
+	}
+
+	// Count the click and its referrer. The repository re-checks expiry, the
+	// click limit, and Enabled in the same atomic step, so a link that stops
+	// being redirectable after the lookup above (expiring, used up by a
+	// concurrent resolve, disabled, or deleted) is neither counted nor
+	// redirected to. Other failures don't block the redirect.
+	counted, err := s.repo.ResolveAndIncrement(ctx, shortCode, referrer, now)
+	if errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrExpired) || errors.Is(err, domain.ErrDisabled) {
+		return nil, err
+	}
+	if err != nil {
+		return record, nil
+	}
+
+	return counted, nil
+}
+
+// Lookup returns the record Resolve would redirect to, failing with the same
+// errors, but counts no click and reports no resolve metric. It backs HEAD
+// requests, which link checkers and unfurlers send without a real visit.
+func (s *URLService) Lookup(ctx context.Context, shortCode string) (*domain.URLRecord, error) {
+	return s.lookup(ctx, shortCode, s.clock.Now())
+}
+
+// lookup finds a record that can be redirected to at now.
+func (s *URLService) lookup(ctx context.Context, shortCode string, now time.Time) (*domain.URLRecord, error) {
+	if v, ok := s.generator.(ChecksumVerifier); ok && !v.VerifyChecksum(shortCode) {
+		return nil, &domain.ChecksumError{Suggestion: v.Suggest(shortCode)}
+	}
+
 	record, err := s.repo.FindByShortCode(ctx, shortCode)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Check expiration
-	if record.IsExpired(s.clock.Now()) {
-		return "", domain.ErrExpired
+	if record.IsExpired(now) || record.ClickLimitReached() {
+		return nil, domain.ErrExpired
 	}
 
-	// Increment click count (fire and forget - don't block redirect)
-	_ = s.repo.IncrementClickCount(ctx, shortCode, s.clock.Now())
+	if !record.Enabled {
+		return nil, domain.ErrDisabled
+	}
 
-	return record.LongURL, nil
+	if !s.destinationAllowed(record.LongURL) {
+		slog.ErrorContext(ctx, "refusing to redirect to unsafe destination",
+			"short_code", shortCode, "long_url", domain.RedactURL(record.LongURL))
+		return nil, domain.ErrUnsafeDestination
+	}
+
+	return record, nil
 }
 
 // GetStats returns the full record for the given short code.
 // Returns domain.ErrNotFound if not found, domain.ErrExpired if expired.
 func (s *URLService) GetStats(ctx context.Context, shortCode string) (*domain.URLRecord, error) {
-	record, err := s.repo.FindByShortCode(ctx, shortCode)
+	record, err := s.GetStatsIncludingExpired(ctx, shortCode)
 	if err != nil {
 		return nil, err
 	}
@@ -117,3 +649,189 @@ func (s *URLService) GetStats(ctx context.Context, shortCode string) (*domain.UR
 
 	return record, nil
 }
+
+// GetStatsIncludingExpired is GetStats for operators inspecting links after
+// they lapse: expired records are returned as they were. Returns
+// domain.ErrNotFound if not found.
+func (s *URLService) GetStatsIncludingExpired(ctx context.Context, shortCode string) (*domain.URLRecord, error) {
+	return s.repo.FindByShortCode(ctx, shortCode)
+}
+
+// GetReferrers returns up to limit of the sites that sent the most clicks
+// to the given short code, most first. Clicks without a Referer are counted
+// as domain.ReferrerDirect. Returns domain.ErrNotFound if not found,
+// domain.ErrExpired if expired.
+func (s *URLService) GetReferrers(ctx context.Context, shortCode string, limit int) ([]domain.ReferrerCount, error) {
+	record, err := s.GetStats(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.TopReferrers(record.Referrers, limit), nil
+}
+
+// GetClickSeries returns the clicks per UTC day from from through to, in
+// order, including days without clicks. Returns domain.ErrNotFound if not
+// found, domain.ErrExpired if expired.
+func (s *URLService) GetClickSeries(ctx context.Context, shortCode string, from, to time.Time) ([]domain.DayClicks, error) {
+	record, err := s.GetStats(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.ClickSeries(record.ClicksByDay, from, to), nil
+}
+
+// GetStatsBatch returns the records for the given short codes in request order.
+// Codes that are not found or have expired are omitted from the result.
+// When consistent is true, all records are read as a single snapshot so the
+// numbers are mutually consistent; otherwise each code is read independently.
+func (s *URLService) GetStatsBatch(ctx context.Context, shortCodes []string, consistent bool) ([]*domain.URLRecord, error) {
+	var found map[string]*domain.URLRecord
+
+	if consistent {
+		snapshot, err := s.repo.FindByShortCodes(ctx, shortCodes)
+		if err != nil {
+			return nil, err
+		}
+		found = snapshot
+	} else {
+		found = make(map[string]*domain.URLRecord, len(shortCodes))
+		for _, code := range shortCodes {
+			record, err := s.repo.FindByShortCode(ctx, code)
+			if errors.Is(err, domain.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			found[code] = record
+		}
+	}
+
+	now := s.clock.Now()
+	records := make([]*domain.URLRecord, 0, len(found))
+	for _, code := range shortCodes {
+		record, ok := found[code]
+		if !ok || record.IsExpired(now) {
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// UpdateTTL keeps a live link alive for ttl from now, replacing its current
+// expiry (which may move it earlier as well as later). Expired links can't
+// be revived. Returns domain.ErrNotFound or domain.ErrExpired accordingly,
+// or domain.ErrExpiryTooLate if the new expiry is past the policy's
+// MaxExpiryAbsolute.
+func (s *URLService) UpdateTTL(ctx context.Context, shortCode string, ttl time.Duration) (*domain.URLRecord, error) {
+	record, err := s.repo.FindByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	if record.IsExpired(now) {
+		return nil, domain.ErrExpired
+	}
+
+	newExpiry := now.Add(ttl)
+	if err := s.ttl.Load().CheckExpiry(newExpiry); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateExpiry(ctx, shortCode, newExpiry); err != nil {
+		return nil, err
+	}
+
+	previous := "never"
+	if !record.NeverExpires() {
+		previous = record.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	event := domain.HistoryEvent{
+		Type:  domain.EventTTLExtended,
+		At:    now,
+		Actor: domain.ActorFromContext(ctx),
+		Details: map[string]string{
+			"ttl_seconds":         strconv.FormatInt(int64(ttl/time.Second), 10),
+			"previous_expires_at": previous,
+		},
+	}
+	if err := s.repo.AppendHistory(ctx, shortCode, event); err != nil {
+		return nil, fmt.Errorf("recording history: %w", err)
+	}
+
+	record.ExpiresAt = newExpiry
+	record.History = domain.AppendHistory(record.History, event)
+	return record, nil
+}
+
+// SetEnabled enables or disables the given short code. Disabled codes stop
+// resolving with domain.ErrDisabled but still report stats.
+// Returns domain.ErrNotFound if not found or domain.ErrExpired if expired.
+func (s *URLService) SetEnabled(ctx context.Context, shortCode string, enabled bool) (*domain.URLRecord, error) {
+	record, err := s.repo.FindByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	if record.IsExpired(now) {
+		return nil, domain.ErrExpired
+	}
+	if record.Enabled == enabled {
+		return record, nil
+	}
+
+	if err := s.repo.SetEnabled(ctx, shortCode, enabled); err != nil {
+		return nil, err
+	}
+
+	eventType := domain.EventDisabled
+	if enabled {
+		eventType = domain.EventEnabled
+	}
+	event := domain.HistoryEvent{
+		Type:  eventType,
+		At:    now,
+		Actor: domain.ActorFromContext(ctx),
+	}
+	if err := s.repo.AppendHistory(ctx, shortCode, event); err != nil {
+		return nil, fmt.Errorf("recording history: %w", err)
+	}
+
+	record.Enabled = enabled
+	record.History = domain.AppendHistory(record.History, event)
+	return record, nil
+}
+
+// List returns a page of stored records, including expired ones not yet
+// deleted, along with the total number of records.
+func (s *URLService) List(ctx context.Context, offset, limit int) ([]*domain.URLRecord, int, error) {
+	return s.repo.List(ctx, offset, limit)
+}
+
+// ListByTag is List restricted to records labeled with tag.
+func (s *URLService) ListByTag(ctx context.Context, tag string, offset, limit int) ([]*domain.URLRecord, int, error) {
+	return s.repo.ListByTag(ctx, tag, offset, limit)
+}
+
+// GetHistory returns the lifecycle history of the given short code, oldest
+// first. History stays available after expiry for auditing.
+// Returns domain.ErrNotFound if not found.
+func (s *URLService) GetHistory(ctx context.Context, shortCode string) ([]domain.HistoryEvent, error) {
+	record, err := s.repo.FindByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return record.History, nil
+}
+
+// GetStoreStats summarizes every stored link as of now, listing the top
+// most-clicked. Expired links not yet purged are counted as expired.
+func (s *URLService) GetStoreStats(ctx context.Context, top int) (domain.StoreStats, error) {
+	return s.repo.Stats(ctx, s.clock.Now(), top)
+}
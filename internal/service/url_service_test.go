@@ -2,7 +2,11 @@ package service_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -37,13 +41,14 @@ func TestURLService_Create_Success(t *testing.T) {
 
 	svc := service.NewURLService(repo, gen, clock)
 
-	record, err := svc.Create(context.Background(), "https://example.com", time.Hour)
+	record, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
 	require.NoError(t, err)
 
 	assert.Len(t, record.ShortCode, 8)
 	assert.Equal(t, "https://example.com", record.LongURL)
 	assert.Equal(t, clock.Now(), record.CreatedAt)
 	assert.Equal(t, clock.Now().Add(time.Hour), record.ExpiresAt)
+	assert.Equal(t, time.Hour, record.TTL)
 	assert.Equal(t, int64(0), record.ClickCount)
 }
 
@@ -55,11 +60,270 @@ func TestURLService_Create_UsesDefaultTTL(t *testing.T) {
 	svc := service.NewURLService(repo, gen, clock)
 
 	// Pass 0 duration to use default
-	record, err := svc.Create(context.Background(), "https://example.com", 0)
+	record, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: 0})
 	require.NoError(t, err)
 
 	// Default TTL is 24 hours
 	assert.Equal(t, clock.Now().Add(24*time.Hour), record.ExpiresAt)
+	assert.Equal(t, 24*time.Hour, record.TTL)
+}
+
+func TestURLService_Create_StoresTTL(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	policy := domain.TTLPolicy{Default: 2 * time.Hour, Min: time.Minute, Max: 48 * time.Hour}
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), clock, service.WithTTLPolicy(policy))
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		params domain.CreateParams
+		want   time.Duration
+	}{
+		{name: "requested", params: domain.CreateParams{LongURL: "https://example.com/a", TTL: 90 * time.Minute}, want: 90 * time.Minute},
+		{name: "default", params: domain.CreateParams{LongURL: "https://example.com/b"}, want: 2 * time.Hour},
+		{name: "no expiry", params: domain.CreateParams{LongURL: "https://example.com/c", NoExpiry: true}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created, _, err := svc.Create(ctx, tt.params)
+			require.NoError(t, err)
+
+			stats, err := svc.GetStats(ctx, created.ShortCode)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, stats.TTL)
+
+			// A later TTL change moves the expiry but not the recorded TTL.
+			if tt.want > 0 {
+				updated, err := svc.UpdateTTL(ctx, created.ShortCode, 10*time.Minute)
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, updated.TTL)
+			}
+		})
+	}
+}
+
+func TestURLService_Create_UsesPolicyDefaultTTL(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	policy := domain.TTLPolicy{Default: 2 * time.Hour, Min: time.Minute, Max: 48 * time.Hour}
+	svc := service.NewURLService(repo, gen, clock, service.WithTTLPolicy(policy))
+
+	record, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com"})
+	require.NoError(t, err)
+
+	assert.Equal(t, clock.Now().Add(2*time.Hour), record.ExpiresAt)
+}
+
+func TestURLService_CreateIdempotent_ReplaysKey(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock, service.WithIdempotencyWindow(time.Hour))
+	params := domain.CreateParams{LongURL: "https://example.com"}
+
+	first, _, replayed, err := svc.CreateIdempotent(context.Background(), "key-1", params)
+	require.NoError(t, err)
+	assert.False(t, replayed)
+
+	clock.Advance(59 * time.Minute)
+	again, _, replayed, err := svc.CreateIdempotent(context.Background(), "key-1", params)
+	require.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, first.ShortCode, again.ShortCode)
+
+	other, _, replayed, err := svc.CreateIdempotent(context.Background(), "key-2", params)
+	require.NoError(t, err)
+	assert.False(t, replayed)
+	assert.NotEqual(t, first.ShortCode, other.ShortCode)
+
+	// Once the window has passed the key creates a new link.
+	clock.Advance(2 * time.Minute)
+	later, _, replayed, err := svc.CreateIdempotent(context.Background(), "key-1", params)
+	require.NoError(t, err)
+	assert.False(t, replayed)
+	assert.NotEqual(t, first.ShortCode, later.ShortCode)
+}
+
+func TestURLService_CreateIdempotent_ConcurrentRetriesCreateOnce(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock, service.WithIdempotencyWindow(time.Hour))
+
+	const retries = 20
+	codes := make([]string, retries)
+	var wg sync.WaitGroup
+	for i := range retries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record, _, _, err := svc.CreateIdempotent(context.Background(), "key-1", domain.CreateParams{LongURL: "https://example.com"})
+			if assert.NoError(t, err) {
+				codes[i] = record.ShortCode
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, code := range codes {
+		assert.Equal(t, codes[0], code)
+	}
+	_, total, err := repo.List(context.Background(), 0, retries)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+}
+
+func TestURLService_CreateIdempotent_FailedCreateFreesKey(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock, service.WithIdempotencyWindow(time.Hour))
+
+	_, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", CustomAlias: "taken"})
+	require.NoError(t, err)
+
+	_, _, _, err = svc.CreateIdempotent(context.Background(), "key-1", domain.CreateParams{LongURL: "https://example.com", CustomAlias: "taken"})
+	require.ErrorIs(t, err, domain.ErrAliasTaken)
+
+	record, _, replayed, err := svc.CreateIdempotent(context.Background(), "key-1", domain.CreateParams{LongURL: "https://example.com"})
+	require.NoError(t, err)
+	assert.False(t, replayed)
+	assert.NotEqual(t, "taken", record.ShortCode)
+}
+
+func TestURLService_CreateIdempotent_ScopedToCaller(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock, service.WithIdempotencyWindow(time.Hour))
+	params := domain.CreateParams{LongURL: "https://example.com"}
+
+	alice := domain.WithClientID(context.Background(), "key:alice")
+	first, _, _, err := svc.CreateIdempotent(alice, "key-1", params)
+	require.NoError(t, err)
+
+	for name, ctx := range map[string]context.Context{
+		"other client":    domain.WithClientID(context.Background(), "key:bob"),
+		"anonymous":       context.Background(),
+		"other namespace": domain.WithCodePrefix(alice, "t1-"),
+	} {
+		record, _, replayed, err := svc.CreateIdempotent(ctx, "key-1", params)
+		require.NoError(t, err, name)
+		assert.False(t, replayed, name)
+		assert.NotEqual(t, first.ShortCode, record.ShortCode, name)
+	}
+
+	again, _, replayed, err := svc.CreateIdempotent(alice, "key-1", params)
+	require.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, first.ShortCode, again.ShortCode)
+}
+
+func TestURLService_CreateIdempotent_RejectsKeyReusedWithOtherParams(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock, service.WithIdempotencyWindow(time.Hour))
+
+	first, _, _, err := svc.CreateIdempotent(context.Background(), "key-1", domain.CreateParams{LongURL: "https://example.com/a"})
+	require.NoError(t, err)
+
+	for _, params := range []domain.CreateParams{
+		{LongURL: "https://example.com/b"},
+		{LongURL: "https://example.com/a", MaxClicks: 5},
+		{LongURL: "https://example.com/a", Tags: []string{"promo"}},
+	} {
+		_, _, _, err := svc.CreateIdempotent(context.Background(), "key-1", params)
+		assert.ErrorIs(t, err, domain.ErrIdempotencyKeyReused, "%+v", params)
+	}
+
+	again, _, replayed, err := svc.CreateIdempotent(context.Background(), "key-1", domain.CreateParams{LongURL: "https://example.com/a"})
+	require.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, first.ShortCode, again.ShortCode)
+}
+
+func TestURLService_CreateIdempotent_DisabledByDefault(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock)
+
+	first, _, _, err := svc.CreateIdempotent(context.Background(), "key-1", domain.CreateParams{LongURL: "https://example.com"})
+	require.NoError(t, err)
+	second, _, replayed, err := svc.CreateIdempotent(context.Background(), "key-1", domain.CreateParams{LongURL: "https://example.com"})
+	require.NoError(t, err)
+
+	assert.False(t, replayed)
+	assert.NotEqual(t, first.ShortCode, second.ShortCode)
+}
+
+func TestURLService_Create_MaxExpiryAbsolute(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	policy := domain.DefaultTTLPolicy()
+	policy.MaxExpiryAbsolute = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		wantErr bool
+	}{
+		{name: "well before the cap", ttl: 24 * time.Hour},
+		{name: "exactly at the cap", ttl: policy.MaxExpiryAbsolute.Sub(clock.Now())},
+		{name: "within max TTL but past the cap", ttl: 30 * 24 * time.Hour, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.LessOrEqual(t, tt.ttl, policy.Max)
+			svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), clock, service.WithTTLPolicy(policy))
+
+			record, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: tt.ttl})
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, domain.ErrExpiryTooLate)
+				assert.Contains(t, err.Error(), "2024-02-01T00:00:00Z")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, clock.Now().Add(tt.ttl), record.ExpiresAt)
+		})
+	}
+}
+
+func TestURLService_SetTTLPolicy(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), clock)
+	ctx := context.Background()
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: 30 * 24 * time.Hour})
+	require.NoError(t, err)
+
+	policy := domain.DefaultTTLPolicy()
+	policy.Default = 2 * time.Hour
+	policy.MaxExpiryAbsolute = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	svc.SetTTLPolicy(policy)
+
+	_, _, err = svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: 30 * 24 * time.Hour})
+	assert.ErrorIs(t, err, domain.ErrExpiryTooLate)
+	_, err = svc.UpdateTTL(ctx, record.ShortCode, 30*24*time.Hour)
+	assert.ErrorIs(t, err, domain.ErrExpiryTooLate)
+
+	record, _, err = svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, clock.Now().Add(2*time.Hour), record.ExpiresAt, "new default TTL applies")
+}
+
+func TestURLService_Create_MaxExpiryAbsolute_AppliesToDefaultTTL(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC))
+	policy := domain.DefaultTTLPolicy()
+	policy.MaxExpiryAbsolute = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), clock, service.WithTTLPolicy(policy))
+
+	// The 24h default would run 12 hours past the cap.
+	_, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com"})
+	assert.ErrorIs(t, err, domain.ErrExpiryTooLate)
+
+	// As the clock moves on, shorter TTLs start crossing it too.
+	clock.Advance(11 * time.Hour)
+	_, _, err = svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: 2 * time.Hour})
+	assert.ErrorIs(t, err, domain.ErrExpiryTooLate)
 }
 
 func TestURLService_Create_StoresInRepository(t *testing.T) {
@@ -69,7 +333,7 @@ func TestURLService_Create_StoresInRepository(t *testing.T) {
 
 	svc := service.NewURLService(repo, gen, clock)
 
-	record, err := svc.Create(context.Background(), "https://example.com", time.Hour)
+	record, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
 	require.NoError(t, err)
 
 	// Verify stored in repository
@@ -90,14 +354,16 @@ func TestURLService_Create_RetriesOnCollision(t *testing.T) {
 	svc := service.NewURLServiceWithGenerator(repo, mockGen, clock)
 
 	// First create succeeds with code0001
-	record1, err := svc.Create(context.Background(), "https://first.com", time.Hour)
+	record1, attempts, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://first.com", TTL: time.Hour})
 	require.NoError(t, err)
 	assert.Equal(t, "code0001", record1.ShortCode)
+	assert.Equal(t, 1, attempts)
 
-	// Second create: code0001 collides, code0001 collides, code0001 collides, code0004 succeeds
-	record2, err := svc.Create(context.Background(), "https://second.com", time.Hour)
+	// Second create: code0001 collides twice, then code0004 succeeds
+	record2, attempts, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://second.com", TTL: time.Hour})
 	require.NoError(t, err)
 	assert.Equal(t, "code0004", record2.ShortCode)
+	assert.Equal(t, 3, attempts)
 }
 
 func TestURLService_Create_FailsAfterMaxRetries(t *testing.T) {
@@ -112,15 +378,85 @@ func TestURLService_Create_FailsAfterMaxRetries(t *testing.T) {
 	svc := service.NewURLServiceWithGenerator(repo, mockGen, clock)
 
 	// First create succeeds
-	_, err := svc.Create(context.Background(), "https://first.com", time.Hour)
+	_, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://first.com", TTL: time.Hour})
 	require.NoError(t, err)
 
 	// Second create fails after 5 retries (all collide)
-	_, err = svc.Create(context.Background(), "https://second.com", time.Hour)
+	_, attempts, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://second.com", TTL: time.Hour})
 	assert.Error(t, err)
+	assert.Equal(t, 5, attempts)
 	assert.Contains(t, err.Error(), "max retries exceeded")
 }
 
+func TestURLService_Create_ConfigurableMaxRetries(t *testing.T) {
+	const retries = 8
+
+	// newService returns a service whose next create collides n times on
+	// "samecode" before the generator offers a free code.
+	newService := func(t *testing.T, n int) *service.URLService {
+		codes := []string{"samecode"}
+		for range n {
+			codes = append(codes, "samecode")
+		}
+		codes = append(codes, "freecode")
+		svc := service.NewURLServiceWithGenerator(repository.NewMemoryRepository(), &MockGenerator{codes: codes},
+			domain.NewMockClock(time.Now()), service.WithMaxRetries(retries))
+		_, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://first.com", TTL: time.Hour})
+		require.NoError(t, err)
+		return svc
+	}
+
+	t.Run("succeeds on the last allowed attempt", func(t *testing.T) {
+		svc := newService(t, retries-1)
+
+		record, attempts, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://second.com", TTL: time.Hour})
+		require.NoError(t, err)
+		assert.Equal(t, "freecode", record.ShortCode)
+		assert.Equal(t, retries, attempts)
+	})
+
+	t.Run("fails once every allowed attempt collides", func(t *testing.T) {
+		svc := newService(t, retries)
+
+		_, attempts, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://second.com", TTL: time.Hour})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "max retries exceeded")
+		assert.Equal(t, retries, attempts)
+	})
+
+	t.Run("non-positive keeps the default", func(t *testing.T) {
+		codes := make([]string, service.DefaultMaxRetries+1)
+		for i := range codes {
+			codes[i] = "samecode"
+		}
+		svc := service.NewURLServiceWithGenerator(repository.NewMemoryRepository(), &MockGenerator{codes: codes},
+			domain.NewMockClock(time.Now()), service.WithMaxRetries(0))
+		_, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://first.com", TTL: time.Hour})
+		require.NoError(t, err)
+
+		_, attempts, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://second.com", TTL: time.Hour})
+		require.Error(t, err)
+		assert.Equal(t, service.DefaultMaxRetries, attempts)
+	})
+}
+
+func TestURLService_Create_CapacityExceeded(t *testing.T) {
+	repo := repository.NewMemoryRepositoryWithCapacity(1)
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	_, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://first.com", TTL: time.Hour})
+	require.NoError(t, err)
+
+	_, _, err = svc.Create(context.Background(), domain.CreateParams{LongURL: "https://second.com", TTL: time.Hour})
+	assert.ErrorIs(t, err, domain.ErrCapacityExceeded)
+
+	_, _, err = svc.Create(context.Background(), domain.CreateParams{LongURL: "https://third.com", TTL: time.Hour, CustomAlias: "third"})
+	assert.ErrorIs(t, err, domain.ErrCapacityExceeded)
+}
+
 func TestURLService_Resolve_Success(t *testing.T) {
 	repo := repository.NewMemoryRepository()
 	gen := shortcode.NewGenerator()
@@ -129,12 +465,12 @@ func TestURLService_Resolve_Success(t *testing.T) {
 	svc := service.NewURLService(repo, gen, clock)
 
 	// Create a URL
-	record, _ := svc.Create(context.Background(), "https://example.com", time.Hour)
+	record, _, _ := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
 
 	// Resolve it
-	longURL, err := svc.Resolve(context.Background(), record.ShortCode)
+	resolved, err := svc.Resolve(context.Background(), record.ShortCode, domain.Visit{})
 	require.NoError(t, err)
-	assert.Equal(t, "https://example.com", longURL)
+	assert.Equal(t, "https://example.com", resolved.LongURL)
 }
 
 func TestURLService_Resolve_IncrementsClickCount(t *testing.T) {
@@ -144,17 +480,89 @@ func TestURLService_Resolve_IncrementsClickCount(t *testing.T) {
 
 	svc := service.NewURLService(repo, gen, clock)
 
-	record, _ := svc.Create(context.Background(), "https://example.com", time.Hour)
+	record, _, _ := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
 
 	// Resolve multiple times
 	for i := 0; i < 5; i++ {
-		_, err := svc.Resolve(context.Background(), record.ShortCode)
+		_, err := svc.Resolve(context.Background(), record.ShortCode, domain.Visit{})
 		require.NoError(t, err)
 	}
 
 	// Check click count
 	stats, _ := svc.GetStats(context.Background(), record.ShortCode)
 	assert.Equal(t, int64(5), stats.ClickCount)
+
+	// The resolved record reflects the click it counted
+	resolved, err := svc.Resolve(context.Background(), record.ShortCode, domain.Visit{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), resolved.ClickCount)
+}
+
+func TestURLService_GetReferrers_AggregatesResolves(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	record, _, _ := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+
+	referers := []string{
+		"https://news.example.com/story/1",
+		"https://News.Example.com/story/2",
+		"https://t.co/abc",
+		"",
+		"https://news.example.com/",
+	}
+	for _, referer := range referers {
+		_, err := svc.Resolve(context.Background(), record.ShortCode, domain.Visit{Referer: referer})
+		require.NoError(t, err)
+	}
+
+	top, err := svc.GetReferrers(context.Background(), record.ShortCode, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ReferrerCount{
+		{Referrer: "news.example.com", Clicks: 3},
+		{Referrer: domain.ReferrerDirect, Clicks: 1},
+	}, top)
+
+	// Lookups are not visits
+	_, err = svc.Lookup(context.Background(), record.ShortCode)
+	require.NoError(t, err)
+	all, err := svc.GetReferrers(context.Background(), record.ShortCode, 10)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	_, err = svc.GetReferrers(context.Background(), "notexist", 10)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestURLService_Lookup_DoesNotCountClick(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	record, _, _ := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+
+	found, err := svc.Lookup(context.Background(), record.ShortCode)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", found.LongURL)
+
+	stats, _ := svc.GetStats(context.Background(), record.ShortCode)
+	assert.Equal(t, int64(0), stats.ClickCount)
+	assert.True(t, stats.LastAccessedAt.IsZero())
+
+	// Lookup fails the way Resolve does
+	_, err = svc.SetEnabled(context.Background(), record.ShortCode, false)
+	require.NoError(t, err)
+	_, err = svc.Lookup(context.Background(), record.ShortCode)
+	assert.ErrorIs(t, err, domain.ErrDisabled)
+
+	clock.Advance(2 * time.Hour)
+	_, err = svc.Lookup(context.Background(), record.ShortCode)
+	assert.ErrorIs(t, err, domain.ErrExpired)
 }
 
 func TestURLService_Resolve_UpdatesLastAccessedAt(t *testing.T) {
@@ -164,13 +572,13 @@ func TestURLService_Resolve_UpdatesLastAccessedAt(t *testing.T) {
 
 	svc := service.NewURLService(repo, gen, clock)
 
-	record, _ := svc.Create(context.Background(), "https://example.com", time.Hour)
+	record, _, _ := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
 
 	// Advance clock
 	clock.Advance(30 * time.Minute)
 
 	// Resolve
-	_, _ = svc.Resolve(context.Background(), record.ShortCode)
+	_, _ = svc.Resolve(context.Background(), record.ShortCode, domain.Visit{})
 
 	// Check LastAccessedAt
 	stats, _ := svc.GetStats(context.Background(), record.ShortCode)
@@ -184,7 +592,7 @@ func TestURLService_Resolve_NotFound(t *testing.T) {
 
 	svc := service.NewURLService(repo, gen, clock)
 
-	_, err := svc.Resolve(context.Background(), "notexist")
+	_, err := svc.Resolve(context.Background(), "notexist", domain.Visit{})
 	assert.ErrorIs(t, err, domain.ErrNotFound)
 }
 
@@ -196,17 +604,17 @@ func TestURLService_Resolve_Expired(t *testing.T) {
 	svc := service.NewURLService(repo, gen, clock)
 
 	// Create URL with 1 hour TTL
-	record, _ := svc.Create(context.Background(), "https://example.com", time.Hour)
+	record, _, _ := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
 
 	// URL works before expiration
-	_, err := svc.Resolve(context.Background(), record.ShortCode)
+	_, err := svc.Resolve(context.Background(), record.ShortCode, domain.Visit{})
 	require.NoError(t, err)
 
 	// Advance clock past expiration
 	clock.Advance(time.Hour + time.Second)
 
 	// URL is now expired
-	_, err = svc.Resolve(context.Background(), record.ShortCode)
+	_, err = svc.Resolve(context.Background(), record.ShortCode, domain.Visit{})
 	assert.ErrorIs(t, err, domain.ErrExpired)
 }
 
@@ -217,15 +625,138 @@ func TestURLService_Resolve_JustBeforeExpiration(t *testing.T) {
 
 	svc := service.NewURLService(repo, gen, clock)
 
-	record, _ := svc.Create(context.Background(), "https://example.com", time.Hour)
+	record, _, _ := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
 
 	// Advance to 1 second before expiration
 	clock.Advance(time.Hour - time.Second)
 
 	// Should still work
-	longURL, err := svc.Resolve(context.Background(), record.ShortCode)
+	resolved, err := svc.Resolve(context.Background(), record.ShortCode, domain.Visit{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", resolved.LongURL)
+}
+
+func TestURLService_Resolve_MaxClicks_OneTimeLink(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	record, _, err := svc.Create(context.Background(), domain.CreateParams{
+		LongURL:   "https://example.com",
+		TTL:       time.Hour,
+		MaxClicks: 1,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), record.MaxClicks)
+
+	_, err = svc.Resolve(context.Background(), record.ShortCode, domain.Visit{})
+	require.NoError(t, err)
+
+	_, err = svc.Resolve(context.Background(), record.ShortCode, domain.Visit{})
+	assert.ErrorIs(t, err, domain.ErrExpired)
+}
+
+func TestURLService_Resolve_MaxClicks_ConcurrentResolves(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	record, _, err := svc.Create(context.Background(), domain.CreateParams{
+		LongURL:   "https://example.com",
+		TTL:       time.Hour,
+		MaxClicks: 5,
+	})
+	require.NoError(t, err)
+
+	const numGoroutines = 50
+	var succeeded atomic.Int64
+
+	var wg sync.WaitGroup
+	wg.Add(numGoroutines)
+
+	for i := 0; i < numGoroutines; i++ {
+		go func() {
+			defer wg.Done()
+			_, err := svc.Resolve(context.Background(), record.ShortCode, domain.Visit{})
+			if err == nil {
+				succeeded.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, domain.ErrExpired)
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int64(5), succeeded.Load(), "exactly MaxClicks resolves should succeed")
+}
+
+func TestURLService_Resolve_ExpiredAfterLookup_NotCounted(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	ctx := context.Background()
+
+	stale := &staleLookupRepository{Repository: repo}
+	svc := service.NewURLService(stale, shortcode.NewGenerator(), clock)
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+
+	// The lookup still sees the link as live, but it expired just before
+	// the click is counted.
+	stale.snapshot, err = repo.FindByShortCode(ctx, record.ShortCode)
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateExpiry(ctx, record.ShortCode, clock.Now().Add(-time.Nanosecond)))
+
+	_, err = svc.Resolve(ctx, record.ShortCode, domain.Visit{})
+	assert.ErrorIs(t, err, domain.ErrExpired)
+
+	found, err := repo.FindByShortCode(ctx, record.ShortCode)
+	require.NoError(t, err)
+	assert.Zero(t, found.ClickCount)
+}
+
+func TestURLService_Resolve_ConcurrentWithExpiry(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock)
+	ctx := context.Background()
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+
+	const numGoroutines = 100
+	var succeeded atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(numGoroutines + 1)
+
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, repo.UpdateExpiry(ctx, record.ShortCode, clock.Now().Add(-time.Nanosecond)))
+	}()
+	for i := 0; i < numGoroutines; i++ {
+		go func() {
+			defer wg.Done()
+			_, err := svc.Resolve(ctx, record.ShortCode, domain.Visit{})
+			if err == nil {
+				succeeded.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, domain.ErrExpired)
+		}()
+	}
+	wg.Wait()
+
+	found, err := repo.FindByShortCode(ctx, record.ShortCode)
 	require.NoError(t, err)
-	assert.Equal(t, "https://example.com", longURL)
+	assert.Equal(t, succeeded.Load(), found.ClickCount, "only successful resolves are counted")
+
+	_, err = svc.Resolve(ctx, record.ShortCode, domain.Visit{})
+	assert.ErrorIs(t, err, domain.ErrExpired)
 }
 
 func TestURLService_GetStats_Success(t *testing.T) {
@@ -235,7 +766,7 @@ func TestURLService_GetStats_Success(t *testing.T) {
 
 	svc := service.NewURLService(repo, gen, clock)
 
-	record, _ := svc.Create(context.Background(), "https://example.com", time.Hour)
+	record, _, _ := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
 
 	stats, err := svc.GetStats(context.Background(), record.ShortCode)
 	require.NoError(t, err)
@@ -266,7 +797,7 @@ func TestURLService_GetStats_Expired(t *testing.T) {
 
 	svc := service.NewURLService(repo, gen, clock)
 
-	record, _ := svc.Create(context.Background(), "https://example.com", time.Hour)
+	record, _, _ := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
 
 	// Advance past expiration
 	clock.Advance(2 * time.Hour)
@@ -274,3 +805,1650 @@ func TestURLService_GetStats_Expired(t *testing.T) {
 	_, err := svc.GetStats(context.Background(), record.ShortCode)
 	assert.ErrorIs(t, err, domain.ErrExpired)
 }
+
+func TestURLService_GetStatsIncludingExpired(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	record, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+
+	clock.Advance(2 * time.Hour)
+
+	stats, err := svc.GetStatsIncludingExpired(context.Background(), record.ShortCode)
+	require.NoError(t, err)
+	assert.Equal(t, record.ShortCode, stats.ShortCode)
+	assert.True(t, stats.IsExpired(clock.Now()))
+
+	_, err = svc.GetStatsIncludingExpired(context.Background(), "missing1")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestURLService_GetStatsBatch_OmitsMissingAndExpired(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	mockGen := &MockGenerator{codes: []string{"short001", "long0001"}}
+
+	svc := service.NewURLServiceWithGenerator(repo, mockGen, clock)
+
+	_, _, _ = svc.Create(context.Background(), domain.CreateParams{LongURL: "https://short.com", TTL: time.Minute})
+	_, _, _ = svc.Create(context.Background(), domain.CreateParams{LongURL: "https://long.com", TTL: time.Hour})
+
+	clock.Advance(2 * time.Minute)
+
+	for _, consistent := range []bool{false, true} {
+		records, err := svc.GetStatsBatch(context.Background(),
+			[]string{"long0001", "short001", "notexist"}, consistent)
+		require.NoError(t, err)
+
+		require.Len(t, records, 1)
+		assert.Equal(t, "long0001", records[0].ShortCode)
+	}
+}
+
+func TestURLService_GetStatsBatch_ConsistentUnderConcurrentIncrements(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	mockGen := &MockGenerator{codes: []string{"codeAAAA", "codeBBBB"}}
+
+	svc := service.NewURLServiceWithGenerator(repo, mockGen, clock)
+
+	_, _, _ = svc.Create(context.Background(), domain.CreateParams{LongURL: "https://a.com", TTL: time.Hour})
+	_, _, _ = svc.Create(context.Background(), domain.CreateParams{LongURL: "https://b.com", TTL: time.Hour})
+
+	ctx := context.Background()
+	const increments = 2000
+
+	// The writer always bumps A before B, so any point-in-time view
+	// has A equal to B or exactly one ahead.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		now := clock.Now()
+		for i := 0; i < increments; i++ {
+			_ = repo.IncrementClickCount(ctx, "codeAAAA", now)
+			_ = repo.IncrementClickCount(ctx, "codeBBBB", now)
+		}
+	}()
+
+	for reading := true; reading; {
+		select {
+		case <-done:
+			reading = false
+		default:
+		}
+
+		records, err := svc.GetStatsBatch(ctx, []string{"codeBBBB", "codeAAAA"}, true)
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+
+		diff := records[1].ClickCount - records[0].ClickCount
+		assert.True(t, diff == 0 || diff == 1,
+			"snapshot must be consistent: A=%d B=%d", records[1].ClickCount, records[0].ClickCount)
+	}
+
+	records, _ := svc.GetStatsBatch(ctx, []string{"codeAAAA", "codeBBBB"}, true)
+	assert.Equal(t, int64(increments), records[0].ClickCount)
+	assert.Equal(t, int64(increments), records[1].ClickCount)
+}
+
+func TestURLService_Resolve_WithChecksum_ValidCode(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator(shortcode.WithChecksum())
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	record, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+
+	resolved, err := svc.Resolve(context.Background(), record.ShortCode, domain.Visit{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", resolved.LongURL)
+}
+
+func TestURLService_Resolve_WithChecksum_RejectsCorruptedCode(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator(shortcode.WithChecksum())
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	record, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+
+	// Swap in a different alphabet character for the check character
+	code := record.ShortCode
+	replacement := byte('2')
+	if code[len(code)-1] == replacement {
+		replacement = '3'
+	}
+	corrupted := code[:len(code)-1] + string(replacement)
+
+	_, err = svc.Resolve(context.Background(), corrupted, domain.Visit{})
+	assert.ErrorIs(t, err, domain.ErrInvalidChecksum)
+
+	stats, _ := svc.GetStats(context.Background(), code)
+	assert.Equal(t, int64(0), stats.ClickCount)
+}
+
+// suffixStrategy resolves collisions by appending the attempt number.
+type suffixStrategy struct {
+	seen []string
+}
+
+func (s *suffixStrategy) Next(collided string, attempt int) (string, bool) {
+	s.seen = append(s.seen, collided)
+	return fmt.Sprintf("%s-%d", collided, attempt), true
+}
+
+// giveUpStrategy never produces another candidate.
+type giveUpStrategy struct{}
+
+func (giveUpStrategy) Next(string, int) (string, bool) {
+	return "", false
+}
+
+func TestURLService_Create_UsesCollisionStrategy(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Now())
+	mockGen := &MockGenerator{codes: []string{"taken001", "taken001"}}
+	strategy := &suffixStrategy{}
+
+	svc := service.NewURLServiceWithGenerator(repo, mockGen, clock, service.WithCollisionStrategy(strategy))
+
+	_, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://first.com", TTL: time.Hour})
+	require.NoError(t, err)
+	_ = repo.SaveIfNotExists(context.Background(), &domain.URLRecord{ShortCode: "taken001-1"})
+
+	record, attempts, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://second.com", TTL: time.Hour})
+	require.NoError(t, err)
+
+	assert.Equal(t, "taken001-1-2", record.ShortCode)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, []string{"taken001", "taken001-1"}, strategy.seen)
+	assert.Equal(t, 2, mockGen.index, "strategy should replace calls to the generator")
+}
+
+func TestURLService_Create_CollisionStrategyGivesUp(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Now())
+	mockGen := &MockGenerator{codes: []string{"samecode", "samecode"}}
+
+	svc := service.NewURLServiceWithGenerator(repo, mockGen, clock, service.WithCollisionStrategy(giveUpStrategy{}))
+
+	_, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://first.com", TTL: time.Hour})
+	require.NoError(t, err)
+
+	_, attempts, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://second.com", TTL: time.Hour})
+	assert.ErrorIs(t, err, domain.ErrCodeExists)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestURLService_Create_CodePrefix(t *testing.T) {
+	ctx := context.Background()
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(),
+		domain.NewMockClock(time.Now()), service.WithCodePrefix("acme-"))
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(record.ShortCode, "acme-"), record.ShortCode)
+	assert.Len(t, record.ShortCode, len("acme-")+8)
+
+	resolved, err := svc.Resolve(ctx, record.ShortCode, domain.Visit{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", resolved.LongURL)
+
+	// Custom aliases are stored as given.
+	alias, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour, CustomAlias: "launch"})
+	require.NoError(t, err)
+	assert.Equal(t, "launch", alias.ShortCode)
+}
+
+func TestURLService_Create_CodePrefixFromContext(t *testing.T) {
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(),
+		domain.NewMockClock(time.Now()), service.WithCodePrefix("acme-"))
+	ctx := domain.WithCodePrefix(context.Background(), "t1-")
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(record.ShortCode, "t1-"), record.ShortCode)
+
+	stats, err := svc.GetStats(context.Background(), record.ShortCode)
+	require.NoError(t, err)
+	assert.Equal(t, record.ShortCode, stats.ShortCode)
+}
+
+func TestURLService_Create_CodePrefixCollisions(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	mockGen := &MockGenerator{codes: []string{"taken001", "taken001"}}
+	strategy := &suffixStrategy{}
+	svc := service.NewURLServiceWithGenerator(repo, mockGen, domain.NewMockClock(time.Now()),
+		service.WithCollisionStrategy(strategy), service.WithCodePrefix("t1-"))
+
+	first, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://first.com", TTL: time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, "t1-taken001", first.ShortCode)
+
+	// The same generated code in another namespace doesn't collide.
+	other, _, err := svc.Create(domain.WithCodePrefix(context.Background(), "t2-"),
+		domain.CreateParams{LongURL: "https://other.com", TTL: time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, "t2-taken001", other.ShortCode)
+
+	mockGen.codes, mockGen.index = []string{"taken001"}, 0
+	record, attempts, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://second.com", TTL: time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, "t1-taken001-1", record.ShortCode)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, []string{"taken001"}, strategy.seen, "strategy should see codes without the prefix")
+}
+
+func TestURLService_Create_CodePrefixLimitsReuse(t *testing.T) {
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(),
+		domain.NewMockClock(time.Now()), service.WithLongURLReuse())
+	t1 := domain.WithCodePrefix(context.Background(), "t1-")
+	t2 := domain.WithCodePrefix(context.Background(), "t2-")
+	params := domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour}
+
+	first, _, err := svc.Create(t1, params)
+	require.NoError(t, err)
+	again, _, err := svc.Create(t1, params)
+	require.NoError(t, err)
+	assert.Equal(t, first.ShortCode, again.ShortCode)
+
+	other, _, err := svc.Create(t2, params)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(other.ShortCode, "t2-"), other.ShortCode)
+}
+
+func TestURLService_Create_TenantPrefixes(t *testing.T) {
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(),
+		domain.NewMockClock(time.Now()), service.WithLongURLReuse(), service.WithTenantPrefixes("t1-", "t2-"))
+	t1 := domain.WithCodePrefix(context.Background(), "t1-")
+	t2 := domain.WithCodePrefix(context.Background(), "t2-")
+	params := domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour}
+
+	tenant, _, err := svc.Create(t1, params)
+	require.NoError(t, err)
+
+	unprefixed, _, err := svc.Create(context.Background(), params)
+	require.NoError(t, err)
+	assert.NotEqual(t, tenant.ShortCode, unprefixed.ShortCode, "callers without a prefix must not reuse a tenant's link")
+
+	for name, ctx := range map[string]context.Context{"other tenant": t1, "no prefix": context.Background()} {
+		_, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour, CustomAlias: "t2-mine"})
+		assert.ErrorIs(t, err, domain.ErrInvalidAlias, name)
+	}
+
+	record, _, err := svc.Create(t2, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour, CustomAlias: "t2-mine"})
+	require.NoError(t, err)
+	assert.Equal(t, "t2-mine", record.ShortCode)
+
+	record, _, err = svc.Create(t1, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour, CustomAlias: "plain"})
+	require.NoError(t, err)
+	assert.Equal(t, "plain", record.ShortCode)
+}
+
+func TestURLService_Resolve_ClickDedup_SuppressesRapidRepeats(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLService(repo, gen, clock, service.WithClickDedupWindow(2*time.Second))
+
+	record, _, _ := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	alice := domain.Visit{ClientIP: "203.0.113.1"}
+	bob := domain.Visit{ClientIP: "203.0.113.2"}
+
+	// Double-click and refresh from the same IP count once
+	for i := 0; i < 3; i++ {
+		resolved, err := svc.Resolve(context.Background(), record.ShortCode, alice)
+		require.NoError(t, err, "redirect must still succeed")
+		assert.Equal(t, "https://example.com", resolved.LongURL)
+		clock.Advance(500 * time.Millisecond)
+	}
+
+	// A different IP is counted independently
+	_, err := svc.Resolve(context.Background(), record.ShortCode, bob)
+	require.NoError(t, err)
+
+	stats, _ := svc.GetStats(context.Background(), record.ShortCode)
+	assert.Equal(t, int64(2), stats.ClickCount)
+
+	// Once the window has passed, the same IP counts again
+	clock.Advance(2 * time.Second)
+	_, err = svc.Resolve(context.Background(), record.ShortCode, alice)
+	require.NoError(t, err)
+
+	stats, _ = svc.GetStats(context.Background(), record.ShortCode)
+	assert.Equal(t, int64(3), stats.ClickCount)
+}
+
+func TestURLService_Resolve_ClickDedup_CountsTowardClickLimit(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), clock,
+		service.WithClickDedupWindow(time.Minute))
+	ctx := context.Background()
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour, MaxClicks: 1})
+	require.NoError(t, err)
+
+	visit := domain.Visit{ClientIP: "203.0.113.1"}
+	_, err = svc.Resolve(ctx, record.ShortCode, visit)
+	require.NoError(t, err)
+	_, err = svc.Resolve(ctx, record.ShortCode, visit)
+	assert.ErrorIs(t, err, domain.ErrExpired, "a repeat must not get around the click limit")
+}
+
+func TestURLService_Resolve_ClickDedup_OffByDefault(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	record, _, _ := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	visit := domain.Visit{ClientIP: "203.0.113.1"}
+
+	for i := 0; i < 3; i++ {
+		_, err := svc.Resolve(context.Background(), record.ShortCode, visit)
+		require.NoError(t, err)
+	}
+
+	stats, _ := svc.GetStats(context.Background(), record.ShortCode)
+	assert.Equal(t, int64(3), stats.ClickCount)
+}
+
+func TestURLService_GetHistory_RecordsCreation(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	ctx := domain.WithActor(context.Background(), "team-marketing")
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com/reset?token=secret", TTL: time.Hour})
+	require.NoError(t, err)
+
+	// History outlives expiry for auditing
+	clock.Advance(2 * time.Hour)
+
+	events, err := svc.GetHistory(context.Background(), record.ShortCode)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	assert.Equal(t, domain.EventCreated, events[0].Type)
+	assert.Equal(t, time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC), events[0].At)
+	assert.Equal(t, "team-marketing", events[0].Actor)
+	assert.Equal(t, "https://example.com/reset", events[0].Details["long_url"], "query string must be redacted")
+	assert.Equal(t, "3600", events[0].Details["ttl_seconds"])
+}
+
+func TestURLService_GetHistory_NotFound(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Now())
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	_, err := svc.GetHistory(context.Background(), "notexist")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestURLService_Create_WithCustomAlias(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	record, attempts, err := svc.Create(context.Background(), domain.CreateParams{
+		LongURL:     "https://example.com/sale",
+		TTL:         time.Hour,
+		CustomAlias: "spring-offer",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "spring-offer", record.ShortCode)
+	assert.Zero(t, attempts, "aliases are not generated")
+
+	resolved, err := svc.Resolve(context.Background(), "spring-offer", domain.Visit{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/sale", resolved.LongURL)
+}
+
+func TestURLService_Create_CustomAliasTaken(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Now())
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	params := domain.CreateParams{LongURL: "https://example.com", CustomAlias: "spring-offer"}
+	_, _, err := svc.Create(context.Background(), params)
+	require.NoError(t, err)
+
+	_, _, err = svc.Create(context.Background(), params)
+	assert.ErrorIs(t, err, domain.ErrAliasTaken)
+}
+
+func TestURLService_Create_CustomAliasMustPassChecksum(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator(shortcode.WithChecksum())
+	clock := domain.NewMockClock(time.Now())
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	// Hyphenated aliases never look like generated codes
+	_, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", CustomAlias: "spring-offer"})
+	require.NoError(t, err)
+
+	// An 8-char alias that fails the checksum would be unreachable
+	code := gen.Generate()
+	replacement := byte('2')
+	if code[len(code)-1] == replacement {
+		replacement = '3'
+	}
+	_, _, err = svc.Create(context.Background(), domain.CreateParams{
+		LongURL:     "https://example.com",
+		CustomAlias: code[:len(code)-1] + string(replacement),
+	})
+	assert.ErrorIs(t, err, domain.ErrInvalidAlias)
+}
+
+func TestURLService_Create_StoresRedirectPermanent(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Now())
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	record, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", Permanent: true})
+	require.NoError(t, err)
+
+	resolved, err := svc.Resolve(context.Background(), record.ShortCode, domain.Visit{})
+	require.NoError(t, err)
+	assert.True(t, resolved.RedirectPermanent)
+}
+
+func TestURLService_Create_ReusesExistingCodeWhenEnabled(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLService(repo, gen, clock, service.WithLongURLReuse())
+
+	first, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+
+	second, attempts, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, first.ShortCode, second.ShortCode)
+	assert.Zero(t, attempts, "reused records need no code")
+
+	other, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://other.com", TTL: time.Hour})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ShortCode, other.ShortCode)
+
+	// Once the original expires, a fresh code is issued
+	clock.Advance(2 * time.Hour)
+	third, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ShortCode, third.ShortCode)
+}
+
+func TestURLService_Create_ReuseSkipsLinksThatCannotRedirect(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("disabled", func(t *testing.T) {
+		svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(),
+			domain.NewMockClock(time.Now()), service.WithLongURLReuse())
+
+		first, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com"})
+		require.NoError(t, err)
+		_, err = svc.SetEnabled(ctx, first.ShortCode, false)
+		require.NoError(t, err)
+
+		second, attempts, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com"})
+		require.NoError(t, err)
+		assert.NotEqual(t, first.ShortCode, second.ShortCode)
+		assert.Equal(t, 1, attempts, "a fresh code should have been generated")
+	})
+
+	t.Run("click limit used up", func(t *testing.T) {
+		svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(),
+			domain.NewMockClock(time.Now()), service.WithLongURLReuse())
+
+		first, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", MaxClicks: 1})
+		require.NoError(t, err)
+		_, err = svc.Resolve(ctx, first.ShortCode, domain.Visit{})
+		require.NoError(t, err)
+
+		second, attempts, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com"})
+		require.NoError(t, err)
+		assert.NotEqual(t, first.ShortCode, second.ShortCode)
+		assert.Equal(t, 1, attempts, "a fresh code should have been generated")
+	})
+}
+
+func TestURLService_Create_ReuseRequiresMatchingSettings(t *testing.T) {
+	ctx := context.Background()
+	base := domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour, Tags: []string{"a", "b"}}
+
+	tests := []struct {
+		name   string
+		first  func(*domain.CreateParams)
+		second func(*domain.CreateParams)
+		reuse  bool
+	}{
+		{name: "same settings", second: func(p *domain.CreateParams) { p.Tags = []string{"b", "a"} }, reuse: true},
+		{name: "permanent", second: func(p *domain.CreateParams) { p.Permanent = true }},
+		{name: "no expiry", second: func(p *domain.CreateParams) { p.NoExpiry = true }},
+		{name: "tags", second: func(p *domain.CreateParams) { p.Tags = []string{"a"} }},
+		{name: "click limit requested", second: func(p *domain.CreateParams) { p.MaxClicks = 5 }},
+		{name: "existing link click limited", first: func(p *domain.CreateParams) { p.MaxClicks = 5 }},
+		{name: "existing link outlives the request", first: func(p *domain.CreateParams) { p.TTL = 24 * time.Hour }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(),
+				domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)), service.WithLongURLReuse())
+
+			firstParams, secondParams := base, base
+			if tt.first != nil {
+				tt.first(&firstParams)
+			}
+			if tt.second != nil {
+				tt.second(&secondParams)
+			}
+
+			first, _, err := svc.Create(ctx, firstParams)
+			require.NoError(t, err)
+			second, _, err := svc.Create(ctx, secondParams)
+			require.NoError(t, err)
+			assert.Equal(t, tt.reuse, first.ShortCode == second.ShortCode)
+		})
+	}
+}
+
+func TestURLService_Create_UniqueCodesByDefault(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Now())
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	first, _, _ := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com"})
+	second, _, _ := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com"})
+
+	assert.NotEqual(t, first.ShortCode, second.ShortCode)
+}
+
+type recordingMetrics struct {
+	created    int
+	collisions int
+	dropped    int
+	outcomes   []service.ResolveOutcome
+}
+
+func (m *recordingMetrics) CodeCreated() { m.created++ }
+
+func (m *recordingMetrics) CodeCollided() { m.collisions++ }
+
+func (m *recordingMetrics) NotificationDropped() { m.dropped++ }
+
+func (m *recordingMetrics) Resolved(outcome service.ResolveOutcome) {
+	m.outcomes = append(m.outcomes, outcome)
+}
+
+func TestURLService_ReportsMetrics(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	metrics := &recordingMetrics{}
+
+	svc := service.NewURLService(repo, gen, clock, service.WithMetrics(metrics), service.WithLongURLReuse())
+	ctx := context.Background()
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+	_, _, err = svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+	_, _, err = svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", CustomAlias: "my-alias"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, metrics.created, "reused codes should not count as created")
+
+	_, _ = svc.Resolve(ctx, record.ShortCode, domain.Visit{})
+	_, _ = svc.Resolve(ctx, "missing1", domain.Visit{})
+	clock.Advance(2 * time.Hour)
+	_, _ = svc.Resolve(ctx, record.ShortCode, domain.Visit{})
+
+	assert.Equal(t, []service.ResolveOutcome{
+		service.ResolveOK,
+		service.ResolveNotFound,
+		service.ResolveExpired,
+	}, metrics.outcomes)
+}
+
+func TestURLService_ReportsCollisions(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Now())
+	metrics := &recordingMetrics{}
+
+	// The second create collides twice on code0001 before code0004 is free.
+	mockGen := &MockGenerator{codes: []string{"code0001", "code0001", "code0001", "code0004"}}
+	svc := service.NewURLServiceWithGenerator(repo, mockGen, clock, service.WithMetrics(metrics))
+
+	_, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://first.com"})
+	require.NoError(t, err)
+	assert.Zero(t, metrics.collisions)
+
+	_, attempts, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://second.com"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 2, metrics.collisions)
+	assert.Equal(t, 2, metrics.created)
+}
+
+func TestURLService_ReportsCollisionsWhenRetriesRunOut(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Now())
+	metrics := &recordingMetrics{}
+
+	mockGen := &MockGenerator{codes: []string{"samecode", "samecode", "samecode", "samecode"}}
+	svc := service.NewURLServiceWithGenerator(repo, mockGen, clock, service.WithMetrics(metrics), service.WithMaxRetries(3))
+
+	_, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://first.com"})
+	require.NoError(t, err)
+
+	_, _, err = svc.Create(context.Background(), domain.CreateParams{LongURL: "https://second.com"})
+	require.Error(t, err)
+	assert.Equal(t, 3, metrics.collisions, "every taken candidate should be counted, the last one included")
+}
+
+// spacedGenerator is a MockGenerator reporting a code space.
+type spacedGenerator struct {
+	MockGenerator
+	space float64
+}
+
+func (g *spacedGenerator) CodeSpace() float64 { return g.space }
+
+func TestURLService_CodeSpaceSaturation(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Now())
+	ctx := context.Background()
+
+	// Four characters from a two-letter alphabet leave 16 codes.
+	gen := &spacedGenerator{
+		MockGenerator: MockGenerator{codes: []string{"aaaa", "aaab", "aaba", "aabb"}},
+		space:         16,
+	}
+	svc := service.NewURLServiceWithGenerator(repo, gen, clock)
+	assert.True(t, svc.CodeSpaceKnown())
+
+	saturation, err := svc.CodeSpaceSaturation(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, saturation)
+
+	for range 4 {
+		_, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com"})
+		require.NoError(t, err)
+	}
+	saturation, err = svc.CodeSpaceSaturation(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.25, saturation, 1e-9)
+
+	unknown := service.NewURLServiceWithGenerator(repo, &MockGenerator{}, clock)
+	assert.False(t, unknown.CodeSpaceKnown())
+	_, err = unknown.CodeSpaceSaturation(ctx)
+	assert.ErrorIs(t, err, service.ErrUnknownCodeSpace)
+}
+
+func TestURLService_UpdateTTL_ExtendsFromNow(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	start := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	clock := domain.NewMockClock(start)
+	svc := service.NewURLService(repo, gen, clock)
+
+	record, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+
+	clock.Advance(30 * time.Minute)
+	ctx := domain.WithActor(context.Background(), "admin")
+	updated, err := svc.UpdateTTL(ctx, record.ShortCode, 48*time.Hour)
+	require.NoError(t, err)
+
+	wantExpiry := start.Add(30 * time.Minute).Add(48 * time.Hour)
+	assert.Equal(t, wantExpiry, updated.ExpiresAt)
+
+	stored, err := svc.GetStats(context.Background(), record.ShortCode)
+	require.NoError(t, err)
+	assert.Equal(t, wantExpiry, stored.ExpiresAt)
+
+	// Still live after the original expiry
+	clock.Advance(2 * time.Hour)
+	_, err = svc.Resolve(context.Background(), record.ShortCode, domain.Visit{})
+	assert.NoError(t, err)
+
+	history, err := svc.GetHistory(context.Background(), record.ShortCode)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, domain.EventTTLExtended, history[1].Type)
+	assert.Equal(t, "admin", history[1].Actor)
+	assert.Equal(t, "172800", history[1].Details["ttl_seconds"])
+}
+
+func TestURLService_UpdateTTL_MaxExpiryAbsolute(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	policy := domain.DefaultTTLPolicy()
+	policy.MaxExpiryAbsolute = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	repo := repository.NewMemoryRepository()
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock, service.WithTTLPolicy(policy))
+
+	record, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+
+	_, err = svc.UpdateTTL(context.Background(), record.ShortCode, 30*24*time.Hour)
+	require.ErrorIs(t, err, domain.ErrExpiryTooLate)
+
+	stored, err := repo.FindByShortCode(context.Background(), record.ShortCode)
+	require.NoError(t, err)
+	assert.Equal(t, record.ExpiresAt, stored.ExpiresAt, "rejected update must not change the expiry")
+}
+
+func TestURLService_UpdateTTL_MissingOrExpired(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repo, gen, clock)
+
+	_, err := svc.UpdateTTL(context.Background(), "missing1", time.Hour)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	record, _, _ := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	clock.Advance(2 * time.Hour)
+
+	_, err = svc.UpdateTTL(context.Background(), record.ShortCode, time.Hour)
+	assert.ErrorIs(t, err, domain.ErrExpired)
+}
+
+func TestURLService_Create_HashGenerator_SameURLSameCode(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen, err := shortcode.NewHashGenerator("salt", 8, shortcode.Alphabet)
+	require.NoError(t, err)
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLServiceWithGenerator(repo, gen, clock)
+
+	first, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com/a", TTL: time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, gen.GenerateFor("https://example.com/a"), first.ShortCode)
+
+	again, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com/a", TTL: time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, first.ShortCode, again.ShortCode)
+
+	other, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com/b", TTL: time.Hour})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ShortCode, other.ShortCode)
+}
+
+func TestURLService_Create_HashGenerator_NewLinkForDifferentSettings(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen, err := shortcode.NewHashGenerator("salt", 8, shortcode.Alphabet)
+	require.NoError(t, err)
+	svc := service.NewURLServiceWithGenerator(repo, gen, domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+	ctx := context.Background()
+
+	limited, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com/a", TTL: time.Hour, MaxClicks: 1})
+	require.NoError(t, err)
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com/a", TTL: time.Hour})
+	require.NoError(t, err)
+	assert.NotEqual(t, limited.ShortCode, record.ShortCode, "another caller's one-time link must not be handed out")
+	assert.Zero(t, record.MaxClicks)
+}
+
+func TestURLService_Create_HashGenerator_FallsBackWhenCodeTakenByOtherURL(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen, err := shortcode.NewHashGenerator("salt", 8, shortcode.Alphabet)
+	require.NoError(t, err)
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLServiceWithGenerator(repo, gen, clock)
+
+	// Squat the derived code with a different URL
+	derived := gen.GenerateFor("https://example.com/a")
+	_, _, err = svc.Create(context.Background(), domain.CreateParams{
+		LongURL: "https://example.com/squatter", TTL: time.Hour, CustomAlias: derived,
+	})
+	require.NoError(t, err)
+
+	record, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com/a", TTL: time.Hour})
+	require.NoError(t, err)
+	assert.NotEqual(t, derived, record.ShortCode)
+	assert.Equal(t, "https://example.com/a", record.LongURL)
+}
+
+func TestURLService_SetEnabled_DisabledLinkStopsResolvingButKeepsStats(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	gen := shortcode.NewGenerator()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLService(repo, gen, clock)
+
+	record, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+	assert.True(t, record.Enabled, "new links should be enabled")
+
+	_, err = svc.Resolve(context.Background(), record.ShortCode, domain.Visit{})
+	require.NoError(t, err)
+
+	ctx := domain.WithActor(context.Background(), "admin")
+	disabled, err := svc.SetEnabled(ctx, record.ShortCode, false)
+	require.NoError(t, err)
+	assert.False(t, disabled.Enabled)
+
+	_, err = svc.Resolve(context.Background(), record.ShortCode, domain.Visit{})
+	assert.ErrorIs(t, err, domain.ErrDisabled)
+
+	stats, err := svc.GetStats(context.Background(), record.ShortCode)
+	require.NoError(t, err)
+	assert.False(t, stats.Enabled)
+	assert.Equal(t, int64(1), stats.ClickCount, "disabled resolves must not count clicks")
+
+	_, err = svc.SetEnabled(ctx, record.ShortCode, true)
+	require.NoError(t, err)
+
+	_, err = svc.Resolve(context.Background(), record.ShortCode, domain.Visit{})
+	assert.NoError(t, err)
+
+	history, err := svc.GetHistory(context.Background(), record.ShortCode)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, domain.EventDisabled, history[1].Type)
+	assert.Equal(t, "admin", history[1].Actor)
+	assert.Equal(t, domain.EventEnabled, history[2].Type)
+}
+
+func TestURLService_SetEnabled_NotFound(t *testing.T) {
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), domain.NewMockClock(time.Now()))
+
+	_, err := svc.SetEnabled(context.Background(), "notexist", false)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestURLService_CreateBatch_CollectsPartialFailures(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock)
+
+	results := svc.CreateBatch(context.Background(), []domain.CreateParams{
+		{LongURL: "https://example.com/a", TTL: time.Hour, CustomAlias: "dup-alias"},
+		{LongURL: "https://example.com/b", TTL: time.Hour, CustomAlias: "dup-alias"},
+		{LongURL: "https://example.com/c", TTL: time.Hour},
+	})
+
+	require.Len(t, results, 3)
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, "dup-alias", results[0].Record.ShortCode)
+	assert.ErrorIs(t, results[1].Err, domain.ErrAliasTaken)
+	assert.Nil(t, results[1].Record)
+	require.NoError(t, results[2].Err, "a failed item must not abort the batch")
+	assert.Equal(t, "https://example.com/c", results[2].Record.LongURL)
+}
+
+func TestURLService_PurgeExpired_ReturnsDeletedCodes(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLServiceWithGenerator(repo, &MockGenerator{codes: []string{"short001", "long0001"}}, clock)
+	ctx := context.Background()
+
+	_, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com/a", TTL: time.Minute})
+	require.NoError(t, err)
+	_, _, err = svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com/b", TTL: time.Hour})
+	require.NoError(t, err)
+
+	clock.Advance(10 * time.Minute)
+
+	codes, err := svc.PurgeExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"short001"}, codes)
+
+	_, err = repo.FindByShortCode(ctx, "long0001")
+	assert.NoError(t, err)
+}
+
+func TestURLService_RunReaper_PurgesUntilCancelled(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLServiceWithGenerator(repo, &MockGenerator{codes: []string{"short001"}}, clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Minute})
+	require.NoError(t, err)
+	clock.Advance(time.Hour)
+
+	done := make(chan struct{})
+	go func() {
+		svc.RunReaper(ctx, 5*time.Millisecond)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		_, err := repo.FindByShortCode(context.Background(), "short001")
+		return err != nil
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("reaper did not stop after cancel")
+	}
+}
+
+func TestURLService_Create_NoExpiry(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLServiceWithGenerator(repo, &MockGenerator{codes: []string{"forever1", "shortttl"}}, clock)
+	ctx := context.Background()
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com/forever", NoExpiry: true})
+	require.NoError(t, err)
+	assert.True(t, record.ExpiresAt.IsZero())
+	assert.True(t, record.NeverExpires())
+	assert.Equal(t, "true", record.History[0].Details["no_expiry"])
+
+	_, _, err = svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com/brief", TTL: time.Minute})
+	require.NoError(t, err)
+
+	clock.Advance(10 * 365 * 24 * time.Hour)
+
+	resolved, err := svc.Resolve(ctx, "forever1", domain.Visit{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/forever", resolved.LongURL)
+
+	stats, err := svc.GetStats(ctx, "forever1")
+	require.NoError(t, err)
+	assert.True(t, stats.ExpiresAt.IsZero())
+
+	purged, err := svc.PurgeExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shortttl"}, purged)
+
+	_, err = repo.FindByShortCode(ctx, "forever1")
+	assert.NoError(t, err, "never-expiring links must survive purges")
+}
+
+func TestURLService_Create_NoExpiryRefusedUnderAbsoluteCap(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	policy := domain.DefaultTTLPolicy()
+	policy.MaxExpiryAbsolute = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), clock, service.WithTTLPolicy(policy))
+
+	_, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", NoExpiry: true})
+	assert.ErrorIs(t, err, domain.ErrExpiryTooLate)
+}
+
+func TestURLService_AsyncClicks_FlushCountsEveryResolve(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	// A small buffer forces some resolves to fall back to synchronous writes
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock, service.WithAsyncClicks(16, time.Hour))
+	ctx := context.Background()
+	t.Cleanup(func() { _ = svc.Close(ctx) })
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+
+	const goroutines, perGoroutine = 20, 50
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perGoroutine {
+				_, err := svc.Resolve(ctx, record.ShortCode, domain.Visit{Referer: "https://news.example.com/a"})
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.NoError(t, svc.Flush(ctx))
+
+	stats, err := svc.GetStats(ctx, record.ShortCode)
+	require.NoError(t, err)
+	assert.Equal(t, int64(goroutines*perGoroutine), stats.ClickCount)
+	assert.Equal(t, clock.Now(), stats.LastAccessedAt)
+
+	referrers, err := svc.GetReferrers(ctx, record.ShortCode, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ReferrerCount{{Referrer: "news.example.com", Clicks: goroutines * perGoroutine}}, referrers)
+}
+
+func TestURLService_AsyncClicks_ResolveDefersWrite(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock, service.WithAsyncClicks(100, time.Hour))
+	ctx := context.Background()
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+
+	resolved, err := svc.Resolve(ctx, record.ShortCode, domain.Visit{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resolved.ClickCount, "the returned record reflects the click")
+
+	require.NoError(t, svc.Close(ctx))
+	stored, err := repo.FindByShortCode(ctx, record.ShortCode)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stored.ClickCount, "Close writes queued clicks")
+
+	// After Close, clicks are written synchronously
+	_, err = svc.Resolve(ctx, record.ShortCode, domain.Visit{})
+	require.NoError(t, err)
+	stored, err = repo.FindByShortCode(ctx, record.ShortCode)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stored.ClickCount)
+	assert.NoError(t, svc.Flush(ctx))
+}
+
+func TestURLService_AsyncClicks_ClickLimitStaysExact(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock, service.WithAsyncClicks(100, time.Hour))
+	defer svc.Close(context.Background())
+	ctx := context.Background()
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour, MaxClicks: 1})
+	require.NoError(t, err)
+
+	_, err = svc.Resolve(ctx, record.ShortCode, domain.Visit{})
+	require.NoError(t, err)
+	_, err = svc.Resolve(ctx, record.ShortCode, domain.Visit{})
+	assert.ErrorIs(t, err, domain.ErrExpired, "the limit must hold before the next flush")
+
+	stored, err := repo.FindByShortCode(ctx, record.ShortCode)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stored.ClickCount, "limited links are counted without buffering")
+}
+
+func TestURLService_FlushAndCloseWithoutAsyncClicks(t *testing.T) {
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), domain.RealClock{})
+
+	assert.NoError(t, svc.Flush(context.Background()))
+	assert.NoError(t, svc.Close(context.Background()))
+}
+
+// recordingNotifier collects notifier calls for assertions.
+type recordingNotifier struct {
+	mu      sync.Mutex
+	created []*domain.URLRecord
+	expired [][]string
+}
+
+func (n *recordingNotifier) OnCreated(_ context.Context, record *domain.URLRecord) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.created = append(n.created, record)
+}
+
+func (n *recordingNotifier) OnExpired(_ context.Context, codes []string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.expired = append(n.expired, codes)
+}
+
+func TestURLService_NotifiesCreatedLinks(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	notifier := &recordingNotifier{}
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), clock,
+		service.WithNotifier(notifier), service.WithLongURLReuse())
+	ctx := context.Background()
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+	_, _, err = svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com/alias", CustomAlias: "my-alias"})
+	require.NoError(t, err)
+
+	// Reused and failed creates are not reported
+	_, _, err = svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+	_, _, err = svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com/other", CustomAlias: "my-alias"})
+	require.ErrorIs(t, err, domain.ErrAliasTaken)
+
+	require.NoError(t, svc.Close(ctx))
+
+	require.Len(t, notifier.created, 2)
+	codes := []string{notifier.created[0].ShortCode, notifier.created[1].ShortCode}
+	assert.ElementsMatch(t, []string{record.ShortCode, "my-alias"}, codes)
+	for _, created := range notifier.created {
+		if created.ShortCode == record.ShortCode {
+			assert.Equal(t, "https://example.com", created.LongURL)
+			assert.Equal(t, clock.Now().Add(time.Hour), created.ExpiresAt)
+		}
+	}
+	assert.Empty(t, notifier.expired)
+}
+
+// blockingNotifier holds each OnCreated call until release is closed or
+// its context is canceled, announcing the call on started.
+type blockingNotifier struct {
+	started  chan string
+	release  chan struct{}
+	canceled atomic.Int64
+}
+
+func (n *blockingNotifier) OnCreated(ctx context.Context, record *domain.URLRecord) {
+	n.started <- record.ShortCode
+	select {
+	case <-n.release:
+	case <-ctx.Done():
+		n.canceled.Add(1)
+	}
+}
+
+func (n *blockingNotifier) OnExpired(context.Context, []string) {}
+
+func TestURLService_NotifierQueue_DropsWhenFull(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	notifier := &blockingNotifier{started: make(chan string, 3), release: make(chan struct{})}
+	metrics := &recordingMetrics{}
+	svc := service.NewURLServiceWithGenerator(repository.NewMemoryRepository(),
+		&MockGenerator{codes: []string{"code0001", "code0002", "code0003"}}, clock,
+		service.WithNotifier(notifier), service.WithNotifierQueue(1, 1), service.WithMetrics(metrics))
+	ctx := context.Background()
+	create := func() {
+		_, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+		require.NoError(t, err)
+	}
+
+	// The only worker is busy with the first event and the queue holds the
+	// second, so the third is dropped.
+	create()
+	assert.Equal(t, "code0001", <-notifier.started)
+	create()
+	create()
+	assert.Equal(t, 1, metrics.dropped)
+
+	close(notifier.release)
+	require.NoError(t, svc.Close(ctx))
+	assert.Equal(t, "code0002", <-notifier.started)
+	assert.Empty(t, notifier.started, "the dropped event must not be delivered")
+}
+
+func TestURLService_Close_CancelsSlowNotifications(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	notifier := &blockingNotifier{started: make(chan string, 1), release: make(chan struct{})}
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), clock,
+		service.WithNotifier(notifier))
+
+	_, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+	<-notifier.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, svc.Close(ctx), context.DeadlineExceeded)
+	assert.Eventually(t, func() bool { return notifier.canceled.Load() == 1 }, time.Second, time.Millisecond,
+		"the running call should see its context canceled")
+}
+
+func TestURLService_NotifiesPurgedLinks(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	notifier := &recordingNotifier{}
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), clock, service.WithNotifier(notifier))
+	ctx := context.Background()
+
+	// Nothing purged, nothing reported
+	_, err := svc.PurgeExpired(ctx)
+	require.NoError(t, err)
+
+	short, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com/a", TTL: time.Minute})
+	require.NoError(t, err)
+	_, _, err = svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com/b", TTL: time.Hour})
+	require.NoError(t, err)
+
+	clock.Advance(10 * time.Minute)
+	codes, err := svc.PurgeExpired(ctx)
+	require.NoError(t, err)
+	require.NoError(t, svc.Close(ctx))
+
+	assert.Equal(t, []string{short.ShortCode}, codes)
+	assert.Equal(t, [][]string{{short.ShortCode}}, notifier.expired)
+}
+
+// flakyRepository fails the first failures saves with err.
+type flakyRepository struct {
+	repository.Repository
+	failures int
+	err      error
+	saves    int
+}
+
+func (r *flakyRepository) SaveIfNotExists(ctx context.Context, record *domain.URLRecord) error {
+	r.saves++
+	if r.saves <= r.failures {
+		return r.err
+	}
+	return r.Repository.SaveIfNotExists(ctx, record)
+}
+
+// staleLookupRepository answers FindByShortCode with snapshot when set,
+// standing in for a read that raced a concurrent write.
+type staleLookupRepository struct {
+	repository.Repository
+	snapshot *domain.URLRecord
+}
+
+func (r *staleLookupRepository) FindByShortCode(ctx context.Context, code string) (*domain.URLRecord, error) {
+	if r.snapshot != nil {
+		return r.snapshot.Clone(), nil
+	}
+	return r.Repository.FindByShortCode(ctx, code)
+}
+
+// recordingSleeper records requested waits without sleeping.
+type recordingSleeper struct {
+	waits []time.Duration
+}
+
+func (s *recordingSleeper) Sleep(_ context.Context, d time.Duration) error {
+	s.waits = append(s.waits, d)
+	return nil
+}
+
+func TestURLService_Create_RetriesTransientSaveErrors(t *testing.T) {
+	transient := errors.New("database is locked")
+
+	for _, alias := range []string{"", "my-alias"} {
+		t.Run("alias="+alias, func(t *testing.T) {
+			repo := &flakyRepository{Repository: repository.NewMemoryRepository(), failures: 2, err: transient}
+			sleeper := &recordingSleeper{}
+			svc := service.NewURLServiceWithGenerator(repo, &MockGenerator{codes: []string{"code0001"}}, domain.NewMockClock(time.Now()),
+				service.WithSaveRetries(service.SaveRetryPolicy{Retries: 3, InitialBackoff: 100 * time.Millisecond}),
+				service.WithSleeper(sleeper.Sleep))
+
+			record, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour, CustomAlias: alias})
+			require.NoError(t, err)
+			assert.Equal(t, 3, repo.saves)
+
+			stored, err := repo.FindByShortCode(context.Background(), record.ShortCode)
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com", stored.LongURL)
+
+			// Waits double from InitialBackoff, each jittered down by at most half.
+			require.Len(t, sleeper.waits, 2)
+			assert.GreaterOrEqual(t, sleeper.waits[0], 50*time.Millisecond)
+			assert.LessOrEqual(t, sleeper.waits[0], 100*time.Millisecond)
+			assert.GreaterOrEqual(t, sleeper.waits[1], 100*time.Millisecond)
+			assert.LessOrEqual(t, sleeper.waits[1], 200*time.Millisecond)
+		})
+	}
+}
+
+func TestURLService_Create_GivesUpAfterSaveRetries(t *testing.T) {
+	transient := errors.New("database is locked")
+	repo := &flakyRepository{Repository: repository.NewMemoryRepository(), failures: 10, err: transient}
+	sleeper := &recordingSleeper{}
+	svc := service.NewURLServiceWithGenerator(repo, &MockGenerator{codes: []string{"code0001"}}, domain.NewMockClock(time.Now()),
+		service.WithSaveRetries(service.SaveRetryPolicy{Retries: 2, InitialBackoff: time.Second, MaxBackoff: time.Second}),
+		service.WithSleeper(sleeper.Sleep))
+
+	_, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	assert.ErrorIs(t, err, transient)
+	assert.Equal(t, 3, repo.saves)
+	require.Len(t, sleeper.waits, 2)
+	assert.LessOrEqual(t, sleeper.waits[1], time.Second, "waits are capped at MaxBackoff")
+}
+
+func TestURLService_Create_DoesNotRetryPermanentSaveErrors(t *testing.T) {
+	for _, err := range []error{domain.ErrCapacityExceeded, context.Canceled} {
+		t.Run(err.Error(), func(t *testing.T) {
+			repo := &flakyRepository{Repository: repository.NewMemoryRepository(), failures: 1, err: err}
+			sleeper := &recordingSleeper{}
+			svc := service.NewURLServiceWithGenerator(repo, &MockGenerator{codes: []string{"code0001"}}, domain.NewMockClock(time.Now()),
+				service.WithSaveRetries(service.SaveRetryPolicy{Retries: 3}),
+				service.WithSleeper(sleeper.Sleep))
+
+			_, _, createErr := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+			assert.ErrorIs(t, createErr, err)
+			assert.Equal(t, 1, repo.saves)
+			assert.Empty(t, sleeper.waits)
+		})
+	}
+}
+
+func TestURLService_Create_CollisionsRetryWithoutBackoff(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	sleeper := &recordingSleeper{}
+	svc := service.NewURLServiceWithGenerator(repo, &MockGenerator{codes: []string{"code0001", "code0001", "code0002"}}, domain.NewMockClock(time.Now()),
+		service.WithSaveRetries(service.SaveRetryPolicy{Retries: 3}),
+		service.WithSleeper(sleeper.Sleep))
+
+	_, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://first.com", TTL: time.Hour})
+	require.NoError(t, err)
+	record, attempts, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://second.com", TTL: time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, "code0002", record.ShortCode)
+	assert.Equal(t, 2, attempts)
+	assert.Empty(t, sleeper.waits)
+}
+
+func TestURLService_Create_SaveRetriesOffByDefault(t *testing.T) {
+	transient := errors.New("database is locked")
+	repo := &flakyRepository{Repository: repository.NewMemoryRepository(), failures: 1, err: transient}
+	svc := service.NewURLServiceWithGenerator(repo, &MockGenerator{codes: []string{"code0001"}}, domain.NewMockClock(time.Now()))
+
+	_, _, err := svc.Create(context.Background(), domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	assert.ErrorIs(t, err, transient)
+	assert.Equal(t, 1, repo.saves)
+}
+
+func TestURLService_DeleteAndRestore(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock)
+	ctx := context.Background()
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: 24 * time.Hour})
+	require.NoError(t, err)
+	code := record.ShortCode
+
+	require.NoError(t, svc.Delete(ctx, code))
+	_, err = svc.Resolve(ctx, code, domain.Visit{})
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = svc.GetStats(ctx, code)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.ErrorIs(t, svc.Delete(ctx, code), domain.ErrNotFound)
+
+	clock.Advance(time.Hour)
+	restored, err := svc.Restore(ctx, code)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", restored.LongURL)
+
+	resolved, err := svc.Resolve(ctx, code, domain.Visit{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resolved.ClickCount)
+
+	history, err := svc.GetHistory(ctx, code)
+	require.NoError(t, err)
+	var types []domain.EventType
+	for _, event := range history {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []domain.EventType{domain.EventCreated, domain.EventDeleted, domain.EventRestored}, types)
+}
+
+func TestURLService_Restore_PastRetention(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock, service.WithDeleteRetention(time.Hour))
+	ctx := context.Background()
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", NoExpiry: true})
+	require.NoError(t, err)
+	require.NoError(t, svc.Delete(ctx, record.ShortCode))
+
+	clock.Advance(2 * time.Hour)
+	_, err = svc.Restore(ctx, record.ShortCode)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	purged, err := svc.PurgeExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{record.ShortCode}, purged)
+}
+
+func TestURLService_BulkDelete(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	newSeeded := func(t *testing.T) (*service.URLService, repository.Repository) {
+		repo := repository.NewMemoryRepository()
+		svc := service.NewURLServiceWithGenerator(repo, &MockGenerator{codes: []string{"spring01", "spring02", "summer01", "forever1"}}, clock)
+		ctx := context.Background()
+		for _, params := range []domain.CreateParams{
+			{LongURL: "https://example.com/spring/a", TTL: time.Hour},
+			{LongURL: "https://example.com/spring/b", TTL: 48 * time.Hour},
+			{LongURL: "https://example.com/summer/a", TTL: 72 * time.Hour},
+			{LongURL: "https://example.com/spring/c", NoExpiry: true},
+		} {
+			_, _, err := svc.Create(ctx, params)
+			require.NoError(t, err)
+		}
+		return svc, repo
+	}
+
+	t.Run("by prefix", func(t *testing.T) {
+		svc, repo := newSeeded(t)
+
+		codes, err := svc.DeleteByPrefix(context.Background(), "https://example.com/spring/")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"forever1", "spring01", "spring02"}, codes)
+
+		_, err = repo.FindByShortCode(context.Background(), "spring01")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		restored, err := svc.Restore(context.Background(), "spring01")
+		require.NoError(t, err, "links deleted by prefix can be restored")
+		assert.Equal(t, "https://example.com/spring/a", restored.LongURL)
+
+		_, err = svc.DeleteByPrefix(context.Background(), "")
+		assert.ErrorIs(t, err, repository.ErrEmptyPrefix)
+	})
+
+	t.Run("by expiry", func(t *testing.T) {
+		svc, repo := newSeeded(t)
+
+		codes, err := svc.DeleteExpiringBefore(context.Background(), clock.Now().Add(60*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"spring01", "spring02"}, codes)
+
+		_, err = repo.FindByShortCode(context.Background(), "forever1")
+		assert.NoError(t, err, "links that never expire are kept")
+		_, err = repo.FindByShortCode(context.Background(), "summer01")
+		assert.NoError(t, err)
+	})
+}
+
+func TestURLService_PurgeExpired_KeepsDeletedWithinRetention(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock)
+	ctx := context.Background()
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", NoExpiry: true})
+	require.NoError(t, err)
+	require.NoError(t, svc.Delete(ctx, record.ShortCode))
+
+	clock.Advance(service.DefaultDeleteRetention - time.Minute)
+	purged, err := svc.PurgeExpired(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, purged)
+
+	_, err = svc.Restore(ctx, record.ShortCode)
+	assert.NoError(t, err)
+}
+
+func TestURLService_GetStoreStats(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock)
+	ctx := context.Background()
+
+	short, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://short.example", TTL: time.Hour})
+	require.NoError(t, err)
+	long, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://long.example", TTL: 48 * time.Hour})
+	require.NoError(t, err)
+	for range 3 {
+		_, err = svc.Resolve(ctx, long.ShortCode, domain.Visit{})
+		require.NoError(t, err)
+	}
+	_, err = svc.Resolve(ctx, short.ShortCode, domain.Visit{})
+	require.NoError(t, err)
+
+	clock.Advance(2 * time.Hour)
+	stats, err := svc.GetStoreStats(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.TotalLinks)
+	assert.Equal(t, int64(1), stats.ActiveLinks)
+	assert.Equal(t, int64(1), stats.ExpiredLinks, "expiry is judged by the service clock")
+	assert.Equal(t, int64(4), stats.TotalClicks)
+	require.Len(t, stats.TopLinks, 1)
+	assert.Equal(t, long.ShortCode, stats.TopLinks[0].ShortCode)
+}
+
+func TestURLService_Resolve_BotFilter(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), clock,
+		service.WithBotFilter([]string{"MonitorBot", "curl/"}))
+	ctx := context.Background()
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		userAgent string
+		counted   bool
+	}{
+		{userAgent: "Mozilla/5.0 (X11; Linux x86_64) Firefox/121.0", counted: true},
+		{userAgent: "monitorbot/1.0", counted: false},
+		{userAgent: "curl/8.4.0", counted: false},
+		{userAgent: "", counted: false},
+		{userAgent: "Googlebot/2.1", counted: true},
+	}
+	want := int64(0)
+	for _, tc := range testCases {
+		resolved, err := svc.Resolve(ctx, record.ShortCode, domain.Visit{UserAgent: tc.userAgent})
+		require.NoError(t, err, "redirect must still succeed for %q", tc.userAgent)
+		assert.Equal(t, "https://example.com", resolved.LongURL)
+
+		if tc.counted {
+			want++
+		}
+		stats, err := svc.GetStats(ctx, record.ShortCode)
+		require.NoError(t, err)
+		assert.Equal(t, want, stats.ClickCount, "after %q", tc.userAgent)
+	}
+}
+
+func TestURLService_Resolve_BotFilter_CountsTowardClickLimit(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), clock,
+		service.WithBotFilter(nil))
+	ctx := context.Background()
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour, MaxClicks: 2})
+	require.NoError(t, err)
+
+	for _, ua := range []string{"curl/8.4.0", ""} {
+		_, err := svc.Resolve(ctx, record.ShortCode, domain.Visit{UserAgent: ua})
+		require.NoError(t, err)
+	}
+	_, err = svc.Resolve(ctx, record.ShortCode, domain.Visit{UserAgent: "curl/8.4.0"})
+	assert.ErrorIs(t, err, domain.ErrExpired)
+
+	stats, err := svc.GetStats(ctx, record.ShortCode)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.ClickCount)
+}
+
+func TestURLService_Resolve_DestinationCheck_RefusesBeforeCounting(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	repo := repository.NewMemoryRepository()
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock,
+		service.WithDestinationCheck(func(longURL string) bool {
+			return strings.HasPrefix(longURL, "https:")
+		}))
+	ctx := context.Background()
+
+	// Imported records skip Create's URL validation.
+	require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{
+		ShortCode: "unsafe01",
+		LongURL:   "javascript:alert(1)",
+		MaxClicks: 1,
+		Enabled:   true,
+	}))
+
+	_, err := svc.Resolve(ctx, "unsafe01", domain.Visit{})
+	assert.ErrorIs(t, err, domain.ErrUnsafeDestination)
+	_, err = svc.Lookup(ctx, "unsafe01")
+	assert.ErrorIs(t, err, domain.ErrUnsafeDestination)
+
+	record, err := repo.FindByShortCode(ctx, "unsafe01")
+	require.NoError(t, err)
+	assert.Zero(t, record.ClickCount, "refused resolves must not use up the click limit")
+}
+
+func TestURLService_Resolve_RefusesUnsafeSchemesByDefault(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	repo := repository.NewMemoryRepository()
+	svc := service.NewURLService(repo, shortcode.NewGenerator(), clock)
+	ctx := context.Background()
+
+	for code, longURL := range map[string]string{
+		"unsafe01": "javascript:alert(1)",
+		"unsafe02": "DATA:text/html,<script>alert(1)</script>",
+		"unsafe03": "vbscript:msgbox(1)",
+	} {
+		require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: code, LongURL: longURL, Enabled: true}))
+
+		_, err := svc.Resolve(ctx, code, domain.Visit{})
+		assert.ErrorIs(t, err, domain.ErrUnsafeDestination, longURL)
+		_, err = svc.Lookup(ctx, code)
+		assert.ErrorIs(t, err, domain.ErrUnsafeDestination, longURL)
+	}
+
+	require.NoError(t, repo.SaveIfNotExists(ctx, &domain.URLRecord{ShortCode: "safe0001", LongURL: "mailto:user@example.com", Enabled: true}))
+	record, err := svc.Resolve(ctx, "safe0001", domain.Visit{})
+	require.NoError(t, err)
+	assert.Equal(t, "mailto:user@example.com", record.LongURL)
+}
+
+func TestURLService_Resolve_BotFilter_DefaultPatterns(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), clock,
+		service.WithBotFilter(nil))
+	ctx := context.Background()
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+
+	for _, ua := range []string{
+		"Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)",
+		"facebookexternalhit/1.1",
+		"Wget/1.21",
+		"python-requests/2.31.0",
+	} {
+		_, err := svc.Resolve(ctx, record.ShortCode, domain.Visit{UserAgent: ua})
+		require.NoError(t, err)
+	}
+	_, err = svc.Resolve(ctx, record.ShortCode, domain.Visit{UserAgent: "Mozilla/5.0 (iPhone) Safari/604.1"})
+	require.NoError(t, err)
+
+	stats, err := svc.GetStats(ctx, record.ShortCode)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.ClickCount)
+}
+
+func TestURLService_Resolve_BotFilter_OffByDefault(t *testing.T) {
+	clock := domain.NewMockClock(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	svc := service.NewURLService(repository.NewMemoryRepository(), shortcode.NewGenerator(), clock)
+	ctx := context.Background()
+
+	record, _, err := svc.Create(ctx, domain.CreateParams{LongURL: "https://example.com", TTL: time.Hour})
+	require.NoError(t, err)
+
+	_, err = svc.Resolve(ctx, record.ShortCode, domain.Visit{UserAgent: "Googlebot/2.1"})
+	require.NoError(t, err)
+
+	stats, err := svc.GetStats(ctx, record.ShortCode)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.ClickCount)
+}
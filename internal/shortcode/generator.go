@@ -2,41 +2,257 @@ package shortcode
 
 import (
 	"crypto/rand"
-	"math/big"
+	"fmt"
+	"math"
+	"strings"
 )
 
 // Alphabet excludes ambiguous characters: 0, O, I, l, 1
-const alphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
-const codeLength = 8
+const Alphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// DefaultLength is the code length used by NewGenerator.
+const DefaultLength = 8
+
+// MinLength is the shortest code length NewGeneratorWithConfig accepts.
+const MinLength = 4
+
+// ambiguousReplacements maps characters excluded from the alphabet to the
+// alphabet character a user most likely meant when typing them.
+var ambiguousReplacements = map[byte]byte{
+	'0': 'o',
+	'O': 'o',
+	'1': 'i',
+	'l': 'i',
+	'I': 'i',
+}
 
 // Generator generates random short codes.
 type Generator struct {
 	alphabet string
 	length   int
+	checksum bool
+}
+
+// Option configures a Generator.
+type Option func(*Generator)
+
+// WithChecksum makes the last character of every generated code a check
+// character computed from the preceding ones (a weighted sum modulo the
+// alphabet size).
+// This trades one character of code space for typo detection.
+func WithChecksum() Option {
+	return func(g *Generator) {
+		g.checksum = true
+	}
 }
 
 // NewGenerator creates a new short code generator.
-func NewGenerator() *Generator {
-	return &Generator{
-		alphabet: alphabet,
-		length:   codeLength,
+func NewGenerator(opts ...Option) *Generator {
+	g := &Generator{
+		alphabet: Alphabet,
+		length:   DefaultLength,
+	}
+	for _, opt := range opts {
+		opt(g)
 	}
+	return g
 }
 
-// Generate creates a new random short code.
-// The code is 8 characters long using crypto/rand for security.
+// NewGeneratorWithConfig creates a generator producing codes of the given
+// length from the given alphabet. The length includes the check character
+// when WithChecksum is used. The alphabet must hold at least two distinct
+// ASCII letters or digits.
+func NewGeneratorWithConfig(length int, alphabet string, opts ...Option) (*Generator, error) {
+	if length < MinLength {
+		return nil, fmt.Errorf("code length must be at least %d, got %d", MinLength, length)
+	}
+	if err := validateAlphabet(alphabet); err != nil {
+		return nil, err
+	}
+
+	g := NewGenerator(opts...)
+	g.length = length
+	g.alphabet = alphabet
+	return g, nil
+}
+
+func validateAlphabet(alphabet string) error {
+	if len(alphabet) < 2 {
+		return fmt.Errorf("alphabet must have at least 2 characters, got %d", len(alphabet))
+	}
+
+	seen := make(map[rune]bool, len(alphabet))
+	for _, c := range alphabet {
+		isAlnum := (c >= '0' && c <= '9') || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+		if !isAlnum {
+			return fmt.Errorf("alphabet may only contain ASCII letters and digits, got %q", c)
+		}
+		if seen[c] {
+			return fmt.Errorf("alphabet contains duplicate character %q", c)
+		}
+		seen[c] = true
+	}
+
+	return nil
+}
+
+// Generate creates a new random short code of the configured length
+// (8 characters by default) using crypto/rand for security.
 func (g *Generator) Generate() string {
-	b := make([]byte, g.length)
-	alphabetLen := big.NewInt(int64(len(g.alphabet)))
+	b := make([]byte, g.randomLen(), g.length)
+	g.fillRandom(b)
+
+	if g.checksum {
+		b = append(b, g.checkChar(string(b)))
+	}
+
+	return string(b)
+}
+
+// GenerateBatch creates n codes like Generate, drawing the random bytes
+// for all of them in one read.
+func (g *Generator) GenerateBatch(n int) []string {
+	if n <= 0 {
+		return nil
+	}
+
+	randomLen := g.randomLen()
+	chars := make([]byte, n*randomLen)
+	g.fillRandom(chars)
+
+	codes := make([]string, n)
+	for i := range codes {
+		b := chars[i*randomLen : (i+1)*randomLen : (i+1)*randomLen]
+		if g.checksum {
+			b = append(b, g.checkChar(string(b)))
+		}
+		codes[i] = string(b)
+	}
+	return codes
+}
+
+// CodeSpace returns how many distinct codes the generator can produce: the
+// alphabet size to the power of the random length, since a check character
+// adds none.
+func (g *Generator) CodeSpace() float64 {
+	return math.Pow(float64(len(g.alphabet)), float64(g.randomLen()))
+}
+
+// randomLen is the number of random characters in a code, excluding the
+// check character.
+func (g *Generator) randomLen() int {
+	if g.checksum {
+		return g.length - 1
+	}
+	return g.length
+}
+
+// fillRandom fills b with uniformly chosen alphabet characters. It reads
+// random bytes in blocks and maps each to a character by its remainder
+// modulo the alphabet size, discarding bytes from the incomplete final
+// cycle of 256 so that no character is favored.
+func (g *Generator) fillRandom(b []byte) {
+	n := len(g.alphabet)
+	limit := 256 - 256%n
 
-	for i := range b {
-		n, err := rand.Int(rand.Reader, alphabetLen)
-		if err != nil {
+	// Over-read slightly so one block is usually enough despite rejections.
+	buf := make([]byte, len(b)+len(b)/4+4)
+	for filled := 0; filled < len(b); {
+		if _, err := rand.Read(buf); err != nil {
 			// Fallback should never happen with crypto/rand
 			panic("crypto/rand failed: " + err.Error())
 		}
-		b[i] = g.alphabet[n.Int64()]
+		for _, r := range buf {
+			if int(r) >= limit {
+				continue
+			}
+			b[filled] = g.alphabet[int(r)%n]
+			filled++
+			if filled == len(b) {
+				break
+			}
+		}
 	}
+}
 
-	return string(b)
+// VerifyChecksum reports whether the code's check character matches.
+// Only codes shaped like generated ones (the configured length, no hyphen)
+// are checked; anything else, such as a custom alias, passes. It always
+// returns true when the generator was built without WithChecksum.
+func (g *Generator) VerifyChecksum(code string) bool {
+	if !g.checksum || len(code) != g.length || strings.Contains(code, "-") {
+		return true
+	}
+	if !g.inAlphabet(code) {
+		return false
+	}
+	return g.checkChar(code[:len(code)-1]) == code[len(code)-1]
+}
+
+// Suggest returns the code the user most likely intended when code fails
+// its checksum, trying ambiguous-character substitutions and adjacent
+// transpositions. It returns an empty string if no candidate validates.
+func (g *Generator) Suggest(code string) string {
+	if !g.checksum || len(code) != g.length {
+		return ""
+	}
+
+	replaced := []byte(code)
+	for i := range replaced {
+		if r, ok := ambiguousReplacements[replaced[i]]; ok {
+			replaced[i] = r
+		}
+	}
+	if candidate := string(replaced); candidate != code && g.VerifyChecksum(candidate) {
+		return candidate
+	}
+
+	for i := 0; i < len(replaced)-1; i++ {
+		swapped := append([]byte(nil), replaced...)
+		swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+		if candidate := string(swapped); candidate != code && g.VerifyChecksum(candidate) {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// checkChar computes the check character for the given input. Each position
+// is weighted by a distinct integer coprime to the alphabet size, so every
+// single-character substitution changes the sum and is detected.
+func (g *Generator) checkChar(input string) byte {
+	n := len(g.alphabet)
+	sum := 0
+	weight := 0
+
+	for i := 0; i < len(input); i++ {
+		weight = nextCoprime(weight, n)
+		sum += weight * strings.IndexByte(g.alphabet, input[i])
+	}
+
+	return g.alphabet[(n-sum%n)%n]
+}
+
+// nextCoprime returns the smallest integer greater than after that is
+// coprime to n.
+func nextCoprime(after, n int) int {
+	for k := after + 1; ; k++ {
+		a, b := k, n
+		for b != 0 {
+			a, b = b, a%b
+		}
+		if a == 1 {
+			return k
+		}
+	}
+}
+
+func (g *Generator) inAlphabet(code string) bool {
+	for i := 0; i < len(code); i++ {
+		if strings.IndexByte(g.alphabet, code[i]) < 0 {
+			return false
+		}
+	}
+	return true
 }
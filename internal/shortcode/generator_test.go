@@ -1,12 +1,17 @@
 package shortcode_test
 
 import (
+	"crypto/rand"
+	"fmt"
+	"math"
+	"math/big"
 	"strings"
 	"testing"
 
 	"url-shortener/internal/shortcode"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGenerator_ExcludesAmbiguousCharacters(t *testing.T) {
@@ -59,3 +64,316 @@ func TestGenerator_ProducesUniqueCodesStatistically(t *testing.T) {
 	// (collision probability is negligible)
 	assert.Len(t, seen, count, "all generated codes should be unique")
 }
+
+func TestGenerator_GenerateBatch(t *testing.T) {
+	gen, err := shortcode.NewGeneratorWithConfig(6, "abc123")
+	require.NoError(t, err)
+
+	codes := gen.GenerateBatch(500)
+	require.Len(t, codes, 500)
+
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		assert.Len(t, code, 6)
+		for _, c := range code {
+			assert.True(t, strings.ContainsRune("abc123", c), "code %q contains invalid char %q", code, string(c))
+		}
+		seen[code] = true
+	}
+	assert.Greater(t, len(seen), 490, "batched codes should be independent")
+
+	assert.Empty(t, gen.GenerateBatch(0))
+}
+
+func TestGenerator_GenerateBatch_WithChecksum(t *testing.T) {
+	gen := shortcode.NewGenerator(shortcode.WithChecksum())
+
+	for _, code := range gen.GenerateBatch(200) {
+		assert.Len(t, code, 8)
+		assert.True(t, gen.VerifyChecksum(code), "code %q should pass its checksum", code)
+	}
+}
+
+func TestGenerator_CharacterFrequenciesAreUniform(t *testing.T) {
+	// 62 characters is the worst case for modulo bias: 256 % 62 leaves 8
+	// characters a quarter more likely unless excess bytes are rejected.
+	alphabets := map[string]string{
+		"default":      shortcode.Alphabet,
+		"alphanumeric": "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz",
+	}
+
+	for name, alphabet := range alphabets {
+		t.Run(name, func(t *testing.T) {
+			gen, err := shortcode.NewGeneratorWithConfig(8, alphabet)
+			require.NoError(t, err)
+
+			counts := make(map[rune]int, len(alphabet))
+			total := 0
+			for _, code := range gen.GenerateBatch(25000) {
+				for _, c := range code {
+					counts[c]++
+					total++
+				}
+			}
+
+			// Pearson's chi-square test at a significance level of 1e-6: a
+			// fair generator fails it once in a million runs, while the
+			// modulo bias above puts the statistic at ten or more times the
+			// critical value.
+			expected := float64(total) / float64(len(alphabet))
+			var chiSquare float64
+			for _, c := range alphabet {
+				d := float64(counts[c]) - expected
+				chiSquare += d * d / expected
+			}
+			assert.Less(t, chiSquare, chiSquareCritical(len(alphabet)-1),
+				"character frequencies don't fit a uniform distribution: %v", counts)
+		})
+	}
+}
+
+// chiSquareCritical approximates the chi-square value with df degrees of
+// freedom exceeded with probability 1e-6, by the Wilson-Hilferty
+// transformation, which is accurate to well under 1% for df above 30.
+func chiSquareCritical(df int) float64 {
+	const z = 4.7534 // upper 1e-6 quantile of the standard normal
+	k := float64(df)
+	return k * math.Pow(1-2/(9*k)+z*math.Sqrt(2/(9*k)), 3)
+}
+
+func TestGenerator_WithChecksum_ProducesValidCodes(t *testing.T) {
+	gen := shortcode.NewGenerator(shortcode.WithChecksum())
+
+	for i := 0; i < 1000; i++ {
+		code := gen.Generate()
+		assert.Len(t, code, 8, "checksum must not change code length")
+		assert.True(t, gen.VerifyChecksum(code), "code %q should pass its checksum", code)
+	}
+}
+
+func TestGenerator_WithChecksum_DetectsSingleCharacterTypos(t *testing.T) {
+	gen := shortcode.NewGenerator(shortcode.WithChecksum())
+	allowed := "23456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+	for i := 0; i < 100; i++ {
+		code := gen.Generate()
+		for pos := 0; pos < len(code); pos++ {
+			for _, c := range allowed {
+				if byte(c) == code[pos] {
+					continue
+				}
+				corrupted := code[:pos] + string(c) + code[pos+1:]
+				assert.False(t, gen.VerifyChecksum(corrupted),
+					"corrupted code %q (from %q) should fail checksum", corrupted, code)
+			}
+		}
+	}
+}
+
+func TestGenerator_WithChecksum_SuggestsIntendedCode(t *testing.T) {
+	gen := shortcode.NewGenerator(shortcode.WithChecksum())
+
+	for i := 0; i < 100; i++ {
+		code := gen.Generate()
+		if code[0] == code[1] {
+			continue
+		}
+
+		transposed := string([]byte{code[1], code[0]}) + code[2:]
+		if gen.VerifyChecksum(transposed) {
+			continue // A small fraction of transpositions keep the same sum
+		}
+
+		assert.NotEmpty(t, gen.Suggest(transposed), "should suggest a fix for %q", transposed)
+	}
+}
+
+func TestGenerator_WithoutChecksum_AcceptsAnyCode(t *testing.T) {
+	gen := shortcode.NewGenerator()
+
+	assert.True(t, gen.VerifyChecksum("anything"))
+	assert.Empty(t, gen.Suggest("anything"))
+}
+
+func TestNewGeneratorWithConfig_CustomLengthAndAlphabet(t *testing.T) {
+	testCases := []struct {
+		name     string
+		length   int
+		alphabet string
+	}{
+		{name: "short friendly codes", length: 6, alphabet: shortcode.Alphabet},
+		{name: "long high-volume codes", length: 10, alphabet: shortcode.Alphabet},
+		{name: "lowercase only", length: 8, alphabet: "abcdefghijkmnpqrstuvwxyz"},
+		{name: "binary alphabet at min length", length: shortcode.MinLength, alphabet: "01"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gen, err := shortcode.NewGeneratorWithConfig(tc.length, tc.alphabet)
+			require.NoError(t, err)
+
+			for i := 0; i < 200; i++ {
+				code := gen.Generate()
+				assert.Len(t, code, tc.length)
+				for _, c := range code {
+					assert.True(t, strings.ContainsRune(tc.alphabet, c),
+						"code %q contains char %q outside alphabet", code, string(c))
+				}
+			}
+		})
+	}
+}
+
+func TestNewGeneratorWithConfig_WithChecksum(t *testing.T) {
+	gen, err := shortcode.NewGeneratorWithConfig(6, "abcdefghijk", shortcode.WithChecksum())
+	require.NoError(t, err)
+
+	for i := 0; i < 200; i++ {
+		code := gen.Generate()
+		assert.Len(t, code, 6)
+		assert.True(t, gen.VerifyChecksum(code), "generated code %q should verify", code)
+	}
+}
+
+func TestNewGeneratorWithConfig_RejectsInvalidConfig(t *testing.T) {
+	testCases := []struct {
+		name     string
+		length   int
+		alphabet string
+	}{
+		{name: "length below minimum", length: 3, alphabet: shortcode.Alphabet},
+		{name: "zero length", length: 0, alphabet: shortcode.Alphabet},
+		{name: "empty alphabet", length: 8, alphabet: ""},
+		{name: "single character alphabet", length: 8, alphabet: "a"},
+		{name: "duplicate characters", length: 8, alphabet: "abcabc"},
+		{name: "hyphen reserved for aliases", length: 8, alphabet: "abc-"},
+		{name: "url-unsafe characters", length: 8, alphabet: "abc/?"},
+		{name: "non-ascii characters", length: 8, alphabet: "abcé"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gen, err := shortcode.NewGeneratorWithConfig(tc.length, tc.alphabet)
+			assert.Error(t, err)
+			assert.Nil(t, gen)
+		})
+	}
+}
+
+func TestHashGenerator_IsDeterministic(t *testing.T) {
+	gen, err := shortcode.NewHashGenerator("salt", 8, shortcode.Alphabet)
+	require.NoError(t, err)
+
+	code := gen.GenerateFor("https://example.com/page")
+	assert.Len(t, code, 8)
+	assert.Equal(t, code, gen.GenerateFor("https://example.com/page"))
+
+	again, err := shortcode.NewHashGenerator("salt", 8, shortcode.Alphabet)
+	require.NoError(t, err)
+	assert.Equal(t, code, again.GenerateFor("https://example.com/page"),
+		"a new generator with the same salt should derive the same code")
+
+	salted, err := shortcode.NewHashGenerator("other-salt", 8, shortcode.Alphabet)
+	require.NoError(t, err)
+	assert.NotEqual(t, code, salted.GenerateFor("https://example.com/page"))
+}
+
+func TestHashGenerator_DifferentURLsDiffer(t *testing.T) {
+	gen, err := shortcode.NewHashGenerator("salt", 8, shortcode.Alphabet)
+	require.NoError(t, err)
+
+	codes := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		url := fmt.Sprintf("https://example.com/page/%d", i)
+		code := gen.GenerateFor(url)
+		if prev, dup := codes[code]; dup {
+			t.Fatalf("%q and %q both hashed to %q", prev, url, code)
+		}
+		codes[code] = url
+	}
+}
+
+func TestHashGenerator_UsesConfiguredAlphabet(t *testing.T) {
+	const alphabet = "abcdef"
+	gen, err := shortcode.NewHashGenerator("salt", 12, alphabet)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		code := gen.GenerateFor(fmt.Sprintf("https://example.com/%d", i))
+		assert.Len(t, code, 12)
+		for _, c := range code {
+			assert.True(t, strings.ContainsRune(alphabet, c), "code %q has char %q outside alphabet", code, c)
+		}
+		assert.Len(t, gen.Generate(), 12)
+	}
+}
+
+func TestNewHashGenerator_RejectsInvalidConfig(t *testing.T) {
+	_, err := shortcode.NewHashGenerator("salt", 2, shortcode.Alphabet)
+	assert.Error(t, err)
+
+	_, err = shortcode.NewHashGenerator("salt", 64, shortcode.Alphabet)
+	assert.Error(t, err)
+
+	_, err = shortcode.NewHashGenerator("salt", 8, "a")
+	assert.Error(t, err)
+}
+
+// generatePerChar is the previous Generate, which drew each character with
+// its own crypto/rand.Int call. It is kept as a benchmark baseline.
+func generatePerChar(alphabet string, length int) string {
+	b := make([]byte, length)
+	alphabetLen := big.NewInt(int64(len(alphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, alphabetLen)
+		if err != nil {
+			panic(err)
+		}
+		b[i] = alphabet[n.Int64()]
+	}
+	return string(b)
+}
+
+func BenchmarkGenerate_PerCharRandInt(b *testing.B) {
+	for b.Loop() {
+		generatePerChar(shortcode.Alphabet, shortcode.DefaultLength)
+	}
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	gen := shortcode.NewGenerator()
+	for b.Loop() {
+		gen.Generate()
+	}
+}
+
+func BenchmarkGenerateBatch100(b *testing.B) {
+	gen := shortcode.NewGenerator()
+	for b.Loop() {
+		gen.GenerateBatch(100)
+	}
+}
+
+func TestValidatePrefix(t *testing.T) {
+	for _, prefix := range []string{"t1-", "acme_", "A", "0123456789abcdef"} {
+		assert.NoError(t, shortcode.ValidatePrefix(prefix), prefix)
+	}
+	for _, prefix := range []string{"", "t1/", "t 1", "tenant.", "0123456789abcdefg"} {
+		assert.Error(t, shortcode.ValidatePrefix(prefix), prefix)
+	}
+}
+
+func TestGenerator_CodeSpace(t *testing.T) {
+	g, err := shortcode.NewGeneratorWithConfig(5, "abc")
+	require.NoError(t, err)
+	assert.InDelta(t, 243, g.CodeSpace(), 1e-9)
+
+	// The check character is derived, so it adds no codes.
+	g, err = shortcode.NewGeneratorWithConfig(5, "abc", shortcode.WithChecksum())
+	require.NoError(t, err)
+	assert.InDelta(t, 81, g.CodeSpace(), 1e-9)
+
+	h, err := shortcode.NewHashGenerator("salt", 5, "abc")
+	require.NoError(t, err)
+	assert.InDelta(t, 243, h.CodeSpace(), 1e-9)
+}
@@ -0,0 +1,72 @@
+package shortcode
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// maxHashLength keeps hashed codes well within the entropy of a SHA-256
+// digest for any valid alphabet.
+const maxHashLength = 32
+
+// HashGenerator derives codes deterministically from the long URL, so the
+// same URL always maps to the same code without a lookup.
+type HashGenerator struct {
+	salt     string
+	alphabet string
+	length   int
+
+	// fallback produces random codes for Generate, used when the hashed
+	// code is already taken by a different URL.
+	fallback *Generator
+}
+
+// NewHashGenerator creates a generator whose codes are the SHA-256 digest of
+// salt plus the long URL, encoded in the given alphabet and truncated to
+// length. Changing the salt changes every code.
+func NewHashGenerator(salt string, length int, alphabet string) (*HashGenerator, error) {
+	if length > maxHashLength {
+		return nil, fmt.Errorf("hashed code length must not exceed %d, got %d", maxHashLength, length)
+	}
+	fallback, err := NewGeneratorWithConfig(length, alphabet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HashGenerator{
+		salt:     salt,
+		alphabet: alphabet,
+		length:   length,
+		fallback: fallback,
+	}, nil
+}
+
+// GenerateFor returns the code for longURL. It is deterministic for a given
+// salt, length, and alphabet.
+func (g *HashGenerator) GenerateFor(longURL string) string {
+	digest := sha256.Sum256([]byte(g.salt + longURL))
+
+	n := new(big.Int).SetBytes(digest[:])
+	base := big.NewInt(int64(len(g.alphabet)))
+	digit := new(big.Int)
+
+	b := make([]byte, g.length)
+	for i := range b {
+		n.DivMod(n, base, digit)
+		b[i] = g.alphabet[digit.Int64()]
+	}
+
+	return string(b)
+}
+
+// CodeSpace returns how many distinct codes the generator can produce.
+func (g *HashGenerator) CodeSpace() float64 {
+	return g.fallback.CodeSpace()
+}
+
+// Generate returns a random code of the same shape, for use when the
+// hashed code collides with a different URL.
+func (g *HashGenerator) Generate() string {
+	return g.fallback.Generate()
+}
@@ -0,0 +1,22 @@
+package shortcode
+
+import "fmt"
+
+// MaxPrefixLength keeps namespaced codes short enough to type.
+const MaxPrefixLength = 16
+
+// ValidatePrefix checks a prefix that namespaces generated codes, such as
+// "t1-". It must be 1 to MaxPrefixLength ASCII letters, digits, '-', or
+// '_', so namespaced codes stay safe in URL paths.
+func ValidatePrefix(prefix string) error {
+	if prefix == "" || len(prefix) > MaxPrefixLength {
+		return fmt.Errorf("code prefix must be 1 to %d characters, got %q", MaxPrefixLength, prefix)
+	}
+	for i := 0; i < len(prefix); i++ {
+		c := prefix[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return fmt.Errorf("code prefix %q contains invalid character %q", prefix, c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Package webhook delivers link lifecycle events to an HTTP endpoint.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"url-shortener/internal/domain"
+)
+
+// Event types sent in Event.Type.
+const (
+	EventLinkCreated  = "link.created"
+	EventLinksExpired = "links.expired"
+)
+
+// Defaults for the Config fields left zero.
+const (
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultTimeout        = 5 * time.Second
+)
+
+// Event is the JSON body POSTed to the webhook.
+type Event struct {
+	Type       string `json:"type"`
+	OccurredAt string `json:"occurred_at"`
+
+	// Link is set for link.created.
+	Link *Link `json:"link,omitempty"`
+
+	// ShortCodes is set for links.expired.
+	ShortCodes []string `json:"short_codes,omitempty"`
+}
+
+// Link describes a created link.
+type Link struct {
+	ShortCode string `json:"short_code"`
+	LongURL   string `json:"long_url"`
+	CreatedAt string `json:"created_at"`
+
+	// ExpiresAt is null for links that never expire.
+	ExpiresAt *string `json:"expires_at"`
+}
+
+// Config configures a Notifier.
+type Config struct {
+	// URL receives each event as a JSON POST.
+	URL string
+
+	// MaxAttempts bounds deliveries of one event, including the first.
+	// It defaults to 3.
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the first retry, doubling for each
+	// one after. It defaults to 500ms.
+	InitialBackoff time.Duration
+
+	// Client sends the requests. It defaults to a client with a 5s
+	// timeout.
+	Client *http.Client
+
+	// Clock stamps OccurredAt. It defaults to domain.RealClock.
+	Clock domain.Clock
+}
+
+// Notifier POSTs link events to a webhook, retrying with exponential
+// backoff when the request fails or the receiver answers 429 or 5xx. It
+// implements service.Notifier.
+type Notifier struct {
+	cfg Config
+}
+
+// New creates a Notifier for cfg.
+func New(cfg Config) *Notifier {
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = defaultInitialBackoff
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultTimeout}
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = domain.RealClock{}
+	}
+	return &Notifier{cfg: cfg}
+}
+
+// OnCreated sends a link.created event for record.
+func (n *Notifier) OnCreated(ctx context.Context, record *domain.URLRecord) {
+	link := &Link{
+		ShortCode: record.ShortCode,
+		LongURL:   record.LongURL,
+		CreatedAt: record.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if !record.NeverExpires() {
+		expiresAt := record.ExpiresAt.UTC().Format(time.RFC3339)
+		link.ExpiresAt = &expiresAt
+	}
+	n.send(ctx, Event{Type: EventLinkCreated, Link: link})
+}
+
+// OnExpired sends a links.expired event listing codes.
+func (n *Notifier) OnExpired(ctx context.Context, codes []string) {
+	n.send(ctx, Event{Type: EventLinksExpired, ShortCodes: codes})
+}
+
+// send delivers event, logging it as dropped once the attempts run out or
+// ctx is done.
+func (n *Notifier) send(ctx context.Context, event Event) {
+	event.OccurredAt = n.cfg.Clock.Now().UTC().Format(time.RFC3339)
+	body, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("encoding webhook event", "type", event.Type, "error", err)
+		return
+	}
+
+	backoff := n.cfg.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(ctx, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt == n.cfg.MaxAttempts {
+			slog.Warn("webhook delivery failed, dropping event",
+				"type", event.Type, "attempts", attempt, "error", err)
+			return
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			slog.Warn("webhook delivery canceled, dropping event",
+				"type", event.Type, "attempts", attempt, "error", ctx.Err())
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// post makes one delivery attempt, reporting whether a failure is worth
+// retrying.
+func (n *Notifier) post(ctx context.Context, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.cfg.Client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook answered %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("webhook answered %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,139 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"url-shortener/internal/domain"
+	"url-shortener/internal/webhook"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver records the events POSTed to it, answering with statuses in
+// turn and 204 once they run out.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	events   []webhook.Event
+	calls    int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls++
+	var event webhook.Event
+	if err := json.NewDecoder(req.Body).Decode(&event); err == nil && req.Header.Get("Content-Type") == "application/json" {
+		r.events = append(r.events, event)
+	}
+
+	status := http.StatusNoContent
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func newNotifier(t *testing.T, statuses ...int) (*webhook.Notifier, *receiver) {
+	t.Helper()
+	recv := &receiver{statuses: statuses}
+	srv := httptest.NewServer(recv)
+	t.Cleanup(srv.Close)
+
+	return webhook.New(webhook.Config{
+		URL:            srv.URL,
+		InitialBackoff: time.Millisecond,
+		Clock:          domain.NewMockClock(time.Date(2024, 1, 15, 12, 30, 0, 0, time.UTC)),
+	}), recv
+}
+
+func TestNotifier_OnCreated(t *testing.T) {
+	n, recv := newNotifier(t)
+
+	n.OnCreated(context.Background(), &domain.URLRecord{
+		ShortCode: "Ab2CdE3F",
+		LongURL:   "https://example.com",
+		CreatedAt: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+		ExpiresAt: time.Date(2024, 1, 16, 12, 0, 0, 0, time.UTC),
+	})
+	n.OnCreated(context.Background(), &domain.URLRecord{ShortCode: "forever1", LongURL: "https://example.com"})
+
+	require.Len(t, recv.events, 2)
+	event := recv.events[0]
+	assert.Equal(t, webhook.EventLinkCreated, event.Type)
+	assert.Equal(t, "2024-01-15T12:30:00Z", event.OccurredAt)
+	require.NotNil(t, event.Link)
+	assert.Equal(t, "Ab2CdE3F", event.Link.ShortCode)
+	assert.Equal(t, "https://example.com", event.Link.LongURL)
+	assert.Equal(t, "2024-01-15T12:00:00Z", event.Link.CreatedAt)
+	require.NotNil(t, event.Link.ExpiresAt)
+	assert.Equal(t, "2024-01-16T12:00:00Z", *event.Link.ExpiresAt)
+	assert.Nil(t, recv.events[1].Link.ExpiresAt, "never-expiring links have no expiry")
+}
+
+func TestNotifier_OnExpired(t *testing.T) {
+	n, recv := newNotifier(t)
+
+	n.OnExpired(context.Background(), []string{"expired1", "expired2"})
+
+	require.Len(t, recv.events, 1)
+	assert.Equal(t, webhook.EventLinksExpired, recv.events[0].Type)
+	assert.Equal(t, []string{"expired1", "expired2"}, recv.events[0].ShortCodes)
+	assert.Nil(t, recv.events[0].Link)
+}
+
+func TestNotifier_RetriesServerErrors(t *testing.T) {
+	n, recv := newNotifier(t, http.StatusInternalServerError, http.StatusTooManyRequests)
+
+	n.OnExpired(context.Background(), []string{"expired1"})
+
+	assert.Equal(t, 3, recv.calls)
+	assert.Len(t, recv.events, 3, "each attempt resends the event")
+}
+
+func TestNotifier_GivesUpAfterMaxAttempts(t *testing.T) {
+	n, recv := newNotifier(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+
+	n.OnExpired(context.Background(), []string{"expired1"})
+
+	assert.Equal(t, 3, recv.calls)
+}
+
+func TestNotifier_DoesNotRetryClientErrors(t *testing.T) {
+	n, recv := newNotifier(t, http.StatusBadRequest)
+
+	n.OnExpired(context.Background(), []string{"expired1"})
+
+	assert.Equal(t, 1, recv.calls)
+}
+
+func TestNotifier_StopsRetryingWhenCanceled(t *testing.T) {
+	recv := &receiver{statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}}
+	srv := httptest.NewServer(recv)
+	t.Cleanup(srv.Close)
+	n := webhook.New(webhook.Config{URL: srv.URL, InitialBackoff: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	done := make(chan struct{})
+	go func() {
+		n.OnExpired(ctx, []string{"expired1"})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnExpired kept backing off after its context was canceled")
+	}
+	assert.Equal(t, 1, recv.calls)
+}